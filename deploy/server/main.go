@@ -19,6 +19,9 @@ import (
 	"time"
 
 	"github.com/ahmed-agiza/EDAViewer/server/goopendb"
+	_ "github.com/ahmed-agiza/EDAViewer/server/lefdef" // Registers the native parsing backend
+	"github.com/ahmed-agiza/EDAViewer/server/render"
+	"github.com/ahmed-agiza/EDAViewer/server/verify"
 	"github.com/apex/gateway"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/session"
@@ -35,9 +38,10 @@ type ReponseMessage struct {
 
 // UploadedDesign models the parsing request payload
 type UploadedDesign struct {
-	Meta   []goopendb.DesignFile
-	Files  []string
-	Delete []string
+	Meta    []goopendb.DesignFile
+	Files   []string
+	Delete  []string
+	Options goopendb.JSONOptions
 }
 
 // SigningResponseUpload models the upload field in the SigningResponse
@@ -183,14 +187,14 @@ func signS3Delete(key string) (string, error) {
 }
 
 // Uploads file to S3
-func uploadToS3(content []byte) (*SigningResponse, error) {
+func uploadToS3(content io.Reader, fileName string) (*SigningResponse, error) {
 	uploadBucket := os.Getenv("S3_BUCKET")
-	key := generateKeyPrefix() + "/design.json"
+	key := generateKeyPrefix() + "/" + fileName
 	uploader := s3manager.NewUploader(awsSession)
 	_, err := uploader.Upload(&s3manager.UploadInput{
 		Bucket: aws.String(uploadBucket),
 		Key:    aws.String(key),
-		Body:   bytes.NewReader(content),
+		Body:   content,
 	})
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "%v\n", err.Error())
@@ -314,14 +318,37 @@ func UploadHandler(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 	}
-	design, err := goopendb.ParseDesignToJSON(designFiles, false)
+	parsedDesign, err := goopendb.ParseDesign(designFiles)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	if uploadedReq.Options.Thumbnail {
+		if parsedDesign.Thumbnail, err = render.Thumbnail(parsedDesign); err != nil {
+			fmt.Fprintf(os.Stderr, "Thumbnail error: %v\n", err)
+		}
+	}
+	if parsedDesign.Markers, err = verify.Run(parsedDesign, uploadedReq.Options.Checks); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err = uploadedReq.Options.Validate(parsedDesign); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
-	// Upload results to S3
-	signData, err := uploadToS3(design)
+	// Stream the design to S3 while it is encoded
+	designReader, designWriter := io.Pipe()
+	go func() {
+		designWriter.CloseWithError(goopendb.WriteDesign(designWriter, parsedDesign, &uploadedReq.Options))
+	}()
+	fileName := "design.json"
+	if uploadedReq.Options.Format == goopendb.FormatProtobuf {
+		fileName = "design.pb"
+	}
+	signData, err := uploadToS3(designReader, fileName)
+	// Unblocks the encoder if the upload stopped reading
+	designReader.Close()
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "%v\n", err.Error())
 		http.Error(w, "Failed to parse the design", 500)
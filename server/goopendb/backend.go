@@ -0,0 +1,99 @@
+package goopendb
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+)
+
+// Parsing backend names
+const (
+	BackendOpenDB = "opendb" // OpenDB through cgo
+	BackendNative = "native" // Pure Go LEF/DEF parser
+)
+
+// BackendEnv is the environment variable that overrides the default backend
+const BackendEnv = "EDAV_BACKEND"
+
+// Backend is a LEF/DEF parser that builds a Design
+type Backend interface {
+	ParseLEF(filepath string) error
+	ParseLEFTechnology(filepath string) error
+	ParseLEFLibrary(filepath string) error
+	ParseDEF(filepath string) error
+	GetDesign() (*Design, error)
+	FreeDatabase() error
+}
+
+// LEFOnlyBackend is a Backend that builds designs from LEF files without a
+// DEF file
+type LEFOnlyBackend interface {
+	Backend
+	SupportsLEFOnly() bool
+}
+
+// supportsLEFOnly checks if GetDesign works without ParseDEF
+func supportsLEFOnly(db Backend) bool {
+	lefOnly, ok := db.(LEFOnlyBackend)
+	return ok && lefOnly.SupportsLEFOnly()
+}
+
+// BackendFactory creates a new backend instance
+type BackendFactory func() (Backend, error)
+
+var backendsMu sync.RWMutex
+var backends = make(map[string]BackendFactory)
+
+// RegisterBackend makes a backend available by name, backends register themselves in init
+func RegisterBackend(name string, factory BackendFactory) {
+	backendsMu.Lock()
+	defer backendsMu.Unlock()
+	if factory == nil {
+		panic("goopendb: RegisterBackend factory is nil")
+	}
+	if _, dup := backends[name]; dup {
+		panic("goopendb: RegisterBackend called twice for backend " + name)
+	}
+	backends[name] = factory
+}
+
+// Backends returns the sorted names of the registered backends
+func Backends() []string {
+	backendsMu.RLock()
+	defer backendsMu.RUnlock()
+	var names []string
+	for name := range backends {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// DefaultBackend returns the backend named by EDAV_BACKEND, falling back to
+// OpenDB when it is compiled in and the native parser otherwise
+func DefaultBackend() string {
+	if name, ok := os.LookupEnv(BackendEnv); ok && len(name) > 0 {
+		return name
+	}
+	backendsMu.RLock()
+	defer backendsMu.RUnlock()
+	if _, ok := backends[BackendOpenDB]; ok {
+		return BackendOpenDB
+	}
+	return BackendNative
+}
+
+// NewBackend creates a backend by name, empty name selects the default backend
+func NewBackend(name string) (Backend, error) {
+	if len(name) == 0 {
+		name = DefaultBackend()
+	}
+	backendsMu.RLock()
+	factory, ok := backends[name]
+	backendsMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("Unknown parsing backend %v", name)
+	}
+	return factory()
+}
@@ -6,9 +6,13 @@
 #include "opendb/defin.h"
 #include "opendb/geom.h"
 #include "opendb/lefin.h"
+#include <algorithm>
+#include <cstring>
 #include <map>
 #include <memory>
 #include <signal.h>
+#include <string>
+#include <tuple>
 #include <vector>
 
 dbDatabase DatabaseNew(void) {
@@ -84,6 +88,42 @@ double DbuToMeters(dbDatabase dbPtr, int dist) {
   return dist / (db->getTech()->getDbUnitsPerMicron() * 1e+6);
 }
 
+// Populates the generated via parameters and per-layer shapes of dbVia or
+// dbTechVia
+template <typename T>
+static void
+populateViaShapes(Via *via, T *dbVia, std::map<uint, Rect *> &rectMap,
+                  uint &rectId,
+                  std::map<odb::dbTechViaGenerateRule *, ViaRule *> &ruleMap) {
+  via->hasParams = dbVia->hasParams();
+  memset(&via->params, 0, sizeof(ViaParams));
+  if (via->hasParams) {
+    odb::dbViaParams viaParams;
+    dbVia->getViaParams(viaParams);
+    via->params = castViaParams((void *)&viaParams);
+  }
+  via->pattern = nullptr;
+  std::string pattern = dbVia->getPattern();
+  if (!pattern.empty()) {
+    via->pattern = strdup(pattern.c_str());
+  }
+  via->rule = nullptr;
+  odb::dbTechViaGenerateRule *rule = dbVia->getViaGenerateRule();
+  if (rule && ruleMap.count(rule)) {
+    via->rule = ruleMap[rule];
+  }
+  odb::dbSet<odb::dbBox> boxes = dbVia->getBoxes();
+  via->boxSz = boxes.size();
+  via->boxes = (Rect **)malloc(via->boxSz * sizeof(Rect *));
+  int boxIndex = 0;
+  for (odb::dbSet<odb::dbBox>::iterator boxIt = boxes.begin();
+       boxIt != boxes.end(); ++boxIt) {
+    Rect *box = castBox(*boxIt, rectId++);
+    via->boxes[boxIndex++] = box;
+    rectMap[box->id] = box;
+  }
+}
+
 Design *GetDesign(dbDatabase dbPtr) {
   odb::dbDatabase *db = (odb::dbDatabase *)dbPtr;
 
@@ -100,8 +140,15 @@ Design *GetDesign(dbDatabase dbPtr) {
   odb::dbGCellGrid *gcellGrid = block->getGCellGrid();
   odb::dbSet<odb::dbRow> rowSet = block->getRows();
   odb::dbSet<odb::dbLib> libSet = db->getLibs();
+  odb::dbSet<odb::dbBlockage> blockageSet = block->getBlockages();
+  odb::dbSet<odb::dbObstruction> obstructionSet = block->getObstructions();
+  odb::dbSet<odb::dbRegion> regionSet = block->getRegions();
+  odb::dbSet<odb::dbFill> fillSet = block->getFills();
 
   design->name = strdup(block->getConstName());
+  design->dbuPerMicron = db->getTech()->getDbUnitsPerMicron();
+  design->lefUnits = db->getTech()->getLefUnits();
+  design->defUnits = block->getDefUnits();
 
   std::map<uint, Rect *> rectMap;
   std::map<uint, Instance *> instanceMap;
@@ -109,15 +156,23 @@ Design *GetDesign(dbDatabase dbPtr) {
   std::map<uint, Pin *> blockPinMap;
   std::map<uint, Net *> netMap;
   std::map<uint, Via *> viaMap;
-  std::map<uint, Via *> routingViaMap;
+  std::map<uint, Via *> techViaMap; // Tech and block via IDs overlap
+  // Block via IDs follow the tech via IDs so the design IDs are unique
+  uint blockViaOffset = db->getTech()->getVias().size();
+  std::vector<Via *> routingVias; // In the order of first use
   std::map<uint, Layer *> layerMap;
   std::map<uint, Site *> siteMap;
   std::map<uint, std::map<uint, Geometry *>>
       mpinGeometryMap; // master -> mpin -> geom
   std::map<uint, Geometry *> masterObsGeometryMap;
   std::map<uint, Geometry *> geometryMap;
+  std::map<odb::dbMaster *, Master *> masterMap;
+  std::map<odb::dbTechViaGenerateRule *, ViaRule *> viaRuleMap;
+  std::map<uint, Region *> regionMap;
+  std::map<uint, Group *> groupMap;
   uint geometryId = 1;
   uint rectId = 1;
+  uint blockageId = 1;
 
   /** Collect database objects **/
   // Layers
@@ -142,6 +197,120 @@ Design *GetDesign(dbDatabase dbPtr) {
     odb::dbTechLayerDir dir = it->getDirection();
     layer->layerType = castLayerType(&typ);
     layer->direction = castLayerDirection(&dir);
+    if (it->hasXYPitch()) {
+      layer->pitchX = it->getPitchX();
+      layer->pitchY = it->getPitchY();
+    } else {
+      layer->pitchX = it->getPitch();
+      layer->pitchY = it->getPitch();
+    }
+    if (it->hasXYOffset()) {
+      layer->offsetX = it->getOffsetX();
+      layer->offsetY = it->getOffsetY();
+    } else {
+      layer->offsetX = it->getOffset();
+      layer->offsetY = it->getOffset();
+    }
+    layer->minWidth = it->getMinWidth();
+    layer->maxWidth = -1;
+    if (it->hasMaxWidth()) {
+      layer->maxWidth = it->getMaxWidth();
+    }
+    layer->minStep = it->getMinStep();
+    uint thickness;
+    layer->thickness = -1;
+    if (it->getThickness(thickness)) {
+      layer->thickness = thickness;
+    }
+    layer->resistance = it->getResistance();
+    layer->capacitance = it->getCapacitance();
+    layer->edgeCapacitance = it->getEdgeCapacitance();
+
+    // Parallel run length spacing
+    layer->spacingTableWidths = nullptr;
+    layer->spacingTableWidthSz = 0;
+    layer->spacingTableLengths = nullptr;
+    layer->spacingTableLengthSz = 0;
+    layer->spacingTable = nullptr;
+    if (it->hasV55SpacingRules()) {
+      std::vector<uint> widths;
+      std::vector<uint> lengths;
+      std::vector<std::vector<uint>> table;
+      it->getV55SpacingWidthsAndLengths(widths, lengths);
+      it->getV55SpacingTable(table);
+      layer->spacingTableWidthSz = widths.size();
+      layer->spacingTableLengthSz = lengths.size();
+      layer->spacingTableWidths =
+          (int *)malloc(layer->spacingTableWidthSz * sizeof(int));
+      layer->spacingTableLengths =
+          (int *)malloc(layer->spacingTableLengthSz * sizeof(int));
+      layer->spacingTable =
+          (int *)malloc(layer->spacingTableWidthSz *
+                        layer->spacingTableLengthSz * sizeof(int));
+      for (size_t i = 0; i < widths.size(); ++i) {
+        layer->spacingTableWidths[i] = widths[i];
+        for (size_t j = 0; j < lengths.size(); ++j) {
+          int spacing = 0;
+          if (i < table.size() && j < table[i].size()) {
+            spacing = table[i][j];
+          }
+          layer->spacingTable[i * lengths.size() + j] = spacing;
+        }
+      }
+      for (size_t j = 0; j < lengths.size(); ++j) {
+        layer->spacingTableLengths[j] = lengths[j];
+      }
+    }
+
+    // Influence spacing
+    std::vector<odb::dbTechV55InfluenceEntry *> influenceRules;
+    it->getV55InfluenceRules(influenceRules);
+    layer->influenceSz = influenceRules.size();
+    layer->influenceWidths = (int *)malloc(layer->influenceSz * sizeof(int));
+    layer->influenceWithins = (int *)malloc(layer->influenceSz * sizeof(int));
+    layer->influenceSpacings =
+        (int *)malloc(layer->influenceSz * sizeof(int));
+    for (size_t i = 0; i < influenceRules.size(); ++i) {
+      uint width, within, spacing;
+      influenceRules[i]->getV55InfluenceEntry(width, within, spacing);
+      layer->influenceWidths[i] = width;
+      layer->influenceWithins[i] = within;
+      layer->influenceSpacings[i] = spacing;
+    }
+
+    // Minimum enclosed area
+    std::vector<odb::dbTechMinEncRule *> minEncRules;
+    it->getMinEnclosureRules(minEncRules);
+    layer->minEnclosedAreaSz = minEncRules.size();
+    layer->minEnclosedAreas =
+        (double *)malloc(layer->minEnclosedAreaSz * sizeof(double));
+    layer->minEnclosedAreaWidths =
+        (int *)malloc(layer->minEnclosedAreaSz * sizeof(int));
+    for (size_t i = 0; i < minEncRules.size(); ++i) {
+      uint area, width;
+      minEncRules[i]->getEnclosure(area);
+      layer->minEnclosedAreas[i] = area;
+      layer->minEnclosedAreaWidths[i] = -1;
+      if (minEncRules[i]->getEnclosureWidth(width)) {
+        layer->minEnclosedAreaWidths[i] = width;
+      }
+    }
+
+    // Antenna
+    layer->hasAntennaRule = it->hasDefaultAntennaRule();
+    layer->antennaAreaFactor = 0.0;
+    layer->antennaPAR = 0.0;
+    layer->antennaCAR = 0.0;
+    layer->antennaPSR = 0.0;
+    layer->antennaCSR = 0.0;
+    if (layer->hasAntennaRule) {
+      odb::dbTechLayerAntennaRule *antennaRule = it->getDefaultAntennaRule();
+      layer->antennaAreaFactor = antennaRule->getAreaFactor();
+      layer->antennaPAR = antennaRule->getPAR();
+      layer->antennaCAR = antennaRule->getCAR();
+      layer->antennaPSR = antennaRule->getPSR();
+      layer->antennaCSR = antennaRule->getCSR();
+    }
     layer->dbObject = (void *)*it;
     layers[index++] = layer;
     layerMap[it->getId()] = layer;
@@ -173,6 +342,8 @@ Design *GetDesign(dbDatabase dbPtr) {
     inst->orientation = castOrientation((void *)&(orient));
     inst->master = strdup(it->getMaster()->getConstName());
     inst->isPlaced = it->isPlaced();
+    odb::dbPlacementStatus status = it->getPlacementStatus();
+    inst->placementStatus = castPlacementStatus((void *)&status);
     inst->boundingBox = castBox(it->getBBox(), rectId++);
     inst->halo = castBox(it->getHalo(), rectId++);
     rectMap[inst->boundingBox->id] = inst->boundingBox;
@@ -203,6 +374,9 @@ Design *GetDesign(dbDatabase dbPtr) {
       inst->obstructions = geom;
     }
 
+    inst->region = nullptr;
+    inst->group = nullptr;
+    inst->masterRef = nullptr;
     inst->dbObject = (void *)*it;
     instances[index++] = inst;
     instanceMap[it->getId()] = inst;
@@ -321,6 +495,69 @@ Design *GetDesign(dbDatabase dbPtr) {
   }
   design->blockPins = blockPins;
 
+  // Via rules
+  odb::dbSet<odb::dbTechViaGenerateRule> viaRuleSet =
+      db->getTech()->getViaGenerateRules();
+  design->viaRuleSz = viaRuleSet.size();
+  design->viaRules =
+      (ViaRule **)malloc(design->viaRuleSz * sizeof(ViaRule *));
+  index = 0;
+  for (odb::dbSet<odb::dbTechViaGenerateRule>::iterator it =
+           viaRuleSet.begin();
+       it != viaRuleSet.end(); ++it) {
+    ViaRule *rule = (ViaRule *)malloc(sizeof(ViaRule));
+    rule->id = it->getId();
+    rule->name = strdup(it->getName().c_str());
+    rule->isDefault = it->isDefault();
+    rule->layerSz = it->getViaLayerRuleCount();
+    rule->layers =
+        (ViaRuleLayer *)malloc(rule->layerSz * sizeof(ViaRuleLayer));
+    for (int i = 0; i < rule->layerSz; i++) {
+      odb::dbTechViaLayerRule *layerRule = it->getViaLayerRule(i);
+      ViaRuleLayer *ruleLayer = &rule->layers[i];
+      ruleLayer->layer = nullptr;
+      if (layerRule->getLayer() &&
+          layerMap.count(layerRule->getLayer()->getId())) {
+        ruleLayer->layer = layerMap[layerRule->getLayer()->getId()];
+      }
+      odb::dbTechLayerDir dir = layerRule->getDirection();
+      ruleLayer->direction = castLayerDirection(&dir);
+      ruleLayer->hasEnclosure = layerRule->hasEnclosure();
+      ruleLayer->enclosureOverhang1 = 0;
+      ruleLayer->enclosureOverhang2 = 0;
+      if (ruleLayer->hasEnclosure) {
+        layerRule->getEnclosure(ruleLayer->enclosureOverhang1,
+                                ruleLayer->enclosureOverhang2);
+      }
+      ruleLayer->hasWidth = layerRule->hasWidth();
+      ruleLayer->minWidth = 0;
+      ruleLayer->maxWidth = 0;
+      if (ruleLayer->hasWidth) {
+        layerRule->getWidth(ruleLayer->minWidth, ruleLayer->maxWidth);
+      }
+      ruleLayer->rect = nullptr;
+      if (layerRule->hasRect()) {
+        odb::Rect ruleRect;
+        layerRule->getRect(ruleRect);
+        ruleLayer->rect = castRect((void *)&ruleRect, rectId++);
+        rectMap[ruleLayer->rect->id] = ruleLayer->rect;
+      }
+      ruleLayer->hasSpacing = layerRule->hasSpacing();
+      ruleLayer->spacingX = 0;
+      ruleLayer->spacingY = 0;
+      if (ruleLayer->hasSpacing) {
+        layerRule->getSpacing(ruleLayer->spacingX, ruleLayer->spacingY);
+      }
+      ruleLayer->resistance = 0.0;
+      if (layerRule->hasResistance()) {
+        ruleLayer->resistance = layerRule->getResistance();
+      }
+    }
+    rule->dbObject = (void *)*it;
+    design->viaRules[index++] = rule;
+    viaRuleMap[*it] = rule;
+  }
+
   // Vias
   design->viaDefinitionSz = viaDefinitionSet.size();
   Via **viaDefinitions =
@@ -330,7 +567,7 @@ Design *GetDesign(dbDatabase dbPtr) {
        it != viaDefinitionSet.end(); ++it) {
     Via *via = (Via *)malloc(sizeof(Via));
     odb::dbSet<odb::dbBox> boxes = it->getBoxes();
-    via->id = it->getId();
+    via->id = blockViaOffset + it->getId();
     via->name = strdup(it->getConstName());
     via->topLayer = nullptr;
     via->bottomLayer = nullptr;
@@ -365,6 +602,7 @@ Design *GetDesign(dbDatabase dbPtr) {
     via->rect = rect;
     via->isBlock = (it->getBlockVia() != nullptr);
     via->isTech = (it->getTechVia() != nullptr);
+    populateViaShapes(via, *it, rectMap, rectId, viaRuleMap);
     via->dbObject = (void *)*it;
     viaDefinitions[index++] = via;
     viaMap[it->getId()] = via;
@@ -386,6 +624,8 @@ Design *GetDesign(dbDatabase dbPtr) {
     net->isRouted =
         wire != nullptr || wireType == odb::dbWireType::Value::ROUTED;
     net->wireType = castWireType((void *)&wireType);
+    odb::dbSigType netSigType = it->getSigType();
+    net->use = castSignalType((void *)&netSigType);
     net->dbObject = (void *)*it;
     nets[index++] = net;
     netMap[it->getId()] = net;
@@ -393,6 +633,8 @@ Design *GetDesign(dbDatabase dbPtr) {
     net->edges = nullptr;
     net->specialBoxes = nullptr;
     net->specialBoxSz = 0;
+    net->specialWires = nullptr;
+    net->specialWireSz = 0;
     if (wire) {
       odb::dbRtTree wireTree;
       wireTree.decode(wire, true);
@@ -435,8 +677,8 @@ Design *GetDesign(dbDatabase dbPtr) {
           odb::dbRtTechVia *casted = (odb::dbRtTechVia *)rtEdge;
           odb::dbTechVia *dbVia = casted->getVia();
           int viaId = dbVia->getId();
-          if (viaMap.count(viaId)) {
-            edge.via = viaMap[viaId];
+          if (techViaMap.count(viaId)) {
+            edge.via = techViaMap[viaId];
           } else {
             Via *via = (Via *)malloc(sizeof(Via));
             via->id = dbVia->getId();
@@ -474,9 +716,10 @@ Design *GetDesign(dbDatabase dbPtr) {
                 via->cutLayer = layerMap[viaParams.getCutLayer()->getId()];
               }
             }
+            populateViaShapes(via, dbVia, rectMap, rectId, viaRuleMap);
             via->dbObject = (void *)dbVia;
-            routingViaMap[dbVia->getId()] = via;
-            viaMap[dbVia->getId()] = via;
+            routingVias.push_back(via);
+            techViaMap[dbVia->getId()] = via;
             edge.via = via;
           }
         } else if (rtEdge->getType() == odb::dbRtEdge::Type::VIA) {
@@ -487,7 +730,7 @@ Design *GetDesign(dbDatabase dbPtr) {
             edge.via = viaMap[viaId];
           } else {
             Via *via = (Via *)malloc(sizeof(Via));
-            via->id = dbVia->getId();
+            via->id = blockViaOffset + dbVia->getId();
             via->name = strdup(dbVia->getConstName());
             via->topLayer = nullptr;
             via->bottomLayer = nullptr;
@@ -522,8 +765,9 @@ Design *GetDesign(dbDatabase dbPtr) {
             via->rect = rect;
             via->isBlock = (dbVia->getBlockVia() != nullptr);
             via->isTech = (dbVia->getTechVia() != nullptr);
+            populateViaShapes(via, dbVia, rectMap, rectId, viaRuleMap);
             via->dbObject = (void *)dbVia;
-            routingViaMap[dbVia->getId()] = via;
+            routingVias.push_back(via);
             viaMap[dbVia->getId()] = via;
             edge.via = via;
           }
@@ -536,8 +780,11 @@ Design *GetDesign(dbDatabase dbPtr) {
     odb::dbSet<odb::dbSWire> swires = it->getSWires();
     if (swires.size()) {
       net->specialBoxSz = swires.size();
+      net->specialWireSz = swires.size();
       Geometry **specialBoxes =
           (Geometry **)malloc(net->specialBoxSz * sizeof(Geometry *));
+      SpecialWire *specialWires =
+          (SpecialWire *)malloc(net->specialWireSz * sizeof(SpecialWire));
       int geomIndex = 0;
       for (odb::dbSet<odb::dbSWire>::iterator swireIt = swires.begin();
            swireIt != swires.end(); ++swireIt) {
@@ -546,16 +793,40 @@ Design *GetDesign(dbDatabase dbPtr) {
         geom->id = geometryId++;
         geom->boxSz = boxes.size();
         geom->boxes = (Rect **)malloc(geom->boxSz * sizeof(Rect *));
+        SpecialWire *specialWire = &specialWires[geomIndex];
+        specialWire->id = swireIt->getId();
+        odb::dbWireType swireType = swireIt->getWireType();
+        specialWire->wireType = castWireType((void *)&swireType);
+        odb::dbNet *shield = swireIt->getShield();
+        specialWire->shieldNet =
+            shield ? strdup(shield->getConstName()) : nullptr;
+        specialWire->geometry = geom;
+        specialWire->shapeSz = boxes.size();
+        specialWire->shapes = (SpecialShape *)malloc(specialWire->shapeSz *
+                                                     sizeof(SpecialShape));
         int boxIndex = 0;
         for (odb::dbSet<odb::dbSBox>::iterator boxIt = boxes.begin();
              boxIt != boxes.end(); ++boxIt) {
           Rect *box = castBox(*boxIt, rectId++);
           odb::dbWireShapeType shapeType = boxIt->getWireShapeType();
           box->shapeType = castWireShapeType((void *)&shapeType);
+          SpecialShape *shape = &specialWire->shapes[boxIndex];
+          shape->rect = box;
+          shape->shapeType = box->shapeType;
+          shape->width = 0;
+          // OpenDB does not store the mask of special wires
+          shape->mask = 0;
           if (boxIt->getTechVia()) {
-            box->via = viaMap[boxIt->getTechVia()->getId()];
+            uint viaId = boxIt->getTechVia()->getId();
+            box->via = techViaMap.count(viaId) ? techViaMap[viaId] : nullptr;
           } else if (boxIt->getBlockVia()) {
             box->via = viaMap[boxIt->getBlockVia()->getId()];
+          } else if (boxIt->getDirection() == odb::dbSBox::HORIZONTAL) {
+            shape->width = boxIt->getDY();
+          } else if (boxIt->getDirection() == odb::dbSBox::VERTICAL) {
+            shape->width = boxIt->getDX();
+          } else {
+            shape->width = std::min(boxIt->getDX(), boxIt->getDY());
           }
           geom->boxes[boxIndex++] = box;
           rectMap[box->id] = box;
@@ -564,18 +835,17 @@ Design *GetDesign(dbDatabase dbPtr) {
         specialBoxes[geomIndex++] = geom;
       }
       net->specialBoxes = specialBoxes;
+      net->specialWires = specialWires;
     }
   }
 
   design->nets = nets;
 
   /** Routing VIAs **/
-  design->routingViaSz = routingViaMap.size();
+  design->routingViaSz = routingVias.size();
   design->routingVias = (Via **)malloc(design->routingViaSz * sizeof(Via *));
-  index = 0;
-  for (std::map<uint, Via *>::iterator it = routingViaMap.begin();
-       it != routingViaMap.end(); ++it) {
-    design->routingVias[index++] = it->second;
+  for (size_t i = 0; i < routingVias.size(); i++) {
+    design->routingVias[i] = routingVias[i];
   }
 
   /** Sites **/
@@ -587,6 +857,12 @@ Design *GetDesign(dbDatabase dbPtr) {
       Site *site = (Site *)malloc(sizeof(Site));
       site->id = siteIt->getId();
       site->name = strdup(siteIt->getConstName());
+      site->siteClass = strdup(siteIt->getClass().getString());
+      site->width = siteIt->getWidth();
+      site->height = siteIt->getHeight();
+      site->symmetryX = siteIt->getSymmetryX();
+      site->symmetryY = siteIt->getSymmetryY();
+      site->symmetryR90 = siteIt->getSymmetryR90();
       site->dbObject = (void *)*siteIt;
       siteMap[siteIt->getId()] = site;
     }
@@ -599,6 +875,122 @@ Design *GetDesign(dbDatabase dbPtr) {
     design->sites[index++] = it->second;
   }
 
+  /** Masters **/
+  // Master & terminal IDs are only unique within a library
+  uint masterId = 1;
+  uint masterPinId = 1;
+  std::vector<Master *> masterList;
+  for (odb::dbSet<odb::dbLib>::iterator it = libSet.begin(); it != libSet.end();
+       ++it) {
+    odb::dbSet<odb::dbMaster> masters = it->getMasters();
+    for (odb::dbSet<odb::dbMaster>::iterator masterIt = masters.begin();
+         masterIt != masters.end(); ++masterIt) {
+      odb::dbMaster *dbMaster = *masterIt;
+      Master *master = (Master *)malloc(sizeof(Master));
+      master->id = masterId++;
+      master->name = strdup(dbMaster->getConstName());
+      master->library = strdup(it->getConstName());
+      odb::dbMasterType masterType = dbMaster->getType();
+      master->masterType = castMasterType(&masterType);
+      master->masterClass = strdup(masterType.getString());
+      master->width = dbMaster->getWidth();
+      master->height = dbMaster->getHeight();
+      int x, y;
+      dbMaster->getOrigin(x, y);
+      master->origin.x = x;
+      master->origin.y = y;
+      master->site = nullptr;
+      if (dbMaster->getSite() && siteMap.count(dbMaster->getSite()->getId())) {
+        master->site = siteMap[dbMaster->getSite()->getId()];
+      }
+      master->symmetryX = dbMaster->getSymmetryX();
+      master->symmetryY = dbMaster->getSymmetryY();
+      master->symmetryR90 = dbMaster->getSymmetryR90();
+      master->isFiller = dbMaster->isFiller();
+
+      // Pins
+      odb::dbSet<odb::dbMTerm> mterms = dbMaster->getMTerms();
+      master->pinSz = mterms.size();
+      master->pins = (MasterPin *)malloc(master->pinSz * sizeof(MasterPin));
+      int pinIndex = 0;
+      for (odb::dbSet<odb::dbMTerm>::iterator mtermIt = mterms.begin();
+           mtermIt != mterms.end(); ++mtermIt) {
+        MasterPin *pin = &master->pins[pinIndex++];
+        pin->id = masterPinId++;
+        pin->name = strdup(mtermIt->getConstName());
+        odb::dbIoType ioType = mtermIt->getIoType();
+        pin->direction = castIoType((void *)&ioType);
+        odb::dbSigType sigType = mtermIt->getSigType();
+        pin->signalType = castSignalType((void *)&sigType);
+        odb::dbSet<odb::dbMPin> mpins = mtermIt->getMPins();
+        pin->geometrySz = mpins.size();
+        pin->geometries =
+            (Geometry **)malloc(pin->geometrySz * sizeof(Geometry *));
+        int geomIndex = 0;
+        for (odb::dbSet<odb::dbMPin>::iterator mpinIt = mpins.begin();
+             mpinIt != mpins.end(); ++mpinIt) {
+          if (mpinGeometryMap.count(dbMaster->getId()) &&
+              mpinGeometryMap[dbMaster->getId()].count(mpinIt->getId())) {
+            pin->geometries[geomIndex++] =
+                mpinGeometryMap[dbMaster->getId()][mpinIt->getId()];
+          } else {
+            Geometry *geom = (Geometry *)malloc(sizeof(Geometry));
+            geom->id = geometryId++;
+            odb::dbSet<odb::dbBox> boxes = mpinIt->getGeometry();
+            geom->boxSz = boxes.size();
+            geom->boxes = (Rect **)malloc(geom->boxSz * sizeof(Rect *));
+            int boxIndex = 0;
+            for (odb::dbSet<odb::dbBox>::iterator boxIt = boxes.begin();
+                 boxIt != boxes.end(); ++boxIt) {
+              Rect *box = castBox(*boxIt, rectId++);
+              geom->boxes[boxIndex++] = box;
+              rectMap[box->id] = box;
+            }
+            mpinGeometryMap[dbMaster->getId()][mpinIt->getId()] = geom;
+            geometryMap[geom->id] = geom;
+            pin->geometries[geomIndex++] = geom;
+          }
+        }
+      }
+
+      // Obstructions
+      if (masterObsGeometryMap.count(dbMaster->getId())) {
+        master->obstructions = masterObsGeometryMap[dbMaster->getId()];
+      } else {
+        Geometry *geom = (Geometry *)malloc(sizeof(Geometry));
+        odb::dbSet<odb::dbBox> obs = dbMaster->getObstructions();
+        geom->id = geometryId++;
+        geom->boxSz = obs.size();
+        geom->boxes = (Rect **)malloc(geom->boxSz * sizeof(Rect *));
+        int boxIndex = 0;
+        for (odb::dbSet<odb::dbBox>::iterator boxIt = obs.begin();
+             boxIt != obs.end(); ++boxIt) {
+          Rect *box = castBox(*boxIt, rectId++);
+          geom->boxes[boxIndex++] = box;
+          rectMap[box->id] = box;
+        }
+        masterObsGeometryMap[dbMaster->getId()] = geom;
+        geometryMap[geom->id] = geom;
+        master->obstructions = geom;
+      }
+      master->dbObject = (void *)dbMaster;
+      masterMap[dbMaster] = master;
+      masterList.push_back(master);
+    }
+  }
+  design->masterSz = masterList.size();
+  design->masters = (Master **)malloc(design->masterSz * sizeof(Master *));
+  for (size_t i = 0; i < masterList.size(); ++i) {
+    design->masters[i] = masterList[i];
+  }
+  for (std::map<uint, Instance *>::iterator it = instanceMap.begin();
+       it != instanceMap.end(); ++it) {
+    odb::dbInst *dbInst = (odb::dbInst *)it->second->dbObject;
+    if (masterMap.count(dbInst->getMaster())) {
+      it->second->masterRef = masterMap[dbInst->getMaster()];
+    }
+  }
+
   /** Tracks **/
   design->trackSz = trackGridSet.size();
   design->tracks = (Grid **)malloc(design->trackSz * sizeof(Grid *));
@@ -684,6 +1076,7 @@ Design *GetDesign(dbDatabase dbPtr) {
     row->originX = x;
     row->originY = y;
     row->spacing = it->getSpacing();
+    row->siteCount = it->getSiteCount();
     odb::Rect boundingBox;
     it->getBBox(boundingBox);
     row->boundingBox = castRect(&boundingBox, rectId++);
@@ -691,6 +1084,224 @@ Design *GetDesign(dbDatabase dbPtr) {
     design->rows[index++] = row;
   }
 
+  /** Blockages **/
+  design->blockageSz = blockageSet.size() + obstructionSet.size();
+  design->blockages =
+      (Blockage **)malloc(design->blockageSz * sizeof(Blockage *));
+  index = 0;
+  // Placement blockages
+  for (odb::dbSet<odb::dbBlockage>::iterator it = blockageSet.begin();
+       it != blockageSet.end(); ++it) {
+    Blockage *blockage = (Blockage *)malloc(sizeof(Blockage));
+    blockage->id = blockageId++;
+    blockage->blockageType = BlockageType_PLACEMENT;
+    blockage->rect = castBox(it->getBBox(), rectId++);
+    rectMap[blockage->rect->id] = blockage->rect;
+    blockage->layer = nullptr;
+    blockage->instance = nullptr;
+    if (it->getInstance()) {
+      blockage->instance = instanceMap[it->getInstance()->getId()];
+    }
+    blockage->maxDensity = it->getMaxDensity();
+    blockage->isSoft = it->isSoft();
+    blockage->isPushedDown = it->isPushedDown();
+    blockage->isSlot = false;
+    blockage->isFill = false;
+    blockage->isExceptPGNets = false;
+    blockage->minSpacing = -1;
+    blockage->designRuleWidth = -1;
+    blockage->dbObject = (void *)*it;
+    design->blockages[index++] = blockage;
+  }
+  // Routing blockages (obstructions)
+  for (odb::dbSet<odb::dbObstruction>::iterator it = obstructionSet.begin();
+       it != obstructionSet.end(); ++it) {
+    Blockage *blockage = (Blockage *)malloc(sizeof(Blockage));
+    odb::dbBox *box = it->getBBox();
+    blockage->id = blockageId++;
+    blockage->blockageType = BlockageType_ROUTING;
+    blockage->rect = castBox(box, rectId++);
+    rectMap[blockage->rect->id] = blockage->rect;
+    blockage->layer = nullptr;
+    if (box && box->getTechLayer()) {
+      int layerId = box->getTechLayer()->getId();
+      if (!layerMap.count(layerId)) {
+        fprintf(stderr, "Error parsing %s\n",
+                box->getTechLayer()->getConstName());
+      } else {
+        blockage->layer = layerMap[layerId];
+      }
+    }
+    blockage->instance = nullptr;
+    if (it->getInstance()) {
+      blockage->instance = instanceMap[it->getInstance()->getId()];
+    }
+    blockage->maxDensity = 0.0;
+    blockage->isSoft = false;
+    blockage->isPushedDown = it->isPushedDown();
+    blockage->isSlot = it->isSlotObstruction();
+    blockage->isFill = it->isFillObstruction();
+    blockage->isExceptPGNets = it->isExceptPGNetsObstruction();
+    blockage->minSpacing = -1;
+    blockage->designRuleWidth = -1;
+    if (it->hasMinSpacing()) {
+      blockage->minSpacing = it->getMinSpacing();
+    }
+    if (it->hasEffectiveWidth()) {
+      blockage->designRuleWidth = it->getEffectiveWidth();
+    }
+    blockage->dbObject = (void *)*it;
+    design->blockages[index++] = blockage;
+  }
+
+  /** Regions & groups **/
+  // DEF regions are stored as regions with boundaries, while DEF groups are
+  // stored as boundary-less child regions of the region they reference
+  for (odb::dbSet<odb::dbRegion>::iterator it = regionSet.begin();
+       it != regionSet.end(); ++it) {
+    odb::dbSet<odb::dbBox> boundaries = it->getBoundaries();
+    if (boundaries.size() == 0) {
+      continue;
+    }
+    Region *region = (Region *)malloc(sizeof(Region));
+    region->id = it->getId();
+    region->name = strdup(it->getName().c_str());
+    odb::dbRegionType regionType = it->getRegionType();
+    region->regionType = castRegionType(&regionType);
+    region->boxSz = boundaries.size();
+    region->boxes = (Rect **)malloc(region->boxSz * sizeof(Rect *));
+    int boxIndex = 0;
+    for (odb::dbSet<odb::dbBox>::iterator boxIt = boundaries.begin();
+         boxIt != boundaries.end(); ++boxIt) {
+      Rect *box = castBox(*boxIt, rectId++);
+      region->boxes[boxIndex++] = box;
+      rectMap[box->id] = box;
+    }
+    region->instances = nullptr;
+    region->instanceSz = 0;
+    region->groups = nullptr;
+    region->groupSz = 0;
+    region->dbObject = (void *)*it;
+    regionMap[it->getId()] = region;
+  }
+  for (odb::dbSet<odb::dbRegion>::iterator it = regionSet.begin();
+       it != regionSet.end(); ++it) {
+    if (regionMap.count(it->getId())) {
+      continue;
+    }
+    Group *group = (Group *)malloc(sizeof(Group));
+    group->id = it->getId();
+    group->name = strdup(it->getName().c_str());
+    group->region = nullptr;
+    odb::dbRegion *parent = it->getParent();
+    if (parent && regionMap.count(parent->getId())) {
+      group->region = regionMap[parent->getId()];
+    }
+    odb::dbSet<odb::dbInst> regionInsts = it->getRegionInsts();
+    group->instanceSz = regionInsts.size();
+    group->instances =
+        (Instance **)malloc(group->instanceSz * sizeof(Instance *));
+    int instIndex = 0;
+    for (odb::dbSet<odb::dbInst>::iterator instIt = regionInsts.begin();
+         instIt != regionInsts.end(); ++instIt) {
+      Instance *inst = instanceMap[instIt->getId()];
+      inst->group = group;
+      inst->region = group->region;
+      group->instances[instIndex++] = inst;
+    }
+    group->dbObject = (void *)*it;
+    groupMap[it->getId()] = group;
+  }
+  for (std::map<uint, Region *>::iterator it = regionMap.begin();
+       it != regionMap.end(); ++it) {
+    Region *region = it->second;
+    odb::dbRegion *dbRegion = (odb::dbRegion *)region->dbObject;
+    std::vector<Instance *> members;
+    std::vector<Group *> groups;
+    for (odb::dbInst *dbInst : dbRegion->getRegionInsts()) {
+      Instance *inst = instanceMap[dbInst->getId()];
+      inst->region = region;
+      members.push_back(inst);
+    }
+    for (std::map<uint, Group *>::iterator groupIt = groupMap.begin();
+         groupIt != groupMap.end(); ++groupIt) {
+      Group *group = groupIt->second;
+      if (group->region != region) {
+        continue;
+      }
+      groups.push_back(group);
+      for (int i = 0; i < group->instanceSz; i++) {
+        members.push_back(group->instances[i]);
+      }
+    }
+    region->instanceSz = members.size();
+    region->instances =
+        (Instance **)malloc(region->instanceSz * sizeof(Instance *));
+    for (size_t i = 0; i < members.size(); ++i) {
+      region->instances[i] = members[i];
+    }
+    region->groupSz = groups.size();
+    region->groups = (Group **)malloc(region->groupSz * sizeof(Group *));
+    for (size_t i = 0; i < groups.size(); ++i) {
+      region->groups[i] = groups[i];
+    }
+  }
+  design->regionSz = regionMap.size();
+  design->regions = (Region **)malloc(design->regionSz * sizeof(Region *));
+  index = 0;
+  for (std::map<uint, Region *>::iterator it = regionMap.begin();
+       it != regionMap.end(); ++it) {
+    design->regions[index++] = it->second;
+  }
+  design->groupSz = groupMap.size();
+  design->groups = (Group **)malloc(design->groupSz * sizeof(Group *));
+  index = 0;
+  for (std::map<uint, Group *>::iterator it = groupMap.begin();
+       it != groupMap.end(); ++it) {
+    design->groups[index++] = it->second;
+  }
+
+  /** Fills **/
+  // layer -> mask -> OPC -> shapes
+  std::map<std::tuple<uint, uint, bool>, std::vector<Rect *>> fillShapeMap;
+  std::map<std::tuple<uint, uint, bool>, Layer *> fillLayerMap;
+  for (odb::dbSet<odb::dbFill>::iterator it = fillSet.begin();
+       it != fillSet.end(); ++it) {
+    odb::dbTechLayer *layer = it->getTechLayer();
+    uint layerId = layer ? layer->getId() : 0;
+    std::tuple<uint, uint, bool> key(layerId, it->maskNumber(),
+                                     it->needsOPC());
+    odb::Rect fillRect;
+    it->getRect(fillRect);
+    Rect *rect = castRect((void *)&fillRect, rectId++);
+    rectMap[rect->id] = rect;
+    fillShapeMap[key].push_back(rect);
+    if (layer && layerMap.count(layerId)) {
+      rect->layer = layerMap[layerId];
+      fillLayerMap[key] = layerMap[layerId];
+    } else {
+      fillLayerMap[key] = nullptr;
+    }
+  }
+  design->fillSz = fillShapeMap.size();
+  design->fills = (Fill **)malloc(design->fillSz * sizeof(Fill *));
+  index = 0;
+  for (std::map<std::tuple<uint, uint, bool>, std::vector<Rect *>>::iterator
+           it = fillShapeMap.begin();
+       it != fillShapeMap.end(); ++it) {
+    Fill *fill = (Fill *)malloc(sizeof(Fill));
+    fill->id = index + 1;
+    fill->layer = fillLayerMap[it->first];
+    fill->mask = std::get<1>(it->first);
+    fill->needsOPC = std::get<2>(it->first);
+    fill->boxSz = it->second.size();
+    fill->boxes = (Rect **)malloc(fill->boxSz * sizeof(Rect *));
+    for (size_t i = 0; i < it->second.size(); ++i) {
+      fill->boxes[i] = it->second[i];
+    }
+    design->fills[index++] = fill;
+  }
+
   /** G-Cells **/
   design->gcells = nullptr;
   if (gcellGrid) {
@@ -918,7 +1529,8 @@ Design *GetDesign(dbDatabase dbPtr) {
       if (box->getBlockVia()) {
         rect->via = viaMap[box->getBlockVia()->getId()];
       } else if (box->getTechVia()) {
-        rect->via = viaMap[box->getTechVia()->getId()];
+        uint viaId = box->getTechVia()->getId();
+        rect->via = techViaMap.count(viaId) ? techViaMap[viaId] : nullptr;
       }
     }
     design->rects[index++] = rect;
@@ -999,6 +1611,11 @@ int castMasterType(void *ptr) {
     return MasterType_ENDCAP;
   } else if (typ->isPad()) {
     return MasterType_PAD;
+  } else if (typ->getValue() == odb::dbMasterType::Value::COVER ||
+             typ->getValue() == odb::dbMasterType::Value::COVER_BUMP) {
+    return MasterType_COVER;
+  } else if (typ->getValue() == odb::dbMasterType::Value::RING) {
+    return MasterType_RING;
   }
   return MasterType_CORE;
 }
@@ -1052,6 +1669,23 @@ int castWireShapeType(void *ptr) {
   return WireShapeType_NONE;
 }
 
+// dbPlacementStatus to int
+int castPlacementStatus(void *ptr) {
+  odb::dbPlacementStatus *status = (odb::dbPlacementStatus *)ptr;
+  if (status->getValue() == odb::dbPlacementStatus::Value::UNPLACED) {
+    return PlacementStatus_UNPLACED;
+  } else if (status->getValue() == odb::dbPlacementStatus::Value::SUGGESTED ||
+             status->getValue() == odb::dbPlacementStatus::Value::PLACED) {
+    return PlacementStatus_PLACED;
+  } else if (status->getValue() == odb::dbPlacementStatus::Value::LOCKED ||
+             status->getValue() == odb::dbPlacementStatus::Value::FIRM) {
+    return PlacementStatus_FIXED;
+  } else if (status->getValue() == odb::dbPlacementStatus::Value::COVER) {
+    return PlacementStatus_COVER;
+  }
+  return PlacementStatus_NONE;
+}
+
 // dbPoint to Point
 Point castPoint(void *ptr) {
   odb::Point *pt = (odb::Point *)ptr;
@@ -1146,6 +1780,41 @@ int castRowDirection(void *ptr) {
   }
   return Direction_NONE;
 }
+// dbViaParams to ViaParams
+ViaParams castViaParams(void *ptr) {
+  odb::dbViaParams *params = (odb::dbViaParams *)ptr;
+  ViaParams ret;
+  ret.cutSizeX = params->getXCutSize();
+  ret.cutSizeY = params->getYCutSize();
+  ret.cutSpacingX = params->getXCutSpacing();
+  ret.cutSpacingY = params->getYCutSpacing();
+  ret.bottomEnclosureX = params->getXBottomEnclosure();
+  ret.bottomEnclosureY = params->getYBottomEnclosure();
+  ret.topEnclosureX = params->getXTopEnclosure();
+  ret.topEnclosureY = params->getYTopEnclosure();
+  ret.rows = params->getNumCutRows();
+  ret.cols = params->getNumCutCols();
+  ret.originX = params->getXOrigin();
+  ret.originY = params->getYOrigin();
+  ret.bottomOffsetX = params->getXBottomOffset();
+  ret.bottomOffsetY = params->getYBottomOffset();
+  ret.topOffsetX = params->getXTopOffset();
+  ret.topOffsetY = params->getYTopOffset();
+  return ret;
+}
+
+// dbRegionType to int
+int castRegionType(void *ptr) {
+  odb::dbRegionType *typ = (odb::dbRegionType *)ptr;
+  if (typ->getValue() == odb::dbRegionType::Value::INCLUSIVE) {
+    return RegionType_FENCE;
+  } else if (typ->getValue() == odb::dbRegionType::Value::SUGGESTED) {
+    return RegionType_GUIDE;
+  } else if (typ->getValue() == odb::dbRegionType::Value::EXCLUSIVE) {
+    return RegionType_EXCLUSIVE;
+  }
+  return RegionType_FENCE;
+}
 // dbRtEdge type to int
 int castEdgeType(int type) {
   if (type == odb::dbRtEdge::Type::SEGMENT) {
@@ -1186,12 +1855,33 @@ void FreeNet(Net *net) {
   if (net->specialBoxes) {
     free(net->specialBoxes);
   }
+  if (net->specialWires) {
+    for (int i = 0; i < net->specialWireSz; i++) {
+      free(net->specialWires[i].shapes);
+      free(net->specialWires[i].shieldNet);
+    }
+    free(net->specialWires);
+  }
   free(net);
 }
 void FreeVia(Via *via) {
   free(via->name);
+  if (via->pattern) {
+    free(via->pattern);
+  }
+  if (via->boxes) {
+    free(via->boxes);
+  }
   free(via);
 }
+
+void FreeViaRule(ViaRule *rule) {
+  free(rule->name);
+  if (rule->layers) {
+    free(rule->layers);
+  }
+  free(rule);
+}
 void FreeRect(Rect *rect) { free(rect); }
 
 void FreeLayer(Layer *layer) {
@@ -1199,11 +1889,26 @@ void FreeLayer(Layer *layer) {
   if (layer->alias) {
     free(layer->alias);
   }
+  if (layer->spacingTableWidths) {
+    free(layer->spacingTableWidths);
+  }
+  if (layer->spacingTableLengths) {
+    free(layer->spacingTableLengths);
+  }
+  if (layer->spacingTable) {
+    free(layer->spacingTable);
+  }
+  free(layer->influenceWidths);
+  free(layer->influenceWithins);
+  free(layer->influenceSpacings);
+  free(layer->minEnclosedAreas);
+  free(layer->minEnclosedAreaWidths);
   free(layer);
 }
 
 void FreeSite(Site *site) {
   free(site->name);
+  free(site->siteClass);
   free(site);
 }
 void FreeRow(Row *row) {
@@ -1238,6 +1943,51 @@ void FreeGrid(Grid *grid) {
   free(grid);
 }
 
+void FreeBlockage(Blockage *blockage) { free(blockage); }
+
+void FreeRegion(Region *region) {
+  free(region->name);
+  if (region->boxes) {
+    free(region->boxes);
+  }
+  if (region->instances) {
+    free(region->instances);
+  }
+  if (region->groups) {
+    free(region->groups);
+  }
+  free(region);
+}
+
+void FreeMaster(Master *master) {
+  free(master->name);
+  free(master->library);
+  free(master->masterClass);
+  if (master->pins) {
+    for (int i = 0; i < master->pinSz; i++) {
+      free(master->pins[i].name);
+      free(master->pins[i].geometries);
+    }
+    free(master->pins);
+  }
+  free(master);
+}
+
+void FreeFill(Fill *fill) {
+  if (fill->boxes) {
+    free(fill->boxes);
+  }
+  free(fill);
+}
+
+void FreeGroup(Group *group) {
+  free(group->name);
+  if (group->instances) {
+    free(group->instances);
+  }
+  free(group);
+}
+
 void FreeGeometry(Geometry *geom) {
   if (geom->boxes) {
     free(geom->boxes);
@@ -1311,6 +2061,42 @@ void FreeDesign(Design *design) {
     if (design->gcells) {
       FreeGrid(design->gcells);
     }
+    if (design->blockages) {
+      for (int i = 0; i < design->blockageSz; i++) {
+        FreeBlockage(design->blockages[i]);
+      }
+      free(design->blockages);
+    }
+    if (design->regions) {
+      for (int i = 0; i < design->regionSz; i++) {
+        FreeRegion(design->regions[i]);
+      }
+      free(design->regions);
+    }
+    if (design->groups) {
+      for (int i = 0; i < design->groupSz; i++) {
+        FreeGroup(design->groups[i]);
+      }
+      free(design->groups);
+    }
+    if (design->fills) {
+      for (int i = 0; i < design->fillSz; i++) {
+        FreeFill(design->fills[i]);
+      }
+      free(design->fills);
+    }
+    if (design->masters) {
+      for (int i = 0; i < design->masterSz; i++) {
+        FreeMaster(design->masters[i]);
+      }
+      free(design->masters);
+    }
+    if (design->viaRules) {
+      for (int i = 0; i < design->viaRuleSz; i++) {
+        FreeViaRule(design->viaRules[i]);
+      }
+      free(design->viaRules);
+    }
     if (design->geometries) {
       for (int i = 0; i < design->geometrySz; i++) {
         FreeGeometry(design->geometries[i]);
@@ -41,6 +41,13 @@ const int WireType_ROUTED = 3;
 const int WireType_SHIELD = 4;
 const int WireType_NOSHIELD = 5;
 
+/** dbPlacementStatus **/
+const int PlacementStatus_NONE = 0;
+const int PlacementStatus_UNPLACED = 1;
+const int PlacementStatus_PLACED = 2;
+const int PlacementStatus_FIXED = 3;
+const int PlacementStatus_COVER = 4;
+
 /** dbWireShapeType **/
 const int WireShapeType_NONE = 0;
 const int WireShapeType_RING = 1;
@@ -60,6 +67,8 @@ const int MasterType_BLOCK = 0;
 const int MasterType_CORE = 1;
 const int MasterType_PAD = 2;
 const int MasterType_ENDCAP = 3;
+const int MasterType_COVER = 4;
+const int MasterType_RING = 5;
 
 /** dbTechLayerType **/
 const int LayerType_ROUTING = 0;
@@ -81,6 +90,15 @@ const int EdgeType_VIA = 2;
 const int EdgeType_SHORT = 3;
 const int EdgeType_VWIRE = 4;
 
+/** dbRegionType **/
+const int RegionType_FENCE = 0;     // INCLUSIVE
+const int RegionType_GUIDE = 1;     // SUGGESTED
+const int RegionType_EXCLUSIVE = 2; // EXCLUSIVE
+
+/** Blockage types **/
+const int BlockageType_PLACEMENT = 0;
+const int BlockageType_ROUTING = 1;
+
 const char *LastError;
 
 struct NetRef;
@@ -92,6 +110,12 @@ struct SiteRef;
 struct RowRef;
 struct GridRef;
 struct GCellRef;
+struct BlockageRef;
+struct RegionRef;
+struct GroupRef;
+struct FillRef;
+struct MasterRef;
+struct ViaRuleRef;
 
 /** odb::dbPoint **/
 typedef struct {
@@ -128,6 +152,24 @@ typedef struct {
   struct LayerRef *layer;
 } Edge;
 
+/** odb::dbSBox **/
+typedef struct {
+  Rect *rect;
+  int shapeType;
+  int width;
+  int mask;
+} SpecialShape;
+
+/** odb::dbSWire **/
+typedef struct {
+  int id;
+  int wireType;
+  char *shieldNet;    // Shielded net of SHIELD wires, NULL otherwise
+  Geometry *geometry; // Same geometry as the net special boxes
+  SpecialShape *shapes;
+  int shapeSz;
+} SpecialWire;
+
 /** odb::dbNet  **/
 typedef struct NetRef {
   int id;
@@ -137,11 +179,14 @@ typedef struct NetRef {
   struct PinRef **pins;
   int pinSz;
   int wireType;
+  int use;
   void *dbObject;
   Edge *edges;
   int edgeSz;
   Geometry **specialBoxes;
   int specialBoxSz;
+  SpecialWire *specialWires;
+  int specialWireSz;
 } Net;
 
 /** odb::dbTechLayer **/
@@ -156,10 +201,63 @@ typedef struct LayerRef {
   struct LayerRef *lowerLayer;
   char *name;
   char *alias;
+  int pitchX;
+  int pitchY;
+  int offsetX;
+  int offsetY;
+  int minWidth;
+  int maxWidth; // -1 if not set
+  int minStep;
+  int thickness; // -1 if not set
+  double resistance;
+  double capacitance;
+  double edgeCapacitance;
+  // Parallel run length spacing table (widths x lengths, row major)
+  int *spacingTableWidths;
+  int spacingTableWidthSz;
+  int *spacingTableLengths;
+  int spacingTableLengthSz;
+  int *spacingTable;
+  // Influence spacing table
+  int *influenceWidths;
+  int *influenceWithins;
+  int *influenceSpacings;
+  int influenceSz;
+  // Minimum enclosed area rules
+  double *minEnclosedAreas;
+  int *minEnclosedAreaWidths; // -1 if not set
+  int minEnclosedAreaSz;
+  // Default antenna rule
+  int hasAntennaRule;
+  double antennaAreaFactor;
+  double antennaPAR;
+  double antennaCAR;
+  double antennaPSR;
+  double antennaCSR;
   void *dbObject;
 } Layer;
 
-/** odb::dbVia **/
+/** odb::dbViaParams **/
+typedef struct {
+  int cutSizeX;
+  int cutSizeY;
+  int cutSpacingX;
+  int cutSpacingY;
+  int bottomEnclosureX;
+  int bottomEnclosureY;
+  int topEnclosureX;
+  int topEnclosureY;
+  int rows;
+  int cols;
+  int originX;
+  int originY;
+  int bottomOffsetX;
+  int bottomOffsetY;
+  int topOffsetX;
+  int topOffsetY;
+} ViaParams;
+
+/** odb::dbVia or odb::dbTechVia **/
 typedef struct ViaRef {
   int id;
   char *name;
@@ -169,9 +267,42 @@ typedef struct ViaRef {
   Layer *cutLayer;
   int isBlock;
   int isTech;
+  int hasParams;
+  ViaParams params;
+  char *pattern;
+  Rect **boxes; // Bottom metal, cuts and top metal shapes
+  int boxSz;
+  struct ViaRuleRef *rule;
   void *dbObject;
 } Via;
 
+/** odb::dbTechViaLayerRule **/
+typedef struct {
+  Layer *layer;
+  int direction;
+  int hasEnclosure;
+  int enclosureOverhang1;
+  int enclosureOverhang2;
+  int hasWidth;
+  int minWidth;
+  int maxWidth;
+  Rect *rect; // nullptr if not set
+  int hasSpacing;
+  int spacingX;
+  int spacingY;
+  double resistance;
+} ViaRuleLayer;
+
+/** odb::dbTechViaGenerateRule **/
+typedef struct ViaRuleRef {
+  int id;
+  char *name;
+  int isDefault;
+  ViaRuleLayer *layers;
+  int layerSz;
+  void *dbObject;
+} ViaRule;
+
 /** odb::dbITerm && odb::dbBTerm **/
 typedef struct PinRef {
   int id;
@@ -203,16 +334,57 @@ typedef struct InstanceRef {
   Pin **pins;
   int pinSz;
   int isPlaced;
+  int placementStatus;
   Rect *boundingBox;
   Rect *halo;
   Geometry *obstructions;
+  struct RegionRef *region;
+  struct GroupRef *group;
+  struct MasterRef *masterRef;
   void *dbObject;
 } Instance;
 
+/** odb::dbMTerm **/
+typedef struct {
+  int id;
+  char *name;
+  int direction;
+  int signalType;
+  Geometry **geometries;
+  int geometrySz;
+} MasterPin;
+
+/** odb::dbMaster **/
+typedef struct MasterRef {
+  int id;
+  char *name;
+  char *library;
+  int masterType;
+  char *masterClass; // Full LEF CLASS including the subclass
+  int width;
+  int height;
+  Point origin;
+  struct SiteRef *site;
+  int symmetryX;
+  int symmetryY;
+  int symmetryR90;
+  int isFiller;
+  MasterPin *pins;
+  int pinSz;
+  Geometry *obstructions;
+  void *dbObject;
+} Master;
+
 /** odb::dbSite **/
 typedef struct SiteRef {
   int id;
   char *name;
+  char *siteClass;
+  int width;
+  int height;
+  int symmetryX;
+  int symmetryY;
+  int symmetryR90;
   void *dbObject;
 } Site;
 
@@ -227,9 +399,62 @@ typedef struct RowRef {
   int originX;
   int originY;
   int spacing;
+  int siteCount;
   Rect *boundingBox;
 } Row;
 
+/** odb::dbBlockage or odb::dbObstruction **/
+typedef struct BlockageRef {
+  int id;
+  int blockageType;
+  Rect *rect;
+  Layer *layer;
+  struct InstanceRef *instance;
+  double maxDensity;
+  int isSoft;
+  int isPushedDown;
+  int isSlot;
+  int isFill;
+  int isExceptPGNets;
+  int minSpacing;      // -1 if not set
+  int designRuleWidth; // -1 if not set
+  void *dbObject;
+} Blockage;
+
+/** odb::dbRegion (DEF REGION) **/
+typedef struct RegionRef {
+  int id;
+  char *name;
+  int regionType;
+  Rect **boxes;
+  int boxSz;
+  Instance **instances;
+  int instanceSz;
+  struct GroupRef **groups;
+  int groupSz;
+  void *dbObject;
+} Region;
+
+/** odb::dbRegion child (DEF GROUP) **/
+typedef struct GroupRef {
+  int id;
+  char *name;
+  Region *region;
+  Instance **instances;
+  int instanceSz;
+  void *dbObject;
+} Group;
+
+/** odb::dbFill shapes sharing the same layer, mask and OPC flag **/
+typedef struct FillRef {
+  int id;
+  Layer *layer;
+  int mask;
+  int needsOPC;
+  Rect **boxes;
+  int boxSz;
+} Fill;
+
 /** odb::dbTrack or  odb::dbGCellGrid **/
 typedef struct GridRef {
   int id;
@@ -274,6 +499,18 @@ typedef struct {
   Geometry **geometries;
   int geometrySz;
   int rowSz;
+  Blockage **blockages;
+  int blockageSz;
+  Region **regions;
+  int regionSz;
+  Group **groups;
+  int groupSz;
+  Fill **fills;
+  int fillSz;
+  Master **masters;
+  int masterSz;
+  ViaRule **viaRules;
+  int viaRuleSz;
   Rect *core;
   double coreArea;
   Rect *die;
@@ -281,6 +518,9 @@ typedef struct {
   double designArea;
   double utilization;
   Rect *boundingBox;
+  int dbuPerMicron; // Database units per micron
+  int lefUnits;     // LEF UNITS DATABASE MICRONS
+  int defUnits;     // DEF UNITS DISTANCE MICRONS
 
   Rect **rects; // For memory cleanup
   int rectSz;
@@ -307,6 +547,12 @@ void FreeLayer(Layer *);
 void FreeSite(Site *);
 void FreeRow(Row *);
 void FreeGrid(Grid *);
+void FreeBlockage(Blockage *);
+void FreeRegion(Region *);
+void FreeGroup(Group *);
+void FreeFill(Fill *);
+void FreeMaster(Master *);
+void FreeViaRule(ViaRule *);
 void FreeRect(Rect *);
 void FreeGeometry(Geometry *);
 void FreeDesign(Design *);
@@ -323,6 +569,8 @@ int castMasterType(void *);
 int castWireType(void *);
 // dbWireShapeType to int
 int castWireShapeType(void *);
+// dbPlacementStatus to int
+int castPlacementStatus(void *);
 // dbPoint to Point
 Point castPoint(void *);
 // dbBox to Rect
@@ -335,6 +583,10 @@ int castLayerType(void *ptr);
 int castLayerDirection(void *ptr);
 // dbRowDir to int
 int castRowDirection(void *ptr);
+// dbViaParams to ViaParams
+ViaParams castViaParams(void *ptr);
+// dbRegionType to int
+int castRegionType(void *ptr);
 // dbRtEdge type to int
 int castEdgeType(int typ);
 
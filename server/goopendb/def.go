@@ -0,0 +1,510 @@
+package goopendb
+
+// DEF serialization of a design
+
+import (
+	"fmt"
+	"io"
+	"math"
+)
+
+// DEFVersion is the DEF version written by WriteDEF
+const DEFVersion = "5.8"
+
+var defOrientationNames = map[Orientation]string{
+	OrientationR0:    "N",
+	OrientationR90:   "W",
+	OrientationR180:  "S",
+	OrientationR270:  "E",
+	OrientationMY:    "FN",
+	OrientationMYR90: "FE",
+	OrientationMX:    "FS",
+	OrientationMXR90: "FW",
+}
+
+// defWriter writes the DEF sections, the first write error is kept
+type defWriter struct {
+	*ErrWriter
+	scale float64 // Database units per DEF distance unit
+}
+
+// dist converts a distance in database units to DEF units
+func (dw *defWriter) dist(v int) int {
+	return int(math.Round(float64(v) / dw.scale))
+}
+
+func (dw *defWriter) point(x, y int) string {
+	return fmt.Sprintf("( %d %d )", dw.dist(x), dw.dist(y))
+}
+
+func (dw *defWriter) rect(r *Rect) string {
+	return dw.point(r.XMin, r.YMin) + " " + dw.point(r.XMax, r.YMax)
+}
+
+// WriteDEF writes the design as a DEF file, distances are written in the
+// design DEF units when they divide the database units
+func WriteDEF(design *Design, w io.Writer) error {
+	if design.DBUPerMicron <= 0 {
+		return fmt.Errorf("Unknown database units")
+	}
+	units := design.DEFUnits
+	if units <= 0 || design.DBUPerMicron%units != 0 {
+		units = design.DBUPerMicron
+	}
+	dw := &defWriter{
+		ErrWriter: NewErrWriter(w),
+		scale:     float64(design.DBUPerMicron) / float64(units),
+	}
+	dw.Printf("VERSION %v ;\n", DEFVersion)
+	dw.Printf("DIVIDERCHAR \"/\" ;\n")
+	dw.Printf("BUSBITCHARS \"[]\" ;\n")
+	dw.Printf("DESIGN %v ;\n", design.Name)
+	dw.Printf("UNITS DISTANCE MICRONS %d ;\n\n", units)
+	if design.Die != nil {
+		dw.Printf("DIEAREA %v ;\n\n", dw.rect(design.Die))
+	}
+	dw.writeRows(design.Rows)
+	dw.writeTracks(design.Tracks, design.GCell)
+	dw.writeVias(design.ViaDefinitions)
+	dw.writeRegions(design.Regions)
+	dw.writeComponents(design.Instances)
+	dw.writePins(design.BlockPins)
+	dw.writeBlockages(design.Blockages)
+	dw.writeFills(design.Fills)
+	var specialNets, nets []*Net
+	for _, net := range design.Nets {
+		if net.IsSpecial {
+			specialNets = append(specialNets, net)
+		} else {
+			nets = append(nets, net)
+		}
+	}
+	dw.writeSpecialNets(specialNets)
+	dw.writeNets(nets)
+	dw.writeGroups(design.Groups)
+	dw.Printf("END DESIGN\n")
+	return dw.Flush()
+}
+
+func (dw *defWriter) writeRows(rows []*Row) {
+	for _, row := range rows {
+		site := ""
+		if row.Site != nil {
+			site = row.Site.Name
+		}
+		countX, countY := row.SiteCount, 1
+		stepX, stepY := row.Spacing, 0
+		if row.Direction == DirectionVERTICAL {
+			countX, countY = 1, row.SiteCount
+			stepX, stepY = 0, row.Spacing
+		}
+		dw.Printf("ROW %v %v %d %d %v DO %d BY %d STEP %d %d ;\n", row.Name, site, dw.dist(row.OriginX),
+			dw.dist(row.OriginY), defOrientationNames[row.Orientation], countX, countY, dw.dist(stepX), dw.dist(stepY))
+	}
+	if len(rows) > 0 {
+		dw.Printf("\n")
+	}
+}
+
+// writeGridPatterns writes the patterns of a track or gcell grid
+func (dw *defWriter) writeGridPatterns(keyword string, grid *Grid, layer string) {
+	axes := []struct {
+		name                   string
+		origins, counts, steps []int
+	}{
+		{"X", grid.GridXPatternOrigins, grid.GridXPatternLineCounts, grid.GridXPatternSteps},
+		{"Y", grid.GridYPatternOrigins, grid.GridYPatternLineCounts, grid.GridYPatternSteps},
+	}
+	for _, axis := range axes {
+		for i := range axis.origins {
+			dw.Printf("%v %v %d DO %d STEP %d%v ;\n", keyword, axis.name, dw.dist(axis.origins[i]), axis.counts[i],
+				dw.dist(axis.steps[i]), layer)
+		}
+	}
+}
+
+func (dw *defWriter) writeTracks(tracks []*Grid, gcell *Grid) {
+	for _, grid := range tracks {
+		layer := ""
+		if grid.Layer != nil {
+			layer = " LAYER " + grid.Layer.Name
+		}
+		dw.writeGridPatterns("TRACKS", grid, layer)
+	}
+	if gcell != nil {
+		dw.writeGridPatterns("GCELLGRID", gcell, "")
+	}
+	if len(tracks) > 0 || gcell != nil {
+		dw.Printf("\n")
+	}
+}
+
+func (dw *defWriter) writeVias(vias []*Via) {
+	var defVias []*Via
+	for _, via := range vias {
+		if !via.IsTech {
+			defVias = append(defVias, via)
+		}
+	}
+	if len(defVias) == 0 {
+		return
+	}
+	dw.Printf("VIAS %d ;\n", len(defVias))
+	for _, via := range defVias {
+		dw.Printf("- %v", via.Name)
+		if params := via.Params; params != nil && via.Rule != nil && via.BottomLayer != nil && via.CutLayer != nil &&
+			via.TopLayer != nil {
+			dw.Printf("\n  + VIARULE %v", via.Rule.Name)
+			dw.Printf("\n  + CUTSIZE %d %d", dw.dist(params.CutSizeX), dw.dist(params.CutSizeY))
+			dw.Printf("\n  + LAYERS %v %v %v", via.BottomLayer.Name, via.CutLayer.Name, via.TopLayer.Name)
+			dw.Printf("\n  + CUTSPACING %d %d", dw.dist(params.CutSpacingX), dw.dist(params.CutSpacingY))
+			dw.Printf("\n  + ENCLOSURE %d %d %d %d", dw.dist(params.BottomEnclosureX), dw.dist(params.BottomEnclosureY),
+				dw.dist(params.TopEnclosureX), dw.dist(params.TopEnclosureY))
+			if params.Rows != 1 || params.Cols != 1 {
+				dw.Printf("\n  + ROWCOL %d %d", params.Rows, params.Cols)
+			}
+			if params.OriginX != 0 || params.OriginY != 0 {
+				dw.Printf("\n  + ORIGIN %d %d", dw.dist(params.OriginX), dw.dist(params.OriginY))
+			}
+			if params.BottomOffsetX != 0 || params.BottomOffsetY != 0 || params.TopOffsetX != 0 || params.TopOffsetY != 0 {
+				dw.Printf("\n  + OFFSET %d %d %d %d", dw.dist(params.BottomOffsetX), dw.dist(params.BottomOffsetY),
+					dw.dist(params.TopOffsetX), dw.dist(params.TopOffsetY))
+			}
+			if via.Pattern != "" {
+				dw.Printf("\n  + PATTERN %v", via.Pattern)
+			}
+		} else {
+			for _, box := range via.Boxes {
+				if box.Layer != nil {
+					dw.Printf("\n  + RECT %v %v", box.Layer.Name, dw.rect(box))
+				}
+			}
+		}
+		dw.Printf(" ;\n")
+	}
+	dw.Printf("END VIAS\n\n")
+}
+
+func (dw *defWriter) writeRegions(regions []*Region) {
+	if len(regions) == 0 {
+		return
+	}
+	dw.Printf("REGIONS %d ;\n", len(regions))
+	for _, region := range regions {
+		dw.Printf("- %v", region.Name)
+		for _, box := range region.Boxes {
+			dw.Printf(" %v", dw.rect(box))
+		}
+		dw.Printf(" + TYPE %v ;\n", region.Type)
+	}
+	dw.Printf("END REGIONS\n\n")
+}
+
+func (dw *defWriter) writeComponents(instances []*Instance) {
+	if len(instances) == 0 {
+		return
+	}
+	dw.Printf("COMPONENTS %d ;\n", len(instances))
+	for _, inst := range instances {
+		dw.Printf("- %v %v", inst.Name, inst.Master)
+		status := inst.Status
+		if status == PlacementStatusNONE && inst.IsPlaced {
+			status = PlacementStatusPLACED
+		}
+		if status != PlacementStatusUNPLACED && status != PlacementStatusNONE && inst.Location != nil {
+			dw.Printf(" + %v %v %v", status, dw.point(inst.Location.X, inst.Location.Y), defOrientationNames[inst.Orientation])
+		} else {
+			dw.Printf(" + UNPLACED")
+		}
+		if halo := inst.Halo; halo != nil && (halo.XMin != 0 || halo.YMin != 0 || halo.XMax != 0 || halo.YMax != 0) {
+			dw.Printf(" + HALO %d %d %d %d", dw.dist(halo.XMin), dw.dist(halo.YMin), dw.dist(halo.XMax), dw.dist(halo.YMax))
+		}
+		// Group members take the region of the group
+		if inst.Region != nil && inst.Group == nil {
+			dw.Printf(" + REGION %v", inst.Region.Name)
+		}
+		dw.Printf(" ;\n")
+	}
+	dw.Printf("END COMPONENTS\n\n")
+}
+
+func (dw *defWriter) writePins(pins []*Pin) {
+	if len(pins) == 0 {
+		return
+	}
+	dw.Printf("PINS %d ;\n", len(pins))
+	for _, pin := range pins {
+		dw.Printf("- %v", pin.Name)
+		if pin.Net != nil {
+			dw.Printf(" + NET %v", pin.Net.Name)
+		}
+		if pin.IsSpecial {
+			dw.Printf(" + SPECIAL")
+		}
+		dw.Printf(" + DIRECTION %v + USE %v", IoType(pin.Direction), pin.SignalType)
+		// Pin shapes are written relative to the pin location
+		x, y := 0, 0
+		if pin.Location != nil {
+			x, y = pin.Location.X, pin.Location.Y
+		}
+		hasShapes := false
+		for _, geom := range pin.Geometries {
+			for _, box := range geom.Boxes {
+				switch {
+				case box.Layer != nil:
+					dw.Printf("\n  + LAYER %v %v %v", box.Layer.Name, dw.point(box.XMin-x, box.YMin-y),
+						dw.point(box.XMax-x, box.YMax-y))
+				case box.Via != nil && box.Via.Rect != nil:
+					dw.Printf("\n  + VIA %v %v", box.Via.Name, dw.point(box.XMin-box.Via.Rect.XMin-x, box.YMin-box.Via.Rect.YMin-y))
+				default:
+					continue
+				}
+				hasShapes = true
+			}
+		}
+		if hasShapes {
+			dw.Printf("\n  + PLACED %v N", dw.point(x, y))
+		}
+		dw.Printf(" ;\n")
+	}
+	dw.Printf("END PINS\n\n")
+}
+
+func (dw *defWriter) writeBlockages(blockages []*Blockage) {
+	if len(blockages) == 0 {
+		return
+	}
+	dw.Printf("BLOCKAGES %d ;\n", len(blockages))
+	for _, blockage := range blockages {
+		if blockage.Type == BlockageTypeROUTING && blockage.Layer != nil {
+			dw.Printf("- LAYER %v", blockage.Layer.Name)
+		} else {
+			dw.Printf("- PLACEMENT")
+		}
+		if blockage.Instance != nil {
+			dw.Printf(" + COMPONENT %v", blockage.Instance.Name)
+		}
+		if blockage.IsPushedDown {
+			dw.Printf(" + PUSHDOWN")
+		}
+		if blockage.Type == BlockageTypeROUTING {
+			if blockage.IsSlot {
+				dw.Printf(" + SLOTS")
+			}
+			if blockage.IsFill {
+				dw.Printf(" + FILLS")
+			}
+			if blockage.IsExceptPGNets {
+				dw.Printf(" + EXCEPTPGNET")
+			}
+			if blockage.MinSpacing >= 0 {
+				dw.Printf(" + SPACING %d", dw.dist(blockage.MinSpacing))
+			} else if blockage.DesignRuleWidth >= 0 {
+				dw.Printf(" + DESIGNRULEWIDTH %d", dw.dist(blockage.DesignRuleWidth))
+			}
+		} else if blockage.IsSoft {
+			dw.Printf(" + SOFT")
+		} else if blockage.MaxDensity > 0 {
+			dw.Printf(" + PARTIAL %g", blockage.MaxDensity)
+		}
+		if blockage.Rect != nil {
+			dw.Printf(" RECT %v", dw.rect(blockage.Rect))
+		}
+		dw.Printf(" ;\n")
+	}
+	dw.Printf("END BLOCKAGES\n\n")
+}
+
+func (dw *defWriter) writeFills(fills []*Fill) {
+	if len(fills) == 0 {
+		return
+	}
+	dw.Printf("FILLS %d ;\n", len(fills))
+	for _, fill := range fills {
+		layer := ""
+		if fill.Layer != nil {
+			layer = fill.Layer.Name
+		}
+		dw.Printf("- LAYER %v", layer)
+		if fill.Mask != 0 {
+			dw.Printf(" + MASK %d", fill.Mask)
+		}
+		if fill.NeedsOPC {
+			dw.Printf(" + OPC")
+		}
+		for _, box := range fill.Boxes {
+			dw.Printf("\n  RECT %v", dw.rect(box))
+		}
+		dw.Printf(" ;\n")
+	}
+	dw.Printf("END FILLS\n\n")
+}
+
+// writeConnections writes the pins of a net and its use
+func (dw *defWriter) writeConnections(net *Net) {
+	for i, pin := range net.Pins {
+		if i%4 == 0 {
+			dw.Printf("\n ")
+		}
+		if pin.IsBlock {
+			dw.Printf(" ( PIN %v )", pin.Name)
+		} else if pin.Instance != nil {
+			dw.Printf(" ( %v %v )", pin.Instance.Name, pin.Name)
+		}
+	}
+	if net.Use != SignalTypeSIGNAL {
+		dw.Printf(" + USE %v", net.Use)
+	}
+}
+
+// wireStatus returns the DEF routing status of a wire type, SHIELD wires
+// name the shielded net
+func wireStatus(typ WireType, shieldNet string) string {
+	switch typ {
+	case WireTypeCOVER, WireTypeFIXED, WireTypeNOSHIELD:
+		return typ.String()
+	case WireTypeSHIELD:
+		if shieldNet != "" {
+			return "SHIELD " + shieldNet
+		}
+	}
+	return "ROUTED"
+}
+
+func (dw *defWriter) writeSpecialNets(nets []*Net) {
+	if len(nets) == 0 {
+		return
+	}
+	dw.Printf("SPECIALNETS %d ;\n", len(nets))
+	for _, net := range nets {
+		dw.Printf("- %v", net.Name)
+		dw.writeConnections(net)
+		for _, wire := range net.SpecialWires {
+			// Shapes that are not on a path follow the wire paths
+			var rects []*Rect
+			keyword := "\n  + " + wireStatus(wire.WireType, wire.ShieldNet)
+			for _, shape := range wire.Shapes {
+				path, ok := dw.specialPath(shape)
+				if !ok {
+					if shape.Rect.Layer != nil {
+						rects = append(rects, shape.Rect)
+					}
+					continue
+				}
+				dw.Printf("%v %v", keyword, path)
+				keyword = "\n    NEW"
+			}
+			for _, rect := range rects {
+				dw.Printf("\n  + RECT %v %v", rect.Layer.Name, dw.rect(rect))
+			}
+		}
+		dw.Printf(" ;\n")
+	}
+	dw.Printf("END SPECIALNETS\n\n")
+}
+
+// specialPath formats a special wire shape as a path, it fails for shapes
+// that are not centered on a path
+func (dw *defWriter) specialPath(shape *SpecialShape) (string, bool) {
+	rect := shape.Rect
+	shapeType := ""
+	if shape.ShapeType != WireShapeTypeNONE {
+		shapeType = " + SHAPE " + shape.ShapeType.String()
+	}
+	if via := rect.Via; via != nil {
+		if via.Rect == nil || via.BottomLayer == nil {
+			return "", false
+		}
+		x, y := rect.XMin-via.Rect.XMin, rect.YMin-via.Rect.YMin
+		return fmt.Sprintf("%v 0%v %v %v", via.BottomLayer.Name, shapeType, dw.point(x, y), via.Name), true
+	}
+	if rect.Layer == nil {
+		return "", false
+	}
+	dx, dy := rect.XMax-rect.XMin, rect.YMax-rect.YMin
+	switch {
+	case shape.Width == dy && dy%2 == 0:
+		y := rect.YMin + dy/2
+		return fmt.Sprintf("%v %d%v %v %v", rect.Layer.Name, dw.dist(shape.Width), shapeType,
+			dw.point(rect.XMin, y), dw.point(rect.XMax, y)), true
+	case shape.Width == dx && dx%2 == 0:
+		x := rect.XMin + dx/2
+		return fmt.Sprintf("%v %d%v %v %v", rect.Layer.Name, dw.dist(shape.Width), shapeType,
+			dw.point(x, rect.YMin), dw.point(x, rect.YMax)), true
+	}
+	return "", false
+}
+
+func (dw *defWriter) writeNets(nets []*Net) {
+	if len(nets) == 0 {
+		return
+	}
+	dw.Printf("NETS %d ;\n", len(nets))
+	for _, net := range nets {
+		dw.Printf("- %v", net.Name)
+		dw.writeConnections(net)
+		keyword := "\n  + " + wireStatus(net.WireType, "")
+		for _, edge := range net.Edges {
+			if dw.writeEdge(keyword, edge) {
+				keyword = "\n    NEW"
+			}
+		}
+		dw.Printf(" ;\n")
+	}
+	dw.Printf("END NETS\n\n")
+}
+
+// writeEdge writes a routing edge as a single path, segments that do not
+// follow the layer width are written as rectangles relative to a point
+func (dw *defWriter) writeEdge(keyword string, edge *Edge) bool {
+	rect := edge.Rect
+	if edge.Layer == nil || rect == nil {
+		return false
+	}
+	layer := edge.Layer.Name
+	switch edge.Type {
+	case EdgeTypeTECHVIA, EdgeTypeVIA:
+		if edge.Via == nil || edge.Via.Rect == nil {
+			return false
+		}
+		x, y := rect.XMin-edge.Via.Rect.XMin, rect.YMin-edge.Via.Rect.YMin
+		dw.Printf("%v %v %v %v", keyword, layer, dw.point(x, y), edge.Via.Name)
+		return true
+	case EdgeTypeVWIRE:
+		dw.Printf("%v %v %v VIRTUAL %v", keyword, layer, dw.point(rect.XMin, rect.YMin), dw.point(rect.XMax, rect.YMax))
+		return true
+	}
+	width := edge.Layer.Width
+	halfWidth := width / 2
+	dx, dy := rect.XMax-rect.XMin, rect.YMax-rect.YMin
+	switch {
+	case width%2 == 0 && dy == width && dx >= width:
+		y := rect.YMin + halfWidth
+		dw.Printf("%v %v %v %v", keyword, layer, dw.point(rect.XMin+halfWidth, y), dw.point(rect.XMax-halfWidth, y))
+	case width%2 == 0 && dx == width && dy >= width:
+		x := rect.XMin + halfWidth
+		dw.Printf("%v %v %v %v", keyword, layer, dw.point(x, rect.YMin+halfWidth), dw.point(x, rect.YMax-halfWidth))
+	default:
+		dw.Printf("%v %v %v RECT ( %d %d %d %d )", keyword, layer, dw.point(rect.XMin, rect.YMin), 0, 0,
+			dw.dist(dx), dw.dist(dy))
+	}
+	return true
+}
+
+func (dw *defWriter) writeGroups(groups []*Group) {
+	if len(groups) == 0 {
+		return
+	}
+	dw.Printf("GROUPS %d ;\n", len(groups))
+	for _, group := range groups {
+		dw.Printf("- %v", group.Name)
+		for _, inst := range group.Instances {
+			dw.Printf(" %v", inst.Name)
+		}
+		if group.Region != nil {
+			dw.Printf(" + REGION %v", group.Region.Name)
+		}
+		dw.Printf(" ;\n")
+	}
+	dw.Printf("END GROUPS\n\n")
+}
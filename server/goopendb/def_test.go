@@ -0,0 +1,129 @@
+package goopendb
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestWriteDEF(t *testing.T) {
+	metal1 := &Layer{ID: 1, Name: "metal1", Width: 140}
+	via1 := &Layer{ID: 2, Name: "via1"}
+	metal2 := &Layer{ID: 3, Name: "metal2"}
+	site := &Site{ID: 1, Name: "core"}
+	techVia := &Via{ID: 1, Name: "via1_4", IsTech: true}
+	defVia := &Via{
+		ID:          2,
+		Name:        "via1_def",
+		Rect:        &Rect{XMin: -70, YMin: -70, XMax: 70, YMax: 70},
+		BottomLayer: metal1,
+		TopLayer:    metal2,
+		Boxes: []*Rect{
+			{XMin: -70, YMin: -70, XMax: 70, YMax: 70, Layer: metal1},
+			{XMin: -36, YMin: -36, XMax: 36, YMax: 36, Layer: via1},
+			{XMin: -70, YMin: -70, XMax: 70, YMax: 70, Layer: metal2},
+		},
+	}
+	inst := &Instance{
+		ID:          1,
+		Name:        "u1",
+		Master:      "INV_X1",
+		Location:    &Point{X: 2000, Y: 2800},
+		Orientation: OrientationMX,
+		IsPlaced:    true,
+	}
+	pin := &Pin{ID: 1, Name: "A", Instance: inst}
+	blockPin := &Pin{
+		ID:         2,
+		Name:       "in",
+		Direction:  Direction(IOTypeOUTPUT),
+		Location:   &Point{X: 100, Y: 0},
+		Geometries: []*Geometry{{ID: 1, Boxes: []*Rect{{XMin: 30, YMin: 0, XMax: 170, YMax: 140, Layer: metal1}}}},
+		IsBlock:    true,
+	}
+	net := &Net{
+		ID:       1,
+		Name:     "n1",
+		IsRouted: true,
+		WireType: WireTypeROUTED,
+		Pins:     []*Pin{pin, blockPin},
+		Edges: []*Edge{
+			{Type: EdgeTypeSEGMENT, Layer: metal1, Rect: &Rect{XMin: -70, YMin: -70, XMax: 270, YMax: 70}},
+			{Type: EdgeTypeSEGMENT, Layer: metal1, Rect: &Rect{XMin: 0, YMin: 0, XMax: 300, YMax: 400}},
+		},
+	}
+	blockPin.Net = net
+	vdd := &Net{
+		ID:        2,
+		Name:      "VDD",
+		IsSpecial: true,
+		SpecialWires: []*SpecialWire{{
+			ID:       1,
+			WireType: WireTypeROUTED,
+			Shapes: []*SpecialShape{
+				{ShapeType: WireShapeTypeFOLLOWPIN, Width: 340, Rect: &Rect{XMax: 4000, YMin: 2630, YMax: 2970, Layer: metal1}},
+				{Rect: &Rect{XMin: 930, YMin: 2730, XMax: 1070, YMax: 2870, Via: defVia}},
+			},
+		}},
+	}
+	design := &Design{
+		Name:           "small",
+		DBUPerMicron:   2000,
+		DEFUnits:       1000,
+		Die:            &Rect{XMax: 4000, YMax: 6000},
+		Instances:      []*Instance{inst},
+		Nets:           []*Net{net, vdd},
+		BlockPins:      []*Pin{blockPin},
+		ViaDefinitions: []*Via{techVia, defVia},
+		Rows: []*Row{
+			{ID: 1, Name: "row0", Site: site, Direction: DirectionHORIZONTAL, Spacing: 380, SiteCount: 10},
+		},
+		Tracks: []*Grid{
+			{ID: 1, Layer: metal1, GridXPatternOrigins: []int{190}, GridXPatternLineCounts: []int{10}, GridXPatternSteps: []int{380}},
+		},
+		Regions: []*Region{
+			{ID: 1, Name: "fence", Boxes: []*Rect{{XMax: 2000, YMax: 2000}}, Type: RegionTypeFENCE},
+			{ID: 2, Name: "guide", Boxes: []*Rect{{XMax: 2000, YMax: 2000}}, Type: RegionTypeGUIDE},
+			{ID: 3, Name: "exclusive", Boxes: []*Rect{{XMax: 2000, YMax: 2000}}, Type: RegionTypeEXCLUSIVE},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteDEF(design, &buf); err != nil {
+		t.Fatal(err)
+	}
+	def := buf.String()
+	for _, line := range []string{
+		"UNITS DISTANCE MICRONS 1000 ;",
+		"DIEAREA ( 0 0 ) ( 2000 3000 ) ;",
+		"ROW row0 core 0 0 N DO 10 BY 1 STEP 190 0 ;",
+		"TRACKS X 95 DO 10 STEP 190 LAYER metal1 ;",
+		"- fence ( 0 0 ) ( 1000 1000 ) + TYPE FENCE ;",
+		"- guide ( 0 0 ) ( 1000 1000 ) + TYPE GUIDE ;",
+		"- exclusive ( 0 0 ) ( 1000 1000 ) + TYPE EXCLUSIVE ;",
+		"VIAS 1 ;",
+		"- via1_def",
+		"  + RECT metal1 ( -35 -35 ) ( 35 35 )",
+		"  + RECT via1 ( -18 -18 ) ( 18 18 )",
+		"  + RECT metal2 ( -35 -35 ) ( 35 35 ) ;",
+		"- u1 INV_X1 + PLACED ( 1000 1400 ) FS ;",
+		"- in + NET n1 + DIRECTION OUTPUT + USE SIGNAL",
+		"  + LAYER metal1 ( -35 0 ) ( 35 70 )",
+		"  ( u1 A ) ( PIN in )",
+		"  + ROUTED metal1 ( 0 0 ) ( 100 0 )",
+		"    NEW metal1 ( 0 0 ) RECT ( 0 0 150 200 ) ;",
+		"SPECIALNETS 1 ;",
+		"  + ROUTED metal1 170 + SHAPE FOLLOWPIN ( 0 1400 ) ( 2000 1400 )",
+		"    NEW metal1 0 ( 500 1400 ) via1_def ;",
+		"END DESIGN",
+	} {
+		if !strings.Contains(def, line+"\n") {
+			t.Errorf("Expected %q in\n%v", line, def)
+		}
+	}
+
+	design.DBUPerMicron = 0
+	if err := WriteDEF(design, &buf); err == nil {
+		t.Errorf("Expected unknown database units error")
+	}
+}
@@ -0,0 +1,518 @@
+package goopendb
+
+// Differences between two versions of a design
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ChangeType is the kind of change of a design object
+type ChangeType int
+
+// ChangeType enum
+const (
+	ChangeAdded ChangeType = iota
+	ChangeRemoved
+	ChangeMoved
+	ChangeOrientation
+	ChangeMaster
+	ChangeConnectivity
+	ChangeRouting
+)
+
+func (typ ChangeType) String() string {
+	switch typ {
+	case ChangeAdded:
+		return "Added"
+	case ChangeRemoved:
+		return "Removed"
+	case ChangeMoved:
+		return "Moved"
+	case ChangeOrientation:
+		return "Orientation"
+	case ChangeMaster:
+		return "Master"
+	case ChangeConnectivity:
+		return "Connectivity"
+	case ChangeRouting:
+		return "Routing"
+	}
+	return "Unknown"
+}
+
+// InstanceState is the placement of an instance in one of the diffed designs
+type InstanceState struct {
+	Master      string `json:",omitempty"`
+	Orientation Orientation
+	IsPlaced    bool
+	Location    *Point `json:",omitempty"`
+	BoundingBox *Rect  `json:",omitempty"`
+}
+
+// InstanceDiff is a changed instance
+type InstanceDiff struct {
+	Name    string
+	Changes []ChangeType
+	Before  *InstanceState `json:",omitempty"` // Nil for added instances
+	After   *InstanceState `json:",omitempty"` // Nil for removed instances
+}
+
+// DiffShape is a routing shape of a net diff
+type DiffShape struct {
+	Layer string `json:",omitempty"`
+	Via   string `json:",omitempty"`
+	XMin  int
+	YMin  int
+	XMax  int
+	YMax  int
+}
+
+// NetDiff is a net with changed connectivity or routing
+type NetDiff struct {
+	Name          string
+	Changes       []ChangeType
+	AddedPins     []string     `json:",omitempty"` // Instance pins as instance/pin, block pins as PIN/pin
+	RemovedPins   []string     `json:",omitempty"`
+	AddedShapes   []*DiffShape `json:",omitempty"`
+	RemovedShapes []*DiffShape `json:",omitempty"`
+}
+
+// PinDiff is a changed block pin
+type PinDiff struct {
+	Name    string
+	Changes []ChangeType
+	Before  *Rect `json:",omitempty"` // Pin shapes bounding box, nil for added pins
+	After   *Rect `json:",omitempty"` // Pin shapes bounding box, nil for removed pins
+}
+
+// DesignDiff is the difference between two versions of a design, markers
+// locate the removed geometry of the first design, the added geometry of the
+// second one and the changed objects
+type DesignDiff struct {
+	Instances []*InstanceDiff
+	Nets      []*NetDiff
+	Pins      []*PinDiff
+	Markers   []*Marker
+}
+
+// Empty checks if the designs are the same
+func (diff *DesignDiff) Empty() bool {
+	return len(diff.Instances) == 0 && len(diff.Nets) == 0 && len(diff.Pins) == 0
+}
+
+// diffDesign resolves the names of the design objects, objects may hold stubs
+type diffDesign struct {
+	instances map[int]*Instance
+	layers    map[int]*Layer
+	// Net pins by net name
+	netPins map[string]map[string]bool
+}
+
+func newDiffDesign(design *Design) *diffDesign {
+	d := &diffDesign{
+		instances: make(map[int]*Instance),
+		layers:    design.LayersByID(),
+		netPins:   make(map[string]map[string]bool),
+	}
+	for _, inst := range design.Instances {
+		d.instances[inst.ID] = inst
+	}
+	nets := make(map[int]string)
+	for _, net := range design.Nets {
+		nets[net.ID] = net.Name
+		d.netPins[net.Name] = make(map[string]bool)
+	}
+	for _, pins := range [][]*Pin{design.InstancePins, design.BlockPins} {
+		for _, pin := range pins {
+			if pin.Net == nil {
+				continue
+			}
+			if name, ok := nets[pin.Net.ID]; ok {
+				d.netPins[name][d.pinName(pin)] = true
+			}
+		}
+	}
+	return d
+}
+
+// pinName names instance pins as instance/pin and block pins as PIN/pin
+func (d *diffDesign) pinName(pin *Pin) string {
+	if pin.Instance != nil {
+		if inst := d.instances[pin.Instance.ID]; inst != nil {
+			return inst.Name + "/" + pin.Name
+		}
+	}
+	return "PIN/" + pin.Name
+}
+
+// pinInstance returns the instance of an instance/pin name
+func pinInstance(pin string, byName map[string]*Instance) *Instance {
+	if i := strings.LastIndex(pin, "/"); i > 0 && !strings.HasPrefix(pin, "PIN/") {
+		return byName[pin[:i]]
+	}
+	return nil
+}
+
+func (d *diffDesign) layerName(layer *Layer) string {
+	if layer == nil {
+		return ""
+	}
+	if layer.Name != "" {
+		return layer.Name
+	}
+	if l := d.layers[layer.ID]; l != nil {
+		return l.Name
+	}
+	return ""
+}
+
+// shapes returns the routing shapes of the net by key
+func (d *diffDesign) shapes(net *Net) map[DiffShape]int {
+	shapes := make(map[DiffShape]int)
+	if net == nil {
+		return shapes
+	}
+	add := func(layer *Layer, via *Via, rect *Rect) {
+		shape := DiffShape{Layer: d.layerName(layer), XMin: rect.XMin, YMin: rect.YMin, XMax: rect.XMax, YMax: rect.YMax}
+		if via != nil {
+			shape.Via = via.Name
+			if shape.Via == "" {
+				shape.Via = fmt.Sprint(via.ID)
+			}
+		}
+		shapes[shape]++
+	}
+	for _, edge := range net.Edges {
+		if edge.Rect != nil && edge.Type != EdgeTypeVWIRE {
+			add(edge.Layer, edge.Via, edge.Rect)
+		}
+	}
+	for _, geom := range net.SpecialBoxes {
+		if geom == nil {
+			continue
+		}
+		for _, rect := range geom.Boxes {
+			add(rect.Layer, rect.Via, rect)
+		}
+	}
+	return shapes
+}
+
+func instanceState(inst *Instance) *InstanceState {
+	state := &InstanceState{
+		Master:      inst.Master,
+		Orientation: inst.Orientation,
+		IsPlaced:    inst.IsPlaced,
+	}
+	if inst.Location != nil {
+		location := inst.Location.Copy()
+		state.Location = &location
+	}
+	if inst.BoundingBox != nil {
+		bbox := inst.BoundingBox.Copy()
+		bbox.Layer, bbox.Via = nil, nil
+		state.BoundingBox = &bbox
+	}
+	return state
+}
+
+// moved checks if two instance states have different placements
+func (state *InstanceState) moved(other *InstanceState) bool {
+	if state.IsPlaced != other.IsPlaced {
+		return true
+	}
+	if state.Location != nil && other.Location != nil {
+		return *state.Location != *other.Location
+	}
+	a, b := state.BoundingBox, other.BoundingBox
+	return a != nil && b != nil && (a.XMin != b.XMin || a.YMin != b.YMin)
+}
+
+// pinBox returns the bounding box of the pin shapes
+func pinBox(pin *Pin) *Rect {
+	var box *Rect
+	VisitGeometries(pin.Geometries, func(layer *Layer, xMin, yMin, xMax, yMax int) {
+		box = extendRect(box, xMin, yMin, xMax, yMax)
+	})
+	if box == nil && pin.Location != nil {
+		box = &Rect{XMin: pin.Location.X, YMin: pin.Location.Y, XMax: pin.Location.X, YMax: pin.Location.Y}
+	}
+	return box
+}
+
+// extendRect grows the rectangle to cover another rectangle, a nil
+// rectangle starts a new one
+func extendRect(r *Rect, xMin, yMin, xMax, yMax int) *Rect {
+	if r == nil {
+		return &Rect{XMin: xMin, YMin: yMin, XMax: xMax, YMax: yMax}
+	}
+	if xMin < r.XMin {
+		r.XMin = xMin
+	}
+	if yMin < r.YMin {
+		r.YMin = yMin
+	}
+	if xMax > r.XMax {
+		r.XMax = xMax
+	}
+	if yMax > r.YMax {
+		r.YMax = yMax
+	}
+	return r
+}
+
+// diffMarker creates a marker on a rectangle
+func diffMarker(typ MarkerType, message string, rect *Rect) *Marker {
+	return &Marker{Type: typ, Message: message, XMin: rect.XMin, YMin: rect.YMin, XMax: rect.XMax, YMax: rect.YMax}
+}
+
+// Diff compares two versions of a design sharing the same technology and
+// libraries. Objects are matched by name: instances are added, removed,
+// moved, re-oriented or re-mastered, nets change their connected pins or
+// their routing shapes and block pins are added, removed or moved
+func Diff(a, b *Design) *DesignDiff {
+	diff := &DesignDiff{}
+	da, db := newDiffDesign(a), newDiffDesign(b)
+
+	before := make(map[string]*Instance)
+	after := make(map[string]*Instance)
+	var namesA, namesB []string
+	for _, inst := range a.Instances {
+		before[inst.Name] = inst
+		namesA = append(namesA, inst.Name)
+	}
+	for _, inst := range b.Instances {
+		after[inst.Name] = inst
+		namesB = append(namesB, inst.Name)
+	}
+	for _, name := range sortedNames(namesA, namesB) {
+		instA, instB := before[name], after[name]
+		d := &InstanceDiff{Name: name}
+		if instA != nil {
+			d.Before = instanceState(instA)
+		}
+		if instB != nil {
+			d.After = instanceState(instB)
+		}
+		switch {
+		case instA == nil:
+			d.Changes = []ChangeType{ChangeAdded}
+		case instB == nil:
+			d.Changes = []ChangeType{ChangeRemoved}
+		default:
+			if d.Before.moved(d.After) {
+				d.Changes = append(d.Changes, ChangeMoved)
+			}
+			if d.Before.Orientation != d.After.Orientation {
+				d.Changes = append(d.Changes, ChangeOrientation)
+			}
+			if d.Before.Master != d.After.Master {
+				d.Changes = append(d.Changes, ChangeMaster)
+			}
+		}
+		if len(d.Changes) == 0 {
+			continue
+		}
+		diff.Instances = append(diff.Instances, d)
+		moved := instA == nil || instB == nil || d.Changes[0] == ChangeMoved
+		if moved && d.Before != nil && d.Before.IsPlaced && d.Before.BoundingBox != nil {
+			m := diffMarker(MarkerRemoved, fmt.Sprintf("Instance %v %v", name, strings.ToLower(d.Changes[0].String())), d.Before.BoundingBox)
+			m.Instances = []string{name}
+			diff.Markers = append(diff.Markers, m)
+		}
+		if d.After != nil && d.After.IsPlaced && d.After.BoundingBox != nil {
+			typ := MarkerChanged
+			if moved {
+				typ = MarkerAdded
+			}
+			var changes []string
+			for _, change := range d.Changes {
+				changes = append(changes, strings.ToLower(change.String()))
+			}
+			m := diffMarker(typ, fmt.Sprintf("Instance %v %v", name, strings.Join(changes, ", ")), d.After.BoundingBox)
+			m.Instances = []string{name}
+			diff.Markers = append(diff.Markers, m)
+		}
+	}
+
+	netsA := make(map[string]*Net)
+	netsB := make(map[string]*Net)
+	namesA, namesB = nil, nil
+	for _, net := range a.Nets {
+		netsA[net.Name] = net
+		namesA = append(namesA, net.Name)
+	}
+	for _, net := range b.Nets {
+		netsB[net.Name] = net
+		namesB = append(namesB, net.Name)
+	}
+	for _, name := range sortedNames(namesA, namesB) {
+		netA, netB := netsA[name], netsB[name]
+		d := &NetDiff{Name: name}
+		switch {
+		case netA == nil:
+			d.Changes = []ChangeType{ChangeAdded}
+		case netB == nil:
+			d.Changes = []ChangeType{ChangeRemoved}
+		}
+		for pin := range db.netPins[name] {
+			if !da.netPins[name][pin] {
+				d.AddedPins = append(d.AddedPins, pin)
+			}
+		}
+		for pin := range da.netPins[name] {
+			if !db.netPins[name][pin] {
+				d.RemovedPins = append(d.RemovedPins, pin)
+			}
+		}
+		sort.Strings(d.AddedPins)
+		sort.Strings(d.RemovedPins)
+		shapesA, shapesB := da.shapes(netA), db.shapes(netB)
+		for shape, count := range shapesB {
+			for i := shapesA[shape]; i < count; i++ {
+				s := shape
+				d.AddedShapes = append(d.AddedShapes, &s)
+			}
+		}
+		for shape, count := range shapesA {
+			for i := shapesB[shape]; i < count; i++ {
+				s := shape
+				d.RemovedShapes = append(d.RemovedShapes, &s)
+			}
+		}
+		sortShapes(d.AddedShapes)
+		sortShapes(d.RemovedShapes)
+		if netA != nil && netB != nil {
+			if len(d.AddedPins) > 0 || len(d.RemovedPins) > 0 {
+				d.Changes = append(d.Changes, ChangeConnectivity)
+			}
+			if len(d.AddedShapes) > 0 || len(d.RemovedShapes) > 0 {
+				d.Changes = append(d.Changes, ChangeRouting)
+			}
+		}
+		if len(d.Changes) == 0 {
+			continue
+		}
+		diff.Nets = append(diff.Nets, d)
+
+		for _, shapes := range []struct {
+			typ    MarkerType
+			change string
+			shapes []*DiffShape
+		}{{MarkerRemoved, "removed", d.RemovedShapes}, {MarkerAdded, "added", d.AddedShapes}} {
+			for _, shape := range shapes.shapes {
+				m := diffMarker(shapes.typ, fmt.Sprintf("Net %v routing %v", name, shapes.change), &Rect{XMin: shape.XMin, YMin: shape.YMin, XMax: shape.XMax, YMax: shape.YMax})
+				m.Layer = shape.Layer
+				m.Nets = []string{name}
+				diff.Markers = append(diff.Markers, m)
+			}
+		}
+		if netA == nil || netB == nil {
+			continue
+		}
+		// Connectivity changes are located on the instances of the changed pins
+		var box *Rect
+		var pins []string
+		for _, changed := range []struct {
+			instances map[string]*Instance
+			pins      []string
+		}{{after, d.AddedPins}, {before, d.RemovedPins}} {
+			for _, pin := range changed.pins {
+				inst := pinInstance(pin, changed.instances)
+				if inst == nil || !inst.IsPlaced || inst.BoundingBox == nil {
+					continue
+				}
+				box = extendRect(box, inst.BoundingBox.XMin, inst.BoundingBox.YMin, inst.BoundingBox.XMax, inst.BoundingBox.YMax)
+				pins = append(pins, pin)
+			}
+		}
+		if box != nil {
+			m := diffMarker(MarkerChanged, fmt.Sprintf("Net %v connectivity changed", name), box)
+			m.Nets = []string{name}
+			m.Pins = pins
+			diff.Markers = append(diff.Markers, m)
+		}
+	}
+
+	pinsA := make(map[string]*Pin)
+	pinsB := make(map[string]*Pin)
+	namesA, namesB = nil, nil
+	for _, pin := range a.BlockPins {
+		pinsA[pin.Name] = pin
+		namesA = append(namesA, pin.Name)
+	}
+	for _, pin := range b.BlockPins {
+		pinsB[pin.Name] = pin
+		namesB = append(namesB, pin.Name)
+	}
+	for _, name := range sortedNames(namesA, namesB) {
+		d := &PinDiff{Name: name}
+		if pin := pinsA[name]; pin != nil {
+			d.Before = pinBox(pin)
+		}
+		if pin := pinsB[name]; pin != nil {
+			d.After = pinBox(pin)
+		}
+		switch {
+		case pinsA[name] == nil:
+			d.Changes = []ChangeType{ChangeAdded}
+		case pinsB[name] == nil:
+			d.Changes = []ChangeType{ChangeRemoved}
+		case d.Before != nil && d.After != nil && *d.Before != *d.After:
+			d.Changes = []ChangeType{ChangeMoved}
+		default:
+			continue
+		}
+		diff.Pins = append(diff.Pins, d)
+		change := strings.ToLower(d.Changes[0].String())
+		if d.Before != nil {
+			m := diffMarker(MarkerRemoved, fmt.Sprintf("Pin %v %v", name, change), d.Before)
+			m.Pins = []string{"PIN/" + name}
+			diff.Markers = append(diff.Markers, m)
+		}
+		if d.After != nil {
+			m := diffMarker(MarkerAdded, fmt.Sprintf("Pin %v %v", name, change), d.After)
+			m.Pins = []string{"PIN/" + name}
+			diff.Markers = append(diff.Markers, m)
+		}
+	}
+	return diff
+}
+
+// sortedNames returns the sorted union of the object names of both designs
+func sortedNames(a, b []string) []string {
+	seen := make(map[string]bool)
+	var names []string
+	for _, name := range append(a, b...) {
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func sortShapes(shapes []*DiffShape) {
+	sort.Slice(shapes, func(i, j int) bool {
+		a, b := shapes[i], shapes[j]
+		if a.Layer != b.Layer {
+			return a.Layer < b.Layer
+		}
+		if a.YMin != b.YMin {
+			return a.YMin < b.YMin
+		}
+		if a.XMin != b.XMin {
+			return a.XMin < b.XMin
+		}
+		if a.YMax != b.YMax {
+			return a.YMax < b.YMax
+		}
+		if a.XMax != b.XMax {
+			return a.XMax < b.XMax
+		}
+		return a.Via < b.Via
+	})
+}
@@ -0,0 +1,110 @@
+package goopendb
+
+import (
+	"reflect"
+	"testing"
+)
+
+// diffVersion builds a design version with the given instance placements,
+// net pins and wires
+func diffVersion(instances map[string]Instance, netPins map[string][]string, wires map[string]Rect, pin Rect) *Design {
+	metal1 := &Layer{ID: 1, Name: "metal1"}
+	design := &Design{Layers: []*Layer{metal1}}
+	byName := make(map[string]*Instance)
+	for _, name := range []string{"u1", "u2", "u3", "u4", "u5"} {
+		inst, ok := instances[name]
+		if !ok {
+			continue
+		}
+		inst.ID = len(design.Instances) + 1
+		inst.Name = name
+		inst.IsPlaced = true
+		inst.Location = &Point{X: inst.BoundingBox.XMin, Y: inst.BoundingBox.YMin}
+		byName[name] = &inst
+		design.Instances = append(design.Instances, &inst)
+	}
+	for _, name := range []string{"n1", "n2"} {
+		net := &Net{ID: len(design.Nets) + 1, Name: name}
+		design.Nets = append(design.Nets, net)
+		for _, pinName := range netPins[name] {
+			p := &Pin{ID: len(design.InstancePins) + 1, Name: pinName[3:], Instance: &Instance{ID: byName[pinName[:2]].ID, InComplete: true}, Net: net}
+			design.InstancePins = append(design.InstancePins, p)
+		}
+		if wire, ok := wires[name]; ok {
+			net.Edges = []*Edge{{Type: EdgeTypeSEGMENT, Layer: &Layer{ID: 1, InComplete: true}, Rect: &wire}}
+		}
+	}
+	design.BlockPins = []*Pin{{
+		ID:         1,
+		Name:       "P",
+		Net:        design.Nets[1],
+		Geometries: []*Geometry{{Boxes: []*Rect{{XMin: pin.XMin, YMin: pin.YMin, XMax: pin.XMax, YMax: pin.YMax, Layer: metal1}}}},
+	}}
+	return design
+}
+
+func TestDiff(t *testing.T) {
+	a := diffVersion(map[string]Instance{
+		"u1": {Master: "INV_X1", BoundingBox: &Rect{XMax: 100, YMax: 100}},
+		"u2": {Master: "BUF_X1", BoundingBox: &Rect{XMin: 200, XMax: 300, YMax: 100}},
+		"u3": {Master: "INV_X1", BoundingBox: &Rect{XMin: 400, XMax: 500, YMax: 100}},
+		"u5": {Master: "INV_X1", BoundingBox: &Rect{XMin: 600, XMax: 700, YMax: 100}},
+	}, map[string][]string{
+		"n1": {"u1/Z", "u2/A"},
+		"n2": {"u2/Z", "u3/A"},
+	}, map[string]Rect{"n1": {XMin: 50, YMin: 50, XMax: 250, YMax: 70}}, Rect{XMin: 1000, XMax: 1100, YMax: 100})
+	b := diffVersion(map[string]Instance{
+		"u1": {Master: "INV_X1", BoundingBox: &Rect{YMin: 200, XMax: 100, YMax: 300}},
+		"u2": {Master: "BUF_X1", Orientation: OrientationMY, BoundingBox: &Rect{XMin: 200, XMax: 300, YMax: 100}},
+		"u3": {Master: "INV_X2", BoundingBox: &Rect{XMin: 400, XMax: 500, YMax: 100}},
+		"u4": {Master: "INV_X1", BoundingBox: &Rect{XMin: 800, XMax: 900, YMax: 100}},
+	}, map[string][]string{
+		"n1": {"u1/Z", "u2/A"},
+		"n2": {"u2/Z", "u4/A"},
+	}, map[string]Rect{"n1": {XMin: 50, YMin: 50, XMax: 250, YMax: 270}}, Rect{XMin: 1000, YMin: 200, XMax: 1100, YMax: 300})
+
+	if !Diff(a, a).Empty() {
+		t.Errorf("Expected no differences between the same designs")
+	}
+	diff := Diff(a, b)
+	changes := make(map[string][]ChangeType)
+	for _, d := range diff.Instances {
+		changes[d.Name] = d.Changes
+	}
+	if !reflect.DeepEqual(changes, map[string][]ChangeType{
+		"u1": {ChangeMoved},
+		"u2": {ChangeOrientation},
+		"u3": {ChangeMaster},
+		"u4": {ChangeAdded},
+		"u5": {ChangeRemoved},
+	}) {
+		t.Errorf("Unexpected instance changes %v", changes)
+	}
+	if len(diff.Nets) != 2 {
+		t.Fatalf("Expected both nets to change, found %v", len(diff.Nets))
+	}
+	n1, n2 := diff.Nets[0], diff.Nets[1]
+	if !reflect.DeepEqual(n1.Changes, []ChangeType{ChangeRouting}) || len(n1.AddedShapes) != 1 || len(n1.RemovedShapes) != 1 ||
+		*n1.AddedShapes[0] != (DiffShape{Layer: "metal1", XMin: 50, YMin: 50, XMax: 250, YMax: 270}) {
+		t.Errorf("Unexpected routing change %+v", n1)
+	}
+	if !reflect.DeepEqual(n2.Changes, []ChangeType{ChangeConnectivity}) ||
+		!reflect.DeepEqual(n2.AddedPins, []string{"u4/A"}) || !reflect.DeepEqual(n2.RemovedPins, []string{"u3/A"}) {
+		t.Errorf("Unexpected connectivity change %+v", n2)
+	}
+	if len(diff.Pins) != 1 || diff.Pins[0].Changes[0] != ChangeMoved || diff.Pins[0].After.YMin != 200 {
+		t.Errorf("Expected the block pin to move")
+	}
+
+	markers := make(map[MarkerType]int)
+	for _, m := range diff.Markers {
+		markers[m.Type]++
+		if m.Type == MarkerChanged && len(m.Nets) == 1 && (m.XMin != 400 || m.XMax != 900 || len(m.Pins) != 2) {
+			t.Errorf("Expected the connectivity marker on both instances, found %+v", m)
+		}
+	}
+	// Moved objects are removed and added, re-oriented and re-mastered instances and the connectivity change are changed
+	if !reflect.DeepEqual(markers, map[MarkerType]int{MarkerAdded: 4, MarkerRemoved: 4, MarkerChanged: 3}) {
+		t.Errorf("Unexpected markers %v", markers)
+	}
+}
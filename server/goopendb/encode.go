@@ -0,0 +1,164 @@
+package goopendb
+
+// Streaming JSON encoding of the compact design
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+)
+
+// Validate checks that the design can be encoded with the options
+func (options *JSONOptions) Validate(design *Design) error {
+	if options == nil {
+		return nil
+	}
+	if options.Format != "" && options.Format != FormatJSON && options.Format != FormatProtobuf {
+		return fmt.Errorf("Unsupported design format %v", options.Format)
+	}
+	if options.Microns && options.Format == FormatProtobuf {
+		return fmt.Errorf("Micron units are not supported by the binary design format")
+	}
+	if options.Microns && design.DBUPerMicron <= 0 {
+		return fmt.Errorf("Unknown database units")
+	}
+	return nil
+}
+
+// designSection is an object list of the design JSON, the objects are
+// compacted one at a time while encoding
+type designSection struct {
+	count   int
+	compact func(i int) interface{}
+}
+
+// sections returns the object lists of the design by JSON key
+func (design *Design) sections(options *JSONOptions) map[string]designSection {
+	sections := map[string]designSection{
+		"Instances":      {len(design.Instances), func(i int) interface{} { return design.Instances[i].compact() }},
+		"Nets":           {len(design.Nets), func(i int) interface{} { return design.Nets[i].compact() }},
+		"InstancePins":   {len(design.InstancePins), func(i int) interface{} { return design.InstancePins[i].compact() }},
+		"BlockPins":      {len(design.BlockPins), func(i int) interface{} { return design.BlockPins[i].compact() }},
+		"RoutingVias":    {len(design.RoutingVias), func(i int) interface{} { return design.RoutingVias[i].compact() }},
+		"ViaDefinitions": {len(design.ViaDefinitions), func(i int) interface{} { return design.ViaDefinitions[i].compact() }},
+		"Layers":         {len(design.Layers), func(i int) interface{} { return design.Layers[i].compact() }},
+		"Rows":           {len(design.Rows), func(i int) interface{} { return design.Rows[i].compact() }},
+		"Tracks":         {len(design.Tracks), func(i int) interface{} { return design.Tracks[i].compact() }},
+		"Sites":          {len(design.Sites), func(i int) interface{} { return design.Sites[i] }},
+		"Geometries":     {len(design.Geometries), func(i int) interface{} { return design.Geometries[i].Copy() }},
+		"Blockages":      {len(design.Blockages), func(i int) interface{} { return design.Blockages[i].compact() }},
+		"Regions":        {len(design.Regions), func(i int) interface{} { return design.Regions[i].compact() }},
+		"Groups":         {len(design.Groups), func(i int) interface{} { return design.Groups[i].compact() }},
+		"Fills":          {len(design.Fills), func(i int) interface{} { return design.Fills[i].compact() }},
+		"Masters":        {len(design.Masters), func(i int) interface{} { return design.Masters[i].compact() }},
+		"ViaRules":       {len(design.ViaRules), func(i int) interface{} { return design.ViaRules[i].compact() }},
+	}
+	if !options.IncludeFills {
+		sections["Fills"] = designSection{}
+	}
+	return sections
+}
+
+// designEncoder writes the design JSON, the first error is kept
+type designEncoder struct {
+	*ErrWriter
+	dbu float64 // Database units per micron of micron JSON, 0 for database units
+}
+
+// writeValue encodes a value of the design, its distances are converted to
+// microns for micron JSON
+func (enc *designEncoder) writeValue(value interface{}) {
+	if enc.Err() != nil {
+		return
+	}
+	var buf bytes.Buffer
+	var err error
+	if enc.dbu > 0 {
+		err = writeMicrons(&buf, reflect.ValueOf(value), enc.dbu)
+	} else {
+		err = json.NewEncoder(&buf).Encode(value)
+	}
+	if err != nil {
+		enc.Fail(err)
+		return
+	}
+	enc.Write(bytes.TrimSuffix(buf.Bytes(), []byte("\n")))
+}
+
+// EncodeDesign writes the compact JSON of a design to w section by section,
+// only one object is compacted and encoded at a time so the memory stays
+// bounded by the largest object instead of the design size. The output is the
+// JSON of CompactDesign, nil options excludes the optional sections
+func EncodeDesign(w io.Writer, design *Design, options *JSONOptions) error {
+	if options == nil {
+		options = &JSONOptions{}
+	}
+	if err := options.Validate(design); err != nil {
+		return err
+	}
+	enc := &designEncoder{ErrWriter: NewErrWriter(w)}
+	header := design.compactHeader()
+	header.Units = UnitsDBU
+	if options.Microns {
+		enc.dbu = float64(design.DBUPerMicron)
+		header.Units = UnitsMicron
+	}
+	sections := design.sections(options)
+
+	// Fields are written in the order of the Design struct
+	headerValue := reflect.ValueOf(header).Elem()
+	designType := headerValue.Type()
+	first := true
+	enc.WriteString("{")
+	for i := 0; i < designType.NumField(); i++ {
+		field := designType.Field(i)
+		tag := field.Tag.Get("json")
+		if field.PkgPath != "" || tag == "-" {
+			continue
+		}
+		section, isSection := sections[field.Name]
+		value := headerValue.Field(i)
+		if strings.Contains(tag, "omitempty") && ((isSection && section.count == 0) || (!isSection && isEmptyValue(value))) {
+			continue
+		}
+		if !first {
+			enc.WriteString(",")
+		}
+		first = false
+		enc.WriteString(`"` + field.Name + `":`)
+		if !isSection {
+			enc.writeValue(value.Interface())
+			continue
+		}
+		enc.WriteString("[")
+		for j := 0; j < section.count; j++ {
+			if j > 0 {
+				enc.WriteString(",")
+			}
+			enc.writeValue(section.compact(j))
+		}
+		enc.WriteString("]")
+	}
+	enc.WriteString("}\n")
+	return enc.Flush()
+}
+
+// isEmptyValue reports if the value is omitted by an omitempty JSON tag
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.String, reflect.Slice, reflect.Map, reflect.Array:
+		return v.Len() == 0
+	case reflect.Ptr, reflect.Interface:
+		return v.IsNil()
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	}
+	return false
+}
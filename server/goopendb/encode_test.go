@@ -0,0 +1,66 @@
+package goopendb
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+// failingWriter fails after writing n bytes
+type failingWriter struct {
+	n int
+}
+
+func (w *failingWriter) Write(p []byte) (int, error) {
+	if len(p) > w.n {
+		return w.n, errors.New("Write failed")
+	}
+	w.n -= len(p)
+	return len(p), nil
+}
+
+func TestEncodeDesign(t *testing.T) {
+	design := spatialDesign()
+	design.Geometries = []*Geometry{design.InstancePins[0].Geometries[0]}
+	design.Fills = []*Fill{{ID: 1, Layer: design.Layers[0], Boxes: []*Rect{{XMax: 100, YMax: 100, Layer: design.Layers[0]}}}}
+	design.Markers = []*Marker{{Type: MarkerShort, XMax: 100, YMax: 100, Nets: []string{"a", "b"}}}
+
+	// The streamed JSON is the JSON of the compact design
+	compactDesign := design.CompactDesign()
+	compactDesign.Units = UnitsDBU
+	var expected bytes.Buffer
+	if err := json.NewEncoder(&expected).Encode(compactDesign); err != nil {
+		t.Fatal(err)
+	}
+	var streamed bytes.Buffer
+	if err := EncodeDesign(&streamed, design, &JSONOptions{IncludeFills: true}); err != nil {
+		t.Fatal(err)
+	}
+	if streamed.String() != expected.String() {
+		t.Errorf("Expected the compact design JSON\n%s\nfound\n%s", expected.String(), streamed.String())
+	}
+	if design.InstancePins[0].Instance == nil || design.Fills[0].Boxes[0].Layer == nil {
+		t.Errorf("Expected the design to be unchanged after encoding")
+	}
+
+	var decoded Design
+	streamed.Reset()
+	if err := EncodeDesign(&streamed, design, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(streamed.Bytes(), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Fills == nil || len(decoded.Fills) != 0 || len(decoded.Instances) != len(design.Instances) {
+		t.Errorf("Expected the design without fills")
+	}
+
+	if err := EncodeDesign(&failingWriter{n: 100}, design, nil); err == nil {
+		t.Errorf("Expected the write error")
+	}
+	design.DBUPerMicron = 0
+	if err := EncodeDesign(&streamed, design, &JSONOptions{Microns: true}); err == nil {
+		t.Errorf("Expected an error for micron JSON without database units")
+	}
+}
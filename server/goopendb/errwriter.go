@@ -0,0 +1,62 @@
+package goopendb
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+)
+
+// ErrWriter is a buffered writer keeping the first error, the following
+// writes are skipped so serializers check the error once at the end
+type ErrWriter struct {
+	w   *bufio.Writer
+	err error
+}
+
+// NewErrWriter creates an ErrWriter writing to w
+func NewErrWriter(w io.Writer) *ErrWriter {
+	return &ErrWriter{w: bufio.NewWriter(w)}
+}
+
+// Printf writes formatted text
+func (ew *ErrWriter) Printf(format string, args ...interface{}) {
+	if ew.err != nil {
+		return
+	}
+	_, ew.err = fmt.Fprintf(ew.w, format, args...)
+}
+
+// Write writes data, it always reports success as errors are kept for Flush
+func (ew *ErrWriter) Write(data []byte) (int, error) {
+	if ew.err == nil {
+		_, ew.err = ew.w.Write(data)
+	}
+	return len(data), nil
+}
+
+// WriteString writes s
+func (ew *ErrWriter) WriteString(s string) {
+	if ew.err == nil {
+		_, ew.err = ew.w.WriteString(s)
+	}
+}
+
+// Fail records err unless an earlier error is kept
+func (ew *ErrWriter) Fail(err error) {
+	if ew.err == nil {
+		ew.err = err
+	}
+}
+
+// Err returns the first error
+func (ew *ErrWriter) Err() error {
+	return ew.err
+}
+
+// Flush writes the buffered data, it returns the first error if any
+func (ew *ErrWriter) Flush() error {
+	if ew.err != nil {
+		return ew.err
+	}
+	return ew.w.Flush()
+}
@@ -0,0 +1,96 @@
+package goopendb
+
+// LEF macro FOREIGN statements, OpenDB does not keep them in the database so
+// the OpenDB backend reads them from the LEF files separately
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// lefForeign is a macro FOREIGN statement with the origin in microns
+type lefForeign struct {
+	name        string
+	hasOrigin   bool
+	x, y        float64
+	orientation Orientation
+}
+
+// foreign converts the statement to the design database units
+func (f *lefForeign) foreign(design *Design) *Foreign {
+	foreign := &Foreign{Name: f.name, Orientation: f.orientation}
+	if f.hasOrigin {
+		foreign.Origin = &Point{X: design.ToDBU(f.x), Y: design.ToDBU(f.y)}
+	}
+	return foreign
+}
+
+// readLEFForeigns scans the FOREIGN statements of the LEF macros by macro name
+func readLEFForeigns(r io.Reader) (map[string]*lefForeign, error) {
+	orientations := map[string]Orientation{}
+	for orient, name := range defOrientationNames {
+		orientations[name] = orient
+	}
+	foreigns := map[string]*lefForeign{}
+	var macro string
+	var statement []string // Words of the current statement
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if idx := strings.IndexByte(line, '#'); idx >= 0 {
+			line = line[:idx]
+		}
+		// Only FOREIGN statements are followed to the next line, the MACRO
+		// and END lines have no semicolon
+		if len(statement) == 0 || statement[0] != "FOREIGN" {
+			statement = nil
+		}
+		for _, word := range strings.Fields(line) {
+			end := strings.HasSuffix(word, ";")
+			if word = strings.TrimSuffix(word, ";"); word != "" {
+				statement = append(statement, word)
+			}
+			if !end {
+				continue
+			}
+			if macro != "" && len(statement) >= 2 && statement[0] == "FOREIGN" {
+				foreigns[macro] = parseLEFForeign(statement, orientations)
+			}
+			statement = nil
+		}
+		if len(statement) == 2 && statement[0] == "MACRO" {
+			macro = statement[1]
+		} else if len(statement) == 2 && statement[0] == "END" && statement[1] == macro {
+			macro = ""
+		}
+	}
+	return foreigns, scanner.Err()
+}
+
+// parseLEFForeign parses the words of a FOREIGN name [x y [orient]] statement
+func parseLEFForeign(words []string, orientations map[string]Orientation) *lefForeign {
+	foreign := &lefForeign{name: words[1]}
+	if len(words) >= 4 {
+		x, errX := strconv.ParseFloat(words[2], 64)
+		y, errY := strconv.ParseFloat(words[3], 64)
+		foreign.x, foreign.y, foreign.hasOrigin = x, y, errX == nil && errY == nil
+	}
+	if len(words) >= 5 {
+		foreign.orientation = orientations[words[4]]
+	}
+	return foreign
+}
+
+// readLEFForeignsFile scans the FOREIGN statements of a LEF file
+func readLEFForeignsFile(filepath string) (map[string]*lefForeign, error) {
+	file, err := os.Open(filepath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return readLEFForeigns(file)
+}
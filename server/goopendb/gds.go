@@ -0,0 +1,238 @@
+package goopendb
+
+// GDSII stream serialization of the design layout
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"time"
+)
+
+// GDSVersion is the GDSII stream version written by WriteGDS
+const GDSVersion = 600
+
+// GDSII record types, the low byte is the record data type
+const (
+	gdsHEADER   = 0x0002
+	gdsBGNLIB   = 0x0102
+	gdsLIBNAME  = 0x0206
+	gdsUNITS    = 0x0305
+	gdsENDLIB   = 0x0400
+	gdsBGNSTR   = 0x0502
+	gdsSTRNAME  = 0x0606
+	gdsENDSTR   = 0x0700
+	gdsBOUNDARY = 0x0800
+	gdsSREF     = 0x0A00
+	gdsTEXT     = 0x0C00
+	gdsLAYER    = 0x0D02
+	gdsDATATYPE = 0x0E02
+	gdsXY       = 0x1003
+	gdsENDEL    = 0x1100
+	gdsSNAME    = 0x1206
+	gdsTEXTTYPE = 0x1602
+	gdsSTRING   = 0x1906
+	gdsSTRANS   = 0x1A01
+	gdsANGLE    = 0x1C05
+)
+
+// gdsReflection is the STRANS flag reflecting about the X axis before rotation
+const gdsReflection = 0x8000
+
+// gdsWriter writes GDSII records, the first write error is kept
+type gdsWriter struct {
+	*ErrWriter
+	layers     GDSLayerMap
+	structures map[string]bool // Written master structures
+}
+
+func (gw *gdsWriter) record(typ uint16, data []byte) {
+	if gw.Err() != nil {
+		return
+	}
+	if len(data)%2 == 1 {
+		data = append(data, 0)
+	}
+	if len(data)+4 > math.MaxUint16 {
+		gw.Fail(fmt.Errorf("GDSII record 0x%04X is too long", typ))
+		return
+	}
+	var header [4]byte
+	binary.BigEndian.PutUint16(header[:2], uint16(len(data)+4))
+	binary.BigEndian.PutUint16(header[2:], typ)
+	gw.Write(header[:])
+	gw.Write(data)
+}
+
+func (gw *gdsWriter) int16s(typ uint16, values ...int) {
+	data := make([]byte, 2*len(values))
+	for i, v := range values {
+		binary.BigEndian.PutUint16(data[2*i:], uint16(int16(v)))
+	}
+	gw.record(typ, data)
+}
+
+func (gw *gdsWriter) int32s(typ uint16, values ...int) {
+	data := make([]byte, 4*len(values))
+	for i, v := range values {
+		binary.BigEndian.PutUint32(data[4*i:], uint32(int32(v)))
+	}
+	gw.record(typ, data)
+}
+
+func (gw *gdsWriter) reals(typ uint16, values ...float64) {
+	data := make([]byte, 8*len(values))
+	for i, v := range values {
+		binary.BigEndian.PutUint64(data[8*i:], gdsReal(v))
+	}
+	gw.record(typ, data)
+}
+
+func (gw *gdsWriter) str(typ uint16, s string) {
+	gw.record(typ, []byte(s))
+}
+
+// gdsReal encodes a GDSII 8-byte real: a sign bit, a 7-bit excess 64 base 16
+// exponent and a 56-bit mantissa
+func gdsReal(v float64) uint64 {
+	if v == 0 {
+		return 0
+	}
+	var sign uint64
+	if v < 0 {
+		sign = 1 << 63
+		v = -v
+	}
+	exp := 64
+	for v >= 1 {
+		v /= 16
+		exp++
+	}
+	for v < 1.0/16 {
+		v *= 16
+		exp--
+	}
+	mantissa := uint64(math.Round(v * (1 << 56)))
+	if mantissa >= 1<<56 {
+		mantissa >>= 4
+		exp++
+	}
+	return sign | uint64(exp)<<56 | mantissa
+}
+
+// timestamp writes a BGNLIB or BGNSTR record with the modification and access time
+func (gw *gdsWriter) timestamp(typ uint16, t time.Time) {
+	stamp := []int{t.Year(), int(t.Month()), t.Day(), t.Hour(), t.Minute(), t.Second()}
+	gw.int16s(typ, append(stamp, stamp...)...)
+}
+
+// boundary writes a rectangle, it is skipped when its layer is not mapped
+func (gw *gdsWriter) boundary(layer *Layer, xMin, yMin, xMax, yMax int) {
+	if layer == nil {
+		return
+	}
+	gdsLayer, ok := gw.layers[layer.Name]
+	if !ok {
+		return
+	}
+	gw.record(gdsBOUNDARY, nil)
+	gw.int16s(gdsLAYER, gdsLayer.Layer)
+	gw.int16s(gdsDATATYPE, gdsLayer.DataType)
+	gw.int32s(gdsXY, xMin, yMin, xMax, yMin, xMax, yMax, xMin, yMax, xMin, yMin)
+	gw.record(gdsENDEL, nil)
+}
+
+func (gw *gdsWriter) writeMaster(master *Master, now time.Time) {
+	gw.timestamp(gdsBGNSTR, now)
+	gw.str(gdsSTRNAME, master.Name)
+	master.VisitShapes(gw.boundary)
+	gw.record(gdsENDSTR, nil)
+	gw.structures[master.Name] = true
+}
+
+// writeInstance places the instance master origin with the instance orientation
+func (gw *gdsWriter) writeInstance(inst *Instance) {
+	if !inst.IsPlaced || inst.Origin == nil || !gw.structures[inst.Master] {
+		return
+	}
+	transform := layoutTransforms[inst.Orientation]
+	gw.record(gdsSREF, nil)
+	gw.str(gdsSNAME, inst.Master)
+	if transform.reflect || transform.angle != 0 {
+		flags := 0
+		if transform.reflect {
+			flags |= gdsReflection
+		}
+		gw.int16s(gdsSTRANS, flags)
+		if transform.angle != 0 {
+			gw.reals(gdsANGLE, float64(transform.angle))
+		}
+	}
+	gw.int32s(gdsXY, inst.Origin.X, inst.Origin.Y)
+	gw.record(gdsENDEL, nil)
+}
+
+// writePin writes the block pin shapes and labels the pin on its first mapped layer
+func (gw *gdsWriter) writePin(pin *Pin) {
+	VisitGeometries(pin.Geometries, gw.boundary)
+	gdsLayer, ok := gw.layers.pinLayer(pin)
+	if !ok || pin.Location == nil {
+		return
+	}
+	gw.record(gdsTEXT, nil)
+	gw.int16s(gdsLAYER, gdsLayer.Layer)
+	gw.int16s(gdsTEXTTYPE, gdsLayer.DataType)
+	gw.int32s(gdsXY, pin.Location.X, pin.Location.Y)
+	gw.str(gdsSTRING, pin.Name)
+	gw.record(gdsENDEL, nil)
+}
+
+// WriteGDS writes the design as a GDSII stream: a structure per used master,
+// and a top structure named after the design holding the instance references,
+// block pins and net wiring. A nil layer map uses DefaultGDSLayerMap
+func WriteGDS(design *Design, layers GDSLayerMap, w io.Writer) error {
+	if design.DBUPerMicron <= 0 {
+		return fmt.Errorf("Unknown database units")
+	}
+	if layers == nil {
+		layers = DefaultGDSLayerMap(design)
+	}
+	name := design.Name
+	if name == "" {
+		name = "TOP"
+	}
+	gw := &gdsWriter{
+		ErrWriter:  NewErrWriter(w),
+		layers:     layers,
+		structures: make(map[string]bool),
+	}
+	now := time.Now()
+	dbu := float64(design.DBUPerMicron)
+
+	gw.int16s(gdsHEADER, GDSVersion)
+	gw.timestamp(gdsBGNLIB, now)
+	gw.str(gdsLIBNAME, name+".DB")
+	// User units are microns
+	gw.reals(gdsUNITS, 1/dbu, 1e-6/dbu)
+
+	for _, master := range design.UsedMasters() {
+		gw.writeMaster(master, now)
+	}
+
+	gw.timestamp(gdsBGNSTR, now)
+	gw.str(gdsSTRNAME, name)
+	for _, inst := range design.Instances {
+		gw.writeInstance(inst)
+	}
+	for _, pin := range design.BlockPins {
+		gw.writePin(pin)
+	}
+	for _, net := range design.Nets {
+		net.VisitWires(gw.boundary)
+		net.VisitSpecialWires(gw.boundary)
+	}
+	gw.record(gdsENDSTR, nil)
+	gw.record(gdsENDLIB, nil)
+	return gw.Flush()
+}
@@ -0,0 +1,254 @@
+package goopendb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strings"
+	"testing"
+)
+
+type gdsRecord struct {
+	typ  uint16
+	data []byte
+}
+
+func readGDSRecords(t *testing.T, stream []byte) []gdsRecord {
+	var records []gdsRecord
+	for len(stream) > 0 {
+		if len(stream) < 4 {
+			t.Fatalf("Truncated record header")
+		}
+		size := int(binary.BigEndian.Uint16(stream))
+		if size < 4 || size%2 != 0 || size > len(stream) {
+			t.Fatalf("Invalid record size %v", size)
+		}
+		records = append(records, gdsRecord{typ: binary.BigEndian.Uint16(stream[2:]), data: stream[4:size]})
+		stream = stream[size:]
+	}
+	return records
+}
+
+// str trims the padding of string records
+func (r gdsRecord) str() string {
+	return strings.TrimRight(string(r.data), "\x00")
+}
+
+func (r gdsRecord) int32s() []int {
+	var values []int
+	for i := 0; i+4 <= len(r.data); i += 4 {
+		values = append(values, int(int32(binary.BigEndian.Uint32(r.data[i:]))))
+	}
+	return values
+}
+
+func TestGDSReal(t *testing.T) {
+	for _, tc := range []struct {
+		value float64
+		bits  uint64
+	}{
+		{0, 0},
+		{1, 0x4110000000000000},
+		{-2, 0xC120000000000000},
+		{90, 0x425A000000000000},
+		{1e-3, 0x3E4189374BC6A7F0},
+		{1e-9, 0x3944B82FA09B5A54},
+	} {
+		if bits := gdsReal(tc.value); bits != tc.bits {
+			t.Errorf("Expected %v to encode as %016X, got %016X", tc.value, tc.bits, bits)
+		}
+	}
+}
+
+func TestWriteGDS(t *testing.T) {
+	metal1 := &Layer{ID: 1, Name: "metal1"}
+	metal2 := &Layer{ID: 2, Name: "metal2"}
+	via1 := &Layer{ID: 3, Name: "via1"}
+	inv := &Master{
+		ID:     1,
+		Name:   "INV_X1",
+		Width:  760,
+		Height: 2800,
+		Pins: []*MasterPin{{
+			ID:         1,
+			Name:       "ZN",
+			Geometries: []*Geometry{{ID: 1, Boxes: []*Rect{{XMin: 100, XMax: 200, YMax: 1000, Layer: metal1}}}},
+		}},
+	}
+	via := &Via{
+		ID:   1,
+		Name: "via1_4",
+		Rect: &Rect{XMin: -70, YMin: -70, XMax: 70, YMax: 70},
+		Boxes: []*Rect{
+			{XMin: -70, YMin: -70, XMax: 70, YMax: 70, Layer: metal1},
+			{XMin: -35, YMin: -35, XMax: 35, YMax: 35, Layer: via1},
+			{XMin: -70, YMin: -70, XMax: 70, YMax: 70, Layer: metal2},
+		},
+	}
+	net := &Net{
+		ID:   1,
+		Name: "n1",
+		Edges: []*Edge{
+			{Type: EdgeTypeSEGMENT, Layer: metal2, Rect: &Rect{XMin: 0, YMin: 0, XMax: 1000, YMax: 140}},
+			{Type: EdgeTypeVIA, Layer: metal1, Via: via, Rect: &Rect{XMin: 930, YMin: 0, XMax: 1070, YMax: 140}},
+			{Type: EdgeTypeVWIRE, Layer: metal2, Rect: &Rect{XMin: 0, YMin: 0, XMax: 10, YMax: 10}},
+		},
+	}
+	design := &Design{
+		Name:         "top",
+		DBUPerMicron: 2000,
+		Layers:       []*Layer{metal1, via1, metal2},
+		Masters:      []*Master{inv},
+		Instances: []*Instance{
+			{ID: 1, Name: "u1", Master: "INV_X1", IsPlaced: true, Origin: &Point{X: 1000, Y: 0}},
+			{ID: 2, Name: "u2", Master: "INV_X1", IsPlaced: true, Origin: &Point{X: 2520, Y: 5600}, Orientation: OrientationMY},
+			{ID: 3, Name: "u3", Master: "INV_X1", Origin: &Point{}},
+		},
+		Nets: []*Net{net},
+	}
+	layers := GDSLayerMap{"metal1": {Layer: 11}, "metal2": {Layer: 13, DataType: 1}}
+
+	var buf bytes.Buffer
+	if err := WriteGDS(design, layers, &buf); err != nil {
+		t.Fatal(err)
+	}
+	records := readGDSRecords(t, buf.Bytes())
+	if records[0].typ != gdsHEADER || records[len(records)-1].typ != gdsENDLIB {
+		t.Fatalf("Expected a GDSII library, got %v records", len(records))
+	}
+
+	var structures, snames []string
+	var boundaries [][]int
+	var angles, reflections int
+	for i, record := range records {
+		switch record.typ {
+		case gdsSTRNAME:
+			structures = append(structures, record.str())
+		case gdsSNAME:
+			snames = append(snames, record.str())
+		case gdsSTRANS:
+			if binary.BigEndian.Uint16(record.data)&gdsReflection != 0 {
+				reflections++
+			}
+		case gdsANGLE:
+			if binary.BigEndian.Uint64(record.data) != gdsReal(180) {
+				t.Errorf("Expected a 180 degrees rotation")
+			}
+			angles++
+		case gdsBOUNDARY:
+			layer := int(binary.BigEndian.Uint16(records[i+1].data))
+			datatype := int(binary.BigEndian.Uint16(records[i+2].data))
+			xy := records[i+3].int32s()
+			boundaries = append(boundaries, []int{layer, datatype, xy[0], xy[1], xy[4], xy[5]})
+		}
+	}
+	if len(structures) != 2 || structures[0] != "INV_X1" || structures[1] != "top" {
+		t.Errorf("Unexpected structures %v", structures)
+	}
+	if len(snames) != 2 || reflections != 1 || angles != 1 {
+		t.Errorf("Expected 2 references with one flipped, got %v with %v reflections", snames, reflections)
+	}
+	expected := [][]int{
+		{11, 0, 100, 0, 200, 1000}, // Master pin
+		{13, 1, 0, 0, 1000, 140},   // Segment
+		{11, 0, 930, 0, 1070, 140}, // Via bottom, via1 is not mapped
+		{13, 1, 930, 0, 1070, 140}, // Via top
+	}
+	if len(boundaries) != len(expected) {
+		t.Fatalf("Expected %v boundaries, got %v", len(expected), boundaries)
+	}
+	for i := range expected {
+		for j := range expected[i] {
+			if boundaries[i][j] != expected[i][j] {
+				t.Errorf("Expected boundary %v, got %v", expected[i], boundaries[i])
+				break
+			}
+		}
+	}
+}
+
+// gdsPlace applies the transformation of an SREF record to a master point
+func gdsPlace(reflect bool, angle float64, origin []int, x, y int) (int, int) {
+	if reflect {
+		y = -y
+	}
+	switch angle {
+	case 90:
+		x, y = -y, x
+	case 180:
+		x, y = -x, -y
+	case 270:
+		x, y = y, -x
+	}
+	return x + origin[0], y + origin[1]
+}
+
+func TestWriteGDSOrientations(t *testing.T) {
+	metal1 := &Layer{ID: 1, Name: "metal1"}
+	inv := &Master{
+		ID:     1,
+		Name:   "INV_X1",
+		Width:  760,
+		Height: 2800,
+		Pins: []*MasterPin{{
+			ID:         1,
+			Name:       "ZN",
+			Geometries: []*Geometry{{ID: 1, Boxes: []*Rect{{XMin: 100, XMax: 200, YMax: 1000, Layer: metal1}}}},
+		}},
+	}
+	design := &Design{Name: "top", DBUPerMicron: 2000, Layers: []*Layer{metal1}, Masters: []*Master{inv}}
+	for i := 0; i < 8; i++ {
+		design.Instances = append(design.Instances, &Instance{
+			ID:          i + 1,
+			Master:      "INV_X1",
+			IsPlaced:    true,
+			Origin:      &Point{X: 5000 * i, Y: 3000},
+			Orientation: Orientation(i),
+		})
+	}
+
+	var buf bytes.Buffer
+	if err := WriteGDS(design, nil, &buf); err != nil {
+		t.Fatal(err)
+	}
+	type reference struct {
+		reflect bool
+		angle   float64
+		origin  []int
+	}
+	var refs []*reference
+	var ref *reference
+	for _, record := range readGDSRecords(t, buf.Bytes()) {
+		switch record.typ {
+		case gdsSREF:
+			ref = &reference{}
+			refs = append(refs, ref)
+		case gdsSTRANS:
+			ref.reflect = binary.BigEndian.Uint16(record.data)&gdsReflection != 0
+		case gdsANGLE:
+			for _, angle := range []float64{90, 180, 270} {
+				if binary.BigEndian.Uint64(record.data) == gdsReal(angle) {
+					ref.angle = angle
+				}
+			}
+		case gdsXY:
+			if ref != nil {
+				ref.origin = record.int32s()
+			}
+		case gdsENDEL:
+			ref = nil
+		}
+	}
+	if len(refs) != len(design.Instances) {
+		t.Fatalf("Expected %v references, found %v", len(design.Instances), len(refs))
+	}
+	// The references place the master corners like the instance orientation
+	for i, inst := range design.Instances {
+		for _, corner := range [][2]int{{0, 0}, {inv.Width, 0}, {0, inv.Height}, {inv.Width, inv.Height}} {
+			x, y := gdsPlace(refs[i].reflect, refs[i].angle, refs[i].origin, corner[0], corner[1])
+			ex, ey := inst.Orientation.Apply(corner[0], corner[1])
+			if x != ex+inst.Origin.X || y != ey+inst.Origin.Y {
+				t.Errorf("Unexpected %v placement of %v: (%v, %v)", inst.Orientation, corner, x, y)
+			}
+		}
+	}
+}
@@ -1,24 +1,16 @@
 package goopendb
 
-// Wrapper for OpenDB Si2 LEF/DEF parser
+// Design model shared by the LEF/DEF parsing backends
 
-// #cgo LDFLAGS: -L${SRCDIR}/c++
-// #cgo LDFLAGS: -ldl -lgoopendb -llefin -llef -ldefin -ldef -lzlib -llefzlib -lopendb -lzutil -ldefout -llefout -ltm -lm -ltcl -lstdc++
-// #include "stdlib.h"
-// #include "c++/goopendb.h"
-import "C"
 import (
 	"bytes"
 	"compress/gzip"
-	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"path"
-	"reflect"
 	"regexp"
 	"strings"
-	"unsafe"
 )
 
 // Orientation is instance placement orientation
@@ -173,6 +165,8 @@ const (
 	MasterTypeCORE
 	MasterTypePAD
 	MasterTypeENDCAP
+	MasterTypeCOVER
+	MasterTypeRING
 )
 
 func (typ MasterType) String() string {
@@ -185,6 +179,10 @@ func (typ MasterType) String() string {
 		return "PAD"
 	case MasterTypeENDCAP:
 		return "ENDCAP"
+	case MasterTypeCOVER:
+		return "COVER"
+	case MasterTypeRING:
+		return "RING"
 	}
 	return "Unknown"
 }
@@ -219,7 +217,7 @@ func (typ SignalType) String() string {
 	case SignalTypeRESET:
 		return "RESET"
 	case SignalTypeSCAN:
-		return "SCA"
+		return "SCAN"
 	case SignalTypeTIEOFF:
 		return "TIEOFF"
 	}
@@ -257,6 +255,34 @@ func (typ WireType) String() string {
 	return "Unknown"
 }
 
+// PlacementStatus is the DEF placement status of a component
+type PlacementStatus int
+
+// PlacementStatus enums
+const (
+	PlacementStatusNONE = iota
+	PlacementStatusUNPLACED
+	PlacementStatusPLACED
+	PlacementStatusFIXED
+	PlacementStatusCOVER
+)
+
+func (status PlacementStatus) String() string {
+	switch status {
+	case PlacementStatusNONE:
+		return "NONE"
+	case PlacementStatusUNPLACED:
+		return "UNPLACED"
+	case PlacementStatusPLACED:
+		return "PLACED"
+	case PlacementStatusFIXED:
+		return "FIXED"
+	case PlacementStatusCOVER:
+		return "COVER"
+	}
+	return "Unknown"
+}
+
 // WireShapeType is connection segment type
 type WireShapeType int
 
@@ -307,15 +333,51 @@ func (typ WireShapeType) String() string {
 	return "Unknown"
 }
 
-// OpenDB is a wrapper for OpenDB database object
-type OpenDB struct {
-	db C.dbDatabase
+// BlockageType is DEF blockage type
+type BlockageType int
+
+// BlockageType enums
+const (
+	BlockageTypePLACEMENT BlockageType = iota
+	BlockageTypeROUTING
+)
+
+func (typ BlockageType) String() string {
+	switch typ {
+	case BlockageTypePLACEMENT:
+		return "PLACEMENT"
+	case BlockageTypeROUTING:
+		return "ROUTING"
+	}
+	return "Unknown"
+}
+
+// RegionType is DEF region type
+type RegionType int
+
+// RegionType enums
+const (
+	RegionTypeFENCE RegionType = iota
+	RegionTypeGUIDE
+	RegionTypeEXCLUSIVE
+)
+
+func (typ RegionType) String() string {
+	switch typ {
+	case RegionTypeFENCE:
+		return "FENCE"
+	case RegionTypeGUIDE:
+		return "GUIDE"
+	case RegionTypeEXCLUSIVE:
+		return "EXCLUSIVE"
+	}
+	return "Unknown"
 }
 
 // Point is an X, Y coordinate
 type Point struct {
-	X int
-	Y int
+	X int `proto:"1"`
+	Y int `proto:"2"`
 }
 
 // Copy Point pointer to a new Point
@@ -328,15 +390,15 @@ func (p *Point) Copy() Point {
 
 // Rect is a rectangle shape holder
 type Rect struct {
-	ID         int
-	XMin       int
-	YMin       int
-	XMax       int
-	YMax       int
-	ShapeType  int
-	Layer      *Layer
-	Via        *Via
-	InComplete bool
+	ID         int    `proto:"1"`
+	XMin       int    `proto:"2"`
+	YMin       int    `proto:"3"`
+	XMax       int    `proto:"4"`
+	YMax       int    `proto:"5"`
+	ShapeType  int    `proto:"6"`
+	Layer      *Layer `proto:"7"`
+	Via        *Via   `proto:"8"`
+	InComplete bool   `proto:"9"`
 }
 
 // Copy Rect pointer to a new Rect
@@ -356,9 +418,9 @@ func (r *Rect) Copy() Rect {
 
 // Geometry is a collecitons of shapes (rect)
 type Geometry struct {
-	ID         int
-	Boxes      []*Rect
-	InComplete bool
+	ID         int     `proto:"1"`
+	Boxes      []*Rect `proto:"2"`
+	InComplete bool    `proto:"3"`
 }
 
 // Copy Geometry pointer to a new Geometry
@@ -416,143 +478,377 @@ func (r *Grid) Copy() Grid {
 
 // Edge is a net routing edge
 type Edge struct {
-	Type  EdgeType
-	Rect  *Rect
-	Via   *Via
-	Layer *Layer
+	Type  EdgeType `proto:"1"`
+	Rect  *Rect    `proto:"2"`
+	Via   *Via     `proto:"3"`
+	Layer *Layer   `proto:"4"`
 }
 
 // Instance is a wrapper for a single instance
 type Instance struct {
-	ID           int
-	Name         string `json:",omitempty"`
-	Location     *Point `json:",omitempty"`
-	Origin       *Point `json:",omitempty"`
-	Orientation  Orientation
-	Master       string `json:",omitempty"`
-	Pins         []*Pin `json:",omitempty"`
-	IsPlaced     bool
-	BoundingBox  *Rect `json:",omitempty"`
-	Halo         *Rect `json:",omitempty"`
-	IsFiller     bool
-	MasterType   MasterType
-	Obstructions *Geometry `json:",omitempty"`
-	InComplete   bool      // The struct contains ID only
+	ID           int             `proto:"1"`
+	Name         string          `json:",omitempty" proto:"2"`
+	Location     *Point          `json:",omitempty" proto:"3"`
+	Origin       *Point          `json:",omitempty" proto:"4"`
+	Orientation  Orientation     `proto:"5"`
+	Master       string          `json:",omitempty" proto:"6"`
+	Pins         []*Pin          `json:",omitempty" proto:"7"`
+	IsPlaced     bool            `proto:"8"`
+	Status       PlacementStatus `proto:"18"`
+	BoundingBox  *Rect           `json:",omitempty" proto:"9"`
+	Halo         *Rect           `json:",omitempty" proto:"10"`
+	IsFiller     bool            `proto:"11"`
+	MasterType   MasterType      `proto:"12"`
+	Obstructions *Geometry       `json:",omitempty" proto:"13"`
+	Region       *Region         `json:",omitempty" proto:"14"`
+	Group        *Group          `json:",omitempty" proto:"15"`
+	MasterRef    *Master         `json:",omitempty" proto:"16"` // Master holds the name only
+	InComplete   bool            `proto:"17"`                   // The struct contains ID only
+}
+
+// MasterPin is a wrapper for a LEF macro pin
+type MasterPin struct {
+	ID         int         `proto:"1"`
+	Name       string      `json:",omitempty" proto:"2"`
+	Direction  IoType      `proto:"3"`
+	SignalType SignalType  `proto:"4"`
+	Geometries []*Geometry `json:",omitempty" proto:"5"`
+}
+
+// Master is a wrapper for a LEF macro
+type Master struct {
+	ID           int          `proto:"1"`
+	Name         string       `json:",omitempty" proto:"2"`
+	Library      string       `json:",omitempty" proto:"3"`
+	Type         MasterType   `proto:"4"`
+	Class        string       `json:",omitempty" proto:"5"` // Full LEF CLASS including the subclass
+	Foreign      *Foreign     `json:",omitempty" proto:"17"`
+	Width        int          `proto:"6"`
+	Height       int          `proto:"7"`
+	Origin       *Point       `json:",omitempty" proto:"8"`
+	Site         *Site        `json:",omitempty" proto:"9"`
+	SymmetryX    bool         `proto:"10"`
+	SymmetryY    bool         `proto:"11"`
+	SymmetryR90  bool         `proto:"12"`
+	IsFiller     bool         `proto:"13"`
+	Pins         []*MasterPin `json:",omitempty" proto:"14"`
+	Obstructions *Geometry    `json:",omitempty" proto:"15"`
+	InComplete   bool         `proto:"16"` // The struct contains ID only
+}
+
+// Foreign is the FOREIGN cell of a master in another database (e.g. GDSII)
+type Foreign struct {
+	Name        string      `proto:"1"`
+	Origin      *Point      `json:",omitempty" proto:"2"`
+	Orientation Orientation `proto:"3"`
 }
 
 // Pin is a wrapper for a single pin
 type Pin struct {
-	ID         int
-	Name       string    `json:",omitempty"`
-	Instance   *Instance `json:",omitempty"`
-	Net        *Net      `json:",omitempty"`
-	Direction  Direction
-	Location   *Point      `json:",omitempty"`
-	Geometries []*Geometry `json:",omitempty"`
-	SignalType SignalType
-	IsBlock    bool
-	IsSpecial  bool
-	InComplete bool // The struct contains ID only
+	ID         int         `proto:"1"`
+	Name       string      `json:",omitempty" proto:"2"`
+	Instance   *Instance   `json:",omitempty" proto:"3"`
+	Net        *Net        `json:",omitempty" proto:"4"`
+	Direction  Direction   `proto:"5"`
+	Location   *Point      `json:",omitempty" proto:"6"`
+	Geometries []*Geometry `json:",omitempty" proto:"7"`
+	SignalType SignalType  `proto:"8"`
+	IsBlock    bool        `proto:"9"`
+	IsSpecial  bool        `proto:"10"`
+	InComplete bool        `proto:"11"` // The struct contains ID only
+}
+
+// SpecialShape is a special wire shape (SBox)
+type SpecialShape struct {
+	Rect      *Rect         `proto:"1"`
+	ShapeType WireShapeType `proto:"2"`
+	Width     int           `proto:"3"` // Zero for via shapes
+	Mask      int           `proto:"4"`
+}
+
+// SpecialWire is a special net wire (SWire)
+type SpecialWire struct {
+	ID        int             `proto:"1"`
+	WireType  WireType        `proto:"2"`
+	ShieldNet string          `json:",omitempty" proto:"5"` // Shielded net of SHIELD wires
+	Geometry  *Geometry       `proto:"3"`                   // The matching geometry in the net special boxes
+	Shapes    []*SpecialShape `proto:"4"`
 }
 
 // Net is a wrapper for a single net
 type Net struct {
-	ID           int
-	Name         string `json:",omitempty"`
-	IsSpecial    bool
-	IsRouted     bool
-	Pins         []*Pin  `json:",omitempty"`
-	Edges        []*Edge `json:",omitempty"`
-	SpecialBoxes []*Geometry
-	InComplete   bool // The struct contains ID only
+	ID           int            `proto:"1"`
+	Name         string         `json:",omitempty" proto:"2"`
+	IsSpecial    bool           `proto:"3"`
+	IsRouted     bool           `proto:"4"`
+	WireType     WireType       `proto:"5"`
+	Use          SignalType     `proto:"11"`
+	Pins         []*Pin         `json:",omitempty" proto:"6"`
+	Edges        []*Edge        `json:",omitempty" proto:"7"`
+	SpecialBoxes []*Geometry    `proto:"8"`
+	SpecialWires []*SpecialWire `json:",omitempty" proto:"9"`
+	InComplete   bool           `proto:"10"` // The struct contains ID only
+}
+
+// SpacingTable is a LEF parallel run length spacing table
+type SpacingTable struct {
+	Widths   []int   `proto:"1"`
+	Lengths  []int   `proto:"2"`
+	Spacings [][]int `proto:"3"` // Spacings[width index][length index]
+}
+
+// InfluenceSpacing is a LEF influence spacing table entry
+type InfluenceSpacing struct {
+	Width   int `proto:"1"`
+	Within  int `proto:"2"`
+	Spacing int `proto:"3"`
+}
+
+// MinEnclosedArea is a LEF minimum enclosed area rule
+type MinEnclosedArea struct {
+	Area  float64 `proto:"1"`
+	Width int     `proto:"2"` // -1 if not set
+}
+
+// AntennaRule is a LEF layer antenna rule
+type AntennaRule struct {
+	AreaFactor float64 `proto:"1"`
+	PAR        float64 `proto:"2"` // Antenna area ratio
+	CAR        float64 `proto:"3"` // Antenna cumulative area ratio
+	PSR        float64 `proto:"4"` // Antenna side area ratio
+	CSR        float64 `proto:"5"` // Antenna cumulative side area ratio
 }
 
 // Layer is a wrapper for a LEF layer
 type Layer struct {
-	ID         int
-	Name       string `json:",omitempty"`
-	Alias      string `json:",omitempty"`
-	Width      int
-	Spacing    int
-	Area       float64
-	Type       LayerType
-	Direction  Direction
-	UpperLayer *Layer `json:",omitempty"`
-	LowerLayer *Layer `json:",omitempty"`
-	InComplete bool   // The struct contains ID only
+	ID               int                 `proto:"1"`
+	Name             string              `json:",omitempty" proto:"2"`
+	Alias            string              `json:",omitempty" proto:"3"`
+	Width            int                 `proto:"4"`
+	Spacing          int                 `proto:"5"`
+	Area             float64             `proto:"6"`
+	Type             LayerType           `proto:"7"`
+	Direction        Direction           `proto:"8"`
+	PitchX           int                 `proto:"9"`
+	PitchY           int                 `proto:"10"`
+	OffsetX          int                 `proto:"11"`
+	OffsetY          int                 `proto:"12"`
+	MinWidth         int                 `proto:"13"`
+	MaxWidth         int                 `proto:"14"` // -1 if not set
+	MinStep          int                 `proto:"15"`
+	Height           int                 `proto:"27"` // Distance from the substrate top, -1 if not set
+	Thickness        int                 `proto:"16"` // -1 if not set
+	Resistance       float64             `proto:"17"`
+	Capacitance      float64             `proto:"18"`
+	EdgeCapacitance  float64             `proto:"19"`
+	SpacingTable     *SpacingTable       `json:",omitempty" proto:"20"`
+	InfluenceSpacing []*InfluenceSpacing `json:",omitempty" proto:"21"`
+	MinEnclosedAreas []*MinEnclosedArea  `json:",omitempty" proto:"22"`
+	AntennaRule      *AntennaRule        `json:",omitempty" proto:"23"`
+	UpperLayer       *Layer              `json:",omitempty" proto:"24"`
+	LowerLayer       *Layer              `json:",omitempty" proto:"25"`
+	InComplete       bool                `proto:"26"` // The struct contains ID only
+}
+
+// ViaParams is a wrapper for generated via parameters
+type ViaParams struct {
+	CutSizeX         int `proto:"1"`
+	CutSizeY         int `proto:"2"`
+	CutSpacingX      int `proto:"3"`
+	CutSpacingY      int `proto:"4"`
+	BottomEnclosureX int `proto:"5"`
+	BottomEnclosureY int `proto:"6"`
+	TopEnclosureX    int `proto:"7"`
+	TopEnclosureY    int `proto:"8"`
+	Rows             int `proto:"9"`
+	Cols             int `proto:"10"`
+	OriginX          int `proto:"11"`
+	OriginY          int `proto:"12"`
+	BottomOffsetX    int `proto:"13"`
+	BottomOffsetY    int `proto:"14"`
+	TopOffsetX       int `proto:"15"`
+	TopOffsetY       int `proto:"16"`
 }
 
 // Via is a wrapper for design via
 type Via struct {
-	ID          int
-	Name        string `json:",omitempty"`
-	Rect        *Rect  `json:",omitempty"`
-	TopLayer    *Layer `json:",omitempty"`
-	CutLayer    *Layer `json:",omitempty"`
-	BottomLayer *Layer `json:",omitempty"`
-	IsBlock     bool
-	IsTech      bool
-	InComplete  bool // The struct contains ID only
+	ID          int        `proto:"1"` // Unique across the routing vias and via definitions
+	Name        string     `json:",omitempty" proto:"2"`
+	Rect        *Rect      `json:",omitempty" proto:"3"`
+	TopLayer    *Layer     `json:",omitempty" proto:"4"`
+	CutLayer    *Layer     `json:",omitempty" proto:"5"`
+	BottomLayer *Layer     `json:",omitempty" proto:"6"`
+	IsBlock     bool       `proto:"7"`
+	IsTech      bool       `proto:"8"`
+	Params      *ViaParams `json:",omitempty" proto:"9"`  // Generated via parameters
+	Pattern     string     `json:",omitempty" proto:"10"` // Cut pattern
+	Boxes       []*Rect    `json:",omitempty" proto:"11"` // Bottom metal, cuts and top metal shapes
+	Rule        *ViaRule   `json:",omitempty" proto:"12"`
+	InComplete  bool       `proto:"13"` // The struct contains ID only
+}
+
+// ViaRuleLayer is a wrapper for a LEF VIARULE GENERATE layer
+type ViaRuleLayer struct {
+	Layer              *Layer    `json:",omitempty" proto:"1"`
+	Direction          Direction `proto:"2"`
+	HasEnclosure       bool      `proto:"3"`
+	EnclosureOverhang1 int       `proto:"4"`
+	EnclosureOverhang2 int       `proto:"5"`
+	HasWidth           bool      `proto:"6"`
+	MinWidth           int       `proto:"7"`
+	MaxWidth           int       `proto:"8"`
+	Rect               *Rect     `json:",omitempty" proto:"9"`
+	HasSpacing         bool      `proto:"10"`
+	SpacingX           int       `proto:"11"`
+	SpacingY           int       `proto:"12"`
+	Resistance         float64   `proto:"13"`
+}
+
+// ViaRule is a wrapper for a LEF VIARULE GENERATE
+type ViaRule struct {
+	ID         int             `proto:"1"`
+	Name       string          `json:",omitempty" proto:"2"`
+	IsDefault  bool            `proto:"3"`
+	Layers     []*ViaRuleLayer `json:",omitempty" proto:"4"`
+	InComplete bool            `proto:"5"` // The struct contains ID only
 }
 
 // Site is a wrapper for a technology sit
 type Site struct {
-	ID         int
-	Name       string `json:",omitempty"`
-	InComplete bool   // The struct contains ID only
+	ID          int    `proto:"1"`
+	Name        string `json:",omitempty" proto:"2"`
+	Class       string `json:",omitempty" proto:"3"`
+	Width       int    `proto:"4"`
+	Height      int    `proto:"5"`
+	SymmetryX   bool   `proto:"6"`
+	SymmetryY   bool   `proto:"7"`
+	SymmetryR90 bool   `proto:"8"`
+	InComplete  bool   `proto:"9"` // The struct contains ID only
 }
 
 // Grid is wrapper for track or gcell grids
 type Grid struct {
-	ID                     int
-	Layer                  *Layer `json:",omitempty"`
-	GridX                  []int
-	GridY                  []int
-	GridXPatternOrigins    []int
-	GridXPatternLineCounts []int
-	GridXPatternSteps      []int
-	GridYPatternOrigins    []int
-	GridYPatternLineCounts []int
-	GridYPatternSteps      []int
-	InComplete             bool // The struct contains ID only
+	ID                     int    `proto:"1"`
+	Layer                  *Layer `json:",omitempty" proto:"2"`
+	GridX                  []int  `proto:"3"`
+	GridY                  []int  `proto:"4"`
+	GridXPatternOrigins    []int  `proto:"5"`
+	GridXPatternLineCounts []int  `proto:"6"`
+	GridXPatternSteps      []int  `proto:"7"`
+	GridYPatternOrigins    []int  `proto:"8"`
+	GridYPatternLineCounts []int  `proto:"9"`
+	GridYPatternSteps      []int  `proto:"10"`
+	InComplete             bool   `proto:"11"` // The struct contains ID only
 }
 
 // Row is wrapper for placement row
 type Row struct {
-	ID          int
-	Name        string `json:",omitempty"`
-	Site        *Site  `json:",omitempty"`
-	Direction   Direction
-	Orientation Orientation
-	OriginX     int
-	OriginY     int
-	Spacing     int
-	BoundingBox *Rect
-	InComplete  bool // The struct contains ID only
+	ID          int         `proto:"1"`
+	Name        string      `json:",omitempty" proto:"2"`
+	Site        *Site       `json:",omitempty" proto:"3"`
+	Direction   Direction   `proto:"4"`
+	Orientation Orientation `proto:"5"`
+	OriginX     int         `proto:"6"`
+	OriginY     int         `proto:"7"`
+	Spacing     int         `proto:"8"`
+	SiteCount   int         `proto:"9"`
+	BoundingBox *Rect       `proto:"10"`
+	InComplete  bool        `proto:"11"` // The struct contains ID only
+}
+
+// Blockage is a wrapper for DEF placement blockage or routing blockage (obstruction),
+// polygon blockages are decomposed into rectangles by the parser
+type Blockage struct {
+	ID              int          `proto:"1"`
+	Type            BlockageType `proto:"2"`
+	Rect            *Rect        `json:",omitempty" proto:"3"`
+	Layer           *Layer       `json:",omitempty" proto:"4"` // Routing blockages only
+	Instance        *Instance    `json:",omitempty" proto:"5"` // Owning instance (COMPONENT)
+	MaxDensity      float64      `proto:"6"`                   // Partial placement blockage density
+	IsSoft          bool         `proto:"7"`
+	IsPushedDown    bool         `proto:"8"`
+	IsSlot          bool         `proto:"9"`
+	IsFill          bool         `proto:"10"`
+	IsExceptPGNets  bool         `proto:"11"`
+	MinSpacing      int          `proto:"12"` // -1 if not set
+	DesignRuleWidth int          `proto:"13"` // -1 if not set
+	InComplete      bool         `proto:"14"` // The struct contains ID only
+}
+
+// Region is a wrapper for DEF region (fence or guide)
+type Region struct {
+	ID         int         `proto:"1"`
+	Name       string      `json:",omitempty" proto:"2"`
+	Type       RegionType  `proto:"3"`
+	Boxes      []*Rect     `json:",omitempty" proto:"4"`
+	Instances  []*Instance `json:",omitempty" proto:"5"` // Member instances, including group members
+	Groups     []*Group    `json:",omitempty" proto:"6"`
+	InComplete bool        `proto:"7"` // The struct contains ID only
+}
+
+// Group is a wrapper for DEF group
+type Group struct {
+	ID         int         `proto:"1"`
+	Name       string      `json:",omitempty" proto:"2"`
+	Region     *Region     `json:",omitempty" proto:"3"`
+	Instances  []*Instance `json:",omitempty" proto:"4"`
+	InComplete bool        `proto:"5"` // The struct contains ID only
+}
+
+// Fill is a wrapper for DEF fill shapes sharing the same layer, mask and OPC flag
+type Fill struct {
+	ID         int     `proto:"1"`
+	Layer      *Layer  `json:",omitempty" proto:"2"`
+	Mask       int     `proto:"3"`
+	NeedsOPC   bool    `proto:"4"`
+	Boxes      []*Rect `json:",omitempty" proto:"5"`
+	InComplete bool    `proto:"6"` // The struct contains ID only
 }
 
 // Design is a wrapper for parsed DEF/LEF
 type Design struct {
-	Name           string
-	Instances      []*Instance
-	Nets           []*Net
-	InstancePins   []*Pin
-	BlockPins      []*Pin
-	RoutingVias    []*Via
-	ViaDefinitions []*Via
-	Layers         []*Layer
-	CoreArea       float64
-	DieArea        float64
-	DesignArea     float64
-	Utilization    float64
-	BoundingBox    *Rect
-	Core           *Rect
-	Die            *Rect
-	Rows           []*Row
-	Tracks         []*Grid
-	Sites          []*Site
-	GCell          *Grid
-	Geometries     []*Geometry
+	Name           string      `proto:"1"`
+	DBUPerMicron   int         `proto:"2"`                   // Database units per micron
+	LEFUnits       int         `proto:"3"`                   // LEF UNITS DATABASE MICRONS
+	DEFUnits       int         `proto:"4"`                   // DEF UNITS DISTANCE MICRONS
+	Units          string      `json:",omitempty" proto:"5"` // Coordinate units of the design JSON
+	Instances      []*Instance `proto:"6"`
+	Nets           []*Net      `proto:"7"`
+	InstancePins   []*Pin      `proto:"8"`
+	BlockPins      []*Pin      `proto:"9"`
+	RoutingVias    []*Via      `proto:"10"`
+	ViaDefinitions []*Via      `proto:"11"`
+	Layers         []*Layer    `proto:"12"`
+	CoreArea       float64     `proto:"13"`
+	DieArea        float64     `proto:"14"`
+	DesignArea     float64     `proto:"15"`
+	Utilization    float64     `proto:"16"`
+	BoundingBox    *Rect       `proto:"17"`
+	Core           *Rect       `proto:"18"`
+	Die            *Rect       `proto:"19"`
+	Rows           []*Row      `proto:"20"`
+	Tracks         []*Grid     `proto:"21"`
+	Sites          []*Site     `proto:"22"`
+	GCell          *Grid       `proto:"23"`
+	Geometries     []*Geometry `proto:"24"`
+	Blockages      []*Blockage `proto:"25"`
+	Regions        []*Region   `proto:"26"`
+	Groups         []*Group    `proto:"27"`
+	Fills          []*Fill     `proto:"28"`
+	Masters        []*Master   `proto:"29"`
+	ViaRules       []*ViaRule  `proto:"30"`
+	Thumbnail      string      `json:",omitempty" proto:"31"` // Preview image URL
+	Markers        []*Marker   `json:",omitempty" proto:"32"` // Verification violations
+	index          *SpatialIndex
+}
+
+// JSONOptions controls the sections included in the design JSON and its format
+type JSONOptions struct {
+	IncludeFills bool     // Fills can dominate the output size
+	Microns      bool     // Emit coordinates and distances in microns instead of database units
+	Checks       []string // Verification checks run by the server on upload, their markers are included
+	Format       string   // Output format, FormatJSON (default) or FormatProtobuf
+	Thumbnail    bool     // Render a PNG thumbnail of the design on upload
 }
 
 // DesignFile represents a wrapper for a submitted design file
@@ -567,8 +863,9 @@ type DesignFile struct {
 
 // DesignFiles represents a wrapper for design files
 type DesignFiles struct {
-	DEF *DesignFile
-	LEF []*DesignFile
+	DEF     *DesignFile
+	LEF     []*DesignFile
+	Backend string // Parsing backend, empty for the default backend
 }
 
 // Validate that the DEF file won't crash OpenDB parser
@@ -587,138 +884,14 @@ func validateDEF(filepath string) (err error) {
 	return
 }
 
-// NewDatabase creates a new OpenDB database
-func NewDatabase() (ret OpenDB, err error) {
-	db := C.DatabaseNew()
-	if db == nil {
-		cErr := C.GoString(C.LastError)
-		if len(cErr) > 0 {
-			err = fmt.Errorf("%v", C.GoString(C.LastError))
-		} else {
-			err = fmt.Errorf("Unknown error has occured")
-		}
-	}
-	ret.db = db
-	return ret, err
-}
-
-// FreeDatabase releases OpenDB database
-func (ref OpenDB) FreeDatabase() (err error) {
-	rc := C.DatabaseFree(ref.db)
-	if rc != 0 {
-		cErr := C.GoString(C.LastError)
-		if len(cErr) > 0 {
-			err = fmt.Errorf("%v", C.GoString(C.LastError))
-		} else {
-			err = fmt.Errorf("Unknown error has occured")
-		}
-	}
-	return
-}
-
-// ParseLEFLibrary reads the library section of a LEF technology file
-func (ref OpenDB) ParseLEFLibrary(filepath string) (err error) {
-	rc := C.ReadLib(ref.db, C.CString(filepath), C.CString(generateLibraryName(filepath)))
-	if rc != 0 {
-		cErr := C.GoString(C.LastError)
-		if len(cErr) > 0 {
-			err = fmt.Errorf("%v", C.GoString(C.LastError))
-		} else {
-			err = fmt.Errorf("Unknown error has occured")
-		}
-	}
-	return
-}
-
-// ParseLEFTechnology reads the technology section of a LEF technology file
-func (ref OpenDB) ParseLEFTechnology(filepath string) (err error) {
-	rc := C.ReadTech(ref.db, C.CString(filepath))
-	if rc != 0 {
-		cErr := C.GoString(C.LastError)
-		if len(cErr) > 0 {
-			err = fmt.Errorf("%v", C.GoString(C.LastError))
-		} else {
-			err = fmt.Errorf("Unknown error has occured")
-		}
-	}
-	return
-
-}
-
-// ParseLEF reads the library and technology section of a LEF technology file
-func (ref OpenDB) ParseLEF(filepath string) (err error) {
-	rc := C.ReadTechAndLib(ref.db, C.CString(filepath), C.CString(generateLibraryName(filepath)))
-	if rc != 0 {
-		cErr := C.GoString(C.LastError)
-		if len(cErr) > 0 {
-			err = fmt.Errorf("%v", C.GoString(C.LastError))
-		} else {
-			err = fmt.Errorf("Unknown error has occured")
-		}
-	}
-	return
-
-}
-
-// ParseDEF reads a design DEF file
-func (ref OpenDB) ParseDEF(filepath string) (err error) {
-	err = validateDEF(filepath)
-	if err != nil {
-		return
-	}
-	rc := C.ReadDesign(ref.db, C.CString(filepath))
-	if rc != 0 {
-		cErr := C.GoString(C.LastError)
-		if len(cErr) > 0 {
-			err = fmt.Errorf("%v", C.GoString(C.LastError))
-		} else {
-			err = fmt.Errorf("Unknown error has occured")
-		}
-	}
-	return
-
-}
-
-// GetDesign converts the parsed design to native go structs
-func (ref OpenDB) GetDesign() (design *Design, err error) {
-	design = &Design{}
-
-	// Get design instances
-	var sz int = 0
-	arrSzPtr := unsafe.Pointer(&sz)
-	designPtr, err := C.GetDesign(ref.db)
-	if err != nil {
-		return
+// LayersByID returns the design layers by ID, objects may hold layer copies
+// that are matched to the design layers by ID
+func (design *Design) LayersByID() map[int]*Layer {
+	layers := make(map[int]*Layer, len(design.Layers))
+	for _, layer := range design.Layers {
+		layers[layer.ID] = layer
 	}
-	defer C.FreeDesign(designPtr)
-	sz = *(*int)(arrSzPtr)
-	design.Name = C.GoString(designPtr.name)
-
-	design.Instances = dbInstanceArrayToSlice(designPtr.instances, int(designPtr.instanceSz), false)
-	design.Nets = dbNetArrayToSlice(designPtr.nets, int(designPtr.netSz), false)
-	design.InstancePins = dbPinArrayToSlice(designPtr.instancePins, int(designPtr.instancePinSz), false)
-	design.BlockPins = dbPinArrayToSlice(designPtr.blockPins, int(designPtr.blockPinSz), false)
-	design.RoutingVias = dbViaArrayToSlice(designPtr.routingVias, int(designPtr.routingViaSz), false)
-	design.ViaDefinitions = dbViaArrayToSlice(designPtr.viaDefinitions, int(designPtr.viaDefinitionSz), false)
-	design.Layers = dbLayerArrayToSlice(designPtr.layers, int(designPtr.layerSz), false)
-	design.CoreArea = float64(designPtr.coreArea)
-	design.DieArea = float64(designPtr.dieArea)
-	design.DesignArea = float64(designPtr.designArea)
-	design.Utilization = float64(designPtr.utilization)
-	design.Core = designPtr.core.Rect(false)
-	design.BoundingBox = designPtr.boundingBox.Rect(false)
-	design.Die = designPtr.die.Rect(false)
-	design.Rows = dbRowArrayToSlice(designPtr.rows, int(designPtr.rowSz), false)
-	design.Tracks = dbTrackArrayToSlice(designPtr.tracks, int(designPtr.trackSz), false)
-	design.Sites = dbSiteArrayToSlice(designPtr.sites, int(designPtr.siteSz), false)
-	design.Geometries = dbGeometryArrayToSlice(designPtr.geometries, int(designPtr.geometrySz), false)
-	design.GCell = nil
-	if designPtr.gcells != nil {
-		design.GCell = designPtr.gcells.Grid(false)
-	}
-	design.buildReferences()
-
-	return
+	return layers
 }
 
 // Populate design objects cross-references
@@ -727,7 +900,12 @@ func (design *Design) buildReferences() {
 	netMap := make(map[int]*Net)
 	pinMap := make(map[int]*Pin)
 	viaMap := make(map[int]*Via)
-	layerMap := make(map[int]*Layer)
+	layerMap := design.LayersByID()
+	regionMap := make(map[int]*Region)
+	groupMap := make(map[int]*Group)
+	masterMap := make(map[int]*Master)
+	siteMap := make(map[int]*Site)
+	viaRuleMap := make(map[int]*ViaRule)
 	for _, inst := range design.Instances {
 		instanceMap[inst.ID] = inst
 	}
@@ -746,8 +924,20 @@ func (design *Design) buildReferences() {
 	for _, via := range design.ViaDefinitions {
 		viaMap[via.ID] = via
 	}
-	for _, layer := range design.Layers {
-		layerMap[layer.ID] = layer
+	for _, region := range design.Regions {
+		regionMap[region.ID] = region
+	}
+	for _, group := range design.Groups {
+		groupMap[group.ID] = group
+	}
+	for _, master := range design.Masters {
+		masterMap[master.ID] = master
+	}
+	for _, site := range design.Sites {
+		siteMap[site.ID] = site
+	}
+	for _, rule := range design.ViaRules {
+		viaRuleMap[rule.ID] = rule
 	}
 
 	for _, inst := range instanceMap {
@@ -756,6 +946,15 @@ func (design *Design) buildReferences() {
 			pins = append(pins, pinMap[pin.ID])
 		}
 		inst.Pins = pins
+		if inst.Region != nil {
+			inst.Region = regionMap[inst.Region.ID]
+		}
+		if inst.Group != nil {
+			inst.Group = groupMap[inst.Group.ID]
+		}
+		if inst.MasterRef != nil {
+			inst.MasterRef = masterMap[inst.MasterRef.ID]
+		}
 	}
 
 	for _, pin := range pinMap {
@@ -799,744 +998,458 @@ func (design *Design) buildReferences() {
 		if via.CutLayer != nil {
 			via.CutLayer = layerMap[via.CutLayer.ID]
 		}
-	}
-}
-
-// CompactDesign returns a smaller representation without circular dependencies for JSON encoding
-func (design *Design) CompactDesign() (compactDesign *Design) {
-	instanceMap := make(map[int]*Instance)
-	netMap := make(map[int]*Net)
-	pinMap := make(map[int]*Pin)
-	viaMap := make(map[int]*Via)
-	layerMap := make(map[int]*Layer)
-	rowMap := make(map[int]*Row)
-	trackMap := make(map[int]*Grid)
-	siteMap := make(map[int]*Site)
-	geometryMap := make(map[int]*Geometry)
-
-	var instances []*Instance
-	var nets []*Net
-	var instancePins []*Pin
-	var blockPins []*Pin
-	var routingVias []*Via
-	var viaDefinitions []*Via
-	var layers []*Layer
-	var rows []*Row
-	var tracks []*Grid
-	var sites []*Site
-	var geometries []*Geometry
-
-	compactDesign = &Design{
-		Name:  design.Name,
-		GCell: nil,
-	}
-
-	for _, inst := range design.Instances {
-		instanceMap[inst.ID] = &(*inst)
-		if instanceMap[inst.ID].Location != nil {
-			cp := instanceMap[inst.ID].Location.Copy()
-			instanceMap[inst.ID].Location = &cp
-		}
-		if instanceMap[inst.ID].Origin != nil {
-			cp := instanceMap[inst.ID].Origin.Copy()
-			instanceMap[inst.ID].Origin = &cp
-		}
-		if instanceMap[inst.ID].BoundingBox != nil {
-			cp := instanceMap[inst.ID].BoundingBox.Copy()
-			instanceMap[inst.ID].BoundingBox = &cp
+		if via.Rule != nil {
+			via.Rule = viaRuleMap[via.Rule.ID]
 		}
-		if instanceMap[inst.ID].Halo != nil {
-			cp := instanceMap[inst.ID].Halo.Copy()
-			instanceMap[inst.ID].Halo = &cp
-		}
-		instances = append(instances, instanceMap[inst.ID])
 	}
-	for _, net := range design.Nets {
-		netMap[net.ID] = &(*net)
-		var geomCopy []*Geometry
-		for _, geom := range netMap[net.ID].SpecialBoxes {
-			geomCopy = append(geomCopy, &Geometry{
-				ID:         geom.ID,
-				InComplete: true,
-			})
+	for _, rule := range viaRuleMap {
+		for _, ruleLayer := range rule.Layers {
+			if ruleLayer.Layer != nil {
+				ruleLayer.Layer = layerMap[ruleLayer.Layer.ID]
+			}
 		}
-		netMap[net.ID].SpecialBoxes = geomCopy
-		nets = append(nets, netMap[net.ID])
 	}
-	for _, pin := range design.InstancePins {
-		pinMap[pin.ID] = &(*pin)
-		if pinMap[pin.ID].Location != nil {
-			cp := pinMap[pin.ID].Location.Copy()
-			pinMap[pin.ID].Location = &cp
+	for _, blockage := range design.Blockages {
+		if blockage.Layer != nil {
+			blockage.Layer = layerMap[blockage.Layer.ID]
 		}
-		var geomCopy []*Geometry
-		for _, geom := range pinMap[pin.ID].Geometries {
-			geomCopy = append(geomCopy, &Geometry{
-				ID:         geom.ID,
-				InComplete: true,
-			})
+		if blockage.Instance != nil {
+			blockage.Instance = instanceMap[blockage.Instance.ID]
 		}
-		pinMap[pin.ID].Geometries = geomCopy
-		instancePins = append(instancePins, pinMap[pin.ID])
 	}
-	for _, pin := range design.BlockPins {
-		pinMap[pin.ID] = &(*pin)
-		if pinMap[pin.ID].Location != nil {
-			cp := pinMap[pin.ID].Location.Copy()
-			pinMap[pin.ID].Location = &cp
-		}
-		var geomCopy []*Geometry
-		for _, geom := range pinMap[pin.ID].Geometries {
-			geomCopy = append(geomCopy, &Geometry{
-				ID:         geom.ID,
-				InComplete: true,
-			})
+	for _, master := range masterMap {
+		if master.Site != nil {
+			master.Site = siteMap[master.Site.ID]
 		}
-		pinMap[pin.ID].Geometries = geomCopy
-		blockPins = append(blockPins, pinMap[pin.ID])
-	}
-	for _, layer := range design.Layers {
-		layerMap[layer.ID] = &(*layer)
-		layers = append(layers, layerMap[layer.ID])
 	}
-
-	for _, via := range design.RoutingVias {
-		viaMap[via.ID] = &(*via)
-		if viaMap[via.ID].Rect != nil {
-			cp := viaMap[via.ID].Rect.Copy()
-			if cp.Layer != nil {
-				cp.Layer = &Layer{
-					ID:         cp.Layer.ID,
-					InComplete: true,
-				}
-			}
-			if cp.Via != nil {
-				cp.Via = &Via{
-					ID:         cp.Via.ID,
-					InComplete: true,
-				}
-			}
-			viaMap[via.ID].Rect = &cp
+	for _, fill := range design.Fills {
+		if fill.Layer != nil {
+			fill.Layer = layerMap[fill.Layer.ID]
 		}
-		routingVias = append(routingVias, viaMap[via.ID])
 	}
-	for _, via := range design.ViaDefinitions {
-		viaMap[via.ID] = &(*via)
-		if viaMap[via.ID].Rect != nil {
-			cp := viaMap[via.ID].Rect.Copy()
-			if cp.Layer != nil {
-				cp.Layer = &Layer{
-					ID:         cp.Layer.ID,
-					InComplete: true,
-				}
-			}
-			if cp.Via != nil {
-				cp.Via = &Via{
-					ID:         cp.Via.ID,
-					InComplete: true,
-				}
-			}
-			viaMap[via.ID].Rect = &cp
+	for _, region := range regionMap {
+		var insts []*Instance
+		for _, inst := range region.Instances {
+			insts = append(insts, instanceMap[inst.ID])
 		}
-		viaDefinitions = append(viaDefinitions, viaMap[via.ID])
+		region.Instances = insts
+		var groups []*Group
+		for _, group := range region.Groups {
+			groups = append(groups, groupMap[group.ID])
+		}
+		region.Groups = groups
 	}
-
-	for _, inst := range instanceMap {
-		var pins []*Pin
-		for _, pin := range inst.Pins {
-			pins = append(pins, &Pin{ID: pin.ID, InComplete: true})
+	for _, group := range groupMap {
+		var insts []*Instance
+		for _, inst := range group.Instances {
+			insts = append(insts, instanceMap[inst.ID])
+		}
+		group.Instances = insts
+		if group.Region != nil {
+			group.Region = regionMap[group.Region.ID]
 		}
-		inst.Pins = pins
 	}
+}
 
-	for _, pin := range pinMap {
-		pin.Instance = nil
-		pin.Net = nil
+// CompactDesign returns a smaller representation without circular dependencies for JSON encoding,
+// the design objects are copied so the design stays usable after encoding
+func (design *Design) CompactDesign() (compactDesign *Design) {
+	compactDesign = design.compactHeader()
+	compactDesign.Instances = make([]*Instance, 0, len(design.Instances))
+	for _, inst := range design.Instances {
+		compactDesign.Instances = append(compactDesign.Instances, inst.compact())
 	}
-	for _, net := range netMap {
-		var pins []*Pin
-		for _, pin := range net.Pins {
-			pins = append(pins, &Pin{ID: pin.ID, InComplete: true})
-		}
-		net.Pins = pins
-		var edges []*Edge
-		for _, edge := range net.Edges {
-			edgeCp := *edge
-			if edgeCp.Via != nil {
-				edgeCp.Via = &Via{ID: edgeCp.Via.ID, InComplete: true}
-			}
-			if edgeCp.Layer != nil {
-				edgeCp.Layer = &Layer{ID: edgeCp.Layer.ID, InComplete: true}
-			}
-			edges = append(edges, &edgeCp)
-		}
-		net.Edges = edges
+	compactDesign.Nets = make([]*Net, 0, len(design.Nets))
+	for _, net := range design.Nets {
+		compactDesign.Nets = append(compactDesign.Nets, net.compact())
 	}
-	for _, layer := range layerMap {
-		if layer.UpperLayer != nil {
-			layer.UpperLayer = &Layer{ID: layer.UpperLayer.ID, InComplete: true}
-		}
-		if layer.LowerLayer != nil {
-			layer.LowerLayer = &Layer{ID: layer.LowerLayer.ID, InComplete: true}
-		}
+	compactDesign.InstancePins = make([]*Pin, 0, len(design.InstancePins))
+	for _, pin := range design.InstancePins {
+		compactDesign.InstancePins = append(compactDesign.InstancePins, pin.compact())
 	}
-	for _, via := range viaMap {
-		if via.TopLayer != nil {
-			via.TopLayer = &Layer{ID: via.TopLayer.ID, InComplete: true}
-		}
-		if via.BottomLayer != nil {
-			via.BottomLayer = &Layer{ID: via.BottomLayer.ID, InComplete: true}
-		}
-		if via.CutLayer != nil {
-			via.CutLayer = &Layer{ID: via.CutLayer.ID, InComplete: true}
-		}
+	compactDesign.BlockPins = make([]*Pin, 0, len(design.BlockPins))
+	for _, pin := range design.BlockPins {
+		compactDesign.BlockPins = append(compactDesign.BlockPins, pin.compact())
 	}
-
-	for _, geom := range design.Geometries {
-		geomCp := geom.Copy()
-		geometryMap[geom.ID] = &geomCp
-		geometries = append(geometries, &geomCp)
+	compactDesign.RoutingVias = make([]*Via, 0, len(design.RoutingVias))
+	for _, via := range design.RoutingVias {
+		compactDesign.RoutingVias = append(compactDesign.RoutingVias, via.compact())
 	}
-	for _, site := range design.Sites {
-		siteMap[site.ID] = &(*site)
-		sites = append(sites, &(*site))
+	compactDesign.ViaDefinitions = make([]*Via, 0, len(design.ViaDefinitions))
+	for _, via := range design.ViaDefinitions {
+		compactDesign.ViaDefinitions = append(compactDesign.ViaDefinitions, via.compact())
 	}
-	for _, track := range design.Tracks {
-		trackCp := track.Copy()
-		if trackCp.Layer != nil {
-			trackCp.Layer = &Layer{
-				ID:         trackCp.Layer.ID,
-				InComplete: true,
-			}
-		}
-		trackMap[track.ID] = &trackCp
+	compactDesign.Layers = make([]*Layer, 0, len(design.Layers))
+	for _, layer := range design.Layers {
+		compactDesign.Layers = append(compactDesign.Layers, layer.compact())
 	}
+	compactDesign.Rows = make([]*Row, 0, len(design.Rows))
 	for _, row := range design.Rows {
-		rowMap[row.ID] = &(*row)
+		compactDesign.Rows = append(compactDesign.Rows, row.compact())
 	}
-
-	for _, track := range trackMap {
-		if track.Layer != nil {
-			track.Layer = &Layer{
-				ID:         track.Layer.ID,
-				InComplete: true,
-			}
-		}
-		tracks = append(tracks, track)
-	}
-	for _, row := range rowMap {
-		if row.BoundingBox != nil {
-			boxCp := row.BoundingBox.Copy()
-			if boxCp.Layer != nil {
-				boxCp.Layer = &Layer{
-					ID:         boxCp.Layer.ID,
-					InComplete: true,
-				}
-			}
-			row.BoundingBox = &boxCp
-		}
-		if row.Site != nil {
-			row.Site = &Site{
-				ID:         row.Site.ID,
-				Name:       row.Site.Name,
-				InComplete: false,
-			}
-		}
-		rows = append(rows, row)
-	}
-
-	compactDesign.Instances = instances
-	compactDesign.Nets = nets
-	compactDesign.InstancePins = instancePins
-	compactDesign.BlockPins = blockPins
-	compactDesign.RoutingVias = routingVias
-	compactDesign.ViaDefinitions = viaDefinitions
-	compactDesign.Layers = layers
-	compactDesign.CoreArea = design.CoreArea
-	compactDesign.DieArea = design.DieArea
-	compactDesign.DesignArea = design.DesignArea
-	compactDesign.Utilization = design.Utilization
-	compactDesign.Rows = rows
-	compactDesign.Tracks = tracks
-	compactDesign.Sites = sites
-	compactDesign.Geometries = geometries
-	if design.GCell != nil {
-		gcellCopy := design.GCell.Copy()
-		compactDesign.GCell = &gcellCopy
+	compactDesign.Tracks = make([]*Grid, 0, len(design.Tracks))
+	for _, track := range design.Tracks {
+		compactDesign.Tracks = append(compactDesign.Tracks, track.compact())
+	}
+	compactDesign.Sites = make([]*Site, 0, len(design.Sites))
+	for _, site := range design.Sites {
+		siteCp := *site
+		compactDesign.Sites = append(compactDesign.Sites, &siteCp)
 	}
-	bboxCopy := design.BoundingBox.Copy()
-	compactDesign.BoundingBox = &bboxCopy
-	coreCopy := design.Core.Copy()
-	compactDesign.Core = &coreCopy
-	dieCopy := design.Die.Copy()
-	compactDesign.Die = &dieCopy
-	if len(compactDesign.Instances) == 0 {
-		compactDesign.Instances = make([]*Instance, 0)
+	compactDesign.Geometries = make([]*Geometry, 0, len(design.Geometries))
+	for _, geom := range design.Geometries {
+		geomCp := geom.Copy()
+		compactDesign.Geometries = append(compactDesign.Geometries, &geomCp)
 	}
-	if len(compactDesign.Instances) == 0 {
-		compactDesign.Instances = make([]*Instance, 0)
+	compactDesign.Blockages = make([]*Blockage, 0, len(design.Blockages))
+	for _, blockage := range design.Blockages {
+		compactDesign.Blockages = append(compactDesign.Blockages, blockage.compact())
 	}
-	if len(compactDesign.Nets) == 0 {
-		compactDesign.Nets = make([]*Net, 0)
+	compactDesign.Regions = make([]*Region, 0, len(design.Regions))
+	for _, region := range design.Regions {
+		compactDesign.Regions = append(compactDesign.Regions, region.compact())
 	}
-	if len(compactDesign.InstancePins) == 0 {
-		compactDesign.InstancePins = make([]*Pin, 0)
+	compactDesign.Groups = make([]*Group, 0, len(design.Groups))
+	for _, group := range design.Groups {
+		compactDesign.Groups = append(compactDesign.Groups, group.compact())
 	}
-	if len(compactDesign.BlockPins) == 0 {
-		compactDesign.BlockPins = make([]*Pin, 0)
+	compactDesign.Fills = make([]*Fill, 0, len(design.Fills))
+	for _, fill := range design.Fills {
+		compactDesign.Fills = append(compactDesign.Fills, fill.compact())
 	}
-	if len(compactDesign.RoutingVias) == 0 {
-		compactDesign.RoutingVias = make([]*Via, 0)
+	compactDesign.Masters = make([]*Master, 0, len(design.Masters))
+	for _, master := range design.Masters {
+		compactDesign.Masters = append(compactDesign.Masters, master.compact())
 	}
-	if len(compactDesign.ViaDefinitions) == 0 {
-		compactDesign.ViaDefinitions = make([]*Via, 0)
+	compactDesign.ViaRules = make([]*ViaRule, 0, len(design.ViaRules))
+	for _, rule := range design.ViaRules {
+		compactDesign.ViaRules = append(compactDesign.ViaRules, rule.compact())
 	}
-	if len(compactDesign.Layers) == 0 {
-		compactDesign.Layers = make([]*Layer, 0)
+	return
+}
+
+// compactHeader copies the design fields other than the object lists
+func (design *Design) compactHeader() *Design {
+	compactDesign := &Design{
+		Name:         design.Name,
+		DBUPerMicron: design.DBUPerMicron,
+		LEFUnits:     design.LEFUnits,
+		DEFUnits:     design.DEFUnits,
+		Units:        design.Units,
+		CoreArea:     design.CoreArea,
+		DieArea:      design.DieArea,
+		DesignArea:   design.DesignArea,
+		Utilization:  design.Utilization,
+		Thumbnail:    design.Thumbnail,
+		Markers:      design.Markers,
 	}
-	if len(compactDesign.Rows) == 0 {
-		compactDesign.Rows = make([]*Row, 0)
+	if design.GCell != nil {
+		gcellCopy := design.GCell.Copy()
+		compactDesign.GCell = &gcellCopy
 	}
-	if len(compactDesign.Tracks) == 0 {
-		compactDesign.Tracks = make([]*Grid, 0)
+	// LEF only designs have no die or core area
+	if design.BoundingBox != nil {
+		bboxCopy := design.BoundingBox.Copy()
+		compactDesign.BoundingBox = &bboxCopy
 	}
-	if len(compactDesign.Sites) == 0 {
-		compactDesign.Sites = make([]*Site, 0)
+	if design.Core != nil {
+		coreCopy := design.Core.Copy()
+		compactDesign.Core = &coreCopy
 	}
-	if len(compactDesign.Geometries) == 0 {
-		compactDesign.Geometries = make([]*Geometry, 0)
+	if design.Die != nil {
+		dieCopy := design.Die.Copy()
+		compactDesign.Die = &dieCopy
 	}
+	return compactDesign
+}
 
-	return
+// compactGeometries replaces geometries by references
+func compactGeometries(geoms []*Geometry) []*Geometry {
+	var geomCopy []*Geometry
+	for _, geom := range geoms {
+		geomCopy = append(geomCopy, &Geometry{ID: geom.ID, InComplete: true})
+	}
+	return geomCopy
 }
 
-func (ref C.Point) Point() *Point {
-	return &Point{X: int(ref.x), Y: int(ref.y)}
+// compactPins replaces pins by references
+func compactPins(pins []*Pin) []*Pin {
+	var pinCopy []*Pin
+	for _, pin := range pins {
+		pinCopy = append(pinCopy, &Pin{ID: pin.ID, InComplete: true})
+	}
+	return pinCopy
 }
 
-func (ref *C.Rect) Rect(idOnly bool) *Rect {
-	rect := &Rect{
-		ID:         int(ref.id),
-		XMin:       int(ref.xMin),
-		YMin:       int(ref.yMin),
-		XMax:       int(ref.xMax),
-		YMax:       int(ref.yMax),
-		ShapeType:  int(ref.shapeType),
-		Layer:      nil,
-		Via:        nil,
-		InComplete: idOnly,
+// compactInstances replaces instances by references
+func compactInstances(insts []*Instance) []*Instance {
+	var instCopy []*Instance
+	for _, inst := range insts {
+		instCopy = append(instCopy, &Instance{ID: inst.ID, InComplete: true})
 	}
-	if ref.layer != nil {
-		rect.Layer = ref.layer.Layer(idOnly)
+	return instCopy
+}
+
+// compactLayer replaces a layer by a reference
+func compactLayer(layer *Layer) *Layer {
+	if layer == nil {
+		return nil
 	}
-	if ref.via != nil {
-		rect.Via = ref.via.Via(idOnly)
+	return &Layer{ID: layer.ID, InComplete: true}
+}
+
+// compactBoxes copies boxes without their layer and via
+func compactBoxes(boxes []*Rect) []*Rect {
+	var boxCopy []*Rect
+	for _, box := range boxes {
+		boxCp := box.Copy()
+		boxCp.Layer = nil
+		boxCp.Via = nil
+		boxCopy = append(boxCopy, &boxCp)
 	}
-	return rect
+	return boxCopy
 }
 
-func (ref *C.Edge) Edge() *Edge {
-	var via *Via = nil
-	var layer *Layer = nil
-	if ref.via != nil {
-		via = ref.via.Via(true)
+func (inst *Instance) compact() *Instance {
+	instCp := *inst
+	if instCp.Location != nil {
+		cp := instCp.Location.Copy()
+		instCp.Location = &cp
+	}
+	if instCp.Origin != nil {
+		cp := instCp.Origin.Copy()
+		instCp.Origin = &cp
+	}
+	if instCp.BoundingBox != nil {
+		cp := instCp.BoundingBox.Copy()
+		instCp.BoundingBox = &cp
 	}
-	if ref.layer != nil {
-		layer = ref.layer.Layer(true)
+	if instCp.Halo != nil {
+		cp := instCp.Halo.Copy()
+		instCp.Halo = &cp
 	}
-	return &Edge{
-		Type:  EdgeType(ref.edgeType),
-		Rect:  ref.rect.Rect(false),
-		Via:   via,
-		Layer: layer,
+	if instCp.Region != nil {
+		instCp.Region = &Region{ID: inst.Region.ID, InComplete: true}
 	}
+	if instCp.Group != nil {
+		instCp.Group = &Group{ID: inst.Group.ID, InComplete: true}
+	}
+	if instCp.MasterRef != nil {
+		instCp.MasterRef = &Master{ID: inst.MasterRef.ID, InComplete: true}
+	}
+	if instCp.Obstructions != nil {
+		cp := instCp.Obstructions.Copy()
+		instCp.Obstructions = &cp
+	}
+	instCp.Pins = compactPins(inst.Pins)
+	return &instCp
 }
 
-func (ref *C.Instance) Instance(idOnly bool) *Instance {
-	if idOnly {
-		// Temporary holder
-		return &Instance{
-			ID:         int(ref.id),
-			InComplete: idOnly,
+func (net *Net) compact() *Net {
+	netCp := *net
+	netCp.SpecialBoxes = compactGeometries(net.SpecialBoxes)
+	var wireCopy []*SpecialWire
+	for _, wire := range net.SpecialWires {
+		wireCp := *wire
+		if wireCp.Geometry != nil {
+			wireCp.Geometry = &Geometry{ID: wireCp.Geometry.ID, InComplete: true}
 		}
-	}
-	return &Instance{
-		ID:           int(ref.id),
-		Name:         C.GoString(ref.name),
-		Location:     ref.location.Point(),
-		Origin:       ref.origin.Point(),
-		Orientation:  Orientation(ref.orientation),
-		Master:       C.GoString(ref.master),
-		Pins:         dbPinArrayToSlice(ref.pins, int(ref.pinSz), true),
-		IsPlaced:     ref.isPlaced == 1,
-		BoundingBox:  ref.boundingBox.Rect(false),
-		Halo:         ref.halo.Rect(false),
-		IsFiller:     ref.isFiller == 1,
-		MasterType:   MasterType(ref.masterType),
-		Obstructions: ref.obstructions.Geometry(idOnly),
-		InComplete:   idOnly,
-	}
-}
-
-func (ref *C.Pin) Pin(idOnly bool) *Pin {
-	if idOnly {
-		// Temporary holder
-		return &Pin{
-			ID:         int(ref.id),
-			InComplete: idOnly,
+		var shapes []*SpecialShape
+		for _, shape := range wire.Shapes {
+			shapeCp := *shape
+			if shapeCp.Rect != nil {
+				shapeCp.Rect = &Rect{ID: shapeCp.Rect.ID, InComplete: true}
+			}
+			shapes = append(shapes, &shapeCp)
 		}
+		wireCp.Shapes = shapes
+		wireCopy = append(wireCopy, &wireCp)
 	}
-	var net *Net = nil
-	var inst *Instance = nil
-	if ref.net != nil {
-		net = ref.net.Net(true)
-	}
-	if ref.instance != nil {
-		inst = ref.instance.Instance(true)
-	}
-	return &Pin{
-		ID:         int(ref.id),
-		Name:       C.GoString(ref.name),
-		Instance:   inst,
-		Net:        net,
-		Direction:  Direction(ref.direction),
-		Location:   ref.location.Point(),
-		Geometries: dbGeometryArrayToSlice(ref.geometries, int(ref.geometrySz), idOnly),
-		IsBlock:    ref.isBlock == 1,
-		IsSpecial:  ref.isSpecial == 1,
-		SignalType: SignalType(ref.signalType),
-		InComplete: idOnly,
-	}
-}
-func (ref *C.Geometry) Geometry(idOnly bool) *Geometry {
-	if idOnly {
-		// Temporary holder
-		return &Geometry{
-			ID:         int(ref.id),
-			InComplete: idOnly,
+	netCp.SpecialWires = wireCopy
+	netCp.Pins = compactPins(net.Pins)
+	var edges []*Edge
+	for _, edge := range net.Edges {
+		edgeCp := *edge
+		if edgeCp.Via != nil {
+			edgeCp.Via = &Via{ID: edgeCp.Via.ID, InComplete: true}
 		}
+		edgeCp.Layer = compactLayer(edgeCp.Layer)
+		edges = append(edges, &edgeCp)
 	}
-	return &Geometry{
-		ID:         int(ref.id),
-		Boxes:      dbRectArrayToSlice(ref.boxes, int(ref.boxSz), idOnly),
-		InComplete: false,
+	netCp.Edges = edges
+	return &netCp
+}
+
+func (pin *Pin) compact() *Pin {
+	pinCp := *pin
+	if pinCp.Location != nil {
+		cp := pinCp.Location.Copy()
+		pinCp.Location = &cp
 	}
+	pinCp.Geometries = compactGeometries(pin.Geometries)
+	pinCp.Instance = nil
+	pinCp.Net = nil
+	return &pinCp
 }
-func (ref *C.Net) Net(idOnly bool) *Net {
-	if idOnly {
-		// Temporary holder
-		return &Net{
-			ID:         int(ref.id),
-			InComplete: idOnly,
+
+func (layer *Layer) compact() *Layer {
+	layerCp := *layer
+	layerCp.UpperLayer = compactLayer(layer.UpperLayer)
+	layerCp.LowerLayer = compactLayer(layer.LowerLayer)
+	return &layerCp
+}
+
+func (via *Via) compact() *Via {
+	viaCp := *via
+	if viaCp.Rect != nil {
+		cp := viaCp.Rect.Copy()
+		cp.Layer = compactLayer(cp.Layer)
+		if cp.Via != nil {
+			cp.Via = &Via{ID: cp.Via.ID, InComplete: true}
 		}
+		viaCp.Rect = &cp
 	}
-	var specialBoxes []*Geometry
-	if ref.specialBoxes != nil {
-		specialBoxes = dbGeometryArrayToSlice(ref.specialBoxes, int(ref.specialBoxSz), idOnly)
-	}
-	return &Net{
-		ID:           int(ref.id),
-		Name:         C.GoString(ref.name),
-		IsSpecial:    ref.isSpecial == 1,
-		IsRouted:     ref.isRouted == 1,
-		Pins:         dbPinArrayToSlice(ref.pins, int(ref.pinSz), true),
-		Edges:        dbEdgeArrayToSlice(ref.edges, int(ref.edgeSz)),
-		SpecialBoxes: specialBoxes,
-		InComplete:   idOnly,
-	}
-}
-func (ref *C.Layer) Layer(idOnly bool) *Layer {
-	if idOnly {
-		// Temporary holder
-		return &Layer{
-			ID:         int(ref.id),
-			InComplete: idOnly,
-		}
+	viaCp.TopLayer = compactLayer(via.TopLayer)
+	viaCp.BottomLayer = compactLayer(via.BottomLayer)
+	viaCp.CutLayer = compactLayer(via.CutLayer)
+	var boxes []*Rect
+	for _, box := range via.Boxes {
+		boxCp := box.Copy()
+		boxCp.Layer = compactLayer(boxCp.Layer)
+		boxCp.Via = nil
+		boxes = append(boxes, &boxCp)
 	}
-	var upperLayer *Layer = nil
-	var lowerLayer *Layer = nil
-	if ref.upperLayer != nil {
-		upperLayer = ref.upperLayer.Layer(true)
-	}
-	if ref.lowerLayer != nil {
-		lowerLayer = ref.lowerLayer.Layer(true)
-	}
-	return &Layer{
-		ID:         int(ref.id),
-		Name:       C.GoString(ref.name),
-		Alias:      C.GoString(ref.alias),
-		Width:      int(ref.width),
-		Spacing:    int(ref.spacing),
-		Area:       float64(ref.area),
-		Type:       LayerType(ref.layerType),
-		Direction:  Direction(ref.direction),
-		UpperLayer: upperLayer,
-		LowerLayer: lowerLayer,
-		InComplete: idOnly,
-	}
-}
-func (ref *C.Via) Via(idOnly bool) *Via {
-	if idOnly {
-		// Temporary holder
-		return &Via{
-			ID:         int(ref.id),
-			InComplete: idOnly,
-		}
+	viaCp.Boxes = boxes
+	if viaCp.Rule != nil {
+		viaCp.Rule = &ViaRule{ID: via.Rule.ID, InComplete: true}
+	}
+	return &viaCp
+}
+
+func (row *Row) compact() *Row {
+	rowCp := *row
+	if rowCp.BoundingBox != nil {
+		boxCp := rowCp.BoundingBox.Copy()
+		boxCp.Layer = compactLayer(boxCp.Layer)
+		rowCp.BoundingBox = &boxCp
 	}
-	var topLayer *Layer = nil
-	var bottomLayer *Layer = nil
-	var cutLayer *Layer = nil
-	if ref.topLayer != nil {
-		topLayer = ref.topLayer.Layer(true)
-	}
-	if ref.bottomLayer != nil {
-		bottomLayer = ref.bottomLayer.Layer(true)
-	}
-	if ref.cutLayer != nil {
-		cutLayer = ref.cutLayer.Layer(true)
-	}
-	return &Via{
-		ID:          int(ref.id),
-		Name:        C.GoString(ref.name),
-		Rect:        ref.rect.Rect(false),
-		IsBlock:     ref.isBlock == 1,
-		IsTech:      ref.isTech == 1,
-		TopLayer:    topLayer,
-		BottomLayer: bottomLayer,
-		CutLayer:    cutLayer,
-		InComplete:  idOnly,
-	}
-}
-func (ref *C.Site) Site(idOnly bool) *Site {
-	if idOnly {
-		// Temporary holder
-		return &Site{
-			ID:         int(ref.id),
-			InComplete: idOnly,
+	if rowCp.Site != nil {
+		rowCp.Site = &Site{
+			ID:         row.Site.ID,
+			Name:       row.Site.Name,
+			InComplete: false,
 		}
 	}
-	return &Site{
-		ID:         int(ref.id),
-		Name:       C.GoString(ref.name),
-		InComplete: idOnly,
+	return &rowCp
+}
+
+func (track *Grid) compact() *Grid {
+	trackCp := track.Copy()
+	trackCp.Layer = compactLayer(trackCp.Layer)
+	return &trackCp
+}
+
+func (blockage *Blockage) compact() *Blockage {
+	blockageCp := *blockage
+	if blockageCp.Rect != nil {
+		boxCp := blockageCp.Rect.Copy()
+		boxCp.Layer = compactLayer(boxCp.Layer)
+		boxCp.Via = nil
+		blockageCp.Rect = &boxCp
+	}
+	blockageCp.Layer = compactLayer(blockage.Layer)
+	if blockageCp.Instance != nil {
+		blockageCp.Instance = &Instance{ID: blockageCp.Instance.ID, InComplete: true}
+	}
+	return &blockageCp
+}
+
+func (region *Region) compact() *Region {
+	regionCp := *region
+	regionCp.Boxes = compactBoxes(region.Boxes)
+	regionCp.Instances = compactInstances(region.Instances)
+	var regionGroups []*Group
+	for _, group := range region.Groups {
+		regionGroups = append(regionGroups, &Group{ID: group.ID, InComplete: true})
+	}
+	regionCp.Groups = regionGroups
+	return &regionCp
+}
+
+func (group *Group) compact() *Group {
+	groupCp := *group
+	groupCp.Instances = compactInstances(group.Instances)
+	if groupCp.Region != nil {
+		groupCp.Region = &Region{ID: groupCp.Region.ID, InComplete: true}
 	}
+	return &groupCp
 }
-func (ref *C.Grid) Grid(idOnly bool) *Grid {
-	if idOnly {
-		// Temporary haolder
-		return &Grid{
-			ID:         int(ref.id),
-			InComplete: idOnly,
+
+func (fill *Fill) compact() *Fill {
+	fillCp := *fill
+	fillCp.Boxes = compactBoxes(fill.Boxes)
+	fillCp.Layer = compactLayer(fill.Layer)
+	return &fillCp
+}
+
+func (master *Master) compact() *Master {
+	masterCp := *master
+	if masterCp.Foreign != nil {
+		foreignCp := *masterCp.Foreign
+		if foreignCp.Origin != nil {
+			cp := foreignCp.Origin.Copy()
+			foreignCp.Origin = &cp
 		}
+		masterCp.Foreign = &foreignCp
 	}
-	var layer *Layer = nil
-	if ref.layer != nil {
-		layer = ref.layer.Layer(true)
+	if masterCp.Origin != nil {
+		cp := masterCp.Origin.Copy()
+		masterCp.Origin = &cp
 	}
-	return &Grid{
-		ID:                     int(ref.id),
-		Layer:                  layer,
-		GridX:                  intArrayToSlice(ref.gridX, int(ref.gridXSz)),
-		GridY:                  intArrayToSlice(ref.gridY, int(ref.gridYSz)),
-		GridXPatternOrigins:    intArrayToSlice(ref.gridXPatternOrigins, int(ref.gridXPatternSz)),
-		GridXPatternLineCounts: intArrayToSlice(ref.gridXPatternLineCounts, int(ref.gridXPatternSz)),
-		GridXPatternSteps:      intArrayToSlice(ref.gridXPatternSteps, int(ref.gridXPatternSz)),
-		GridYPatternOrigins:    intArrayToSlice(ref.gridYPatternOrigins, int(ref.gridYPatternSz)),
-		GridYPatternLineCounts: intArrayToSlice(ref.gridYPatternLineCounts, int(ref.gridYPatternSz)),
-		GridYPatternSteps:      intArrayToSlice(ref.gridYPatternSteps, int(ref.gridYPatternSz)),
-		InComplete:             idOnly,
-	}
-}
-
-func (ref *C.Row) Row(idOnly bool) *Row {
-	if idOnly {
-		// Temporary holder
-		return &Row{
-			ID:         int(ref.id),
-			InComplete: idOnly,
+	if masterCp.Site != nil {
+		masterCp.Site = &Site{
+			ID:         masterCp.Site.ID,
+			Name:       masterCp.Site.Name,
+			InComplete: false,
 		}
 	}
-	var site *Site = nil
-	if ref.site != nil {
-		site = ref.site.Site(true)
-	}
-	return &Row{
-		ID:          int(ref.id),
-		Name:        C.GoString(ref.name),
-		Site:        site,
-		Direction:   Direction(ref.direction),
-		Orientation: Orientation(ref.orientation),
-		OriginX:     int(ref.originX),
-		OriginY:     int(ref.originY),
-		Spacing:     int(ref.spacing),
-		BoundingBox: ref.boundingBox.Rect(false),
-		InComplete:  idOnly,
-	}
-
-}
-
-func doubleArrayToSlice(array *C.double, len int) []C.double {
-	var list []C.double
-	sliceHeader := (*reflect.SliceHeader)((unsafe.Pointer(&list)))
-	sliceHeader.Cap = len
-	sliceHeader.Len = len
-	sliceHeader.Data = uintptr(unsafe.Pointer(array))
-	return list
-}
-
-func dbInstanceArrayToSlice(array **C.Instance, len int, idOnly bool) []*Instance {
-	var list []*C.Instance
-	sliceHeader := (*reflect.SliceHeader)((unsafe.Pointer(&list)))
-	sliceHeader.Cap = len
-	sliceHeader.Len = len
-	sliceHeader.Data = uintptr(unsafe.Pointer(array))
-	var insts []*Instance
-	for _, inst := range list {
-		insts = append(insts, inst.Instance(idOnly))
-	}
-	return insts
-}
-func dbEdgeArrayToSlice(array *C.Edge, len int) []*Edge {
-	var list []C.Edge
-	sliceHeader := (*reflect.SliceHeader)((unsafe.Pointer(&list)))
-	sliceHeader.Cap = len
-	sliceHeader.Len = len
-	sliceHeader.Data = uintptr(unsafe.Pointer(array))
-	var edges []*Edge
-	for _, edge := range list {
-		edges = append(edges, edge.Edge())
-	}
-	return edges
-}
-func dbPinArrayToSlice(array **C.Pin, len int, idOnly bool) []*Pin {
-	var list []*C.Pin
-	sliceHeader := (*reflect.SliceHeader)((unsafe.Pointer(&list)))
-	sliceHeader.Cap = len
-	sliceHeader.Len = len
-	sliceHeader.Data = uintptr(unsafe.Pointer(array))
-	var pins []*Pin
-	for _, pin := range list {
-		pins = append(pins, pin.Pin(idOnly))
-	}
-	return pins
-}
-func dbRectArrayToSlice(array **C.Rect, len int, idOnly bool) []*Rect {
-	var list []*C.Rect
-	sliceHeader := (*reflect.SliceHeader)((unsafe.Pointer(&list)))
-	sliceHeader.Cap = len
-	sliceHeader.Len = len
-	sliceHeader.Data = uintptr(unsafe.Pointer(array))
-	var rects []*Rect
-	for _, rect := range list {
-		rects = append(rects, rect.Rect(idOnly))
-	}
-	return rects
-}
-func dbGeometryArrayToSlice(array **C.Geometry, len int, idOnly bool) []*Geometry {
-	var list []*C.Geometry
-	sliceHeader := (*reflect.SliceHeader)((unsafe.Pointer(&list)))
-	sliceHeader.Cap = len
-	sliceHeader.Len = len
-	sliceHeader.Data = uintptr(unsafe.Pointer(array))
-	var geoms []*Geometry
-	for _, geom := range list {
-		geoms = append(geoms, geom.Geometry(idOnly))
-	}
-	return geoms
-}
-
-func dbNetArrayToSlice(array **C.Net, len int, idOnly bool) []*Net {
-	var list []*C.Net
-	sliceHeader := (*reflect.SliceHeader)((unsafe.Pointer(&list)))
-	sliceHeader.Cap = len
-	sliceHeader.Len = len
-	sliceHeader.Data = uintptr(unsafe.Pointer(array))
-	var nets []*Net
-	for _, net := range list {
-		nets = append(nets, net.Net(idOnly))
-	}
-	return nets
-}
-
-func dbLayerArrayToSlice(array **C.Layer, len int, idOnly bool) []*Layer {
-	var list []*C.Layer
-	sliceHeader := (*reflect.SliceHeader)((unsafe.Pointer(&list)))
-	sliceHeader.Cap = len
-	sliceHeader.Len = len
-	sliceHeader.Data = uintptr(unsafe.Pointer(array))
-	var layers []*Layer
-	for _, layer := range list {
-		layers = append(layers, layer.Layer(idOnly))
+	var pins []*MasterPin
+	for _, pin := range master.Pins {
+		pinCp := *pin
+		pinCp.Geometries = compactGeometries(pin.Geometries)
+		pins = append(pins, &pinCp)
+	}
+	masterCp.Pins = pins
+	if masterCp.Obstructions != nil {
+		masterCp.Obstructions = &Geometry{ID: masterCp.Obstructions.ID, InComplete: true}
+	}
+	return &masterCp
+}
+
+func (rule *ViaRule) compact() *ViaRule {
+	ruleCp := *rule
+	var ruleLayers []*ViaRuleLayer
+	for _, ruleLayer := range rule.Layers {
+		ruleLayerCp := *ruleLayer
+		ruleLayerCp.Layer = compactLayer(ruleLayer.Layer)
+		if ruleLayerCp.Rect != nil {
+			boxCp := ruleLayerCp.Rect.Copy()
+			boxCp.Layer = nil
+			boxCp.Via = nil
+			ruleLayerCp.Rect = &boxCp
+		}
+		ruleLayers = append(ruleLayers, &ruleLayerCp)
 	}
-	return layers
-}
-
-func dbViaArrayToSlice(array **C.Via, len int, idOnly bool) []*Via {
-	var list []*C.Via
-	sliceHeader := (*reflect.SliceHeader)((unsafe.Pointer(&list)))
-	sliceHeader.Cap = len
-	sliceHeader.Len = len
-	sliceHeader.Data = uintptr(unsafe.Pointer(array))
-	var vias []*Via
-	for _, via := range list {
-		vias = append(vias, via.Via(idOnly))
-	}
-	return vias
-}
-func dbRowArrayToSlice(array **C.Row, len int, idOnly bool) []*Row {
-	var list []*C.Row
-	sliceHeader := (*reflect.SliceHeader)((unsafe.Pointer(&list)))
-	sliceHeader.Cap = len
-	sliceHeader.Len = len
-	sliceHeader.Data = uintptr(unsafe.Pointer(array))
-	var Rows []*Row
-	for _, Row := range list {
-		Rows = append(Rows, Row.Row(idOnly))
-	}
-	return Rows
-}
-func dbSiteArrayToSlice(array **C.Site, len int, idOnly bool) []*Site {
-	var list []*C.Site
-	sliceHeader := (*reflect.SliceHeader)((unsafe.Pointer(&list)))
-	sliceHeader.Cap = len
-	sliceHeader.Len = len
-	sliceHeader.Data = uintptr(unsafe.Pointer(array))
-	var Sites []*Site
-	for _, Site := range list {
-		Sites = append(Sites, Site.Site(idOnly))
-	}
-	return Sites
-}
-func dbTrackArrayToSlice(array **C.Grid, len int, idOnly bool) []*Grid {
-	var list []*C.Grid
-	sliceHeader := (*reflect.SliceHeader)((unsafe.Pointer(&list)))
-	sliceHeader.Cap = len
-	sliceHeader.Len = len
-	sliceHeader.Data = uintptr(unsafe.Pointer(array))
-	var Tracks []*Grid
-	for _, Grid := range list {
-		Tracks = append(Tracks, Grid.Grid(idOnly))
-	}
-	return Tracks
-}
-func intArrayToSlice(array *C.int, len int) []int {
-	var list []C.int
-	sliceHeader := (*reflect.SliceHeader)((unsafe.Pointer(&list)))
-	sliceHeader.Cap = len
-	sliceHeader.Len = len
-	sliceHeader.Data = uintptr(unsafe.Pointer(array))
-	var ints []int
-	for _, num := range list {
-		ints = append(ints, int(num))
-	}
-	return ints
+	ruleCp.Layers = ruleLayers
+	return &ruleCp
 }
 
 func generateLibraryName(filepath string) string {
 	return strings.TrimSuffix(path.Base(filepath), path.Ext(filepath))
 }
 
-// ParseDesign parses user uploaded files
+// ParseDesign parses user uploaded files, the DEF file can be missing if the
+// backend supports LEF only designs
 func ParseDesign(files *DesignFiles) (design *Design, err error) {
 	// Validate design files
 	var hasTech = false
@@ -1545,10 +1458,6 @@ func ParseDesign(files *DesignFiles) (design *Design, err error) {
 		err = fmt.Errorf("At least one LEF file is required")
 		return
 	}
-	if files.DEF == nil {
-		err = fmt.Errorf("One DEF file is required")
-		return
-	}
 	for _, file := range files.LEF {
 		if file.IsTech {
 			if hasTech {
@@ -1573,12 +1482,16 @@ func ParseDesign(files *DesignFiles) (design *Design, err error) {
 		err = fmt.Errorf("LEF library file is required")
 		return
 	}
-	var db OpenDB
-	db, err = NewDatabase()
+	var db Backend
+	db, err = NewBackend(files.Backend)
 	if err != nil {
 		return
 	}
 	defer db.FreeDatabase()
+	if files.DEF == nil && !supportsLEFOnly(db) {
+		err = fmt.Errorf("One DEF file is required")
+		return
+	}
 	for _, file := range files.LEF {
 		if file.IsTech && file.IsLibrary {
 			err = db.ParseLEF(file.FilePath)
@@ -1592,10 +1505,12 @@ func ParseDesign(files *DesignFiles) (design *Design, err error) {
 			return
 		}
 	}
-	err = db.ParseDEF(files.DEF.FilePath)
-	if err != nil {
-		err = fmt.Errorf("error parsing DEF file(s): %v", err)
-		return
+	if files.DEF != nil {
+		err = db.ParseDEF(files.DEF.FilePath)
+		if err != nil {
+			err = fmt.Errorf("error parsing DEF file(s): %v", err)
+			return
+		}
 	}
 	design, err = db.GetDesign()
 	if err != nil {
@@ -1605,25 +1520,34 @@ func ParseDesign(files *DesignFiles) (design *Design, err error) {
 	return design, err
 }
 
-// ParseDesign parses user uploaded files into JSON
-func ParseDesignToJSON(files *DesignFiles, compress bool) (designBytes []byte, err error) {
+// ParseDesignToJSON parses user uploaded files into JSON, nil options excludes the optional sections
+//
+// Deprecated: the whole JSON is buffered in memory, use ParseDesign and
+// WriteDesign to stream it
+func ParseDesignToJSON(files *DesignFiles, compress bool, options *JSONOptions) (designBytes []byte, err error) {
 	design, err := ParseDesign(files)
 	if err != nil {
 		return nil, err
 	}
-	compactDesign := design.CompactDesign()
+	return DesignToJSON(design, compress, options)
+}
+
+// DesignToJSON encodes a parsed design into JSON, nil options excludes the optional sections
+//
+// Deprecated: the whole JSON is buffered in memory, use EncodeDesign or
+// WriteDesign to stream it
+func DesignToJSON(design *Design, compress bool, options *JSONOptions) (designBytes []byte, err error) {
 	var buf bytes.Buffer
-	if compress {
-		gz := gzip.NewWriter(&buf)
-		enc := json.NewEncoder(gz)
-		enc.Encode(compactDesign)
-		gz.Close()
-	} else {
-		enc := json.NewEncoder(&buf)
-		// Uncomment for a formatted JSON
-		// enc.SetIndent("", "    ")
-		enc.Encode(compactDesign)
-	}
-	designBytes = buf.Bytes()
-	return
+	if !compress {
+		err = EncodeDesign(&buf, design, options)
+		return buf.Bytes(), err
+	}
+	gz := gzip.NewWriter(&buf)
+	if err = EncodeDesign(gz, design, options); err != nil {
+		return nil, err
+	}
+	if err = gz.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
 }
@@ -1,68 +1,80 @@
 package goopendb
 
 import (
+	"bytes"
+	"encoding/json"
+	"reflect"
 	"testing"
 )
 
-func TestParseLEFDEF(t *testing.T) {
-	defPath := "../example/Nangate45/gcd.def"
-	lefPath := "../example/Nangate45/NangateOpenCellLibrary.mod.lef"
-
-	var db OpenDB
-	db, err := NewDatabase()
-	if err != nil {
-		t.Fatal(err)
-		return
+func TestJSONToMicrons(t *testing.T) {
+	design := &Design{
+		DBUPerMicron: 2000,
+		Units:        UnitsDBU,
+		Die:          &Rect{XMin: 0, YMin: 0, XMax: 4000, YMax: 3000},
+		Layers: []*Layer{
+			{
+				ID:               1,
+				Width:            140,
+				MaxWidth:         -1,
+				Area:             0.02,
+				MinEnclosedAreas: []*MinEnclosedArea{{Area: 400000, Width: -1}},
+			},
+		},
 	}
-	defer db.FreeDatabase()
-	err = db.ParseLEF(lefPath)
+	designBytes, err := DesignToJSON(design, false, &JSONOptions{Microns: true})
 	if err != nil {
 		t.Fatal(err)
 	}
-	err = db.ParseDEF(defPath)
-	if err != nil {
-		t.Fatal(err)
+	// Fields keep the struct order
+	if !bytes.Contains(designBytes, []byte(`"Die":{"ID":0,"XMin":0,"YMin":0,"XMax":2,"YMax":1.5,`)) {
+		t.Errorf("Unexpected die JSON in %s", designBytes)
 	}
-	design, err := db.GetDesign()
-	if err != nil {
+	var converted map[string]interface{}
+	if err := json.Unmarshal(designBytes, &converted); err != nil {
 		t.Fatal(err)
 	}
-
-	if design.Name != "gcd" {
-		t.Fatal("Unexpected design name", design.Name)
+	if converted["Units"] != UnitsMicron {
+		t.Errorf("Expected units to be %v, found %v", UnitsMicron, converted["Units"])
 	}
-
-	expected := map[string]int{
-		"Instances":      182,
-		"Nets":           268,
-		"InstancePins":   1164,
-		"BlockPins":      56,
-		"RoutingVias":    7,
-		"ViaDefinitions": 7,
-		"Layers":         22,
-		"Rows":           15,
-		"Tracks":         10,
-		"Sites":          1,
+	die := converted["Die"].(map[string]interface{})
+	if die["XMax"] != 2.0 || die["YMax"] != 1.5 {
+		t.Errorf("Unexpected die area %v", die)
 	}
-	actual := map[string]int{
-		"Instances":      len(design.Instances),
-		"Nets":           len(design.Nets),
-		"InstancePins":   len(design.InstancePins),
-		"BlockPins":      len(design.BlockPins),
-		"RoutingVias":    len(design.RoutingVias),
-		"ViaDefinitions": len(design.ViaDefinitions),
-		"Layers":         len(design.Layers),
-		"Rows":           len(design.Rows),
-		"Tracks":         len(design.Tracks),
-		"Sites":          len(design.Sites),
+	layer := converted["Layers"].([]interface{})[0].(map[string]interface{})
+	if layer["Width"] != 0.07 || layer["MaxWidth"] != -1.0 || layer["Area"] != 0.02 {
+		t.Errorf("Unexpected layer %v", layer)
 	}
-
-	if design.Name != "gcd" {
-		t.Fatal("Unexpected design name", design.Name)
+	area := layer["MinEnclosedAreas"].([]interface{})[0].(map[string]interface{})
+	if area["Area"] != 0.1 || area["Width"] != -1.0 {
+		t.Errorf("Unexpected min enclosed area %v", area)
 	}
-	for k, v := range expected {
-		if actual[k] != v {
-			t.Errorf("Expected length of %v to be %v, found %v", k, v, actual[k])
+}
+
+func TestMicronFields(t *testing.T) {
+	for typ, fields := range dbuFields {
+		for name := range fields {
+			field, ok := typ.FieldByName(name)
+			if !ok {
+				t.Errorf("%v has no field %v", typ, name)
+				continue
+			}
+			fieldType := field.Type
+			for fieldType.Kind() == reflect.Slice {
+				fieldType = fieldType.Elem()
+			}
+			if kind := fieldType.Kind(); kind != reflect.Int && kind != reflect.Float64 {
+				t.Errorf("%v.%v is not a number", typ, name)
+			}
 		}
 	}
+
+	// Unknown database units keep the values
+	p := &Point{X: 140, Y: -70}
+	if microns := p.Microns(0); microns.X != 140 || microns.Y != -70 {
+		t.Errorf("Unexpected point %+v", microns)
+	}
+	if microns := p.Microns(2000); microns.X != 0.07 || microns.Y != -0.035 {
+		t.Errorf("Unexpected point %+v", microns)
+	}
 }
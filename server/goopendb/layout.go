@@ -0,0 +1,158 @@
+package goopendb
+
+// Layout shapes shared by the GDSII and OASIS writers
+
+// GDSLayer is a GDSII layer and datatype pair, OASIS uses the same numbering
+type GDSLayer struct {
+	Layer    int
+	DataType int
+}
+
+// GDSLayerMap maps LEF layer names to GDSII layers, shapes on layers
+// missing from the map are not written
+type GDSLayerMap map[string]GDSLayer
+
+// DefaultGDSLayerMap numbers the design layers in technology order starting
+// at 1, all with datatype 0
+func DefaultGDSLayerMap(design *Design) GDSLayerMap {
+	layers := make(GDSLayerMap)
+	for i, layer := range design.Layers {
+		layers[layer.Name] = GDSLayer{Layer: i + 1}
+	}
+	return layers
+}
+
+// pinLayer returns the first mapped layer of the pin shapes
+func (layers GDSLayerMap) pinLayer(pin *Pin) (GDSLayer, bool) {
+	for _, geom := range pin.Geometries {
+		for _, rect := range geom.Boxes {
+			if rect.Layer == nil {
+				continue
+			}
+			if layer, ok := layers[rect.Layer.Name]; ok {
+				return layer, true
+			}
+		}
+	}
+	return GDSLayer{}, false
+}
+
+// layoutTransforms maps an orientation to a reflection about the X axis
+// followed by a counterclockwise rotation in degrees
+var layoutTransforms = map[Orientation]struct {
+	reflect bool
+	angle   int
+}{
+	OrientationR0:    {false, 0},
+	OrientationR90:   {false, 90},
+	OrientationR180:  {false, 180},
+	OrientationR270:  {false, 270},
+	OrientationMY:    {true, 180},
+	OrientationMYR90: {true, 270},
+	OrientationMX:    {true, 0},
+	OrientationMXR90: {true, 90},
+}
+
+// Apply orients a point about the origin
+func (o Orientation) Apply(x, y int) (int, int) {
+	switch o {
+	case OrientationR90:
+		return -y, x
+	case OrientationR180:
+		return -x, -y
+	case OrientationR270:
+		return y, -x
+	case OrientationMY:
+		return -x, y
+	case OrientationMYR90:
+		return -y, -x
+	case OrientationMX:
+		return x, -y
+	case OrientationMXR90:
+		return y, x
+	}
+	return x, y
+}
+
+// ShapeVisitor receives a layout rectangle on a technology layer, the layer
+// is nil for shapes without one
+type ShapeVisitor func(layer *Layer, xMin, yMin, xMax, yMax int)
+
+// Placed returns a visitor placing master shapes at the instance origin and
+// orientation before passing them to visit
+func (inst *Instance) Placed(visit ShapeVisitor) ShapeVisitor {
+	return func(layer *Layer, xMin, yMin, xMax, yMax int) {
+		if inst.Origin == nil {
+			return
+		}
+		x1, y1 := inst.Orientation.Apply(xMin, yMin)
+		x2, y2 := inst.Orientation.Apply(xMax, yMax)
+		if x1 > x2 {
+			x1, x2 = x2, x1
+		}
+		if y1 > y2 {
+			y1, y2 = y2, y1
+		}
+		visit(layer, x1+inst.Origin.X, y1+inst.Origin.Y, x2+inst.Origin.X, y2+inst.Origin.Y)
+	}
+}
+
+// visitRect visits a shape, via shapes are expanded to the via boxes placed
+// at the via origin
+func visitRect(rect *Rect, via *Via, visit ShapeVisitor) {
+	if via == nil {
+		visit(rect.Layer, rect.XMin, rect.YMin, rect.XMax, rect.YMax)
+		return
+	}
+	if via.Rect == nil {
+		return
+	}
+	x, y := rect.XMin-via.Rect.XMin, rect.YMin-via.Rect.YMin
+	for _, box := range via.Boxes {
+		visit(box.Layer, box.XMin+x, box.YMin+y, box.XMax+x, box.YMax+y)
+	}
+}
+
+// VisitGeometries visits the geometry shapes, via shapes are expanded to the via boxes
+func VisitGeometries(geometries []*Geometry, visit ShapeVisitor) {
+	for _, geom := range geometries {
+		if geom == nil {
+			continue
+		}
+		for _, rect := range geom.Boxes {
+			visitRect(rect, rect.Via, visit)
+		}
+	}
+}
+
+// VisitShapes visits the master pin and obstruction shapes in master coordinates
+func (master *Master) VisitShapes(visit ShapeVisitor) {
+	for _, pin := range master.Pins {
+		VisitGeometries(pin.Geometries, visit)
+	}
+	VisitGeometries([]*Geometry{master.Obstructions}, visit)
+}
+
+// VisitWires visits the routing segments and vias of the net, virtual
+// segments have no shapes
+func (net *Net) VisitWires(visit ShapeVisitor) {
+	for _, edge := range net.Edges {
+		rect := edge.Rect
+		if rect == nil {
+			continue
+		}
+		switch edge.Type {
+		case EdgeTypeSEGMENT:
+			visit(edge.Layer, rect.XMin, rect.YMin, rect.XMax, rect.YMax)
+		case EdgeTypeTECHVIA, EdgeTypeVIA:
+			if edge.Via != nil {
+				visitRect(rect, edge.Via, visit)
+			}
+		}
+	}
+}
+
+// VisitSpecialWires visits the special wire shapes of the net
+func (net *Net) VisitSpecialWires(visit ShapeVisitor) {
+	VisitGeometries(net.SpecialBoxes, visit)
+}
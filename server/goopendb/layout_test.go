@@ -0,0 +1,54 @@
+package goopendb
+
+import (
+	"testing"
+)
+
+func TestInstancePlaced(t *testing.T) {
+	// A 760 by 2800 master placed with its bounding box at ( 1000 2000 )
+	wide := Rect{XMin: 1000, YMin: 2000, XMax: 1760, YMax: 4800}
+	tall := Rect{XMin: 1000, YMin: 2000, XMax: 3800, YMax: 2760}
+	for _, tc := range []struct {
+		orientation Orientation
+		origin      Point
+		bbox        Rect
+	}{
+		{OrientationR0, Point{X: 1000, Y: 2000}, wide},
+		{OrientationR90, Point{X: 3800, Y: 2000}, tall},
+		{OrientationR180, Point{X: 1760, Y: 4800}, wide},
+		{OrientationR270, Point{X: 1000, Y: 2760}, tall},
+		{OrientationMY, Point{X: 1760, Y: 2000}, wide},
+		{OrientationMYR90, Point{X: 3800, Y: 2760}, tall},
+		{OrientationMX, Point{X: 1000, Y: 4800}, wide},
+		{OrientationMXR90, Point{X: 1000, Y: 2000}, tall},
+	} {
+		origin := tc.origin
+		inst := &Instance{Name: "u1", Orientation: tc.orientation, Origin: &origin}
+		var placed Rect
+		inst.Placed(func(layer *Layer, xMin, yMin, xMax, yMax int) {
+			placed = Rect{XMin: xMin, YMin: yMin, XMax: xMax, YMax: yMax}
+		})(nil, 0, 0, 760, 2800)
+		if placed != tc.bbox {
+			t.Errorf("Expected %v placed at %+v, found %+v", tc.orientation, tc.bbox, placed)
+		}
+	}
+
+	// Master shapes follow the instance orientation
+	metal1 := &Layer{ID: 1, Name: "metal1"}
+	inst := &Instance{Name: "u2", Orientation: OrientationR90, Origin: &Point{X: 3800, Y: 2000}}
+	var shapes []Rect
+	visit := inst.Placed(func(layer *Layer, xMin, yMin, xMax, yMax int) {
+		shapes = append(shapes, Rect{XMin: xMin, YMin: yMin, XMax: xMax, YMax: yMax, Layer: layer})
+	})
+	visit(metal1, 100, 0, 200, 1000)
+	if len(shapes) != 1 || shapes[0] != (Rect{XMin: 2800, YMin: 2100, XMax: 3800, YMax: 2200, Layer: metal1}) {
+		t.Errorf("Unexpected placed shapes %+v", shapes)
+	}
+
+	// Instances without an origin are not placed
+	inst.Origin = nil
+	visit(metal1, 100, 0, 200, 1000)
+	if len(shapes) != 1 {
+		t.Errorf("Expected no shape without an origin, found %+v", shapes[1:])
+	}
+}
@@ -0,0 +1,402 @@
+package goopendb
+
+// LEF serialization of the design technology and cells
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// LEFVersion is the LEF version written by the LEF writers
+const LEFVersion = "5.8"
+
+// lefWriter writes LEF statements with distances in microns, the first write
+// error is kept
+type lefWriter struct {
+	*ErrWriter
+	dbu float64
+}
+
+func newLEFWriter(design *Design, w io.Writer) (*lefWriter, error) {
+	if design.DBUPerMicron <= 0 {
+		return nil, fmt.Errorf("Unknown database units")
+	}
+	lw := &lefWriter{
+		ErrWriter: NewErrWriter(w),
+		dbu:       float64(design.DBUPerMicron),
+	}
+	lw.Printf("VERSION %v ;\n", LEFVersion)
+	lw.Printf("BUSBITCHARS \"[]\" ;\n")
+	lw.Printf("DIVIDERCHAR \"/\" ;\n\n")
+	lw.Printf("UNITS\n  DATABASE MICRONS %d ;\nEND UNITS\n\n", design.DBUPerMicron)
+	return lw, nil
+}
+
+func (lw *lefWriter) finish() error {
+	lw.Printf("END LIBRARY\n")
+	return lw.Flush()
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// micron formats a distance in database units as microns
+func (lw *lefWriter) micron(v int) string {
+	return formatFloat(float64(v) / lw.dbu)
+}
+
+func (lw *lefWriter) microns(values ...int) string {
+	var words []string
+	for _, v := range values {
+		words = append(words, lw.micron(v))
+	}
+	return strings.Join(words, " ")
+}
+
+func (lw *lefWriter) rect(r *Rect) string {
+	return lw.microns(r.XMin, r.YMin, r.XMax, r.YMax)
+}
+
+// writeShapes writes shapes grouped by consecutive layers, via shapes are
+// written as placed vias
+func (lw *lefWriter) writeShapes(indent string, boxes []*Rect) {
+	var layer *Layer
+	for _, box := range boxes {
+		if box.Layer == nil {
+			if via := box.Via; via != nil && via.Rect != nil {
+				lw.Printf("%vVIA %v %v ;\n", indent, lw.microns(box.XMin-via.Rect.XMin, box.YMin-via.Rect.YMin), via.Name)
+				layer = nil
+			}
+			continue
+		}
+		if box.Layer != layer {
+			layer = box.Layer
+			lw.Printf("%vLAYER %v ;\n", indent, layer.Name)
+		}
+		lw.Printf("%v  RECT %v ;\n", indent, lw.rect(box))
+	}
+}
+
+// WriteLEFTechnology writes the technology section of a LEF file, the units,
+// layers, LEF vias and via rules of the design
+func WriteLEFTechnology(design *Design, w io.Writer) error {
+	lw, err := newLEFWriter(design, w)
+	if err != nil {
+		return err
+	}
+	for _, layer := range design.Layers {
+		lw.writeLayer(layer)
+	}
+	for _, via := range design.TechVias() {
+		lw.writeVia(via)
+	}
+	for _, rule := range design.ViaRules {
+		lw.writeViaRule(rule)
+	}
+	return lw.finish()
+}
+
+// WriteLEFLibrary writes the library section of a LEF file, the sites and
+// masters of the design
+func WriteLEFLibrary(design *Design, w io.Writer) error {
+	lw, err := newLEFWriter(design, w)
+	if err != nil {
+		return err
+	}
+	for _, site := range design.Sites {
+		lw.writeSite(site)
+	}
+	for _, master := range design.Masters {
+		lw.writeMaster(master)
+	}
+	return lw.finish()
+}
+
+// TechVias returns the LEF vias of the design, the via definitions of a LEF
+// only design or the routing vias of a DEF design
+func (design *Design) TechVias() []*Via {
+	var vias []*Via
+	seen := make(map[string]bool)
+	for _, list := range [][]*Via{design.ViaDefinitions, design.RoutingVias} {
+		for _, via := range list {
+			if via.IsTech && !seen[via.Name] {
+				seen[via.Name] = true
+				vias = append(vias, via)
+			}
+		}
+	}
+	return vias
+}
+
+// UsedMasters returns the masters placed by the design instances, in the
+// design masters order
+func (design *Design) UsedMasters() []*Master {
+	used := make(map[string]bool)
+	for _, inst := range design.Instances {
+		used[inst.Master] = true
+	}
+	var masters []*Master
+	for _, master := range design.Masters {
+		if used[master.Name] {
+			masters = append(masters, master)
+		}
+	}
+	return masters
+}
+
+func (lw *lefWriter) writeLayer(layer *Layer) {
+	lw.Printf("LAYER %v\n", layer.Name)
+	if layer.Type != LayerTypeNONE {
+		lw.Printf("  TYPE %v ;\n", layer.Type)
+	}
+	if layer.Direction != DirectionNONE {
+		lw.Printf("  DIRECTION %v ;\n", layer.Direction)
+	}
+	if layer.PitchX != 0 || layer.PitchY != 0 {
+		lw.Printf("  PITCH %v ;\n", lw.microns(layer.PitchX, layer.PitchY))
+	}
+	if layer.OffsetX != 0 || layer.OffsetY != 0 {
+		lw.Printf("  OFFSET %v ;\n", lw.microns(layer.OffsetX, layer.OffsetY))
+	}
+	if layer.Width > 0 {
+		lw.Printf("  WIDTH %v ;\n", lw.micron(layer.Width))
+	}
+	if layer.MinWidth > 0 && layer.MinWidth != layer.Width {
+		lw.Printf("  MINWIDTH %v ;\n", lw.micron(layer.MinWidth))
+	}
+	if layer.MaxWidth >= 0 {
+		lw.Printf("  MAXWIDTH %v ;\n", lw.micron(layer.MaxWidth))
+	}
+	if layer.Spacing > 0 {
+		lw.Printf("  SPACING %v ;\n", lw.micron(layer.Spacing))
+	}
+	if table := layer.SpacingTable; table != nil {
+		lw.Printf("  SPACINGTABLE\n    PARALLELRUNLENGTH %v", lw.microns(table.Lengths...))
+		for i, width := range table.Widths {
+			lw.Printf("\n    WIDTH %v %v", lw.micron(width), lw.microns(table.Spacings[i]...))
+		}
+		lw.Printf(" ;\n")
+	}
+	if len(layer.InfluenceSpacing) > 0 {
+		lw.Printf("  SPACINGTABLE\n    INFLUENCE")
+		for _, rule := range layer.InfluenceSpacing {
+			lw.Printf("\n    WIDTH %v WITHIN %v SPACING %v", lw.micron(rule.Width), lw.micron(rule.Within),
+				lw.micron(rule.Spacing))
+		}
+		lw.Printf(" ;\n")
+	}
+	if layer.Area > 0 {
+		lw.Printf("  AREA %v ;\n", formatFloat(layer.Area))
+	}
+	for _, rule := range layer.MinEnclosedAreas {
+		lw.Printf("  MINENCLOSEDAREA %v", formatFloat(rule.Area/(lw.dbu*lw.dbu)))
+		if rule.Width >= 0 {
+			lw.Printf(" WIDTH %v", lw.micron(rule.Width))
+		}
+		lw.Printf(" ;\n")
+	}
+	if layer.MinStep > 0 {
+		lw.Printf("  MINSTEP %v ;\n", lw.micron(layer.MinStep))
+	}
+	if layer.Height >= 0 {
+		lw.Printf("  HEIGHT %v ;\n", lw.micron(layer.Height))
+	}
+	if layer.Thickness >= 0 {
+		lw.Printf("  THICKNESS %v ;\n", lw.micron(layer.Thickness))
+	}
+	if layer.Resistance != 0 {
+		if layer.Type == LayerTypeROUTING {
+			lw.Printf("  RESISTANCE RPERSQ %v ;\n", formatFloat(layer.Resistance))
+		} else {
+			lw.Printf("  RESISTANCE %v ;\n", formatFloat(layer.Resistance))
+		}
+	}
+	if layer.Capacitance != 0 && layer.Type == LayerTypeROUTING {
+		lw.Printf("  CAPACITANCE CPERSQDIST %v ;\n", formatFloat(layer.Capacitance))
+	}
+	if layer.EdgeCapacitance != 0 {
+		lw.Printf("  EDGECAPACITANCE %v ;\n", formatFloat(layer.EdgeCapacitance))
+	}
+	if rule := layer.AntennaRule; rule != nil {
+		// The area factor defaults to 1
+		if rule.AreaFactor != 0 && rule.AreaFactor != 1 {
+			lw.Printf("  ANTENNAAREAFACTOR %v ;\n", formatFloat(rule.AreaFactor))
+		}
+		for _, ratio := range []struct {
+			keyword string
+			value   float64
+		}{
+			{"ANTENNAAREARATIO", rule.PAR},
+			{"ANTENNACUMAREARATIO", rule.CAR},
+			{"ANTENNASIDEAREARATIO", rule.PSR},
+			{"ANTENNACUMSIDEAREARATIO", rule.CSR},
+		} {
+			if ratio.value != 0 {
+				lw.Printf("  %v %v ;\n", ratio.keyword, formatFloat(ratio.value))
+			}
+		}
+	}
+	lw.Printf("END %v\n\n", layer.Name)
+}
+
+func (lw *lefWriter) writeVia(via *Via) {
+	lw.Printf("VIA %v\n", via.Name)
+	if params := via.Params; params != nil && via.Rule != nil && via.BottomLayer != nil && via.CutLayer != nil &&
+		via.TopLayer != nil {
+		lw.Printf("  VIARULE %v ;\n", via.Rule.Name)
+		lw.Printf("  CUTSIZE %v ;\n", lw.microns(params.CutSizeX, params.CutSizeY))
+		lw.Printf("  LAYERS %v %v %v ;\n", via.BottomLayer.Name, via.CutLayer.Name, via.TopLayer.Name)
+		lw.Printf("  CUTSPACING %v ;\n", lw.microns(params.CutSpacingX, params.CutSpacingY))
+		lw.Printf("  ENCLOSURE %v ;\n", lw.microns(params.BottomEnclosureX, params.BottomEnclosureY,
+			params.TopEnclosureX, params.TopEnclosureY))
+		if params.Rows != 1 || params.Cols != 1 {
+			lw.Printf("  ROWCOL %d %d ;\n", params.Rows, params.Cols)
+		}
+		if params.OriginX != 0 || params.OriginY != 0 {
+			lw.Printf("  ORIGIN %v ;\n", lw.microns(params.OriginX, params.OriginY))
+		}
+		if params.BottomOffsetX != 0 || params.BottomOffsetY != 0 || params.TopOffsetX != 0 || params.TopOffsetY != 0 {
+			lw.Printf("  OFFSET %v ;\n", lw.microns(params.BottomOffsetX, params.BottomOffsetY,
+				params.TopOffsetX, params.TopOffsetY))
+		}
+		if via.Pattern != "" {
+			lw.Printf("  PATTERN %v ;\n", via.Pattern)
+		}
+	} else {
+		lw.writeShapes("  ", via.Boxes)
+	}
+	lw.Printf("END %v\n\n", via.Name)
+}
+
+func (lw *lefWriter) writeViaRule(rule *ViaRule) {
+	lw.Printf("VIARULE %v GENERATE", rule.Name)
+	if rule.IsDefault {
+		lw.Printf(" DEFAULT")
+	}
+	lw.Printf("\n")
+	for _, ruleLayer := range rule.Layers {
+		if ruleLayer.Layer == nil {
+			continue
+		}
+		lw.Printf("  LAYER %v ;\n", ruleLayer.Layer.Name)
+		if ruleLayer.Direction != DirectionNONE {
+			lw.Printf("    DIRECTION %v ;\n", ruleLayer.Direction)
+		}
+		if ruleLayer.HasEnclosure {
+			lw.Printf("    ENCLOSURE %v ;\n", lw.microns(ruleLayer.EnclosureOverhang1, ruleLayer.EnclosureOverhang2))
+		}
+		if ruleLayer.HasWidth {
+			lw.Printf("    WIDTH %v TO %v ;\n", lw.micron(ruleLayer.MinWidth), lw.micron(ruleLayer.MaxWidth))
+		}
+		if ruleLayer.Rect != nil {
+			lw.Printf("    RECT %v ;\n", lw.rect(ruleLayer.Rect))
+		}
+		if ruleLayer.HasSpacing {
+			lw.Printf("    SPACING %v BY %v ;\n", lw.micron(ruleLayer.SpacingX), lw.micron(ruleLayer.SpacingY))
+		}
+		if ruleLayer.Resistance != 0 {
+			lw.Printf("    RESISTANCE %v ;\n", formatFloat(ruleLayer.Resistance))
+		}
+	}
+	lw.Printf("END %v\n\n", rule.Name)
+}
+
+// symmetry formats a LEF SYMMETRY statement, empty without symmetry
+func symmetry(indent string, x, y, r90 bool) string {
+	var words []string
+	for _, axis := range []struct {
+		name string
+		set  bool
+	}{{"X", x}, {"Y", y}, {"R90", r90}} {
+		if axis.set {
+			words = append(words, axis.name)
+		}
+	}
+	if len(words) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%vSYMMETRY %v ;\n", indent, strings.Join(words, " "))
+}
+
+func (lw *lefWriter) writeSite(site *Site) {
+	lw.Printf("SITE %v\n", site.Name)
+	if site.Class != "" {
+		lw.Printf("  CLASS %v ;\n", site.Class)
+	}
+	lw.Printf("%v", symmetry("  ", site.SymmetryX, site.SymmetryY, site.SymmetryR90))
+	lw.Printf("  SIZE %v BY %v ;\n", lw.micron(site.Width), lw.micron(site.Height))
+	lw.Printf("END %v\n\n", site.Name)
+}
+
+// lefMacroClasses maps the master classes, stored with the subclass joined by
+// an underscore, to the LEF CLASS statement
+var lefMacroClasses = map[string]string{
+	"COVER":              "COVER",
+	"COVER_BUMP":         "COVER BUMP",
+	"RING":               "RING",
+	"BLOCK":              "BLOCK",
+	"BLOCK_BLACKBOX":     "BLOCK BLACKBOX",
+	"BLOCK_SOFT":         "BLOCK SOFT",
+	"PAD":                "PAD",
+	"PAD_INPUT":          "PAD INPUT",
+	"PAD_OUTPUT":         "PAD OUTPUT",
+	"PAD_INOUT":          "PAD INOUT",
+	"PAD_POWER":          "PAD POWER",
+	"PAD_SPACER":         "PAD SPACER",
+	"PAD_AREAIO":         "PAD AREAIO",
+	"CORE":               "CORE",
+	"CORE_FEEDTHRU":      "CORE FEEDTHRU",
+	"CORE_TIEHIGH":       "CORE TIEHIGH",
+	"CORE_TIELOW":        "CORE TIELOW",
+	"CORE_SPACER":        "CORE SPACER",
+	"CORE_ANTENNACELL":   "CORE ANTENNACELL",
+	"CORE_WELLTAP":       "CORE WELLTAP",
+	"ENDCAP_PRE":         "ENDCAP PRE",
+	"ENDCAP_POST":        "ENDCAP POST",
+	"ENDCAP_TOPLEFT":     "ENDCAP TOPLEFT",
+	"ENDCAP_TOPRIGHT":    "ENDCAP TOPRIGHT",
+	"ENDCAP_BOTTOMLEFT":  "ENDCAP BOTTOMLEFT",
+	"ENDCAP_BOTTOMRIGHT": "ENDCAP BOTTOMRIGHT",
+}
+
+func (lw *lefWriter) writeMaster(master *Master) {
+	lw.Printf("MACRO %v\n", master.Name)
+	if class, ok := lefMacroClasses[master.Class]; ok {
+		lw.Printf("  CLASS %v ;\n", class)
+	}
+	if foreign := master.Foreign; foreign != nil {
+		lw.Printf("  FOREIGN %v", foreign.Name)
+		if foreign.Origin != nil {
+			lw.Printf(" %v %v", lw.microns(foreign.Origin.X, foreign.Origin.Y), defOrientationNames[foreign.Orientation])
+		}
+		lw.Printf(" ;\n")
+	}
+	if origin := master.Origin; origin != nil {
+		lw.Printf("  ORIGIN %v ;\n", lw.microns(origin.X, origin.Y))
+	}
+	lw.Printf("  SIZE %v BY %v ;\n", lw.micron(master.Width), lw.micron(master.Height))
+	lw.Printf("%v", symmetry("  ", master.SymmetryX, master.SymmetryY, master.SymmetryR90))
+	if master.Site != nil {
+		lw.Printf("  SITE %v ;\n", master.Site.Name)
+	}
+	for _, pin := range master.Pins {
+		lw.Printf("  PIN %v\n", pin.Name)
+		lw.Printf("    DIRECTION %v ;\n", pin.Direction)
+		lw.Printf("    USE %v ;\n", pin.SignalType)
+		for _, geom := range pin.Geometries {
+			lw.Printf("    PORT\n")
+			lw.writeShapes("      ", geom.Boxes)
+			lw.Printf("    END\n")
+		}
+		lw.Printf("  END %v\n", pin.Name)
+	}
+	if obs := master.Obstructions; obs != nil && len(obs.Boxes) > 0 {
+		lw.Printf("  OBS\n")
+		lw.writeShapes("    ", obs.Boxes)
+		lw.Printf("  END\n")
+	}
+	lw.Printf("END %v\n\n", master.Name)
+}
@@ -0,0 +1,159 @@
+package goopendb
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestWriteLEFLibrary(t *testing.T) {
+	site := &Site{ID: 1, Name: "core", Class: "CORE", Width: 380, Height: 2800, SymmetryY: true}
+	metal1 := &Layer{ID: 1, Name: "metal1"}
+	via := &Via{ID: 1, Name: "via1_4", Rect: &Rect{XMin: -70, YMin: -70, XMax: 70, YMax: 70}}
+	filler := &Master{ID: 1, Name: "FILLCELL_X1", Class: "CORE_SPACER", Width: 380, Height: 2800, Site: site}
+	inv := &Master{
+		ID:      2,
+		Name:    "INV_X1",
+		Class:   "CORE",
+		Foreign: &Foreign{Name: "INV_X1", Origin: &Point{X: 200}, Orientation: OrientationMY},
+		Width:   760,
+		Height:  2800,
+		Pins: []*MasterPin{{
+			ID:         1,
+			Name:       "ZN",
+			Direction:  IOTypeOUTPUT,
+			Geometries: []*Geometry{{ID: 1, Boxes: []*Rect{{XMin: 100, XMax: 200, YMax: 1000, Layer: metal1}}}},
+		}},
+		Obstructions: &Geometry{ID: 2, Boxes: []*Rect{
+			{XMin: 130, YMin: 330, XMax: 270, YMax: 470, Via: via},
+			{XMin: 0, YMin: 0, XMax: 100, YMax: 100, Layer: metal1},
+		}},
+	}
+	design := &Design{
+		DBUPerMicron: 2000,
+		Sites:        []*Site{site},
+		Masters:      []*Master{filler, inv},
+		Instances:    []*Instance{{ID: 1, Name: "u1", Master: "INV_X1"}},
+	}
+	if used := design.UsedMasters(); len(used) != 1 || used[0] != inv {
+		t.Errorf("Unexpected used masters %v", used)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteLEFLibrary(design, &buf); err != nil {
+		t.Fatal(err)
+	}
+	lef := buf.String()
+	for _, line := range []string{
+		"  DATABASE MICRONS 2000 ;",
+		"  SYMMETRY Y ;",
+		"  SIZE 0.19 BY 1.4 ;",
+		"  CLASS CORE SPACER ;",
+		"  FOREIGN INV_X1 0.1 0 FN ;",
+		"    DIRECTION OUTPUT ;",
+		"      LAYER metal1 ;",
+		"        RECT 0.05 0 0.1 0.5 ;",
+		"    VIA 0.1 0.2 via1_4 ;\n    LAYER metal1 ;\n      RECT 0 0 0.05 0.05 ;",
+		"END LIBRARY",
+	} {
+		if !strings.Contains(lef, line+"\n") {
+			t.Errorf("Expected %q in\n%v", line, lef)
+		}
+	}
+}
+
+func TestWriteLEFTechnology(t *testing.T) {
+	metal1 := &Layer{
+		ID: 1, Name: "metal1", Type: LayerTypeROUTING, Width: 140, MaxWidth: -1, Height: -1, Thickness: -1,
+		Capacitance: 7.7e-05, AntennaRule: &AntennaRule{AreaFactor: 1, PAR: 400},
+	}
+	via1 := &Layer{
+		ID: 2, Name: "via1", Type: LayerTypeCUT, MaxWidth: -1, Height: -1, Thickness: -1,
+		Capacitance: 1e-05, AntennaRule: &AntennaRule{AreaFactor: 2},
+	}
+	design := &Design{DBUPerMicron: 2000, Layers: []*Layer{metal1, via1}}
+	var buf bytes.Buffer
+	if err := WriteLEFTechnology(design, &buf); err != nil {
+		t.Fatal(err)
+	}
+	lef := buf.String()
+	for _, line := range []string{
+		"  CAPACITANCE CPERSQDIST 0.000077 ;\n",
+		"  ANTENNAAREARATIO 400 ;\n",
+		"  ANTENNAAREAFACTOR 2 ;\nEND via1",
+	} {
+		if !strings.Contains(lef, line) {
+			t.Errorf("Expected %q in\n%v", line, lef)
+		}
+	}
+	// Cut layers have no capacitance and the area factor defaults to 1
+	if strings.Count(lef, "CAPACITANCE") != 1 || strings.Count(lef, "ANTENNAAREAFACTOR") != 1 {
+		t.Errorf("Unexpected layer rules in\n%v", lef)
+	}
+}
+
+func TestReadLEFForeigns(t *testing.T) {
+	lef := `MACRO INV_X1
+  CLASS CORE ;
+  FOREIGN INV_X1 0.1 0.2 FS ; # comment
+  PIN A
+    PORT
+      LAYER metal1 ;
+    END
+  END A
+END INV_X1
+MACRO BUF_X1
+  FOREIGN buf
+  ;
+END BUF_X1
+MACRO TAP
+END TAP
+`
+	foreigns, err := readLEFForeigns(strings.NewReader(lef))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(foreigns) != 2 {
+		t.Fatalf("Expected 2 foreign cells, found %v", len(foreigns))
+	}
+	design := &Design{DBUPerMicron: 2000}
+	inv := foreigns["INV_X1"].foreign(design)
+	if inv.Name != "INV_X1" || inv.Origin == nil || *inv.Origin != (Point{X: 200, Y: 400}) || inv.Orientation != OrientationMX {
+		t.Errorf("Unexpected INV_X1 foreign %+v", inv)
+	}
+	if buf := foreigns["BUF_X1"].foreign(design); buf.Name != "buf" || buf.Origin != nil {
+		t.Errorf("Unexpected BUF_X1 foreign %+v", buf)
+	}
+}
+
+func TestReadLEFHeights(t *testing.T) {
+	// Technology only LEF, no macros
+	lef := `VERSION 5.8 ;
+UNITS
+  DATABASE MICRONS 2000 ;
+END UNITS
+LAYER metal1
+  TYPE ROUTING ;
+  HEIGHT 0.37 ; # comment
+  THICKNESS 0.13 ;
+END metal1
+LAYER via1
+  TYPE CUT ;
+END via1
+LAYER metal2
+  TYPE ROUTING ; HEIGHT 0.62 ;
+END metal2
+VIA via1_4 DEFAULT
+  LAYER via1 ;
+    RECT -0.035 -0.035 0.035 0.035 ;
+END via1_4
+END LIBRARY
+`
+	heights, err := readLEFHeights(strings.NewReader(lef))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(heights) != 2 || heights["metal1"] != 0.37 || heights["metal2"] != 0.62 {
+		t.Errorf("Unexpected layer heights %v", heights)
+	}
+}
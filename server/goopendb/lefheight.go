@@ -0,0 +1,68 @@
+package goopendb
+
+// LEF layer HEIGHT statements, OpenDB does not keep them in the database so
+// the OpenDB backend reads them from the LEF files separately
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// readLEFHeights scans the HEIGHT statements of the LEF layers, the heights
+// are in microns by layer name
+func readLEFHeights(r io.Reader) (map[string]float64, error) {
+	heights := map[string]float64{}
+	var block, layer string
+	var statement []string // Words of the current statement
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if idx := strings.IndexByte(line, '#'); idx >= 0 {
+			line = line[:idx]
+		}
+		// Block start and END lines have no semicolon
+		statement = nil
+		for _, word := range strings.Fields(line) {
+			end := strings.HasSuffix(word, ";")
+			if word = strings.TrimSuffix(word, ";"); word != "" {
+				statement = append(statement, word)
+			}
+			if !end {
+				continue
+			}
+			if layer != "" && len(statement) == 2 && statement[0] == "HEIGHT" {
+				if height, err := strconv.ParseFloat(statement[1], 64); err == nil {
+					heights[layer] = height
+				}
+			}
+			statement = nil
+		}
+		if len(statement) != 2 {
+			continue
+		}
+		switch {
+		case statement[0] == "END" && statement[1] == block:
+			block, layer = "", ""
+		case block != "":
+		case statement[0] == "LAYER":
+			block, layer = statement[1], statement[1]
+		case statement[0] == "MACRO":
+			block = statement[1]
+		}
+	}
+	return heights, scanner.Err()
+}
+
+// readLEFHeightsFile scans the layer HEIGHT statements of a LEF file
+func readLEFHeightsFile(filepath string) (map[string]float64, error) {
+	file, err := os.Open(filepath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return readLEFHeights(file)
+}
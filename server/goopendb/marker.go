@@ -0,0 +1,80 @@
+package goopendb
+
+// MarkerType is the kind of a verification marker
+type MarkerType int
+
+// MarkerType enum
+const (
+	MarkerOpen MarkerType = iota
+	MarkerDangling
+	MarkerShort
+	MarkerMinWidth
+	MarkerMinSpacing
+	MarkerMinArea
+	MarkerViaEnclosure
+	MarkerOffRow
+	MarkerOffSite
+	MarkerOrientation
+	MarkerOverlap
+	MarkerOutsideCore
+	MarkerOutsideDie
+	MarkerFence
+	MarkerAdded
+	MarkerRemoved
+	MarkerChanged
+)
+
+func (typ MarkerType) String() string {
+	switch typ {
+	case MarkerOpen:
+		return "Open"
+	case MarkerDangling:
+		return "Dangling"
+	case MarkerShort:
+		return "Short"
+	case MarkerMinWidth:
+		return "MinWidth"
+	case MarkerMinSpacing:
+		return "MinSpacing"
+	case MarkerMinArea:
+		return "MinArea"
+	case MarkerViaEnclosure:
+		return "ViaEnclosure"
+	case MarkerOffRow:
+		return "OffRow"
+	case MarkerOffSite:
+		return "OffSite"
+	case MarkerOrientation:
+		return "Orientation"
+	case MarkerOverlap:
+		return "Overlap"
+	case MarkerOutsideCore:
+		return "OutsideCore"
+	case MarkerOutsideDie:
+		return "OutsideDie"
+	case MarkerFence:
+		return "Fence"
+	case MarkerAdded:
+		return "Added"
+	case MarkerRemoved:
+		return "Removed"
+	case MarkerChanged:
+		return "Changed"
+	}
+	return "Unknown"
+}
+
+// Marker is a verification violation located in the design
+type Marker struct {
+	Type      MarkerType `proto:"1"`
+	Message   string     `proto:"2"`
+	Rule      string     `json:",omitempty" proto:"3"` // Violated LEF rule
+	Layer     string     `json:",omitempty" proto:"4"` // Empty for markers spanning several layers
+	XMin      int        `proto:"5"`
+	YMin      int        `proto:"6"`
+	XMax      int        `proto:"7"`
+	YMax      int        `proto:"8"`
+	Nets      []string   `json:",omitempty" proto:"9"`
+	Pins      []string   `json:",omitempty" proto:"10"` // Instance pins as instance/pin, block pins as PIN/pin
+	Instances []string   `json:",omitempty" proto:"11"`
+}
@@ -0,0 +1,314 @@
+package goopendb
+
+// OASIS serialization of the design layout
+
+import (
+	"fmt"
+	"io"
+	"sort"
+)
+
+// OASISVersion is the OASIS version written by WriteOASIS
+const OASISVersion = "1.0"
+
+const oasisMagic = "%SEMI-OASIS\r\n"
+
+// OASIS record IDs
+const (
+	oasisSTART     = 1
+	oasisEND       = 2
+	oasisCELLNAME  = 3
+	oasisLAYERNAME = 11
+	oasisCELL      = 13
+	oasisPLACEMENT = 17
+	oasisTEXT      = 19
+	oasisRECTANGLE = 20
+)
+
+// OASIS repetition types
+const (
+	oasisRepetitionRow        = 2 // Uniform spacing along X
+	oasisRepetitionVaryingRow = 4 // Arbitrary spacing along X
+)
+
+// oasisEndSize is the fixed size of the END record
+const oasisEndSize = 256
+
+// oasisWriter writes OASIS records, the first write error is kept. Shape modal
+// variables are kept to omit repeated layers and sizes
+type oasisWriter struct {
+	*ErrWriter
+	layers GDSLayerMap
+	cells  map[string]int // Cell reference numbers
+	modal  struct {
+		valid    bool
+		layer    int
+		datatype int
+		width    int
+		height   int
+	}
+}
+
+func (ow *oasisWriter) write(data ...byte) {
+	ow.Write(data)
+}
+
+// unsignedBytes encodes an OASIS unsigned integer, 7 bits per byte starting
+// with the least significant bits
+func unsignedBytes(v uint64) []byte {
+	var data []byte
+	for v >= 0x80 {
+		data = append(data, byte(v&0x7F)|0x80)
+		v >>= 7
+	}
+	return append(data, byte(v))
+}
+
+func (ow *oasisWriter) unsigned(v int) {
+	ow.write(unsignedBytes(uint64(v))...)
+}
+
+// signed encodes an OASIS signed integer, the sign is the least significant bit
+func (ow *oasisWriter) signed(v int) {
+	if v < 0 {
+		ow.write(unsignedBytes(uint64(-v)<<1 | 1)...)
+		return
+	}
+	ow.write(unsignedBytes(uint64(v) << 1)...)
+}
+
+func (ow *oasisWriter) str(s string) {
+	ow.unsigned(len(s))
+	ow.write([]byte(s)...)
+}
+
+// startCell starts a cell by reference number, modal variables are undefined
+// at the start of every cell
+func (ow *oasisWriter) startCell(name string) {
+	ow.write(oasisCELL)
+	ow.unsigned(ow.cells[name])
+	ow.modal.valid = false
+}
+
+// rectangle writes a rectangle, it is skipped when its layer is not mapped
+func (ow *oasisWriter) rectangle(layer *Layer, xMin, yMin, xMax, yMax int) {
+	if layer == nil {
+		return
+	}
+	mapped, ok := ow.layers[layer.Name]
+	if !ok {
+		return
+	}
+	width, height := xMax-xMin, yMax-yMin
+	modal := &ow.modal
+	var info byte = 0x18 // X and Y
+	if !modal.valid || modal.layer != mapped.Layer {
+		info |= 0x01
+	}
+	if !modal.valid || modal.datatype != mapped.DataType {
+		info |= 0x02
+	}
+	if !modal.valid || modal.width != width {
+		info |= 0x40
+	}
+	if !modal.valid || modal.height != height {
+		info |= 0x20
+	}
+	ow.write(oasisRECTANGLE, info)
+	if info&0x01 != 0 {
+		ow.unsigned(mapped.Layer)
+	}
+	if info&0x02 != 0 {
+		ow.unsigned(mapped.DataType)
+	}
+	if info&0x40 != 0 {
+		ow.unsigned(width)
+	}
+	if info&0x20 != 0 {
+		ow.unsigned(height)
+	}
+	ow.signed(xMin)
+	ow.signed(yMin)
+	modal.valid = true
+	modal.layer, modal.datatype = mapped.Layer, mapped.DataType
+	modal.width, modal.height = width, height
+}
+
+// oasisPlacement is a row of references to the same cell with the same
+// orientation and Y location
+type oasisPlacement struct {
+	cell        string
+	orientation Orientation
+	y           int
+	xs          []int
+}
+
+// placements groups the placed instances into rows, in order of appearance
+func (ow *oasisWriter) placements(instances []*Instance) []*oasisPlacement {
+	type rowKey struct {
+		cell        string
+		orientation Orientation
+		y           int
+	}
+	var rows []*oasisPlacement
+	rowMap := make(map[rowKey]*oasisPlacement)
+	for _, inst := range instances {
+		if !inst.IsPlaced || inst.Origin == nil {
+			continue
+		}
+		if _, ok := ow.cells[inst.Master]; !ok {
+			continue
+		}
+		key := rowKey{cell: inst.Master, orientation: inst.Orientation, y: inst.Origin.Y}
+		row := rowMap[key]
+		if row == nil {
+			row = &oasisPlacement{cell: inst.Master, orientation: inst.Orientation, y: inst.Origin.Y}
+			rowMap[key] = row
+			rows = append(rows, row)
+		}
+		row.xs = append(row.xs, inst.Origin.X)
+	}
+	return rows
+}
+
+// writePlacement writes a row of references as a single placement with a
+// repetition along X
+func (ow *oasisWriter) writePlacement(row *oasisPlacement) {
+	sort.Ints(row.xs)
+	transform := layoutTransforms[row.orientation]
+	var info byte = 0xF0 // Cell reference number, X and Y
+	info |= byte(transform.angle/90) << 1
+	if transform.reflect {
+		info |= 0x01
+	}
+	if len(row.xs) > 1 {
+		info |= 0x08
+	}
+	ow.write(oasisPLACEMENT, info)
+	ow.unsigned(ow.cells[row.cell])
+	ow.signed(row.xs[0])
+	ow.signed(row.y)
+	if len(row.xs) == 1 {
+		return
+	}
+	var spaces []int
+	uniform := true
+	for i := 1; i < len(row.xs); i++ {
+		spaces = append(spaces, row.xs[i]-row.xs[i-1])
+		uniform = uniform && spaces[i-1] == spaces[0]
+	}
+	if uniform {
+		ow.unsigned(oasisRepetitionRow)
+		ow.unsigned(len(row.xs) - 2)
+		ow.unsigned(spaces[0])
+		return
+	}
+	ow.unsigned(oasisRepetitionVaryingRow)
+	ow.unsigned(len(row.xs) - 2)
+	for _, space := range spaces {
+		ow.unsigned(space)
+	}
+}
+
+// writePin writes the block pin shapes and labels the pin on its first mapped layer
+func (ow *oasisWriter) writePin(pin *Pin) {
+	VisitGeometries(pin.Geometries, ow.rectangle)
+	mapped, ok := ow.layers.pinLayer(pin)
+	if !ok || pin.Location == nil {
+		return
+	}
+	ow.write(oasisTEXT, 0x5B) // Explicit string, X, Y, text type and layer
+	ow.str(pin.Name)
+	ow.unsigned(mapped.Layer)
+	ow.unsigned(mapped.DataType)
+	ow.signed(pin.Location.X)
+	ow.signed(pin.Location.Y)
+}
+
+// writeEnd writes the END record padded to its fixed size, without validation
+func (ow *oasisWriter) writeEnd() {
+	padding := oasisEndSize - 2
+	for padding+len(unsignedBytes(uint64(padding))) > oasisEndSize-2 {
+		padding--
+	}
+	ow.write(oasisEND)
+	ow.str(string(make([]byte, padding)))
+	ow.unsigned(0)
+}
+
+// WriteOASIS writes the design as an OASIS file with the same structure as
+// WriteGDS: a cell per used master and a top cell named after the design.
+// Instances sharing a master, orientation and row are written as a single
+// placement with a repetition. A nil layer map uses DefaultGDSLayerMap
+func WriteOASIS(design *Design, layers GDSLayerMap, w io.Writer) error {
+	if design.DBUPerMicron <= 0 {
+		return fmt.Errorf("Unknown database units")
+	}
+	if layers == nil {
+		layers = DefaultGDSLayerMap(design)
+	}
+	name := design.Name
+	if name == "" {
+		name = "TOP"
+	}
+	ow := &oasisWriter{
+		ErrWriter: NewErrWriter(w),
+		layers:    layers,
+		cells:     make(map[string]int),
+	}
+	masters := design.UsedMasters()
+
+	ow.write([]byte(oasisMagic)...)
+	ow.write(oasisSTART)
+	ow.str(OASISVersion)
+	// Grid steps per micron as a positive whole number real
+	ow.unsigned(0)
+	ow.unsigned(design.DBUPerMicron)
+	// Table offsets are in the START record, there are no name tables
+	ow.unsigned(0)
+	for i := 0; i < 12; i++ {
+		ow.unsigned(0)
+	}
+
+	// Cell reference numbers are implicit in CELLNAME order
+	for _, master := range masters {
+		ow.cells[master.Name] = len(ow.cells)
+		ow.write(oasisCELLNAME)
+		ow.str(master.Name)
+	}
+	ow.cells[name] = len(ow.cells)
+	ow.write(oasisCELLNAME)
+	ow.str(name)
+	for _, layer := range design.Layers {
+		mapped, ok := layers[layer.Name]
+		if !ok {
+			continue
+		}
+		// Exact layer and datatype intervals
+		ow.write(oasisLAYERNAME)
+		ow.str(layer.Name)
+		ow.unsigned(3)
+		ow.unsigned(mapped.Layer)
+		ow.unsigned(3)
+		ow.unsigned(mapped.DataType)
+	}
+
+	for _, master := range masters {
+		ow.startCell(master.Name)
+		master.VisitShapes(ow.rectangle)
+	}
+
+	ow.startCell(name)
+	for _, row := range ow.placements(design.Instances) {
+		ow.writePlacement(row)
+	}
+	for _, pin := range design.BlockPins {
+		ow.writePin(pin)
+	}
+	for _, net := range design.Nets {
+		net.VisitWires(ow.rectangle)
+		net.VisitSpecialWires(ow.rectangle)
+	}
+	ow.writeEnd()
+	return ow.Flush()
+}
@@ -0,0 +1,328 @@
+package goopendb
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// oasisReader decodes the OASIS records written by WriteOASIS
+type oasisReader struct {
+	t    *testing.T
+	data []byte
+}
+
+func (r *oasisReader) byte() byte {
+	if len(r.data) == 0 {
+		r.t.Fatalf("Unexpected end of OASIS data")
+	}
+	b := r.data[0]
+	r.data = r.data[1:]
+	return b
+}
+
+func (r *oasisReader) unsigned() int {
+	v, shift := 0, uint(0)
+	for {
+		b := r.byte()
+		v |= int(b&0x7F) << shift
+		if b&0x80 == 0 {
+			return v
+		}
+		shift += 7
+	}
+}
+
+func (r *oasisReader) signed() int {
+	v := r.unsigned()
+	if v&1 == 1 {
+		return -(v >> 1)
+	}
+	return v >> 1
+}
+
+func (r *oasisReader) str() string {
+	n := r.unsigned()
+	if n > len(r.data) {
+		r.t.Fatalf("Invalid string length %v", n)
+	}
+	s := string(r.data[:n])
+	r.data = r.data[n:]
+	return s
+}
+
+type oasisShape struct {
+	cell string
+	text string
+	info [6]int // Layer, datatype, x, y, width, height
+}
+
+type oasisInstance struct {
+	cell    string
+	x       int
+	y       int
+	angle   int
+	reflect bool
+}
+
+type oasisLayout struct {
+	unit      int
+	cells     []string
+	layers    map[string][2]int
+	shapes    []oasisShape
+	instances []oasisInstance
+}
+
+func readOASIS(t *testing.T, data []byte) *oasisLayout {
+	if !bytes.HasPrefix(data, []byte(oasisMagic)) {
+		t.Fatalf("Missing OASIS magic")
+	}
+	if len(data) < len(oasisMagic)+oasisEndSize || data[len(data)-oasisEndSize] != oasisEND {
+		t.Fatalf("Missing OASIS END record")
+	}
+	r := &oasisReader{t: t, data: data[len(oasisMagic) : len(data)-oasisEndSize]}
+	layout := &oasisLayout{layers: make(map[string][2]int)}
+	if r.byte() != oasisSTART || r.str() != OASISVersion || r.unsigned() != 0 {
+		t.Fatalf("Invalid START record")
+	}
+	layout.unit = r.unsigned()
+	if r.unsigned() != 0 {
+		t.Fatalf("Expected table offsets in the START record")
+	}
+	for i := 0; i < 12; i++ {
+		r.unsigned()
+	}
+	var cell string
+	var layer, datatype, width, height int
+	for len(r.data) > 0 {
+		switch id := r.byte(); id {
+		case oasisCELLNAME:
+			layout.cells = append(layout.cells, r.str())
+		case oasisLAYERNAME:
+			name := r.str()
+			if r.unsigned() != 3 {
+				t.Fatalf("Expected an exact layer interval")
+			}
+			layer := r.unsigned()
+			if r.unsigned() != 3 {
+				t.Fatalf("Expected an exact datatype interval")
+			}
+			layout.layers[name] = [2]int{layer, r.unsigned()}
+		case oasisCELL:
+			cell = layout.cells[r.unsigned()]
+		case oasisRECTANGLE:
+			info := r.byte()
+			if info&0x01 != 0 {
+				layer = r.unsigned()
+			}
+			if info&0x02 != 0 {
+				datatype = r.unsigned()
+			}
+			if info&0x40 != 0 {
+				width = r.unsigned()
+			}
+			if info&0x20 != 0 {
+				height = r.unsigned()
+			}
+			x, y := r.signed(), r.signed()
+			layout.shapes = append(layout.shapes, oasisShape{cell: cell, info: [6]int{layer, datatype, x, y, width, height}})
+		case oasisTEXT:
+			if r.byte() != 0x5B {
+				t.Fatalf("Unexpected TEXT record")
+			}
+			text := r.str()
+			layer, datatype := r.unsigned(), r.unsigned()
+			x, y := r.signed(), r.signed()
+			layout.shapes = append(layout.shapes, oasisShape{cell: cell, text: text, info: [6]int{layer, datatype, x, y}})
+		case oasisPLACEMENT:
+			info := r.byte()
+			inst := oasisInstance{
+				cell:    layout.cells[r.unsigned()],
+				angle:   int(info>>1&0x03) * 90,
+				reflect: info&0x01 != 0,
+			}
+			inst.x, inst.y = r.signed(), r.signed()
+			spaces := []int{0}
+			if info&0x08 != 0 {
+				switch r.unsigned() {
+				case oasisRepetitionRow:
+					n, space := r.unsigned()+2, r.unsigned()
+					for i := 1; i < n; i++ {
+						spaces = append(spaces, i*space)
+					}
+				case oasisRepetitionVaryingRow:
+					n := r.unsigned() + 2
+					for i := 1; i < n; i++ {
+						spaces = append(spaces, spaces[i-1]+r.unsigned())
+					}
+				default:
+					t.Fatalf("Unexpected repetition")
+				}
+			}
+			for _, space := range spaces {
+				placed := inst
+				placed.x += space
+				layout.instances = append(layout.instances, placed)
+			}
+		default:
+			t.Fatalf("Unexpected record %v", id)
+		}
+	}
+	return layout
+}
+
+func TestWriteOASIS(t *testing.T) {
+	metal1 := &Layer{ID: 1, Name: "metal1"}
+	metal2 := &Layer{ID: 2, Name: "metal2"}
+	inv := &Master{
+		ID:     1,
+		Name:   "INV_X1",
+		Width:  760,
+		Height: 2800,
+		Pins: []*MasterPin{{
+			ID:   1,
+			Name: "ZN",
+			Geometries: []*Geometry{{ID: 1, Boxes: []*Rect{
+				{XMin: 100, XMax: 200, YMax: 1000, Layer: metal1},
+				{XMin: 300, XMax: 400, YMax: 1000, Layer: metal1},
+			}}},
+		}},
+	}
+	pin := &Pin{
+		ID:         1,
+		Name:       "clk",
+		IsBlock:    true,
+		Location:   &Point{X: 5000, Y: -70},
+		Geometries: []*Geometry{{ID: 2, Boxes: []*Rect{{XMin: 4930, YMin: -140, XMax: 5070, YMax: 0, Layer: metal2}}}},
+	}
+	design := &Design{
+		Name:         "top",
+		DBUPerMicron: 2000,
+		Layers:       []*Layer{metal1, metal2},
+		Masters:      []*Master{inv},
+		BlockPins:    []*Pin{pin},
+		Nets: []*Net{{
+			ID:    1,
+			Name:  "clk",
+			Edges: []*Edge{{Type: EdgeTypeSEGMENT, Layer: metal2, Rect: &Rect{XMin: 0, YMin: 0, XMax: 1000, YMax: 140}}},
+		}},
+	}
+	// A uniform row, a varying row and a flipped single instance
+	for i, x := range []int{0, 760, 1520, 5000, 400, 9000} {
+		inst := &Instance{ID: i + 1, Master: "INV_X1", IsPlaced: true, Origin: &Point{X: x}}
+		if i >= 3 {
+			inst.Origin.Y = 2800
+		}
+		if i == 5 {
+			inst.Orientation = OrientationMY
+		}
+		design.Instances = append(design.Instances, inst)
+	}
+	design.Instances = append(design.Instances, &Instance{ID: 7, Master: "INV_X1", Origin: &Point{}})
+
+	var buf bytes.Buffer
+	if err := WriteOASIS(design, GDSLayerMap{"metal1": {Layer: 11}, "metal2": {Layer: 13, DataType: 1}}, &buf); err != nil {
+		t.Fatal(err)
+	}
+	layout := readOASIS(t, buf.Bytes())
+	if layout.unit != 2000 {
+		t.Errorf("Expected 2000 grid steps per micron, found %v", layout.unit)
+	}
+	if strings.Join(layout.cells, " ") != "INV_X1 top" {
+		t.Errorf("Unexpected cells %v", layout.cells)
+	}
+	if layout.layers["metal2"] != [2]int{13, 1} {
+		t.Errorf("Unexpected layer names %v", layout.layers)
+	}
+
+	expectedInstances := []oasisInstance{
+		{cell: "INV_X1", x: 0},
+		{cell: "INV_X1", x: 760},
+		{cell: "INV_X1", x: 1520},
+		{cell: "INV_X1", x: 400, y: 2800},
+		{cell: "INV_X1", x: 5000, y: 2800},
+		{cell: "INV_X1", x: 9000, y: 2800, angle: 180, reflect: true},
+	}
+	if len(layout.instances) != len(expectedInstances) {
+		t.Fatalf("Expected %v instances, found %+v", len(expectedInstances), layout.instances)
+	}
+	for i, inst := range expectedInstances {
+		if layout.instances[i] != inst {
+			t.Errorf("Expected instance %+v, found %+v", inst, layout.instances[i])
+		}
+	}
+
+	expectedShapes := []oasisShape{
+		{cell: "INV_X1", info: [6]int{11, 0, 100, 0, 100, 1000}},
+		{cell: "INV_X1", info: [6]int{11, 0, 300, 0, 100, 1000}},
+		{cell: "top", info: [6]int{13, 1, 4930, -140, 140, 140}},
+		{cell: "top", text: "clk", info: [6]int{13, 1, 5000, -70}},
+		{cell: "top", info: [6]int{13, 1, 0, 0, 1000, 140}},
+	}
+	if len(layout.shapes) != len(expectedShapes) {
+		t.Fatalf("Expected %v shapes, found %+v", len(expectedShapes), layout.shapes)
+	}
+	for i, shape := range expectedShapes {
+		if layout.shapes[i] != shape {
+			t.Errorf("Expected shape %+v, found %+v", shape, layout.shapes[i])
+		}
+	}
+}
+
+func TestWriteOASISSize(t *testing.T) {
+	metal1 := &Layer{ID: 1, Name: "metal1"}
+	metal2 := &Layer{ID: 2, Name: "metal2"}
+	inv := &Master{
+		ID:     1,
+		Name:   "INV_X1",
+		Width:  760,
+		Height: 2800,
+		Pins: []*MasterPin{{
+			ID:         1,
+			Name:       "ZN",
+			Geometries: []*Geometry{{ID: 1, Boxes: []*Rect{{XMin: 100, XMax: 200, YMax: 1000, Layer: metal1}}}},
+		}},
+	}
+	design := &Design{
+		Name:         "top",
+		DBUPerMicron: 2000,
+		Layers:       []*Layer{metal1, metal2},
+		Masters:      []*Master{inv},
+	}
+	// Placement rows and short wires, like a placed and routed block
+	for row := 0; row < 15; row++ {
+		orientation := OrientationR0
+		if row%2 == 1 {
+			orientation = OrientationMX
+		}
+		for col := 0; col < 40; col++ {
+			id := len(design.Instances) + 1
+			x, y := col*inv.Width, row*inv.Height
+			design.Instances = append(design.Instances, &Instance{
+				ID:          id,
+				Master:      "INV_X1",
+				IsPlaced:    true,
+				Origin:      &Point{X: x, Y: y + row%2*inv.Height},
+				Orientation: orientation,
+			})
+			design.Nets = append(design.Nets, &Net{
+				ID:    id,
+				Edges: []*Edge{{Type: EdgeTypeSEGMENT, Layer: metal2, Rect: &Rect{XMin: x, YMin: y, XMax: x + 1500, YMax: y + 140}}},
+			})
+		}
+	}
+
+	var gds, oasis bytes.Buffer
+	if err := WriteGDS(design, nil, &gds); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteOASIS(design, nil, &oasis); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(oasis.Bytes(), []byte("%SEMI-OASIS\r\n")) {
+		t.Fatalf("Missing OASIS magic")
+	}
+	if oasis.Len()*2 > gds.Len() {
+		t.Errorf("Expected OASIS to be less than half the GDSII size, found %v and %v bytes", oasis.Len(), gds.Len())
+	}
+}
@@ -0,0 +1,1093 @@
+//go:build cgo
+// +build cgo
+
+package goopendb
+
+// Wrapper for OpenDB Si2 LEF/DEF parser
+
+// #cgo LDFLAGS: -L${SRCDIR}/c++
+// #cgo LDFLAGS: -ldl -lgoopendb -llefin -llef -ldefin -ldef -lzlib -llefzlib -lopendb -lzutil -ldefout -llefout -ltm -lm -ltcl -lstdc++
+// #include "stdlib.h"
+// #include "c++/goopendb.h"
+import "C"
+import (
+	"fmt"
+	"reflect"
+	"unsafe"
+)
+
+func init() {
+	RegisterBackend(BackendOpenDB, func() (Backend, error) {
+		db, err := NewDatabase()
+		if err != nil {
+			return nil, err
+		}
+		return db, nil
+	})
+}
+
+// OpenDB is a wrapper for OpenDB database object
+type OpenDB struct {
+	db       C.dbDatabase
+	foreigns map[string]*lefForeign // OpenDB does not keep the macro FOREIGN statements
+	heights  map[string]float64     // Nor the layer HEIGHT statements, in microns
+}
+
+// NewDatabase creates a new OpenDB database
+func NewDatabase() (ret OpenDB, err error) {
+	db := C.DatabaseNew()
+	if db == nil {
+		cErr := C.GoString(C.LastError)
+		if len(cErr) > 0 {
+			err = fmt.Errorf("%v", C.GoString(C.LastError))
+		} else {
+			err = fmt.Errorf("Unknown error has occured")
+		}
+	}
+	ret.db = db
+	ret.foreigns = map[string]*lefForeign{}
+	ret.heights = map[string]float64{}
+	return ret, err
+}
+
+// FreeDatabase releases OpenDB database
+func (ref OpenDB) FreeDatabase() (err error) {
+	rc := C.DatabaseFree(ref.db)
+	if rc != 0 {
+		cErr := C.GoString(C.LastError)
+		if len(cErr) > 0 {
+			err = fmt.Errorf("%v", C.GoString(C.LastError))
+		} else {
+			err = fmt.Errorf("Unknown error has occured")
+		}
+	}
+	return
+}
+
+// ParseLEFLibrary reads the library section of a LEF technology file
+func (ref OpenDB) ParseLEFLibrary(filepath string) (err error) {
+	rc := C.ReadLib(ref.db, C.CString(filepath), C.CString(generateLibraryName(filepath)))
+	if rc != 0 {
+		cErr := C.GoString(C.LastError)
+		if len(cErr) > 0 {
+			err = fmt.Errorf("%v", C.GoString(C.LastError))
+		} else {
+			err = fmt.Errorf("Unknown error has occured")
+		}
+	}
+	if err == nil {
+		err = ref.readForeigns(filepath)
+	}
+	if err == nil {
+		err = ref.readHeights(filepath)
+	}
+	return
+}
+
+// ParseLEFTechnology reads the technology section of a LEF technology file
+func (ref OpenDB) ParseLEFTechnology(filepath string) (err error) {
+	rc := C.ReadTech(ref.db, C.CString(filepath))
+	if rc != 0 {
+		cErr := C.GoString(C.LastError)
+		if len(cErr) > 0 {
+			err = fmt.Errorf("%v", C.GoString(C.LastError))
+		} else {
+			err = fmt.Errorf("Unknown error has occured")
+		}
+	}
+	if err == nil {
+		err = ref.readHeights(filepath)
+	}
+	return
+
+}
+
+// ParseLEF reads the library and technology section of a LEF technology file
+func (ref OpenDB) ParseLEF(filepath string) (err error) {
+	rc := C.ReadTechAndLib(ref.db, C.CString(filepath), C.CString(generateLibraryName(filepath)))
+	if rc != 0 {
+		cErr := C.GoString(C.LastError)
+		if len(cErr) > 0 {
+			err = fmt.Errorf("%v", C.GoString(C.LastError))
+		} else {
+			err = fmt.Errorf("Unknown error has occured")
+		}
+	}
+	if err == nil {
+		err = ref.readForeigns(filepath)
+	}
+	if err == nil {
+		err = ref.readHeights(filepath)
+	}
+	return
+
+}
+
+// readForeigns keeps the macro FOREIGN statements of a LEF file
+func (ref OpenDB) readForeigns(filepath string) error {
+	foreigns, err := readLEFForeignsFile(filepath)
+	if err != nil {
+		return err
+	}
+	for name, foreign := range foreigns {
+		ref.foreigns[name] = foreign
+	}
+	return nil
+}
+
+// readHeights keeps the layer HEIGHT statements of a LEF file
+func (ref OpenDB) readHeights(filepath string) error {
+	heights, err := readLEFHeightsFile(filepath)
+	if err != nil {
+		return err
+	}
+	for name, height := range heights {
+		ref.heights[name] = height
+	}
+	return nil
+}
+
+// ParseDEF reads a design DEF file
+func (ref OpenDB) ParseDEF(filepath string) (err error) {
+	err = validateDEF(filepath)
+	if err != nil {
+		return
+	}
+	rc := C.ReadDesign(ref.db, C.CString(filepath))
+	if rc != 0 {
+		cErr := C.GoString(C.LastError)
+		if len(cErr) > 0 {
+			err = fmt.Errorf("%v", C.GoString(C.LastError))
+		} else {
+			err = fmt.Errorf("Unknown error has occured")
+		}
+	}
+	return
+
+}
+
+// DbuToMeters converts a distance in database units to meters
+func (ref OpenDB) DbuToMeters(dist int) float64 {
+	return float64(C.DbuToMeters(ref.db, C.int(dist)))
+}
+
+// GetDesign converts the parsed design to native go structs
+func (ref OpenDB) GetDesign() (design *Design, err error) {
+	design = &Design{}
+
+	// Get design instances
+	var sz int = 0
+	arrSzPtr := unsafe.Pointer(&sz)
+	designPtr, err := C.GetDesign(ref.db)
+	if err != nil {
+		return
+	}
+	defer C.FreeDesign(designPtr)
+	sz = *(*int)(arrSzPtr)
+	design.Name = C.GoString(designPtr.name)
+	design.DBUPerMicron = int(designPtr.dbuPerMicron)
+	design.LEFUnits = int(designPtr.lefUnits)
+	design.DEFUnits = int(designPtr.defUnits)
+
+	design.Instances = dbInstanceArrayToSlice(designPtr.instances, int(designPtr.instanceSz), false)
+	design.Nets = dbNetArrayToSlice(designPtr.nets, int(designPtr.netSz), false)
+	design.InstancePins = dbPinArrayToSlice(designPtr.instancePins, int(designPtr.instancePinSz), false)
+	design.BlockPins = dbPinArrayToSlice(designPtr.blockPins, int(designPtr.blockPinSz), false)
+	design.RoutingVias = dbViaArrayToSlice(designPtr.routingVias, int(designPtr.routingViaSz), false)
+	design.ViaDefinitions = dbViaArrayToSlice(designPtr.viaDefinitions, int(designPtr.viaDefinitionSz), false)
+	design.Layers = dbLayerArrayToSlice(designPtr.layers, int(designPtr.layerSz), false)
+	design.CoreArea = float64(designPtr.coreArea)
+	design.DieArea = float64(designPtr.dieArea)
+	design.DesignArea = float64(designPtr.designArea)
+	design.Utilization = float64(designPtr.utilization)
+	design.Core = designPtr.core.Rect(false)
+	design.BoundingBox = designPtr.boundingBox.Rect(false)
+	design.Die = designPtr.die.Rect(false)
+	design.Rows = dbRowArrayToSlice(designPtr.rows, int(designPtr.rowSz), false)
+	design.Tracks = dbTrackArrayToSlice(designPtr.tracks, int(designPtr.trackSz), false)
+	design.Sites = dbSiteArrayToSlice(designPtr.sites, int(designPtr.siteSz), false)
+	design.Geometries = dbGeometryArrayToSlice(designPtr.geometries, int(designPtr.geometrySz), false)
+	design.Blockages = dbBlockageArrayToSlice(designPtr.blockages, int(designPtr.blockageSz), false)
+	design.Regions = dbRegionArrayToSlice(designPtr.regions, int(designPtr.regionSz), false)
+	design.Groups = dbGroupArrayToSlice(designPtr.groups, int(designPtr.groupSz), false)
+	design.Fills = dbFillArrayToSlice(designPtr.fills, int(designPtr.fillSz), false)
+	design.Masters = dbMasterArrayToSlice(designPtr.masters, int(designPtr.masterSz), false)
+	design.ViaRules = dbViaRuleArrayToSlice(designPtr.viaRules, int(designPtr.viaRuleSz), false)
+	for _, master := range design.Masters {
+		if foreign, ok := ref.foreigns[master.Name]; ok {
+			master.Foreign = foreign.foreign(design)
+		}
+	}
+	for _, layer := range design.Layers {
+		if height, ok := ref.heights[layer.Name]; ok {
+			layer.Height = design.ToDBU(height)
+		}
+	}
+	design.GCell = nil
+	if designPtr.gcells != nil {
+		design.GCell = designPtr.gcells.Grid(false)
+	}
+	design.buildReferences()
+
+	return
+}
+
+func (ref C.Point) Point() *Point {
+	return &Point{X: int(ref.x), Y: int(ref.y)}
+}
+
+func (ref *C.Rect) Rect(idOnly bool) *Rect {
+	rect := &Rect{
+		ID:         int(ref.id),
+		XMin:       int(ref.xMin),
+		YMin:       int(ref.yMin),
+		XMax:       int(ref.xMax),
+		YMax:       int(ref.yMax),
+		ShapeType:  int(ref.shapeType),
+		Layer:      nil,
+		Via:        nil,
+		InComplete: idOnly,
+	}
+	if ref.layer != nil {
+		rect.Layer = ref.layer.Layer(idOnly)
+	}
+	if ref.via != nil {
+		rect.Via = ref.via.Via(idOnly)
+	}
+	return rect
+}
+
+func (ref *C.Edge) Edge() *Edge {
+	var via *Via = nil
+	var layer *Layer = nil
+	if ref.via != nil {
+		via = ref.via.Via(true)
+	}
+	if ref.layer != nil {
+		layer = ref.layer.Layer(true)
+	}
+	return &Edge{
+		Type:  EdgeType(ref.edgeType),
+		Rect:  ref.rect.Rect(false),
+		Via:   via,
+		Layer: layer,
+	}
+}
+
+func (ref *C.Instance) Instance(idOnly bool) *Instance {
+	if idOnly {
+		// Temporary holder
+		return &Instance{
+			ID:         int(ref.id),
+			InComplete: idOnly,
+		}
+	}
+	var region *Region = nil
+	var group *Group = nil
+	var master *Master = nil
+	if ref.region != nil {
+		region = ref.region.Region(true)
+	}
+	if ref.group != nil {
+		group = ref.group.Group(true)
+	}
+	if ref.masterRef != nil {
+		master = ref.masterRef.Master(true)
+	}
+	return &Instance{
+		ID:           int(ref.id),
+		Name:         C.GoString(ref.name),
+		Location:     ref.location.Point(),
+		Origin:       ref.origin.Point(),
+		Orientation:  Orientation(ref.orientation),
+		Master:       C.GoString(ref.master),
+		Pins:         dbPinArrayToSlice(ref.pins, int(ref.pinSz), true),
+		IsPlaced:     ref.isPlaced == 1,
+		Status:       PlacementStatus(ref.placementStatus),
+		BoundingBox:  ref.boundingBox.Rect(false),
+		Halo:         ref.halo.Rect(false),
+		IsFiller:     ref.isFiller == 1,
+		MasterType:   MasterType(ref.masterType),
+		Obstructions: ref.obstructions.Geometry(idOnly),
+		Region:       region,
+		Group:        group,
+		MasterRef:    master,
+		InComplete:   idOnly,
+	}
+}
+
+func (ref *C.Pin) Pin(idOnly bool) *Pin {
+	if idOnly {
+		// Temporary holder
+		return &Pin{
+			ID:         int(ref.id),
+			InComplete: idOnly,
+		}
+	}
+	var net *Net = nil
+	var inst *Instance = nil
+	if ref.net != nil {
+		net = ref.net.Net(true)
+	}
+	if ref.instance != nil {
+		inst = ref.instance.Instance(true)
+	}
+	return &Pin{
+		ID:         int(ref.id),
+		Name:       C.GoString(ref.name),
+		Instance:   inst,
+		Net:        net,
+		Direction:  Direction(ref.direction),
+		Location:   ref.location.Point(),
+		Geometries: dbGeometryArrayToSlice(ref.geometries, int(ref.geometrySz), idOnly),
+		IsBlock:    ref.isBlock == 1,
+		IsSpecial:  ref.isSpecial == 1,
+		SignalType: SignalType(ref.signalType),
+		InComplete: idOnly,
+	}
+}
+func (ref *C.Geometry) Geometry(idOnly bool) *Geometry {
+	if idOnly {
+		// Temporary holder
+		return &Geometry{
+			ID:         int(ref.id),
+			InComplete: idOnly,
+		}
+	}
+	return &Geometry{
+		ID:         int(ref.id),
+		Boxes:      dbRectArrayToSlice(ref.boxes, int(ref.boxSz), idOnly),
+		InComplete: false,
+	}
+}
+func (ref *C.Net) Net(idOnly bool) *Net {
+	if idOnly {
+		// Temporary holder
+		return &Net{
+			ID:         int(ref.id),
+			InComplete: idOnly,
+		}
+	}
+	var specialBoxes []*Geometry
+	var specialWires []*SpecialWire
+	if ref.specialBoxes != nil {
+		specialBoxes = dbGeometryArrayToSlice(ref.specialBoxes, int(ref.specialBoxSz), idOnly)
+	}
+	if ref.specialWires != nil {
+		specialWires = dbSpecialWireArrayToSlice(ref.specialWires, int(ref.specialWireSz), specialBoxes)
+	}
+	return &Net{
+		ID:           int(ref.id),
+		Name:         C.GoString(ref.name),
+		IsSpecial:    ref.isSpecial == 1,
+		IsRouted:     ref.isRouted == 1,
+		WireType:     WireType(ref.wireType),
+		Use:          SignalType(ref.use),
+		Pins:         dbPinArrayToSlice(ref.pins, int(ref.pinSz), true),
+		Edges:        dbEdgeArrayToSlice(ref.edges, int(ref.edgeSz)),
+		SpecialBoxes: specialBoxes,
+		SpecialWires: specialWires,
+		InComplete:   idOnly,
+	}
+}
+func (ref *C.Layer) Layer(idOnly bool) *Layer {
+	if idOnly {
+		// Temporary holder
+		return &Layer{
+			ID:         int(ref.id),
+			InComplete: idOnly,
+		}
+	}
+	var upperLayer *Layer = nil
+	var lowerLayer *Layer = nil
+	if ref.upperLayer != nil {
+		upperLayer = ref.upperLayer.Layer(true)
+	}
+	if ref.lowerLayer != nil {
+		lowerLayer = ref.lowerLayer.Layer(true)
+	}
+	var spacingTable *SpacingTable = nil
+	if ref.spacingTable != nil {
+		widthSz := int(ref.spacingTableWidthSz)
+		lengthSz := int(ref.spacingTableLengthSz)
+		spacings := intArrayToSlice(ref.spacingTable, widthSz*lengthSz)
+		spacingTable = &SpacingTable{
+			Widths:   intArrayToSlice(ref.spacingTableWidths, widthSz),
+			Lengths:  intArrayToSlice(ref.spacingTableLengths, lengthSz),
+			Spacings: make([][]int, widthSz),
+		}
+		for i := 0; i < widthSz; i++ {
+			spacingTable.Spacings[i] = spacings[i*lengthSz : (i+1)*lengthSz]
+		}
+	}
+	var influenceSpacing []*InfluenceSpacing
+	influenceWidths := intArrayToSlice(ref.influenceWidths, int(ref.influenceSz))
+	influenceWithins := intArrayToSlice(ref.influenceWithins, int(ref.influenceSz))
+	influenceSpacings := intArrayToSlice(ref.influenceSpacings, int(ref.influenceSz))
+	for i := range influenceWidths {
+		influenceSpacing = append(influenceSpacing, &InfluenceSpacing{
+			Width:   influenceWidths[i],
+			Within:  influenceWithins[i],
+			Spacing: influenceSpacings[i],
+		})
+	}
+	var minEnclosedAreas []*MinEnclosedArea
+	areas := doubleArrayToSlice(ref.minEnclosedAreas, int(ref.minEnclosedAreaSz))
+	areaWidths := intArrayToSlice(ref.minEnclosedAreaWidths, int(ref.minEnclosedAreaSz))
+	for i := range areas {
+		minEnclosedAreas = append(minEnclosedAreas, &MinEnclosedArea{
+			Area:  float64(areas[i]),
+			Width: areaWidths[i],
+		})
+	}
+	var antennaRule *AntennaRule = nil
+	if ref.hasAntennaRule == 1 {
+		antennaRule = &AntennaRule{
+			AreaFactor: float64(ref.antennaAreaFactor),
+			PAR:        float64(ref.antennaPAR),
+			CAR:        float64(ref.antennaCAR),
+			PSR:        float64(ref.antennaPSR),
+			CSR:        float64(ref.antennaCSR),
+		}
+	}
+	return &Layer{
+		ID:               int(ref.id),
+		Name:             C.GoString(ref.name),
+		Alias:            C.GoString(ref.alias),
+		Width:            int(ref.width),
+		Spacing:          int(ref.spacing),
+		Area:             float64(ref.area),
+		Type:             LayerType(ref.layerType),
+		Direction:        Direction(ref.direction),
+		PitchX:           int(ref.pitchX),
+		PitchY:           int(ref.pitchY),
+		OffsetX:          int(ref.offsetX),
+		OffsetY:          int(ref.offsetY),
+		MinWidth:         int(ref.minWidth),
+		MaxWidth:         int(ref.maxWidth),
+		MinStep:          int(ref.minStep),
+		Height:           -1,
+		Thickness:        int(ref.thickness),
+		Resistance:       float64(ref.resistance),
+		Capacitance:      float64(ref.capacitance),
+		EdgeCapacitance:  float64(ref.edgeCapacitance),
+		SpacingTable:     spacingTable,
+		InfluenceSpacing: influenceSpacing,
+		MinEnclosedAreas: minEnclosedAreas,
+		AntennaRule:      antennaRule,
+		UpperLayer:       upperLayer,
+		LowerLayer:       lowerLayer,
+		InComplete:       idOnly,
+	}
+}
+func (ref *C.Via) Via(idOnly bool) *Via {
+	if idOnly {
+		// Temporary holder
+		return &Via{
+			ID:         int(ref.id),
+			InComplete: idOnly,
+		}
+	}
+	var topLayer *Layer = nil
+	var bottomLayer *Layer = nil
+	var cutLayer *Layer = nil
+	if ref.topLayer != nil {
+		topLayer = ref.topLayer.Layer(true)
+	}
+	if ref.bottomLayer != nil {
+		bottomLayer = ref.bottomLayer.Layer(true)
+	}
+	if ref.cutLayer != nil {
+		cutLayer = ref.cutLayer.Layer(true)
+	}
+	var params *ViaParams = nil
+	var rule *ViaRule = nil
+	if ref.hasParams == 1 {
+		params = &ViaParams{
+			CutSizeX:         int(ref.params.cutSizeX),
+			CutSizeY:         int(ref.params.cutSizeY),
+			CutSpacingX:      int(ref.params.cutSpacingX),
+			CutSpacingY:      int(ref.params.cutSpacingY),
+			BottomEnclosureX: int(ref.params.bottomEnclosureX),
+			BottomEnclosureY: int(ref.params.bottomEnclosureY),
+			TopEnclosureX:    int(ref.params.topEnclosureX),
+			TopEnclosureY:    int(ref.params.topEnclosureY),
+			Rows:             int(ref.params.rows),
+			Cols:             int(ref.params.cols),
+			OriginX:          int(ref.params.originX),
+			OriginY:          int(ref.params.originY),
+			BottomOffsetX:    int(ref.params.bottomOffsetX),
+			BottomOffsetY:    int(ref.params.bottomOffsetY),
+			TopOffsetX:       int(ref.params.topOffsetX),
+			TopOffsetY:       int(ref.params.topOffsetY),
+		}
+	}
+	if ref.rule != nil {
+		rule = ref.rule.ViaRule(true)
+	}
+	var pattern string
+	if ref.pattern != nil {
+		pattern = C.GoString(ref.pattern)
+	}
+	return &Via{
+		ID:          int(ref.id),
+		Name:        C.GoString(ref.name),
+		Rect:        ref.rect.Rect(false),
+		IsBlock:     ref.isBlock == 1,
+		IsTech:      ref.isTech == 1,
+		TopLayer:    topLayer,
+		BottomLayer: bottomLayer,
+		CutLayer:    cutLayer,
+		Params:      params,
+		Pattern:     pattern,
+		Boxes:       dbRectArrayToSlice(ref.boxes, int(ref.boxSz), false),
+		Rule:        rule,
+		InComplete:  idOnly,
+	}
+}
+func (ref *C.Site) Site(idOnly bool) *Site {
+	if idOnly {
+		// Temporary holder
+		return &Site{
+			ID:         int(ref.id),
+			InComplete: idOnly,
+		}
+	}
+	return &Site{
+		ID:          int(ref.id),
+		Name:        C.GoString(ref.name),
+		Class:       C.GoString(ref.siteClass),
+		Width:       int(ref.width),
+		Height:      int(ref.height),
+		SymmetryX:   ref.symmetryX == 1,
+		SymmetryY:   ref.symmetryY == 1,
+		SymmetryR90: ref.symmetryR90 == 1,
+		InComplete:  idOnly,
+	}
+}
+func (ref *C.Grid) Grid(idOnly bool) *Grid {
+	if idOnly {
+		// Temporary haolder
+		return &Grid{
+			ID:         int(ref.id),
+			InComplete: idOnly,
+		}
+	}
+	var layer *Layer = nil
+	if ref.layer != nil {
+		layer = ref.layer.Layer(true)
+	}
+	return &Grid{
+		ID:                     int(ref.id),
+		Layer:                  layer,
+		GridX:                  intArrayToSlice(ref.gridX, int(ref.gridXSz)),
+		GridY:                  intArrayToSlice(ref.gridY, int(ref.gridYSz)),
+		GridXPatternOrigins:    intArrayToSlice(ref.gridXPatternOrigins, int(ref.gridXPatternSz)),
+		GridXPatternLineCounts: intArrayToSlice(ref.gridXPatternLineCounts, int(ref.gridXPatternSz)),
+		GridXPatternSteps:      intArrayToSlice(ref.gridXPatternSteps, int(ref.gridXPatternSz)),
+		GridYPatternOrigins:    intArrayToSlice(ref.gridYPatternOrigins, int(ref.gridYPatternSz)),
+		GridYPatternLineCounts: intArrayToSlice(ref.gridYPatternLineCounts, int(ref.gridYPatternSz)),
+		GridYPatternSteps:      intArrayToSlice(ref.gridYPatternSteps, int(ref.gridYPatternSz)),
+		InComplete:             idOnly,
+	}
+}
+
+func (ref *C.Row) Row(idOnly bool) *Row {
+	if idOnly {
+		// Temporary holder
+		return &Row{
+			ID:         int(ref.id),
+			InComplete: idOnly,
+		}
+	}
+	var site *Site = nil
+	if ref.site != nil {
+		site = ref.site.Site(true)
+	}
+	return &Row{
+		ID:          int(ref.id),
+		Name:        C.GoString(ref.name),
+		Site:        site,
+		Direction:   Direction(ref.direction),
+		Orientation: Orientation(ref.orientation),
+		OriginX:     int(ref.originX),
+		OriginY:     int(ref.originY),
+		Spacing:     int(ref.spacing),
+		SiteCount:   int(ref.siteCount),
+		BoundingBox: ref.boundingBox.Rect(false),
+		InComplete:  idOnly,
+	}
+
+}
+
+func (ref *C.Blockage) Blockage(idOnly bool) *Blockage {
+	if idOnly {
+		// Temporary holder
+		return &Blockage{
+			ID:         int(ref.id),
+			InComplete: idOnly,
+		}
+	}
+	var layer *Layer = nil
+	var inst *Instance = nil
+	if ref.layer != nil {
+		layer = ref.layer.Layer(true)
+	}
+	if ref.instance != nil {
+		inst = ref.instance.Instance(true)
+	}
+	return &Blockage{
+		ID:              int(ref.id),
+		Type:            BlockageType(ref.blockageType),
+		Rect:            ref.rect.Rect(false),
+		Layer:           layer,
+		Instance:        inst,
+		MaxDensity:      float64(ref.maxDensity),
+		IsSoft:          ref.isSoft == 1,
+		IsPushedDown:    ref.isPushedDown == 1,
+		IsSlot:          ref.isSlot == 1,
+		IsFill:          ref.isFill == 1,
+		IsExceptPGNets:  ref.isExceptPGNets == 1,
+		MinSpacing:      int(ref.minSpacing),
+		DesignRuleWidth: int(ref.designRuleWidth),
+		InComplete:      idOnly,
+	}
+}
+
+func (ref *C.Region) Region(idOnly bool) *Region {
+	if idOnly {
+		// Temporary holder
+		return &Region{
+			ID:         int(ref.id),
+			InComplete: idOnly,
+		}
+	}
+	return &Region{
+		ID:         int(ref.id),
+		Name:       C.GoString(ref.name),
+		Type:       RegionType(ref.regionType),
+		Boxes:      dbRectArrayToSlice(ref.boxes, int(ref.boxSz), false),
+		Instances:  dbInstanceArrayToSlice(ref.instances, int(ref.instanceSz), true),
+		Groups:     dbGroupArrayToSlice(ref.groups, int(ref.groupSz), true),
+		InComplete: idOnly,
+	}
+}
+
+func (ref *C.Group) Group(idOnly bool) *Group {
+	if idOnly {
+		// Temporary holder
+		return &Group{
+			ID:         int(ref.id),
+			InComplete: idOnly,
+		}
+	}
+	var region *Region = nil
+	if ref.region != nil {
+		region = ref.region.Region(true)
+	}
+	return &Group{
+		ID:         int(ref.id),
+		Name:       C.GoString(ref.name),
+		Region:     region,
+		Instances:  dbInstanceArrayToSlice(ref.instances, int(ref.instanceSz), true),
+		InComplete: idOnly,
+	}
+}
+
+func (ref *C.Fill) Fill(idOnly bool) *Fill {
+	if idOnly {
+		// Temporary holder
+		return &Fill{
+			ID:         int(ref.id),
+			InComplete: idOnly,
+		}
+	}
+	var layer *Layer = nil
+	if ref.layer != nil {
+		layer = ref.layer.Layer(true)
+	}
+	return &Fill{
+		ID:         int(ref.id),
+		Layer:      layer,
+		Mask:       int(ref.mask),
+		NeedsOPC:   ref.needsOPC == 1,
+		Boxes:      dbRectArrayToSlice(ref.boxes, int(ref.boxSz), false),
+		InComplete: idOnly,
+	}
+}
+
+func (ref *C.Master) Master(idOnly bool) *Master {
+	if idOnly {
+		// Temporary holder
+		return &Master{
+			ID:         int(ref.id),
+			InComplete: idOnly,
+		}
+	}
+	var site *Site = nil
+	if ref.site != nil {
+		site = ref.site.Site(true)
+	}
+	var pinList []C.MasterPin
+	sliceHeader := (*reflect.SliceHeader)((unsafe.Pointer(&pinList)))
+	sliceHeader.Cap = int(ref.pinSz)
+	sliceHeader.Len = int(ref.pinSz)
+	sliceHeader.Data = uintptr(unsafe.Pointer(ref.pins))
+	var pins []*MasterPin
+	for _, pin := range pinList {
+		pins = append(pins, &MasterPin{
+			ID:         int(pin.id),
+			Name:       C.GoString(pin.name),
+			Direction:  IoType(pin.direction),
+			SignalType: SignalType(pin.signalType),
+			Geometries: dbGeometryArrayToSlice(pin.geometries, int(pin.geometrySz), false),
+		})
+	}
+	var obstructions *Geometry = nil
+	if ref.obstructions != nil {
+		obstructions = ref.obstructions.Geometry(false)
+	}
+	return &Master{
+		ID:           int(ref.id),
+		Name:         C.GoString(ref.name),
+		Library:      C.GoString(ref.library),
+		Type:         MasterType(ref.masterType),
+		Class:        C.GoString(ref.masterClass),
+		Width:        int(ref.width),
+		Height:       int(ref.height),
+		Origin:       ref.origin.Point(),
+		Site:         site,
+		SymmetryX:    ref.symmetryX == 1,
+		SymmetryY:    ref.symmetryY == 1,
+		SymmetryR90:  ref.symmetryR90 == 1,
+		IsFiller:     ref.isFiller == 1,
+		Pins:         pins,
+		Obstructions: obstructions,
+		InComplete:   idOnly,
+	}
+}
+
+func (ref *C.ViaRule) ViaRule(idOnly bool) *ViaRule {
+	if idOnly {
+		// Temporary holder
+		return &ViaRule{
+			ID:         int(ref.id),
+			InComplete: idOnly,
+		}
+	}
+	var layerList []C.ViaRuleLayer
+	sliceHeader := (*reflect.SliceHeader)((unsafe.Pointer(&layerList)))
+	sliceHeader.Cap = int(ref.layerSz)
+	sliceHeader.Len = int(ref.layerSz)
+	sliceHeader.Data = uintptr(unsafe.Pointer(ref.layers))
+	var layers []*ViaRuleLayer
+	for _, ruleLayer := range layerList {
+		var layer *Layer = nil
+		var rect *Rect = nil
+		if ruleLayer.layer != nil {
+			layer = ruleLayer.layer.Layer(true)
+		}
+		if ruleLayer.rect != nil {
+			rect = ruleLayer.rect.Rect(false)
+		}
+		layers = append(layers, &ViaRuleLayer{
+			Layer:              layer,
+			Direction:          Direction(ruleLayer.direction),
+			HasEnclosure:       ruleLayer.hasEnclosure == 1,
+			EnclosureOverhang1: int(ruleLayer.enclosureOverhang1),
+			EnclosureOverhang2: int(ruleLayer.enclosureOverhang2),
+			HasWidth:           ruleLayer.hasWidth == 1,
+			MinWidth:           int(ruleLayer.minWidth),
+			MaxWidth:           int(ruleLayer.maxWidth),
+			Rect:               rect,
+			HasSpacing:         ruleLayer.hasSpacing == 1,
+			SpacingX:           int(ruleLayer.spacingX),
+			SpacingY:           int(ruleLayer.spacingY),
+			Resistance:         float64(ruleLayer.resistance),
+		})
+	}
+	return &ViaRule{
+		ID:         int(ref.id),
+		Name:       C.GoString(ref.name),
+		IsDefault:  ref.isDefault == 1,
+		Layers:     layers,
+		InComplete: idOnly,
+	}
+}
+
+func doubleArrayToSlice(array *C.double, len int) []C.double {
+	var list []C.double
+	sliceHeader := (*reflect.SliceHeader)((unsafe.Pointer(&list)))
+	sliceHeader.Cap = len
+	sliceHeader.Len = len
+	sliceHeader.Data = uintptr(unsafe.Pointer(array))
+	return list
+}
+
+func dbInstanceArrayToSlice(array **C.Instance, len int, idOnly bool) []*Instance {
+	var list []*C.Instance
+	sliceHeader := (*reflect.SliceHeader)((unsafe.Pointer(&list)))
+	sliceHeader.Cap = len
+	sliceHeader.Len = len
+	sliceHeader.Data = uintptr(unsafe.Pointer(array))
+	var insts []*Instance
+	for _, inst := range list {
+		insts = append(insts, inst.Instance(idOnly))
+	}
+	return insts
+}
+func dbEdgeArrayToSlice(array *C.Edge, len int) []*Edge {
+	var list []C.Edge
+	sliceHeader := (*reflect.SliceHeader)((unsafe.Pointer(&list)))
+	sliceHeader.Cap = len
+	sliceHeader.Len = len
+	sliceHeader.Data = uintptr(unsafe.Pointer(array))
+	var edges []*Edge
+	for _, edge := range list {
+		edges = append(edges, edge.Edge())
+	}
+	return edges
+}
+
+// Special wires share the geometries (and rects) of the net special boxes
+func dbSpecialWireArrayToSlice(array *C.SpecialWire, wireSz int, specialBoxes []*Geometry) []*SpecialWire {
+	var list []C.SpecialWire
+	sliceHeader := (*reflect.SliceHeader)((unsafe.Pointer(&list)))
+	sliceHeader.Cap = wireSz
+	sliceHeader.Len = wireSz
+	sliceHeader.Data = uintptr(unsafe.Pointer(array))
+	geomMap := make(map[int]*Geometry)
+	for _, geom := range specialBoxes {
+		geomMap[geom.ID] = geom
+	}
+	var wires []*SpecialWire
+	for _, wire := range list {
+		var shapeList []C.SpecialShape
+		shapeHeader := (*reflect.SliceHeader)((unsafe.Pointer(&shapeList)))
+		shapeHeader.Cap = int(wire.shapeSz)
+		shapeHeader.Len = int(wire.shapeSz)
+		shapeHeader.Data = uintptr(unsafe.Pointer(wire.shapes))
+		var geom *Geometry = nil
+		if wire.geometry != nil {
+			geom = geomMap[int(wire.geometry.id)]
+		}
+		var shapes []*SpecialShape
+		for i, shape := range shapeList {
+			var rect *Rect = nil
+			if geom != nil && i < len(geom.Boxes) {
+				rect = geom.Boxes[i]
+			} else if shape.rect != nil {
+				rect = shape.rect.Rect(false)
+			}
+			shapes = append(shapes, &SpecialShape{
+				Rect:      rect,
+				ShapeType: WireShapeType(shape.shapeType),
+				Width:     int(shape.width),
+				Mask:      int(shape.mask),
+			})
+		}
+		wires = append(wires, &SpecialWire{
+			ID:        int(wire.id),
+			WireType:  WireType(wire.wireType),
+			ShieldNet: C.GoString(wire.shieldNet),
+			Geometry:  geom,
+			Shapes:    shapes,
+		})
+	}
+	return wires
+}
+func dbPinArrayToSlice(array **C.Pin, len int, idOnly bool) []*Pin {
+	var list []*C.Pin
+	sliceHeader := (*reflect.SliceHeader)((unsafe.Pointer(&list)))
+	sliceHeader.Cap = len
+	sliceHeader.Len = len
+	sliceHeader.Data = uintptr(unsafe.Pointer(array))
+	var pins []*Pin
+	for _, pin := range list {
+		pins = append(pins, pin.Pin(idOnly))
+	}
+	return pins
+}
+func dbRectArrayToSlice(array **C.Rect, len int, idOnly bool) []*Rect {
+	var list []*C.Rect
+	sliceHeader := (*reflect.SliceHeader)((unsafe.Pointer(&list)))
+	sliceHeader.Cap = len
+	sliceHeader.Len = len
+	sliceHeader.Data = uintptr(unsafe.Pointer(array))
+	var rects []*Rect
+	for _, rect := range list {
+		rects = append(rects, rect.Rect(idOnly))
+	}
+	return rects
+}
+func dbGeometryArrayToSlice(array **C.Geometry, len int, idOnly bool) []*Geometry {
+	var list []*C.Geometry
+	sliceHeader := (*reflect.SliceHeader)((unsafe.Pointer(&list)))
+	sliceHeader.Cap = len
+	sliceHeader.Len = len
+	sliceHeader.Data = uintptr(unsafe.Pointer(array))
+	var geoms []*Geometry
+	for _, geom := range list {
+		geoms = append(geoms, geom.Geometry(idOnly))
+	}
+	return geoms
+}
+
+func dbNetArrayToSlice(array **C.Net, len int, idOnly bool) []*Net {
+	var list []*C.Net
+	sliceHeader := (*reflect.SliceHeader)((unsafe.Pointer(&list)))
+	sliceHeader.Cap = len
+	sliceHeader.Len = len
+	sliceHeader.Data = uintptr(unsafe.Pointer(array))
+	var nets []*Net
+	for _, net := range list {
+		nets = append(nets, net.Net(idOnly))
+	}
+	return nets
+}
+
+func dbLayerArrayToSlice(array **C.Layer, len int, idOnly bool) []*Layer {
+	var list []*C.Layer
+	sliceHeader := (*reflect.SliceHeader)((unsafe.Pointer(&list)))
+	sliceHeader.Cap = len
+	sliceHeader.Len = len
+	sliceHeader.Data = uintptr(unsafe.Pointer(array))
+	var layers []*Layer
+	for _, layer := range list {
+		layers = append(layers, layer.Layer(idOnly))
+	}
+	return layers
+}
+
+func dbViaArrayToSlice(array **C.Via, len int, idOnly bool) []*Via {
+	var list []*C.Via
+	sliceHeader := (*reflect.SliceHeader)((unsafe.Pointer(&list)))
+	sliceHeader.Cap = len
+	sliceHeader.Len = len
+	sliceHeader.Data = uintptr(unsafe.Pointer(array))
+	var vias []*Via
+	for _, via := range list {
+		vias = append(vias, via.Via(idOnly))
+	}
+	return vias
+}
+func dbRowArrayToSlice(array **C.Row, len int, idOnly bool) []*Row {
+	var list []*C.Row
+	sliceHeader := (*reflect.SliceHeader)((unsafe.Pointer(&list)))
+	sliceHeader.Cap = len
+	sliceHeader.Len = len
+	sliceHeader.Data = uintptr(unsafe.Pointer(array))
+	var Rows []*Row
+	for _, Row := range list {
+		Rows = append(Rows, Row.Row(idOnly))
+	}
+	return Rows
+}
+func dbSiteArrayToSlice(array **C.Site, len int, idOnly bool) []*Site {
+	var list []*C.Site
+	sliceHeader := (*reflect.SliceHeader)((unsafe.Pointer(&list)))
+	sliceHeader.Cap = len
+	sliceHeader.Len = len
+	sliceHeader.Data = uintptr(unsafe.Pointer(array))
+	var Sites []*Site
+	for _, Site := range list {
+		Sites = append(Sites, Site.Site(idOnly))
+	}
+	return Sites
+}
+func dbTrackArrayToSlice(array **C.Grid, len int, idOnly bool) []*Grid {
+	var list []*C.Grid
+	sliceHeader := (*reflect.SliceHeader)((unsafe.Pointer(&list)))
+	sliceHeader.Cap = len
+	sliceHeader.Len = len
+	sliceHeader.Data = uintptr(unsafe.Pointer(array))
+	var Tracks []*Grid
+	for _, Grid := range list {
+		Tracks = append(Tracks, Grid.Grid(idOnly))
+	}
+	return Tracks
+}
+func dbBlockageArrayToSlice(array **C.Blockage, len int, idOnly bool) []*Blockage {
+	var list []*C.Blockage
+	sliceHeader := (*reflect.SliceHeader)((unsafe.Pointer(&list)))
+	sliceHeader.Cap = len
+	sliceHeader.Len = len
+	sliceHeader.Data = uintptr(unsafe.Pointer(array))
+	var blockages []*Blockage
+	for _, blockage := range list {
+		blockages = append(blockages, blockage.Blockage(idOnly))
+	}
+	return blockages
+}
+func dbRegionArrayToSlice(array **C.Region, len int, idOnly bool) []*Region {
+	var list []*C.Region
+	sliceHeader := (*reflect.SliceHeader)((unsafe.Pointer(&list)))
+	sliceHeader.Cap = len
+	sliceHeader.Len = len
+	sliceHeader.Data = uintptr(unsafe.Pointer(array))
+	var regions []*Region
+	for _, region := range list {
+		regions = append(regions, region.Region(idOnly))
+	}
+	return regions
+}
+func dbGroupArrayToSlice(array **C.Group, len int, idOnly bool) []*Group {
+	var list []*C.Group
+	sliceHeader := (*reflect.SliceHeader)((unsafe.Pointer(&list)))
+	sliceHeader.Cap = len
+	sliceHeader.Len = len
+	sliceHeader.Data = uintptr(unsafe.Pointer(array))
+	var groups []*Group
+	for _, group := range list {
+		groups = append(groups, group.Group(idOnly))
+	}
+	return groups
+}
+func dbFillArrayToSlice(array **C.Fill, len int, idOnly bool) []*Fill {
+	var list []*C.Fill
+	sliceHeader := (*reflect.SliceHeader)((unsafe.Pointer(&list)))
+	sliceHeader.Cap = len
+	sliceHeader.Len = len
+	sliceHeader.Data = uintptr(unsafe.Pointer(array))
+	var fills []*Fill
+	for _, fill := range list {
+		fills = append(fills, fill.Fill(idOnly))
+	}
+	return fills
+}
+func dbMasterArrayToSlice(array **C.Master, len int, idOnly bool) []*Master {
+	var list []*C.Master
+	sliceHeader := (*reflect.SliceHeader)((unsafe.Pointer(&list)))
+	sliceHeader.Cap = len
+	sliceHeader.Len = len
+	sliceHeader.Data = uintptr(unsafe.Pointer(array))
+	var masters []*Master
+	for _, master := range list {
+		masters = append(masters, master.Master(idOnly))
+	}
+	return masters
+}
+func dbViaRuleArrayToSlice(array **C.ViaRule, len int, idOnly bool) []*ViaRule {
+	var list []*C.ViaRule
+	sliceHeader := (*reflect.SliceHeader)((unsafe.Pointer(&list)))
+	sliceHeader.Cap = len
+	sliceHeader.Len = len
+	sliceHeader.Data = uintptr(unsafe.Pointer(array))
+	var rules []*ViaRule
+	for _, rule := range list {
+		rules = append(rules, rule.ViaRule(idOnly))
+	}
+	return rules
+}
+func intArrayToSlice(array *C.int, len int) []int {
+	var list []C.int
+	sliceHeader := (*reflect.SliceHeader)((unsafe.Pointer(&list)))
+	sliceHeader.Cap = len
+	sliceHeader.Len = len
+	sliceHeader.Data = uintptr(unsafe.Pointer(array))
+	var ints []int
+	for _, num := range list {
+		ints = append(ints, int(num))
+	}
+	return ints
+}
@@ -0,0 +1,145 @@
+//go:build cgo
+// +build cgo
+
+package goopendb
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestParseLEFDEF(t *testing.T) {
+	defPath := "../example/Nangate45/gcd.def"
+	lefPath := "../example/Nangate45/NangateOpenCellLibrary.mod.lef"
+
+	var db OpenDB
+	db, err := NewDatabase()
+	if err != nil {
+		t.Fatal(err)
+		return
+	}
+	defer db.FreeDatabase()
+	err = db.ParseLEF(lefPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.ParseDEF(defPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	design, err := db.GetDesign()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if design.Name != "gcd" {
+		t.Fatal("Unexpected design name", design.Name)
+	}
+
+	expected := map[string]int{
+		"Instances":      182,
+		"Nets":           268,
+		"InstancePins":   1164,
+		"BlockPins":      56,
+		"RoutingVias":    8,
+		"ViaDefinitions": 7,
+		"Layers":         22,
+		"Rows":           15,
+		"Tracks":         10,
+		"Sites":          1,
+		"Blockages":      0,
+		"Regions":        0,
+		"Groups":         0,
+		"Fills":          0,
+		"Masters":        134,
+		"ViaRules":       19,
+	}
+	actual := map[string]int{
+		"Instances":      len(design.Instances),
+		"Nets":           len(design.Nets),
+		"InstancePins":   len(design.InstancePins),
+		"BlockPins":      len(design.BlockPins),
+		"RoutingVias":    len(design.RoutingVias),
+		"ViaDefinitions": len(design.ViaDefinitions),
+		"Layers":         len(design.Layers),
+		"Rows":           len(design.Rows),
+		"Tracks":         len(design.Tracks),
+		"Sites":          len(design.Sites),
+		"Blockages":      len(design.Blockages),
+		"Regions":        len(design.Regions),
+		"Groups":         len(design.Groups),
+		"Fills":          len(design.Fills),
+		"Masters":        len(design.Masters),
+		"ViaRules":       len(design.ViaRules),
+	}
+
+	if design.Name != "gcd" {
+		t.Fatal("Unexpected design name", design.Name)
+	}
+	for k, v := range expected {
+		if actual[k] != v {
+			t.Errorf("Expected length of %v to be %v, found %v", k, v, actual[k])
+		}
+	}
+
+	vias := map[int]*Via{}
+	for _, via := range append(design.RoutingVias, design.ViaDefinitions...) {
+		if other, ok := vias[via.ID]; ok {
+			t.Errorf("Vias %v and %v have the same ID %v", other.Name, via.Name, via.ID)
+		}
+		vias[via.ID] = via
+	}
+}
+
+func TestParseLEFTechnologyHeight(t *testing.T) {
+	tech := `VERSION 5.8 ;
+UNITS
+  DATABASE MICRONS 2000 ;
+END UNITS
+MANUFACTURINGGRID 0.005 ;
+LAYER metal1
+  TYPE ROUTING ;
+  DIRECTION HORIZONTAL ;
+  PITCH 0.14 ;
+  WIDTH 0.07 ;
+  HEIGHT 0.37 ;
+END metal1
+LAYER via1
+  TYPE CUT ;
+END via1
+END LIBRARY
+`
+	file, err := ioutil.TempFile("", "tech*.lef")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(file.Name())
+	_, err = file.WriteString(tech)
+	file.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := NewDatabase()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.FreeDatabase()
+	if err = db.ParseLEFTechnology(file.Name()); err != nil {
+		t.Fatal(err)
+	}
+	design, err := db.GetDesign()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, layer := range design.Layers {
+		expected := -1
+		if layer.Name == "metal1" {
+			expected = 740
+		}
+		if layer.Height != expected {
+			t.Errorf("Expected height of %v to be %v, found %v", layer.Name, expected, layer.Height)
+		}
+	}
+}
@@ -0,0 +1,631 @@
+package goopendb
+
+// Binary design encoding
+//
+// The binary design is the compact design in the protocol buffers wire
+// format, its published schema is design.proto (see DesignSchema). Every
+// design type is a message with its exported fields numbered by their proto
+// struct tag, numbers must never change or be reused. Integers and enums are
+// sint64, object references hold the ID only like in the JSON, rectangle
+// lists are RectArray messages of columns and nested integer lists are
+// IntArray messages. Coordinates are always in database units
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Design output formats
+const (
+	FormatJSON     = "json"
+	FormatProtobuf = "protobuf"
+)
+
+// Protocol buffers wire types
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+// protoKind is the schema type of a field
+type protoKind int
+
+const (
+	protoInt       protoKind = iota // sint64
+	protoFloat                      // double
+	protoBool                       // bool
+	protoString                     // string
+	protoInts                       // repeated sint64
+	protoIntArrays                  // repeated IntArray
+	protoStrings                    // repeated string
+	protoMessage                    // message
+	protoMessages                   // repeated message
+	protoRects                      // RectArray
+)
+
+// protoField is a message field mapped to a struct field
+type protoField struct {
+	name    string
+	number  int
+	index   int
+	kind    protoKind
+	message reflect.Type // Struct type of message fields
+	enum    string       // Go type of enum fields
+}
+
+var designType = reflect.TypeOf(Design{})
+
+var (
+	protoOnce sync.Once
+	// protoTypes are the message types in schema order, protoFields their
+	// fields by number
+	protoTypes  []reflect.Type
+	protoFields map[reflect.Type][]protoField
+	protoErr    error
+)
+
+// designSchema builds the messages of the design types on first use
+func designSchema() error {
+	protoOnce.Do(func() {
+		protoTypes, protoFields, protoErr = protoSchema(designType)
+	})
+	return protoErr
+}
+
+// protoSchema maps the struct types reachable from root to messages, every
+// exported field needs a unique proto tag number
+func protoSchema(root reflect.Type) ([]reflect.Type, map[reflect.Type][]protoField, error) {
+	var types []reflect.Type
+	fields := make(map[reflect.Type][]protoField)
+	queue := []reflect.Type{root}
+	fields[root] = nil
+	for len(queue) > 0 {
+		t := queue[0]
+		queue = queue[1:]
+		types = append(types, t)
+		var messageFields []protoField
+		numbers := make(map[int]string)
+		for i := 0; i < t.NumField(); i++ {
+			sf := t.Field(i)
+			if sf.PkgPath != "" {
+				continue
+			}
+			number, err := strconv.Atoi(sf.Tag.Get("proto"))
+			if err != nil || number <= 0 {
+				return nil, nil, fmt.Errorf("Missing proto field number of %v.%v", t.Name(), sf.Name)
+			}
+			if other, ok := numbers[number]; ok {
+				return nil, nil, fmt.Errorf("Proto field number %v of %v.%v is used by %v", number, t.Name(), sf.Name, other)
+			}
+			numbers[number] = sf.Name
+			f := protoField{name: sf.Name, number: number, index: i}
+			ft := sf.Type
+			switch {
+			case ft.Kind() == reflect.Int:
+				f.kind = protoInt
+				if ft.Name() != "int" {
+					f.enum = ft.Name()
+				}
+			case ft.Kind() == reflect.Float64:
+				f.kind = protoFloat
+			case ft.Kind() == reflect.Bool:
+				f.kind = protoBool
+			case ft.Kind() == reflect.String:
+				f.kind = protoString
+			case ft == reflect.TypeOf([]int{}):
+				f.kind = protoInts
+			case ft == reflect.TypeOf([][]int{}):
+				f.kind = protoIntArrays
+			case ft == reflect.TypeOf([]string{}):
+				f.kind = protoStrings
+			case ft == reflect.TypeOf([]*Rect{}):
+				f.kind = protoRects
+			case ft.Kind() == reflect.Ptr && ft.Elem().Kind() == reflect.Struct:
+				f.kind = protoMessage
+				f.message = ft.Elem()
+			case ft.Kind() == reflect.Slice && ft.Elem().Kind() == reflect.Ptr && ft.Elem().Elem().Kind() == reflect.Struct:
+				f.kind = protoMessages
+				f.message = ft.Elem().Elem()
+			default:
+				return nil, nil, fmt.Errorf("Unsupported binary design field %v.%v", t.Name(), sf.Name)
+			}
+			if _, ok := fields[f.message]; f.message != nil && !ok {
+				fields[f.message] = nil
+				queue = append(queue, f.message)
+			}
+			messageFields = append(messageFields, f)
+		}
+		sort.Slice(messageFields, func(i, j int) bool { return messageFields[i].number < messageFields[j].number })
+		fields[t] = messageFields
+	}
+	return types, fields, nil
+}
+
+// protoTypeNames are the schema types of the field kinds
+var protoTypeNames = map[protoKind]string{
+	protoInt:       "sint64",
+	protoFloat:     "double",
+	protoBool:      "bool",
+	protoString:    "string",
+	protoInts:      "repeated sint64",
+	protoIntArrays: "repeated IntArray",
+	protoStrings:   "repeated string",
+	protoRects:     "RectArray",
+}
+
+// DesignSchema returns the protocol buffers schema of the binary design
+func DesignSchema() (string, error) {
+	if err := designSchema(); err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	b.WriteString("// Binary compact design of EDAViewer, generated by goopendb.DesignSchema\n")
+	b.WriteString("syntax = \"proto3\";\n\npackage edaviewer;\n")
+	for _, t := range protoTypes {
+		fmt.Fprintf(&b, "\nmessage %v {\n", t.Name())
+		for _, f := range protoFields[t] {
+			typ := protoTypeNames[f.kind]
+			switch f.kind {
+			case protoMessage:
+				typ = f.message.Name()
+			case protoMessages:
+				typ = "repeated " + f.message.Name()
+			}
+			fmt.Fprintf(&b, "  %v %v = %v;", typ, f.name, f.number)
+			if f.enum != "" {
+				fmt.Fprintf(&b, " // %v", f.enum)
+			}
+			b.WriteString("\n")
+		}
+		b.WriteString("}\n")
+	}
+	b.WriteString(`
+message IntArray {
+  repeated sint64 Values = 1;
+}
+
+// Columns of a rectangle list. Layer and Via are the IDs of the rectangle
+// layers and vias, -1 without a layer or via. A missing column is all zeros,
+// or all -1 for Layer and Via
+message RectArray {
+  repeated sint64 ID = 1;
+  repeated sint64 XMin = 2;
+  repeated sint64 YMin = 3;
+  repeated sint64 XMax = 4;
+  repeated sint64 YMax = 5;
+  repeated sint64 ShapeType = 6;
+  repeated sint64 Layer = 7;
+  repeated sint64 Via = 8;
+  repeated bool InComplete = 9;
+}
+`)
+	return b.String(), nil
+}
+
+func appendUvarint(buf []byte, v uint64) []byte {
+	var scratch [binary.MaxVarintLen64]byte
+	return append(buf, scratch[:binary.PutUvarint(scratch[:], v)]...)
+}
+
+func appendTag(buf []byte, number, wire int) []byte {
+	return appendUvarint(buf, uint64(number<<3|wire))
+}
+
+// appendSint appends a zigzag encoded sint64
+func appendSint(buf []byte, v int64) []byte {
+	return appendUvarint(buf, uint64(v<<1)^uint64(v>>63))
+}
+
+func appendField(buf []byte, number int, data []byte) []byte {
+	buf = appendTag(buf, number, wireBytes)
+	buf = appendUvarint(buf, uint64(len(data)))
+	return append(buf, data...)
+}
+
+// appendPacked appends the integers as a packed field, empty lists are skipped
+func appendPacked(buf []byte, number int, values []int) []byte {
+	if len(values) == 0 {
+		return buf
+	}
+	var packed []byte
+	for _, v := range values {
+		packed = appendSint(packed, int64(v))
+	}
+	return appendField(buf, number, packed)
+}
+
+// appendRects appends the columns of a rectangle list
+func appendRects(buf []byte, rects []*Rect) []byte {
+	columns := make([][]int, 8)
+	var incomplete []byte
+	hasIncomplete, hasLayer, hasVia := false, false, false
+	for _, r := range rects {
+		if r == nil {
+			r = &Rect{}
+		}
+		layer, via := -1, -1
+		if r.Layer != nil {
+			layer, hasLayer = r.Layer.ID, true
+		}
+		if r.Via != nil {
+			via, hasVia = r.Via.ID, true
+		}
+		for i, v := range []int{r.ID, r.XMin, r.YMin, r.XMax, r.YMax, r.ShapeType, layer, via} {
+			columns[i] = append(columns[i], v)
+		}
+		if r.InComplete {
+			incomplete, hasIncomplete = append(incomplete, 1), true
+		} else {
+			incomplete = append(incomplete, 0)
+		}
+	}
+	for i, column := range columns {
+		skip := (i == 6 && !hasLayer) || (i == 7 && !hasVia)
+		if i < 6 {
+			skip = true
+			for _, v := range column {
+				if v != 0 {
+					skip = false
+					break
+				}
+			}
+		}
+		if !skip {
+			buf = appendPacked(buf, i+1, column)
+		}
+	}
+	if hasIncomplete {
+		buf = appendField(buf, 9, incomplete)
+	}
+	return buf
+}
+
+// appendMessage appends the non-zero fields of a struct
+func appendMessage(buf []byte, v reflect.Value) []byte {
+	return appendFields(buf, v, protoFields[v.Type()])
+}
+
+// appendFields appends the non-zero fields of a struct out of fields
+func appendFields(buf []byte, v reflect.Value, fields []protoField) []byte {
+	for _, f := range fields {
+		fv := v.Field(f.index)
+		switch f.kind {
+		case protoInt:
+			if fv.Int() != 0 {
+				buf = appendSint(appendTag(buf, f.number, wireVarint), fv.Int())
+			}
+		case protoFloat:
+			if fv.Float() != 0 {
+				var scratch [8]byte
+				binary.LittleEndian.PutUint64(scratch[:], math.Float64bits(fv.Float()))
+				buf = append(appendTag(buf, f.number, wireFixed64), scratch[:]...)
+			}
+		case protoBool:
+			if fv.Bool() {
+				buf = append(appendTag(buf, f.number, wireVarint), 1)
+			}
+		case protoString:
+			if fv.Len() > 0 {
+				buf = appendField(buf, f.number, []byte(fv.String()))
+			}
+		case protoInts:
+			buf = appendPacked(buf, f.number, fv.Interface().([]int))
+		case protoIntArrays:
+			for _, values := range fv.Interface().([][]int) {
+				buf = appendField(buf, f.number, appendPacked(nil, 1, values))
+			}
+		case protoStrings:
+			for _, s := range fv.Interface().([]string) {
+				buf = appendField(buf, f.number, []byte(s))
+			}
+		case protoRects:
+			if fv.Len() > 0 {
+				buf = appendField(buf, f.number, appendRects(nil, fv.Interface().([]*Rect)))
+			}
+		case protoMessage:
+			if !fv.IsNil() {
+				buf = appendField(buf, f.number, appendMessage(nil, fv.Elem()))
+			}
+		case protoMessages:
+			for i := 0; i < fv.Len(); i++ {
+				var data []byte
+				if !fv.Index(i).IsNil() {
+					data = appendMessage(nil, fv.Index(i).Elem())
+				}
+				buf = appendField(buf, f.number, data)
+			}
+		}
+	}
+	return buf
+}
+
+// EncodeDesignProto writes the binary compact design to w section by
+// section like EncodeDesign, nil options excludes the optional sections
+func EncodeDesignProto(w io.Writer, design *Design, options *JSONOptions) error {
+	if options == nil {
+		options = &JSONOptions{}
+	}
+	if err := options.Validate(design); err != nil {
+		return err
+	}
+	if options.Microns {
+		return fmt.Errorf("Micron units are not supported by the binary design format")
+	}
+	if err := designSchema(); err != nil {
+		return err
+	}
+	bw := bufio.NewWriter(w)
+	header := design.compactHeader()
+	header.Units = UnitsDBU
+	headerValue := reflect.ValueOf(header).Elem()
+	sections := design.sections(options)
+	for _, f := range protoFields[designType] {
+		section, isSection := sections[f.name]
+		if !isSection {
+			if _, err := bw.Write(appendFields(nil, headerValue, []protoField{f})); err != nil {
+				return err
+			}
+			continue
+		}
+		for i := 0; i < section.count; i++ {
+			element := reflect.ValueOf(section.compact(i))
+			if element.Kind() == reflect.Ptr {
+				element = element.Elem()
+			}
+			if _, err := bw.Write(appendField(nil, f.number, appendMessage(nil, element))); err != nil {
+				return err
+			}
+		}
+	}
+	return bw.Flush()
+}
+
+// protoReader reads the fields of a message
+type protoReader struct {
+	data []byte
+	err  error
+}
+
+func (r *protoReader) uvarint() uint64 {
+	v, n := binary.Uvarint(r.data)
+	if n <= 0 {
+		r.err = fmt.Errorf("Truncated binary design")
+		r.data = nil
+		return 0
+	}
+	r.data = r.data[n:]
+	return v
+}
+
+func (r *protoReader) sint() int {
+	v := r.uvarint()
+	return int(int64(v>>1) ^ -int64(v&1))
+}
+
+func (r *protoReader) bytes(n uint64) []byte {
+	if uint64(len(r.data)) < n {
+		r.err = fmt.Errorf("Truncated binary design")
+		r.data = nil
+		return nil
+	}
+	b := r.data[:n]
+	r.data = r.data[n:]
+	return b
+}
+
+// field reads the next field, the value of varint fields is returned in v
+// and of length delimited and fixed size fields in data
+func (r *protoReader) field() (number, wire int, v uint64, data []byte) {
+	key := r.uvarint()
+	number, wire = int(key>>3), int(key&7)
+	switch wire {
+	case wireVarint:
+		v = r.uvarint()
+	case wireFixed64:
+		data = r.bytes(8)
+	case wireBytes:
+		data = r.bytes(r.uvarint())
+	case wireFixed32:
+		data = r.bytes(4)
+	default:
+		r.err = fmt.Errorf("Invalid binary design wire type %v", wire)
+	}
+	return
+}
+
+// packedInts reads packed or single sint64 values
+func packedInts(wire int, v uint64, data []byte) ([]int, error) {
+	if wire == wireVarint {
+		return []int{int(int64(v>>1) ^ -int64(v&1))}, nil
+	}
+	r := &protoReader{data: data}
+	var values []int
+	for len(r.data) > 0 && r.err == nil {
+		values = append(values, r.sint())
+	}
+	return values, r.err
+}
+
+// decodeRects reads the columns of a rectangle list, layers and vias are references
+func decodeRects(data []byte) ([]*Rect, error) {
+	columns := make([][]int, 8)
+	var incomplete []int
+	r := &protoReader{data: data}
+	for len(r.data) > 0 && r.err == nil {
+		number, wire, v, fieldData := r.field()
+		if r.err != nil {
+			break
+		}
+		if number < 1 || number > 9 {
+			continue
+		}
+		values, err := packedInts(wire, v, fieldData)
+		if number == 9 && wire == wireBytes {
+			values = nil
+			for _, b := range fieldData {
+				values = append(values, int(b))
+			}
+			err = nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if number == 9 {
+			incomplete = append(incomplete, values...)
+		} else {
+			columns[number-1] = append(columns[number-1], values...)
+		}
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+	count := len(incomplete)
+	for _, column := range columns {
+		if len(column) > count {
+			count = len(column)
+		}
+	}
+	value := func(column []int, i, missing int) int {
+		if len(column) == 0 {
+			return missing
+		}
+		if i >= len(column) {
+			return 0
+		}
+		return column[i]
+	}
+	rects := make([]*Rect, count)
+	for i := range rects {
+		rect := &Rect{
+			ID:         value(columns[0], i, 0),
+			XMin:       value(columns[1], i, 0),
+			YMin:       value(columns[2], i, 0),
+			XMax:       value(columns[3], i, 0),
+			YMax:       value(columns[4], i, 0),
+			ShapeType:  value(columns[5], i, 0),
+			InComplete: value(incomplete, i, 0) != 0,
+		}
+		if layer := value(columns[6], i, -1); layer >= 0 {
+			rect.Layer = &Layer{ID: layer, InComplete: true}
+		}
+		if via := value(columns[7], i, -1); via >= 0 {
+			rect.Via = &Via{ID: via, InComplete: true}
+		}
+		rects[i] = rect
+	}
+	return rects, nil
+}
+
+// decodeMessage reads the fields of a message into a struct, unknown fields are skipped
+func decodeMessage(data []byte, v reflect.Value) error {
+	fields := make(map[int]protoField)
+	for _, f := range protoFields[v.Type()] {
+		fields[f.number] = f
+	}
+	r := &protoReader{data: data}
+	for len(r.data) > 0 {
+		number, wire, value, fieldData := r.field()
+		if r.err != nil {
+			return r.err
+		}
+		f, ok := fields[number]
+		if !ok {
+			continue
+		}
+		fv := v.Field(f.index)
+		expected := wireBytes
+		switch f.kind {
+		case protoInt, protoBool:
+			expected = wireVarint
+		case protoFloat:
+			expected = wireFixed64
+		case protoInts:
+			expected = wire
+		}
+		if wire != expected {
+			return fmt.Errorf("Invalid binary design field %v", f.name)
+		}
+		switch f.kind {
+		case protoInt:
+			fv.SetInt(int64(value>>1) ^ -int64(value&1))
+		case protoFloat:
+			fv.SetFloat(math.Float64frombits(binary.LittleEndian.Uint64(fieldData)))
+		case protoBool:
+			fv.SetBool(value != 0)
+		case protoString:
+			fv.SetString(string(fieldData))
+		case protoInts:
+			values, err := packedInts(wire, value, fieldData)
+			if err != nil {
+				return err
+			}
+			fv.Set(reflect.AppendSlice(fv, reflect.ValueOf(values)))
+		case protoIntArrays:
+			var values []int
+			inner := &protoReader{data: fieldData}
+			for len(inner.data) > 0 && inner.err == nil {
+				innerNumber, innerWire, innerValue, innerData := inner.field()
+				if inner.err == nil && innerNumber == 1 {
+					innerValues, err := packedInts(innerWire, innerValue, innerData)
+					if err != nil {
+						return err
+					}
+					values = append(values, innerValues...)
+				}
+			}
+			if inner.err != nil {
+				return inner.err
+			}
+			fv.Set(reflect.Append(fv, reflect.ValueOf(values)))
+		case protoStrings:
+			fv.Set(reflect.Append(fv, reflect.ValueOf(string(fieldData))))
+		case protoRects:
+			rects, err := decodeRects(fieldData)
+			if err != nil {
+				return err
+			}
+			fv.Set(reflect.AppendSlice(fv, reflect.ValueOf(rects)))
+		case protoMessage, protoMessages:
+			message := reflect.New(f.message)
+			if err := decodeMessage(fieldData, message.Elem()); err != nil {
+				return err
+			}
+			if f.kind == protoMessage {
+				fv.Set(message)
+			} else {
+				fv.Set(reflect.Append(fv, message))
+			}
+		}
+	}
+	return nil
+}
+
+// DecodeDesignProto parses a binary compact design
+func DecodeDesignProto(data []byte) (*Design, error) {
+	if err := designSchema(); err != nil {
+		return nil, err
+	}
+	design := &Design{}
+	if err := decodeMessage(data, reflect.ValueOf(design).Elem()); err != nil {
+		return nil, err
+	}
+	return design, nil
+}
+
+// WriteDesign writes the compact design to w in the format of the options
+func WriteDesign(w io.Writer, design *Design, options *JSONOptions) error {
+	if options != nil && options.Format == FormatProtobuf {
+		return EncodeDesignProto(w, design, options)
+	}
+	return EncodeDesign(w, design, options)
+}
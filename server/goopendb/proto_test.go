@@ -0,0 +1,104 @@
+package goopendb
+
+import (
+	"bytes"
+	"io/ioutil"
+	"reflect"
+	"testing"
+)
+
+func TestDesignProto(t *testing.T) {
+	design := spatialDesign()
+	design.Name = "spatial"
+	design.DBUPerMicron = 2000
+	design.Utilization = 0.42
+	design.Geometries = []*Geometry{design.InstancePins[0].Geometries[0], design.Nets[1].SpecialBoxes[0]}
+	design.Layers[0].SpacingTable = &SpacingTable{Widths: []int{0, 180}, Lengths: []int{0}, Spacings: [][]int{{140}, {160}}}
+	design.Layers[0].MaxWidth = -1
+	design.Instances[0].Orientation = OrientationMY
+	design.Regions = []*Region{{ID: 1, Name: "fence", Type: RegionTypeFENCE, Boxes: []*Rect{{XMax: 3000, YMax: 2800}}, Instances: design.Instances[:1]}}
+	design.Fills = []*Fill{{ID: 1, Layer: design.Layers[0], Boxes: []*Rect{{XMax: 100, YMax: 100}}}}
+	design.Markers = []*Marker{{Type: MarkerShort, XMin: -10, XMax: 100, YMax: 100, Nets: []string{"VDD", ""}}}
+
+	var binary bytes.Buffer
+	if err := EncodeDesignProto(&binary, design, &JSONOptions{IncludeFills: true, Format: FormatProtobuf}); err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := DecodeDesignProto(binary.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Units != UnitsDBU || decoded.Utilization != 0.42 || decoded.Instances[0].Orientation != OrientationMY {
+		t.Errorf("Unexpected design header or instance")
+	}
+	boxes := decoded.Geometries[1].Boxes
+	if len(boxes) != 1000 || boxes[999].XMin != 23800 || boxes[999].Layer == nil || boxes[999].Layer.ID != 1 || boxes[999].Via != nil {
+		t.Errorf("Unexpected special wire boxes")
+	}
+	if via := decoded.Nets[0].Edges[1].Via; via == nil || via.ID != 1 || !via.InComplete {
+		t.Errorf("Expected a via reference, found %+v", via)
+	}
+
+	// The decoded design has the JSON of the compact design
+	var expected, found bytes.Buffer
+	if err := EncodeDesign(&expected, design, &JSONOptions{IncludeFills: true}); err != nil {
+		t.Fatal(err)
+	}
+	if err := EncodeDesign(&found, decoded, &JSONOptions{IncludeFills: true}); err != nil {
+		t.Fatal(err)
+	}
+	if found.String() != expected.String() {
+		t.Errorf("Expected the compact design\n%s\nfound\n%s", expected.String(), found.String())
+	}
+	if binary.Len()*4 > expected.Len() {
+		t.Errorf("Expected the binary design (%v bytes) to be smaller than the JSON (%v bytes)", binary.Len(), expected.Len())
+	}
+
+	if _, err := DecodeDesignProto(binary.Bytes()[:binary.Len()-1]); err == nil {
+		t.Errorf("Expected an error for a truncated design")
+	}
+	if err := WriteDesign(&binary, design, &JSONOptions{Format: FormatProtobuf, Microns: true}); err == nil {
+		t.Errorf("Expected an error for micron units")
+	}
+	if err := WriteDesign(&binary, design, &JSONOptions{Format: "xml"}); err == nil {
+		t.Errorf("Expected an error for an unsupported format")
+	}
+}
+
+func TestDesignSchema(t *testing.T) {
+	published, err := ioutil.ReadFile("design.proto")
+	if err != nil {
+		t.Fatal(err)
+	}
+	schema, err := DesignSchema()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(published) != schema {
+		t.Errorf("design.proto is out of date, regenerate it with DesignSchema")
+	}
+}
+
+func TestProtoSchemaErrors(t *testing.T) {
+	type missing struct {
+		ID   int `proto:"1"`
+		Name string
+	}
+	type duplicate struct {
+		ID   int    `proto:"1"`
+		Name string `proto:"1"`
+	}
+	type unsupported struct {
+		ID     int            `proto:"1"`
+		Values map[string]int `proto:"2"`
+	}
+	type nested struct {
+		ID    int      `proto:"1"`
+		Child *missing `proto:"2"`
+	}
+	for _, v := range []interface{}{missing{}, duplicate{}, unsupported{}, nested{}} {
+		if _, _, err := protoSchema(reflect.TypeOf(v)); err == nil {
+			t.Errorf("Expected an invalid schema of %T", v)
+		}
+	}
+}
@@ -0,0 +1,214 @@
+package goopendb
+
+// Design regions holding the objects visible in a viewport
+
+// RegionOptions selects the objects of a design region
+type RegionOptions struct {
+	Layers []string // Layer names, empty for all layers
+	// LOD is the level of detail as the smallest visible size in database
+	// units, usually the size of a screen pixel. Pins, wires, vias and
+	// special wires narrower than LOD and instances smaller than LOD are
+	// dropped, zero keeps all objects
+	LOD int
+}
+
+// visible applies the level of detail rules to a shape
+func (options *RegionOptions) visible(shape *Shape) bool {
+	if options.LOD <= 0 {
+		return true
+	}
+	width, height := shape.XMax-shape.XMin, shape.YMax-shape.YMin
+	if shape.Kind == ShapeInstance {
+		return width >= options.LOD || height >= options.LOD
+	}
+	return width >= options.LOD && height >= options.LOD
+}
+
+// Region returns a design holding the objects intersecting the rectangle,
+// nil selects the design bounding box. Nets keep their visible wires and
+// special wire boxes only, technology data is kept whole and library masters
+// are dropped. Verification markers are kept when they intersect the rectangle
+func (design *Design) Region(rect *Rect, options *RegionOptions) *Design {
+	if options == nil {
+		options = &RegionOptions{}
+	}
+	if rect == nil {
+		rect = design.BoundingBox
+	}
+	region := &Design{
+		Name:           design.Name,
+		DBUPerMicron:   design.DBUPerMicron,
+		LEFUnits:       design.LEFUnits,
+		DEFUnits:       design.DEFUnits,
+		Units:          design.Units,
+		RoutingVias:    design.RoutingVias,
+		ViaDefinitions: design.ViaDefinitions,
+		Layers:         design.Layers,
+		CoreArea:       design.CoreArea,
+		DieArea:        design.DieArea,
+		DesignArea:     design.DesignArea,
+		Utilization:    design.Utilization,
+		BoundingBox:    design.BoundingBox,
+		Core:           design.Core,
+		Die:            design.Die,
+		Tracks:         design.Tracks,
+		Sites:          design.Sites,
+		GCell:          design.GCell,
+		Groups:         design.Groups,
+	}
+	if rect == nil {
+		return region
+	}
+
+	var filter *QueryFilter
+	// Objects may hold layer copies, layers are matched by ID
+	layers := design.LayersByID()
+	var names map[string]bool
+	if len(options.Layers) > 0 {
+		filter = &QueryFilter{Layers: options.Layers}
+		names = make(map[string]bool)
+		for _, name := range options.Layers {
+			names[name] = true
+		}
+	}
+	layerVisible := func(layer *Layer) bool {
+		return names == nil || layer == nil || (layers[layer.ID] != nil && names[layers[layer.ID].Name])
+	}
+	instances := make(map[*Instance]bool)
+	pins := make(map[*Pin]bool)
+	edges := make(map[*Edge]bool)
+	boxes := make(map[*Rect]bool)
+	for _, shape := range design.Query(rect, filter) {
+		if !options.visible(shape) {
+			continue
+		}
+		switch shape.Kind {
+		case ShapeInstance:
+			instances[shape.Instance] = true
+		case ShapePin:
+			pins[shape.Pin] = true
+		case ShapeWire, ShapeVia:
+			edges[shape.Edge] = true
+		case ShapeSpecialWire:
+			boxes[shape.Rect] = true
+		}
+	}
+	// Instance pins are kept with their instance only
+	for pin := range pins {
+		if pin.Instance != nil && !instances[pin.Instance] {
+			delete(pins, pin)
+		}
+	}
+	visiblePins := func(all []*Pin) []*Pin {
+		var kept []*Pin
+		for _, pin := range all {
+			if pins[pin] {
+				kept = append(kept, pin)
+			}
+		}
+		return kept
+	}
+
+	geometries := make(map[int]*Geometry)
+	for _, inst := range design.Instances {
+		if instances[inst] {
+			instCp := *inst
+			instCp.Pins = visiblePins(inst.Pins)
+			region.Instances = append(region.Instances, &instCp)
+		}
+	}
+	region.InstancePins = visiblePins(design.InstancePins)
+	region.BlockPins = visiblePins(design.BlockPins)
+	for _, regionPins := range [][]*Pin{region.InstancePins, region.BlockPins} {
+		for _, pin := range regionPins {
+			for _, geom := range pin.Geometries {
+				geometries[geom.ID] = geom
+			}
+		}
+	}
+
+	for _, net := range design.Nets {
+		netCp := *net
+		netCp.Pins = visiblePins(net.Pins)
+		netCp.Edges = nil
+		for _, edge := range net.Edges {
+			if edges[edge] {
+				netCp.Edges = append(netCp.Edges, edge)
+			}
+		}
+		netCp.SpecialBoxes = nil
+		for _, geom := range net.SpecialBoxes {
+			geomCp := &Geometry{ID: geom.ID, InComplete: geom.InComplete}
+			for _, box := range geom.Boxes {
+				if boxes[box] {
+					geomCp.Boxes = append(geomCp.Boxes, box)
+				}
+			}
+			if len(geomCp.Boxes) > 0 {
+				netCp.SpecialBoxes = append(netCp.SpecialBoxes, geomCp)
+				geometries[geom.ID] = geomCp
+			}
+		}
+		netCp.SpecialWires = nil
+		for _, wire := range net.SpecialWires {
+			wireCp := *wire
+			wireCp.Shapes = nil
+			for _, shape := range wire.Shapes {
+				if boxes[shape.Rect] {
+					wireCp.Shapes = append(wireCp.Shapes, shape)
+				}
+			}
+			if len(wireCp.Shapes) > 0 {
+				netCp.SpecialWires = append(netCp.SpecialWires, &wireCp)
+			}
+		}
+		if len(netCp.Pins) > 0 || len(netCp.Edges) > 0 || len(netCp.SpecialBoxes) > 0 {
+			region.Nets = append(region.Nets, &netCp)
+		}
+	}
+	for _, geom := range design.Geometries {
+		if geomCp := geometries[geom.ID]; geomCp != nil {
+			region.Geometries = append(region.Geometries, geomCp)
+		}
+	}
+
+	for _, row := range design.Rows {
+		if box := row.BoundingBox; box != nil && intersects(box.XMin, box.YMin, box.XMax, box.YMax, rect) {
+			region.Rows = append(region.Rows, row)
+		}
+	}
+	for _, blockage := range design.Blockages {
+		if box := blockage.Rect; box != nil && layerVisible(blockage.Layer) && intersects(box.XMin, box.YMin, box.XMax, box.YMax, rect) {
+			region.Blockages = append(region.Blockages, blockage)
+		}
+	}
+	for _, r := range design.Regions {
+		for _, box := range r.Boxes {
+			if intersects(box.XMin, box.YMin, box.XMax, box.YMax, rect) {
+				region.Regions = append(region.Regions, r)
+				break
+			}
+		}
+	}
+	for _, fill := range design.Fills {
+		if !layerVisible(fill.Layer) {
+			continue
+		}
+		fillCp := *fill
+		fillCp.Boxes = nil
+		for _, box := range fill.Boxes {
+			if intersects(box.XMin, box.YMin, box.XMax, box.YMax, rect) {
+				fillCp.Boxes = append(fillCp.Boxes, box)
+			}
+		}
+		if len(fillCp.Boxes) > 0 {
+			region.Fills = append(region.Fills, &fillCp)
+		}
+	}
+	for _, marker := range design.Markers {
+		if intersects(marker.XMin, marker.YMin, marker.XMax, marker.YMax, rect) {
+			region.Markers = append(region.Markers, marker)
+		}
+	}
+	return region
+}
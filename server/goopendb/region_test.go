@@ -0,0 +1,57 @@
+package goopendb
+
+import (
+	"testing"
+)
+
+func TestDesignRegion(t *testing.T) {
+	design := spatialDesign()
+	design.Geometries = []*Geometry{design.InstancePins[0].Geometries[0], design.Nets[1].SpecialBoxes[0]}
+	design.Markers = []*Marker{
+		{Type: MarkerMinSpacing, XMin: 3000, XMax: 3100, YMax: 100},
+		{Type: MarkerMinArea, XMin: 9000, XMax: 9100, YMax: 100},
+	}
+
+	region := design.Region(&Rect{XMin: 1000, XMax: 4500, YMax: 3500}, nil)
+	if len(region.Instances) != 1 || len(region.InstancePins) != 1 || len(region.Instances[0].Pins) != 1 {
+		t.Errorf("Expected the instance with its pin")
+	}
+	if len(region.Nets) != 2 || len(region.Nets[0].Edges) != 2 {
+		t.Fatalf("Expected both nets with the routed wire and via")
+	}
+	// Special wires at x = 4000, 4200 and 4400 for y < 3500
+	if boxes := region.Nets[1].SpecialBoxes; len(boxes) != 1 || len(boxes[0].Boxes) != 27 {
+		t.Errorf("Unexpected special boxes %v", boxes)
+	}
+	if len(region.Geometries) != 2 || len(region.Geometries[1].Boxes) != 27 {
+		t.Errorf("Expected the pin and the visible special wire geometries")
+	}
+	if len(region.Markers) != 1 || region.Markers[0].Type != MarkerMinSpacing {
+		t.Errorf("Expected the marker in the region")
+	}
+	if len(design.Nets[1].SpecialBoxes[0].Boxes) != 1000 {
+		t.Errorf("Expected the design to be unchanged")
+	}
+
+	// Level of detail drops the pin and the thin shapes
+	region = design.Region(&Rect{XMin: 1000, XMax: 4500, YMax: 3500}, &RegionOptions{LOD: 120})
+	if len(region.Instances) != 1 || len(region.InstancePins) != 0 || len(region.Instances[0].Pins) != 0 {
+		t.Errorf("Expected the instance without its pin")
+	}
+	if len(region.Nets) != 1 || len(region.Nets[0].Edges) != 2 {
+		t.Errorf("Expected the routed net only")
+	}
+
+	region = design.Region(&Rect{XMin: 1000, XMax: 4500, YMax: 3500}, &RegionOptions{Layers: []string{"via1"}})
+	if len(region.Instances) != 1 || len(region.InstancePins) != 0 || len(region.Nets) != 1 || len(region.Nets[0].Edges) != 1 {
+		t.Errorf("Expected the instance and the via")
+	}
+
+	// Encoding a region keeps the design usable
+	if _, err := DesignToJSON(design.Region(&Rect{XMax: 30000, YMax: 4000}, nil), false, nil); err != nil {
+		t.Fatal(err)
+	}
+	if design.InstancePins[0].Instance == nil || design.InstancePins[0].Geometries[0].Boxes == nil {
+		t.Errorf("Expected the design to be unchanged after encoding")
+	}
+}
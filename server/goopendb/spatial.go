@@ -0,0 +1,412 @@
+package goopendb
+
+// Spatial index of the design shapes for region queries and hit-testing
+
+import (
+	"container/heap"
+	"math"
+	"sort"
+)
+
+// ShapeKind is the design object kind of an indexed shape
+type ShapeKind int
+
+// ShapeKind enum
+const (
+	ShapeInstance ShapeKind = iota
+	ShapePin
+	ShapeWire
+	ShapeVia
+	ShapeSpecialWire
+)
+
+func (kind ShapeKind) String() string {
+	switch kind {
+	case ShapeInstance:
+		return "Instance"
+	case ShapePin:
+		return "Pin"
+	case ShapeWire:
+		return "Wire"
+	case ShapeVia:
+		return "Via"
+	case ShapeSpecialWire:
+		return "SpecialWire"
+	}
+	return "Unknown"
+}
+
+// Shape is an indexed design rectangle with the objects it belongs to,
+// via shapes are indexed once for every via box
+type Shape struct {
+	Kind     ShapeKind
+	XMin     int
+	YMin     int
+	XMax     int
+	YMax     int
+	Layer    *Layer    // Nil for instances
+	Rect     *Rect     // Source rectangle, via boxes share the via rectangle
+	Instance *Instance // Instances and instance pins
+	Pin      *Pin      // Pins
+	Net      *Net      // Wires, vias, special wires and connected pins
+	Edge     *Edge     // Wires and vias of routed nets
+	Geometry *Geometry // Pins and special wires
+}
+
+// Area of the shape in square database units
+func (shape *Shape) Area() float64 {
+	return float64(shape.XMax-shape.XMin) * float64(shape.YMax-shape.YMin)
+}
+
+// QueryFilter selects the shapes returned by spatial queries, empty lists
+// match all shapes
+type QueryFilter struct {
+	Kinds  []ShapeKind
+	Layers []string // Layer names, instances have no layer and are always matched
+}
+
+// spatialNodeSize is the maximum number of entries of an index node
+const spatialNodeSize = 16
+
+// spatialNode is an R-tree node, leaves hold shapes and inner nodes hold
+// children
+type spatialNode struct {
+	xMin, yMin, xMax, yMax int
+	children               []*spatialNode
+	shapes                 []*Shape
+}
+
+// SpatialIndex is a static R-tree over the design shapes, the index is not
+// updated when the design changes
+type SpatialIndex struct {
+	root   *spatialNode
+	layers map[int]*Layer // Design layers by ID, shapes may hold layer copies
+	size   int
+}
+
+// Len returns the number of indexed shapes
+func (index *SpatialIndex) Len() int {
+	return index.size
+}
+
+// Shapes returns all the indexed shapes
+func (index *SpatialIndex) Shapes() []*Shape {
+	shapes := make([]*Shape, 0, index.size)
+	if index.root == nil {
+		return shapes
+	}
+	stack := []*spatialNode{index.root}
+	for len(stack) > 0 {
+		node := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		stack = append(stack, node.children...)
+		shapes = append(shapes, node.shapes...)
+	}
+	return shapes
+}
+
+// NewSpatialIndex indexes the placed instances, pins, routed wires, vias and
+// special wires of the design
+func NewSpatialIndex(design *Design) *SpatialIndex {
+	var shapes []*Shape
+	add := func(shape Shape) ShapeVisitor {
+		return func(layer *Layer, xMin, yMin, xMax, yMax int) {
+			s := shape
+			s.Layer, s.XMin, s.YMin, s.XMax, s.YMax = layer, xMin, yMin, xMax, yMax
+			shapes = append(shapes, &s)
+		}
+	}
+	addGeometries := func(geometries []*Geometry, shape Shape, placed func(ShapeVisitor) ShapeVisitor) {
+		for _, geom := range geometries {
+			if geom == nil {
+				continue
+			}
+			for _, rect := range geom.Boxes {
+				shape.Geometry, shape.Rect = geom, rect
+				visit := add(shape)
+				if placed != nil {
+					visit = placed(visit)
+				}
+				visitRect(rect, rect.Via, visit)
+			}
+		}
+	}
+	for _, inst := range design.Instances {
+		if !inst.IsPlaced || inst.BoundingBox == nil {
+			continue
+		}
+		bbox := inst.BoundingBox
+		add(Shape{Kind: ShapeInstance, Rect: bbox, Instance: inst})(nil, bbox.XMin, bbox.YMin, bbox.XMax, bbox.YMax)
+		for _, pin := range inst.Pins {
+			addGeometries(pin.Geometries, Shape{Kind: ShapePin, Instance: inst, Pin: pin, Net: pin.Net}, inst.Placed)
+		}
+	}
+	for _, pin := range design.BlockPins {
+		addGeometries(pin.Geometries, Shape{Kind: ShapePin, Pin: pin, Net: pin.Net}, nil)
+	}
+	for _, net := range design.Nets {
+		for _, edge := range net.Edges {
+			rect := edge.Rect
+			if rect == nil {
+				continue
+			}
+			switch edge.Type {
+			case EdgeTypeSEGMENT:
+				add(Shape{Kind: ShapeWire, Rect: rect, Net: net, Edge: edge})(edge.Layer, rect.XMin, rect.YMin, rect.XMax, rect.YMax)
+			case EdgeTypeTECHVIA, EdgeTypeVIA:
+				if edge.Via != nil {
+					visitRect(rect, edge.Via, add(Shape{Kind: ShapeVia, Rect: rect, Net: net, Edge: edge}))
+				}
+			}
+		}
+		addGeometries(net.SpecialBoxes, Shape{Kind: ShapeSpecialWire, Net: net}, nil)
+	}
+
+	index := &SpatialIndex{
+		root:   packShapes(shapes),
+		layers: design.LayersByID(),
+		size:   len(shapes),
+	}
+	return index
+}
+
+// packShapes builds the tree bottom up with Sort-Tile-Recursive packing
+func packShapes(shapes []*Shape) *spatialNode {
+	var nodes []*spatialNode
+	tiles(len(shapes), func(i, j int) bool {
+		return shapes[i].XMin+shapes[i].XMax < shapes[j].XMin+shapes[j].XMax
+	}, func(i, j int) bool {
+		return shapes[i].YMin+shapes[i].YMax < shapes[j].YMin+shapes[j].YMax
+	}, func(i, j int) {
+		shapes[i], shapes[j] = shapes[j], shapes[i]
+	}, func(lo, hi int) {
+		node := &spatialNode{shapes: shapes[lo:hi:hi]}
+		node.xMin, node.yMin, node.xMax, node.yMax = math.MaxInt32, math.MaxInt32, math.MinInt32, math.MinInt32
+		for _, shape := range node.shapes {
+			node.extend(shape.XMin, shape.YMin, shape.XMax, shape.YMax)
+		}
+		nodes = append(nodes, node)
+	})
+	for len(nodes) > 1 {
+		level := nodes
+		nodes = nil
+		tiles(len(level), func(i, j int) bool {
+			return level[i].xMin+level[i].xMax < level[j].xMin+level[j].xMax
+		}, func(i, j int) bool {
+			return level[i].yMin+level[i].yMax < level[j].yMin+level[j].yMax
+		}, func(i, j int) {
+			level[i], level[j] = level[j], level[i]
+		}, func(lo, hi int) {
+			node := &spatialNode{children: level[lo:hi:hi]}
+			node.xMin, node.yMin, node.xMax, node.yMax = math.MaxInt32, math.MaxInt32, math.MinInt32, math.MinInt32
+			for _, child := range node.children {
+				node.extend(child.xMin, child.yMin, child.xMax, child.yMax)
+			}
+			nodes = append(nodes, node)
+		})
+	}
+	if len(nodes) == 0 {
+		return nil
+	}
+	return nodes[0]
+}
+
+// tiles sorts n entries into vertical slices by X center, sorts every slice
+// by Y center and groups consecutive entries into nodes
+func tiles(n int, lessX, lessY func(i, j int) bool, swap func(i, j int), node func(lo, hi int)) {
+	nodeCount := (n + spatialNodeSize - 1) / spatialNodeSize
+	sliceSize := int(math.Ceil(math.Sqrt(float64(nodeCount)))) * spatialNodeSize
+	sort.Sort(&entries{n: n, lo: 0, less: lessX, swap: swap})
+	for lo := 0; lo < n; lo += sliceSize {
+		hi := lo + sliceSize
+		if hi > n {
+			hi = n
+		}
+		sort.Sort(&entries{n: hi - lo, lo: lo, less: lessY, swap: swap})
+		for i := lo; i < hi; i += spatialNodeSize {
+			j := i + spatialNodeSize
+			if j > hi {
+				j = hi
+			}
+			node(i, j)
+		}
+	}
+}
+
+// entries sorts a range of a slice through index callbacks
+type entries struct {
+	n, lo int
+	less  func(i, j int) bool
+	swap  func(i, j int)
+}
+
+func (e *entries) Len() int           { return e.n }
+func (e *entries) Less(i, j int) bool { return e.less(e.lo+i, e.lo+j) }
+func (e *entries) Swap(i, j int)      { e.swap(e.lo+i, e.lo+j) }
+
+func (node *spatialNode) extend(xMin, yMin, xMax, yMax int) {
+	if xMin < node.xMin {
+		node.xMin = xMin
+	}
+	if yMin < node.yMin {
+		node.yMin = yMin
+	}
+	if xMax > node.xMax {
+		node.xMax = xMax
+	}
+	if yMax > node.yMax {
+		node.yMax = yMax
+	}
+}
+
+// intersects checks if the bounds touch the rectangle
+func intersects(xMin, yMin, xMax, yMax int, rect *Rect) bool {
+	return xMin <= rect.XMax && xMax >= rect.XMin && yMin <= rect.YMax && yMax >= rect.YMin
+}
+
+// distance from the point to the bounds, zero inside
+func distance(xMin, yMin, xMax, yMax int, point *Point) float64 {
+	dx := math.Max(math.Max(float64(xMin-point.X), float64(point.X-xMax)), 0)
+	dy := math.Max(math.Max(float64(yMin-point.Y), float64(point.Y-yMax)), 0)
+	return math.Hypot(dx, dy)
+}
+
+// matcher returns the filter predicate of the index
+func (index *SpatialIndex) matcher(filter *QueryFilter) func(*Shape) bool {
+	if filter == nil {
+		return func(*Shape) bool { return true }
+	}
+	kinds := make(map[ShapeKind]bool)
+	for _, kind := range filter.Kinds {
+		kinds[kind] = true
+	}
+	var layers map[int]bool
+	if len(filter.Layers) > 0 {
+		names := make(map[string]bool)
+		for _, name := range filter.Layers {
+			names[name] = true
+		}
+		layers = make(map[int]bool)
+		for id, layer := range index.layers {
+			if names[layer.Name] {
+				layers[id] = true
+			}
+		}
+	}
+	return func(shape *Shape) bool {
+		if len(kinds) > 0 && !kinds[shape.Kind] {
+			return false
+		}
+		if layers != nil && shape.Kind != ShapeInstance && (shape.Layer == nil || !layers[shape.Layer.ID]) {
+			return false
+		}
+		return true
+	}
+}
+
+// Query returns the shapes intersecting the rectangle, shapes touching the
+// rectangle edges are included
+func (index *SpatialIndex) Query(rect *Rect, filter *QueryFilter) []*Shape {
+	var found []*Shape
+	if index.root == nil || rect == nil {
+		return found
+	}
+	match := index.matcher(filter)
+	stack := []*spatialNode{index.root}
+	for len(stack) > 0 {
+		node := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if !intersects(node.xMin, node.yMin, node.xMax, node.yMax, rect) {
+			continue
+		}
+		stack = append(stack, node.children...)
+		for _, shape := range node.shapes {
+			if intersects(shape.XMin, shape.YMin, shape.XMax, shape.YMax, rect) && match(shape) {
+				found = append(found, shape)
+			}
+		}
+	}
+	return found
+}
+
+// nearestItem is a node or a shape queued by its distance to the point
+type nearestItem struct {
+	dist  float64
+	area  float64
+	node  *spatialNode
+	shape *Shape
+}
+
+type nearestQueue []*nearestItem
+
+func (q nearestQueue) Len() int { return len(q) }
+func (q nearestQueue) Less(i, j int) bool {
+	if q[i].dist != q[j].dist {
+		return q[i].dist < q[j].dist
+	}
+	// Nodes before shapes at the same distance so every shape at that
+	// distance is queued before one is returned, then smaller shapes first
+	if (q[i].shape == nil) != (q[j].shape == nil) {
+		return q[i].shape == nil
+	}
+	return q[i].area < q[j].area
+}
+func (q nearestQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *nearestQueue) Push(x interface{}) { *q = append(*q, x.(*nearestItem)) }
+func (q *nearestQueue) Pop() interface{} {
+	old := *q
+	item := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return item
+}
+
+// Nearest returns the shape closest to the point, or nil if no shape matches
+// the filter. When the point is inside several shapes the smallest one is
+// returned, so a pin is picked over the instance holding it
+func (index *SpatialIndex) Nearest(point *Point, filter *QueryFilter) *Shape {
+	if index.root == nil || point == nil {
+		return nil
+	}
+	match := index.matcher(filter)
+	q := &nearestQueue{{node: index.root, dist: distance(index.root.xMin, index.root.yMin, index.root.xMax, index.root.yMax, point)}}
+	for q.Len() > 0 {
+		item := heap.Pop(q).(*nearestItem)
+		if item.shape != nil {
+			return item.shape
+		}
+		for _, child := range item.node.children {
+			heap.Push(q, &nearestItem{node: child, dist: distance(child.xMin, child.yMin, child.xMax, child.yMax, point)})
+		}
+		for _, shape := range item.node.shapes {
+			if match(shape) {
+				heap.Push(q, &nearestItem{shape: shape, area: shape.Area(), dist: distance(shape.XMin, shape.YMin, shape.XMax, shape.YMax, point)})
+			}
+		}
+	}
+	return nil
+}
+
+// Index returns the spatial index of the design, the index is built on first
+// use and has to be rebuilt with BuildIndex after changing the design
+func (design *Design) Index() *SpatialIndex {
+	if design.index == nil {
+		design.BuildIndex()
+	}
+	return design.index
+}
+
+// BuildIndex rebuilds the spatial index of the design
+func (design *Design) BuildIndex() *SpatialIndex {
+	design.index = NewSpatialIndex(design)
+	return design.index
+}
+
+// Query returns the design shapes intersecting the rectangle
+func (design *Design) Query(rect *Rect, filter *QueryFilter) []*Shape {
+	return design.Index().Query(rect, filter)
+}
+
+// Nearest returns the design shape closest to the point
+func (design *Design) Nearest(point *Point, filter *QueryFilter) *Shape {
+	return design.Index().Nearest(point, filter)
+}
@@ -0,0 +1,185 @@
+package goopendb
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// spatialDesign has a placed instance with a pin, a routed net with a via and
+// a grid of special wires
+func spatialDesign() *Design {
+	metal1 := &Layer{ID: 1, Name: "metal1"}
+	via1 := &Layer{ID: 2, Name: "via1"}
+	metal2 := &Layer{ID: 3, Name: "metal2"}
+	inst := &Instance{
+		ID:          1,
+		Name:        "u1",
+		IsPlaced:    true,
+		Origin:      &Point{X: 1000, Y: 0},
+		Orientation: OrientationR0,
+		BoundingBox: &Rect{XMin: 1000, XMax: 1760, YMax: 2800},
+	}
+	net := &Net{ID: 1, Name: "n1"}
+	pin := &Pin{
+		ID:         1,
+		Name:       "A",
+		Instance:   inst,
+		Net:        net,
+		Geometries: []*Geometry{{ID: 1, Boxes: []*Rect{{XMin: 100, YMin: 500, XMax: 200, YMax: 1000, Layer: &Layer{ID: 1, InComplete: true}}}}},
+	}
+	inst.Pins = []*Pin{pin}
+	net.Pins = []*Pin{pin}
+	via := &Via{
+		ID:   1,
+		Name: "VIA12",
+		Rect: &Rect{XMin: -70, YMin: -70, XMax: 70, YMax: 70},
+		Boxes: []*Rect{
+			{XMin: -70, YMin: -70, XMax: 70, YMax: 70, Layer: metal1},
+			{XMin: -35, YMin: -35, XMax: 35, YMax: 35, Layer: via1},
+			{XMin: -70, YMin: -70, XMax: 70, YMax: 70, Layer: metal2},
+		},
+	}
+	net.Edges = []*Edge{
+		{Type: EdgeTypeSEGMENT, Layer: metal2, Rect: &Rect{XMin: 1150, YMin: 3000, XMax: 3000, YMax: 3140}},
+		{Type: EdgeTypeVIA, Via: via, Rect: &Rect{XMin: 1080, YMin: 2930, XMax: 1220, YMax: 3070}},
+		{Type: EdgeTypeVWIRE, Rect: &Rect{XMin: 5000, YMin: 5000, XMax: 5000, YMax: 5000}},
+	}
+	power := &Net{ID: 2, Name: "VDD", IsSpecial: true}
+	geom := &Geometry{ID: 2}
+	for i := 0; i < 100; i++ {
+		for j := 0; j < 10; j++ {
+			x, y := 4000+i*200, j*400
+			geom.Boxes = append(geom.Boxes, &Rect{XMin: x, YMin: y, XMax: x + 100, YMax: y + 100, Layer: metal1})
+		}
+	}
+	power.SpecialBoxes = []*Geometry{geom}
+	return &Design{
+		Layers:       []*Layer{metal1, via1, metal2},
+		Instances:    []*Instance{inst, {ID: 2, Name: "unplaced", BoundingBox: &Rect{XMax: 100, YMax: 100}}},
+		InstancePins: inst.Pins,
+		Nets:         []*Net{net, power},
+	}
+}
+
+func TestSpatialIndex(t *testing.T) {
+	design := spatialDesign()
+	index := design.Index()
+	// Instance, pin, wire, three via boxes and the special wires
+	if index.Len() != 1006 {
+		t.Fatalf("Expected 1006 shapes, found %v", index.Len())
+	}
+
+	shapes := design.Query(&Rect{XMin: 1050, YMin: 600, XMax: 1150, YMax: 3000}, nil)
+	kinds := make(map[ShapeKind]int)
+	for _, shape := range shapes {
+		kinds[shape.Kind]++
+	}
+	if kinds[ShapeInstance] != 1 || kinds[ShapePin] != 1 || kinds[ShapeWire] != 1 || kinds[ShapeVia] != 3 || len(shapes) != 6 {
+		t.Errorf("Unexpected query result %v", kinds)
+	}
+	for _, shape := range shapes {
+		if shape.Kind == ShapePin && (shape.XMin != 1100 || shape.YMin != 500 || shape.Pin.Name != "A" || shape.Net.Name != "n1") {
+			t.Errorf("Unexpected pin shape %+v", shape)
+		}
+	}
+
+	filter := &QueryFilter{Kinds: []ShapeKind{ShapeVia, ShapePin}, Layers: []string{"metal1"}}
+	shapes = design.Query(&Rect{XMax: 4000, YMax: 4000}, filter)
+	if len(shapes) != 2 {
+		t.Errorf("Expected the pin and the via bottom shape, found %v", len(shapes))
+	}
+	shapes = design.Query(&Rect{XMax: 4000, YMax: 4000}, &QueryFilter{Layers: []string{"metal2"}})
+	if len(shapes) != 3 {
+		t.Errorf("Expected the instance, the wire and the via top shape, found %v", len(shapes))
+	}
+
+	// Region queries match a linear scan
+	rng := rand.New(rand.NewSource(1))
+	var all []*Shape
+	all = append(all, index.Shapes()...)
+	if len(all) != index.Len() || len(index.Query(&Rect{XMin: -1 << 30, YMin: -1 << 30, XMax: 1 << 30, YMax: 1 << 30}, nil)) != len(all) {
+		t.Fatalf("Expected all %v shapes, found %v", index.Len(), len(all))
+	}
+	for i := 0; i < 50; i++ {
+		x, y := rng.Intn(25000), rng.Intn(4000)
+		rect := &Rect{XMin: x, YMin: y, XMax: x + rng.Intn(3000), YMax: y + rng.Intn(1000)}
+		expected := 0
+		for _, shape := range all {
+			if intersects(shape.XMin, shape.YMin, shape.XMax, shape.YMax, rect) {
+				expected++
+			}
+		}
+		if found := len(index.Query(rect, nil)); found != expected {
+			t.Errorf("Expected %v shapes in %+v, found %v", expected, rect, found)
+		}
+	}
+}
+
+func TestSpatialNearest(t *testing.T) {
+	design := spatialDesign()
+	for _, tc := range []struct {
+		point    Point
+		filter   *QueryFilter
+		kind     ShapeKind
+		expected Rect
+	}{
+		{Point{X: 1150, Y: 700}, nil, ShapePin, Rect{XMin: 1100, YMin: 500, XMax: 1200, YMax: 1000}},
+		{Point{X: 1500, Y: 700}, nil, ShapeInstance, Rect{XMin: 1000, XMax: 1760, YMax: 2800}},
+		{Point{X: 1150, Y: 3000}, nil, ShapeVia, Rect{XMin: 1115, YMin: 2965, XMax: 1185, YMax: 3035}},
+		{Point{X: 2000, Y: 3500}, nil, ShapeWire, Rect{XMin: 1150, YMin: 3000, XMax: 3000, YMax: 3140}},
+		{Point{X: 9050, Y: 1300}, nil, ShapeSpecialWire, Rect{XMin: 9000, YMin: 1200, XMax: 9100, YMax: 1300}},
+		{Point{X: 1500, Y: 700}, &QueryFilter{Kinds: []ShapeKind{ShapeSpecialWire}}, ShapeSpecialWire, Rect{XMin: 4000, YMin: 800, XMax: 4100, YMax: 900}},
+	} {
+		shape := design.Nearest(&tc.point, tc.filter)
+		if shape == nil {
+			t.Errorf("Expected a shape near %v", tc.point)
+			continue
+		}
+		if shape.Kind != tc.kind || shape.XMin != tc.expected.XMin || shape.YMin != tc.expected.YMin ||
+			shape.XMax != tc.expected.XMax || shape.YMax != tc.expected.YMax {
+			t.Errorf("Expected %v %+v near %v, found %v %+v", tc.kind, tc.expected, tc.point, shape.Kind, shape)
+		}
+	}
+	if shape := design.Nearest(&Point{}, &QueryFilter{Layers: []string{"metal3"}}); shape == nil || shape.Kind != ShapeInstance {
+		t.Errorf("Expected only instances to match unknown layers")
+	}
+	if shape := (&Design{}).Nearest(&Point{}, nil); shape != nil {
+		t.Errorf("Expected no shape in an empty design")
+	}
+}
+
+func TestSpatialNearestInside(t *testing.T) {
+	// A large instance with a pin in its corner, other instances spread the
+	// pin and the instance over different tree nodes
+	metal1 := &Layer{ID: 1, Name: "metal1"}
+	block := &Instance{
+		ID:          1,
+		Name:        "block",
+		IsPlaced:    true,
+		Origin:      &Point{},
+		Orientation: OrientationR0,
+		BoundingBox: &Rect{XMax: 100000, YMax: 100000},
+	}
+	pin := &Pin{
+		ID:         1,
+		Name:       "A",
+		Instance:   block,
+		Geometries: []*Geometry{{ID: 1, Boxes: []*Rect{{XMin: 90000, YMin: 90000, XMax: 90100, YMax: 90100, Layer: metal1}}}},
+	}
+	block.Pins = []*Pin{pin}
+	design := &Design{Layers: []*Layer{metal1}, Instances: []*Instance{block}, InstancePins: block.Pins}
+	for i := 0; i < 40; i++ {
+		for j := 0; j < 40; j++ {
+			x, y := i*5000, j*5000
+			design.Instances = append(design.Instances, &Instance{
+				ID:          len(design.Instances) + 1,
+				IsPlaced:    true,
+				BoundingBox: &Rect{XMin: x, YMin: y, XMax: x + 10, YMax: y + 10},
+			})
+		}
+	}
+	shape := design.Nearest(&Point{X: 90050, Y: 90050}, nil)
+	if shape == nil || shape.Kind != ShapePin || shape.Pin != pin {
+		t.Errorf("Expected the pin inside the instance, found %+v", shape)
+	}
+}
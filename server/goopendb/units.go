@@ -0,0 +1,229 @@
+package goopendb
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Coordinate units of the design JSON
+const (
+	UnitsDBU    = "dbu"
+	UnitsMicron = "micron"
+)
+
+// MicronPoint is a Point in microns
+type MicronPoint struct {
+	X float64
+	Y float64
+}
+
+// MicronRect is a Rect in microns
+type MicronRect struct {
+	XMin float64
+	YMin float64
+	XMax float64
+	YMax float64
+}
+
+// dbuUnit is the unit of a field in database units
+type dbuUnit struct {
+	power int  // 1 for distances, 2 for areas
+	unset bool // -1 means "not set" and is kept in microns
+}
+
+var (
+	dbuDistance      = dbuUnit{power: 1}
+	dbuUnsetDistance = dbuUnit{power: 1, unset: true}
+	dbuArea          = dbuUnit{power: 2}
+)
+
+// dbuFields are the fields of the design types in database units, the other
+// fields are kept as they are in micron JSON
+var dbuFields = map[reflect.Type]map[string]dbuUnit{
+	reflect.TypeOf(Point{}):            {"X": dbuDistance, "Y": dbuDistance},
+	reflect.TypeOf(Rect{}):             {"XMin": dbuDistance, "YMin": dbuDistance, "XMax": dbuDistance, "YMax": dbuDistance},
+	reflect.TypeOf(Marker{}):           {"XMin": dbuDistance, "YMin": dbuDistance, "XMax": dbuDistance, "YMax": dbuDistance},
+	reflect.TypeOf(Master{}):           {"Width": dbuDistance, "Height": dbuDistance},
+	reflect.TypeOf(Site{}):             {"Width": dbuDistance, "Height": dbuDistance},
+	reflect.TypeOf(SpecialShape{}):     {"Width": dbuDistance},
+	reflect.TypeOf(SpacingTable{}):     {"Widths": dbuDistance, "Lengths": dbuDistance, "Spacings": dbuDistance},
+	reflect.TypeOf(InfluenceSpacing{}): {"Width": dbuDistance, "Within": dbuDistance, "Spacing": dbuDistance},
+	reflect.TypeOf(MinEnclosedArea{}):  {"Area": dbuArea, "Width": dbuUnsetDistance},
+	reflect.TypeOf(Layer{}): {
+		"Width": dbuDistance, "Spacing": dbuDistance,
+		"PitchX": dbuDistance, "PitchY": dbuDistance, "OffsetX": dbuDistance, "OffsetY": dbuDistance,
+		"MinWidth": dbuDistance, "MaxWidth": dbuUnsetDistance, "MinStep": dbuDistance,
+		"Height": dbuUnsetDistance, "Thickness": dbuUnsetDistance,
+	},
+	reflect.TypeOf(ViaParams{}): {
+		"CutSizeX": dbuDistance, "CutSizeY": dbuDistance, "CutSpacingX": dbuDistance, "CutSpacingY": dbuDistance,
+		"BottomEnclosureX": dbuDistance, "BottomEnclosureY": dbuDistance,
+		"TopEnclosureX": dbuDistance, "TopEnclosureY": dbuDistance,
+		"OriginX": dbuDistance, "OriginY": dbuDistance,
+		"BottomOffsetX": dbuDistance, "BottomOffsetY": dbuDistance,
+		"TopOffsetX": dbuDistance, "TopOffsetY": dbuDistance,
+	},
+	reflect.TypeOf(ViaRuleLayer{}): {
+		"EnclosureOverhang1": dbuDistance, "EnclosureOverhang2": dbuDistance,
+		"MinWidth": dbuDistance, "MaxWidth": dbuDistance, "SpacingX": dbuDistance, "SpacingY": dbuDistance,
+	},
+	reflect.TypeOf(Grid{}): {
+		"GridX": dbuDistance, "GridY": dbuDistance,
+		"GridXPatternOrigins": dbuDistance, "GridXPatternSteps": dbuDistance,
+		"GridYPatternOrigins": dbuDistance, "GridYPatternSteps": dbuDistance,
+	},
+	reflect.TypeOf(Row{}):      {"OriginX": dbuDistance, "OriginY": dbuDistance, "Spacing": dbuDistance},
+	reflect.TypeOf(Blockage{}): {"MinSpacing": dbuUnsetDistance, "DesignRuleWidth": dbuUnsetDistance},
+}
+
+// ToMicrons converts a distance in database units to microns
+func (design *Design) ToMicrons(dist int) float64 {
+	if design.DBUPerMicron <= 0 {
+		return float64(dist)
+	}
+	return float64(dist) / float64(design.DBUPerMicron)
+}
+
+// ToDBU converts a distance in microns to database units
+func (design *Design) ToDBU(microns float64) int {
+	if design.DBUPerMicron <= 0 {
+		return int(math.Round(microns))
+	}
+	return int(math.Round(microns * float64(design.DBUPerMicron)))
+}
+
+// micronScale returns the database units per micron, 1 if unknown
+func micronScale(dbuPerMicron int) float64 {
+	if dbuPerMicron <= 0 {
+		return 1
+	}
+	return float64(dbuPerMicron)
+}
+
+// Microns converts the point to microns
+func (p *Point) Microns(dbuPerMicron int) MicronPoint {
+	scale := micronScale(dbuPerMicron)
+	return MicronPoint{
+		X: float64(p.X) / scale,
+		Y: float64(p.Y) / scale,
+	}
+}
+
+// Microns converts the rect to microns
+func (r *Rect) Microns(dbuPerMicron int) MicronRect {
+	scale := micronScale(dbuPerMicron)
+	return MicronRect{
+		XMin: float64(r.XMin) / scale,
+		YMin: float64(r.YMin) / scale,
+		XMax: float64(r.XMax) / scale,
+		YMax: float64(r.YMax) / scale,
+	}
+}
+
+// writeMicrons writes the JSON of a design value with the fields in database
+// units converted to microns, the output matches encoding/json otherwise
+func writeMicrons(buf *bytes.Buffer, v reflect.Value, dbu float64) error {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			buf.WriteString("null")
+			return nil
+		}
+		return writeMicrons(buf, v.Elem(), dbu)
+	case reflect.Slice:
+		if v.IsNil() {
+			buf.WriteString("null")
+			return nil
+		}
+		buf.WriteByte('[')
+		for i := 0; i < v.Len(); i++ {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := writeMicrons(buf, v.Index(i), dbu); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+		return nil
+	case reflect.Struct:
+		units := dbuFields[v.Type()]
+		buf.WriteByte('{')
+		first := true
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Type().Field(i)
+			tag := field.Tag.Get("json")
+			if field.PkgPath != "" || tag == "-" {
+				continue
+			}
+			value := v.Field(i)
+			if strings.Contains(tag, "omitempty") && isEmptyValue(value) {
+				continue
+			}
+			name := field.Name
+			if idx := strings.IndexByte(tag, ','); idx > 0 {
+				name = tag[:idx]
+			}
+			if !first {
+				buf.WriteByte(',')
+			}
+			first = false
+			buf.WriteString(strconv.Quote(name) + ":")
+			var err error
+			if unit, ok := units[field.Name]; ok {
+				err = writeMicronValue(buf, value, math.Pow(dbu, float64(unit.power)), unit.unset)
+			} else {
+				err = writeMicrons(buf, value, dbu)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+		return nil
+	}
+	valueBytes, err := json.Marshal(v.Interface())
+	buf.Write(valueBytes)
+	return err
+}
+
+// writeMicronValue writes a number, or a (nested) slice of numbers, divided by
+// scale
+func writeMicronValue(buf *bytes.Buffer, v reflect.Value, scale float64, keepUnset bool) error {
+	var f float64
+	switch v.Kind() {
+	case reflect.Slice:
+		if v.IsNil() {
+			buf.WriteString("null")
+			return nil
+		}
+		buf.WriteByte('[')
+		for i := 0; i < v.Len(); i++ {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := writeMicronValue(buf, v.Index(i), scale, keepUnset); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+		return nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		f = float64(v.Int())
+	case reflect.Float32, reflect.Float64:
+		f = v.Float()
+	default:
+		return fmt.Errorf("Unsupported database units field type %v", v.Type())
+	}
+	if !(keepUnset && f == -1) {
+		f /= scale
+	}
+	valueBytes, err := json.Marshal(f)
+	buf.Write(valueBytes)
+	return err
+}
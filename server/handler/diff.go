@@ -0,0 +1,33 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/ahmed-agiza/EDAViewer/server/goopendb"
+)
+
+// HandleDesignDiff parses two DEF versions of a design sharing the uploaded
+// LEF files and responds with their differences, the first DEF is the base
+func HandleDesignDiff(w http.ResponseWriter, r *http.Request) {
+	lefs, defs, cleanup, ok := receiveFiles(w, r)
+	defer cleanup()
+	if !ok {
+		return
+	}
+	if len(defs) != 2 {
+		http.Error(w, "Two DEF files are required", http.StatusBadRequest)
+		return
+	}
+	var designs [2]*goopendb.Design
+	for i, version := range []string{"base", "revised"} {
+		var err error
+		if designs[i], err = goopendb.ParseDesign(&goopendb.DesignFiles{DEF: defs[i], LEF: lefs}); err != nil {
+			http.Error(w, "Invalid "+version+" design: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	w.Header().Add("Accept-Charset", "utf-8")
+	w.Header().Add("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(goopendb.Diff(designs[0], designs[1]))
+}
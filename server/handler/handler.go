@@ -1,6 +1,8 @@
 package handler
 
 import (
+	"bytes"
+	"compress/gzip"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -11,6 +13,9 @@ import (
 	"time"
 
 	"github.com/ahmed-agiza/EDAViewer/server/goopendb"
+	_ "github.com/ahmed-agiza/EDAViewer/server/lefdef" // Registers the native parsing backend
+	"github.com/ahmed-agiza/EDAViewer/server/render"
+	"github.com/ahmed-agiza/EDAViewer/server/verify"
 	"github.com/go-chi/chi"
 	"github.com/go-chi/chi/middleware"
 	"github.com/rs/cors"
@@ -25,8 +30,16 @@ const RequestMaxMemory int64 = 2 * 1024 * 1024 //2MB
 // TemporaryDirectory is a temporary path to store uploaded files, empty string indicates the system's temproary directory
 const TemporaryDirectory string = ""
 
-// HandleDesignUpload handles user uploaded design
-func HandleDesignUpload(w http.ResponseWriter, r *http.Request) {
+// receiveFiles stores the uploaded LEF and DEF files in temporary files, it
+// responds with an error and returns false on failure. The returned cleanup
+// removes the temporary files
+func receiveFiles(w http.ResponseWriter, r *http.Request) (lefs, defs []*goopendb.DesignFile, cleanup func(), ok bool) {
+	var tempFiles []string
+	cleanup = func() {
+		for _, name := range tempFiles {
+			os.Remove(name)
+		}
+	}
 	if len(TemporaryDirectory) > 0 {
 		os.Mkdir(TemporaryDirectory, 0600)
 	} else {
@@ -61,8 +74,6 @@ func HandleDesignUpload(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	designFiles := &goopendb.DesignFiles{}
-
 	for i := range files {
 		filename := strings.ToLower(files[i].Filename)
 		if !strings.HasSuffix(filename, ".def") && !strings.HasSuffix(filename, ".lef") {
@@ -70,22 +81,21 @@ func HandleDesignUpload(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 		file, err := files[i].Open()
-		defer file.Close()
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "%v", err)
 			http.Error(w, "Failed to handle the uploaded file: "+files[i].Filename, 503)
 			return
 		}
+		defer file.Close()
 
 		out, err := ioutil.TempFile(TemporaryDirectory, filename)
-
-		defer out.Close()
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "%v", err)
 			http.Error(w, "Failed to handle the uploaded file: "+files[i].Filename, 503)
 			return
 		}
-		defer os.Remove(out.Name())
+		defer out.Close()
+		tempFiles = append(tempFiles, out.Name())
 
 		_, err = io.Copy(out, file)
 
@@ -97,27 +107,173 @@ func HandleDesignUpload(w http.ResponseWriter, r *http.Request) {
 		fileMeta := filesMeta[i]
 		fileMeta.FilePath = out.Name()
 		if fileMeta.Type == "def" {
-			if designFiles.DEF != nil {
-				http.Error(w, "Only one DEF file per design is supported", http.StatusBadRequest)
-				return
-			}
-			designFiles.DEF = &fileMeta
+			defs = append(defs, &fileMeta)
 		} else if fileMeta.Type == "lef" {
-			designFiles.LEF = append(designFiles.LEF, &fileMeta)
+			lefs = append(lefs, &fileMeta)
 		} else {
 			http.Error(w, "Invalid file type "+fileMeta.Type, http.StatusBadRequest)
 			return
 		}
 	}
-	design, err := goopendb.ParseDesignToJSON(designFiles, true)
+	return lefs, defs, cleanup, true
+}
+
+// receiveDesign stores the uploaded files of a design in temporary files, it
+// responds with an error and returns false on failure. The returned cleanup
+// removes the temporary files
+func receiveDesign(w http.ResponseWriter, r *http.Request) (designFiles *goopendb.DesignFiles, cleanup func(), ok bool) {
+	lefs, defs, cleanup, ok := receiveFiles(w, r)
+	if !ok {
+		return nil, cleanup, false
+	}
+	if len(defs) > 1 {
+		http.Error(w, "Only one DEF file per design is supported", http.StatusBadRequest)
+		return nil, cleanup, false
+	}
+	designFiles = &goopendb.DesignFiles{LEF: lefs}
+	if len(defs) == 1 {
+		designFiles.DEF = defs[0]
+	}
+	return designFiles, cleanup, true
+}
+
+// receiveOptions reads the options form value of the upload, it responds with
+// an error and returns false if the options are invalid
+func receiveOptions(w http.ResponseWriter, r *http.Request) (*goopendb.JSONOptions, bool) {
+	options := &goopendb.JSONOptions{}
+	if formOptions := r.MultipartForm.Value["options"]; len(formOptions) == 1 {
+		err := json.Unmarshal([]byte(formOptions[0]), options)
+		if err != nil {
+			http.Error(w, "Invalid design options", http.StatusBadRequest)
+			return nil, false
+		}
+	}
+	return options, true
+}
+
+// renderThumbnail sets the design thumbnail if the options ask for it,
+// rendering delays the response so it is only done on request
+func renderThumbnail(design *goopendb.Design, options *goopendb.JSONOptions) {
+	if !options.Thumbnail {
+		return
+	}
+	var err error
+	if design.Thumbnail, err = render.Thumbnail(design); err != nil {
+		fmt.Fprintf(os.Stderr, "Thumbnail error: %v\n", err)
+	}
+}
+
+// HandleDesignUpload handles user uploaded design
+func HandleDesignUpload(w http.ResponseWriter, r *http.Request) {
+	designFiles, cleanup, ok := receiveDesign(w, r)
+	defer cleanup()
+	if !ok {
+		return
+	}
+	options, ok := receiveOptions(w, r)
+	if !ok {
+		return
+	}
+	design, err := goopendb.ParseDesign(designFiles)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	w.Header().Add("Accept-Charset", "utf-8")
-	w.Header().Add("Content-Type", "application/json")
+	renderThumbnail(design, options)
+	if design.Markers, err = verify.Run(design, options.Checks); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	writeDesign(w, r, design, options)
+}
+
+// ProtobufContentType is the media type of the binary design format
+const ProtobufContentType = "application/x-protobuf"
+
+// acceptsProtobuf checks if the Accept header of the request asks for the binary design format
+func acceptsProtobuf(r *http.Request) bool {
+	for _, mediaType := range strings.Split(r.Header.Get("Accept"), ",") {
+		if strings.TrimSpace(strings.Split(mediaType, ";")[0]) == ProtobufContentType {
+			return true
+		}
+	}
+	return false
+}
+
+// writeDesign streams the gzipped design to the response, in the binary
+// format if the request accepts it and JSON otherwise
+func writeDesign(w http.ResponseWriter, r *http.Request, design *goopendb.Design, options *goopendb.JSONOptions) {
+	if acceptsProtobuf(r) {
+		options.Format = goopendb.FormatProtobuf
+	}
+	if err := options.Validate(design); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if options.Format == goopendb.FormatProtobuf {
+		w.Header().Add("Content-Type", ProtobufContentType)
+	} else {
+		w.Header().Add("Accept-Charset", "utf-8")
+		w.Header().Add("Content-Type", "application/json")
+	}
 	w.Header().Add("Content-Encoding", "gzip")
-	w.Write(design)
+	w.Header().Add("Vary", "Accept")
+	gz := gzip.NewWriter(w)
+	// The response has started, errors can only be logged
+	if err := goopendb.WriteDesign(gz, design, options); err != nil {
+		fmt.Fprintf(os.Stderr, "Design encoding error: %v\n", err)
+		return
+	}
+	gz.Close()
+}
+
+// layoutFormat is a layout export format
+type layoutFormat struct {
+	write     func(*goopendb.Design, goopendb.GDSLayerMap, io.Writer) error
+	extension string
+}
+
+// layoutFormats are the formats of the export API
+var layoutFormats = map[string]layoutFormat{
+	"gds":   {write: goopendb.WriteGDS, extension: ".gds"},
+	"oasis": {write: goopendb.WriteOASIS, extension: ".oas"},
+}
+
+// HandleLayoutExport exports the uploaded design in the layout format of the
+// URL (gds or oasis), the optional "layermap" form value maps LEF layer names
+// to layers as {"metal1": {"Layer": 11, "DataType": 0}}
+func HandleLayoutExport(w http.ResponseWriter, r *http.Request) {
+	format, ok := layoutFormats[chi.URLParam(r, "format")]
+	if !ok {
+		http.Error(w, "Unsupported export format "+chi.URLParam(r, "format"), http.StatusNotFound)
+		return
+	}
+	designFiles, cleanup, ok := receiveDesign(w, r)
+	defer cleanup()
+	if !ok {
+		return
+	}
+	var layers goopendb.GDSLayerMap
+	if formLayers := r.MultipartForm.Value["layermap"]; len(formLayers) == 1 {
+		err := json.Unmarshal([]byte(formLayers[0]), &layers)
+		if err != nil {
+			http.Error(w, "Invalid layer map", http.StatusBadRequest)
+			return
+		}
+	}
+	design, err := goopendb.ParseDesign(designFiles)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var buf bytes.Buffer
+	if err = format.write(design, layers, &buf); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Add("Content-Type", "application/octet-stream")
+	w.Header().Add("Content-Disposition", fmt.Sprintf("attachment; filename=%q", design.Name+format.extension))
+	w.Write(buf.Bytes())
 }
 
 // NewRouter returns the HTTP handler that implements the server login
@@ -142,12 +298,22 @@ func NewRouter() http.Handler {
 			"http://api.edaviewer.com",
 			"https://api.edaviewer.com",
 		},
-		AllowedMethods:   []string{"GET", "POST"},
+		AllowedMethods:   []string{"GET", "POST", "DELETE"},
 		AllowCredentials: true,
 	})
 	router.Use(corsRules.Handler)
 
 	router.Post("/", HandleDesignUpload)
+	router.Post("/export/{format}", HandleLayoutExport)
+	router.Post("/render/{format}", HandleRender)
+	router.Post("/diff", HandleDesignDiff)
+	router.Post("/designs", HandleDesignStore)
+	router.Get("/designs/{id}/region", HandleDesignRegion)
+	router.Get("/designs/{id}/render/{format}", HandleStoredRender)
+	router.Get("/designs/{id}/tiles", HandleTileMetadata)
+	router.Get("/designs/{id}/tiles/{z}/{x}/{y}", HandleTile)
+	router.Get("/designs/{id}/checks/{check}", HandleDesignCheck)
+	router.Delete("/designs/{id}", HandleDesignDelete)
 
 	return router
 }
@@ -0,0 +1,82 @@
+package handler
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/ahmed-agiza/EDAViewer/server/goopendb"
+	"github.com/ahmed-agiza/EDAViewer/server/lefdef"
+)
+
+const lefPath = "../example/Nangate45/NangateOpenCellLibrary.mod.lef"
+
+// defBackend is a native backend that requires a DEF file
+type defBackend struct {
+	goopendb.Backend
+}
+
+func init() {
+	goopendb.RegisterBackend("def-only", func() (goopendb.Backend, error) {
+		return defBackend{lefdef.NewBackend()}, nil
+	})
+}
+
+// uploadLEF posts the example LEF file as the only design file
+func uploadLEF(t *testing.T, backend string) *httptest.ResponseRecorder {
+	t.Helper()
+	defer os.Setenv(goopendb.BackendEnv, os.Getenv(goopendb.BackendEnv))
+	os.Setenv(goopendb.BackendEnv, backend)
+	lef, err := ioutil.ReadFile(lefPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	form.WriteField("meta", `[{"Type": "lef", "IsTech": true, "IsLibrary": true}]`)
+	file, err := form.CreateFormFile("files", "NangateOpenCellLibrary.lef")
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.Write(lef)
+	form.Close()
+	request := httptest.NewRequest("POST", "/", &body)
+	request.Header.Set("Content-Type", form.FormDataContentType())
+	recorder := httptest.NewRecorder()
+	HandleDesignUpload(recorder, request)
+	return recorder
+}
+
+func TestUploadLEFOnly(t *testing.T) {
+	recorder := uploadLEF(t, goopendb.BackendNative)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Unexpected response %v: %v", recorder.Code, recorder.Body.String())
+	}
+	gz, err := gzip.NewReader(recorder.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var design struct {
+		Layers  []json.RawMessage
+		Masters []json.RawMessage
+	}
+	if err = json.NewDecoder(gz).Decode(&design); err != nil {
+		t.Fatal(err)
+	}
+	if len(design.Layers) != 22 || len(design.Masters) != 134 {
+		t.Errorf("Expected 22 layers and 134 masters, found %v and %v", len(design.Layers), len(design.Masters))
+	}
+
+	// Backends without LEF only designs still require the DEF file
+	recorder = uploadLEF(t, "def-only")
+	if recorder.Code != http.StatusBadRequest || !strings.Contains(recorder.Body.String(), "DEF file is required") {
+		t.Errorf("Unexpected response %v: %v", recorder.Code, recorder.Body.String())
+	}
+}
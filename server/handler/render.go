@@ -0,0 +1,157 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/ahmed-agiza/EDAViewer/server/goopendb"
+	"github.com/ahmed-agiza/EDAViewer/server/render"
+	"github.com/go-chi/chi"
+)
+
+// splitList splits a comma separated query value
+func splitList(value string) []string {
+	if value == "" {
+		return nil
+	}
+	return strings.Split(value, ",")
+}
+
+// parseBBox parses a "xMin,yMin,xMax,yMax" rectangle in database units, nil
+// for an empty value
+func parseBBox(bbox string) (*goopendb.Rect, error) {
+	if bbox == "" {
+		return nil, nil
+	}
+	values := splitList(bbox)
+	if len(values) != 4 {
+		return nil, fmt.Errorf("Invalid bbox %v", bbox)
+	}
+	var coords [4]int
+	for i, v := range values {
+		var err error
+		if coords[i], err = strconv.Atoi(strings.TrimSpace(v)); err != nil {
+			return nil, fmt.Errorf("Invalid bbox %v", bbox)
+		}
+	}
+	return &goopendb.Rect{XMin: coords[0], YMin: coords[1], XMax: coords[2], YMax: coords[3]}, nil
+}
+
+// parseRenderQuery reads the render options of the query string: bbox (xMin,
+// yMin, xMax, yMax in database units), width, layers and objects as comma
+// separated lists, and colors as a JSON color scheme
+func parseRenderQuery(query url.Values) (*render.Options, error) {
+	options := &render.Options{
+		Layers:  splitList(query.Get("layers")),
+		Objects: splitList(query.Get("objects")),
+	}
+	var err error
+	if options.Viewport, err = parseBBox(query.Get("bbox")); err != nil {
+		return nil, err
+	}
+	if width := query.Get("width"); width != "" {
+		if options.Width, err = strconv.Atoi(width); err != nil {
+			return nil, fmt.Errorf("Invalid width %v", width)
+		}
+	}
+	if colors := query.Get("colors"); colors != "" {
+		options.Colors = render.DefaultColorScheme()
+		if err := json.Unmarshal([]byte(colors), options.Colors); err != nil {
+			return nil, fmt.Errorf("Invalid colors")
+		}
+	}
+	return options, nil
+}
+
+// imageFormat is a render image format
+type imageFormat struct {
+	write       func(*goopendb.Design, *render.Options, io.Writer) error
+	contentType string
+}
+
+// imageFormats are the formats of the render API
+var imageFormats = map[string]imageFormat{
+	"svg": {write: render.WriteSVG, contentType: "image/svg+xml"},
+	"png": {write: render.WritePNG, contentType: "image/png"},
+}
+
+// renderFormat returns the image format of the URL, it responds with an error
+// and returns false for unsupported formats
+func renderFormat(w http.ResponseWriter, r *http.Request) (imageFormat, bool) {
+	format, ok := imageFormats[chi.URLParam(r, "format")]
+	if !ok {
+		http.Error(w, "Unsupported image format "+chi.URLParam(r, "format"), http.StatusNotFound)
+	}
+	return format, ok
+}
+
+// writeImage renders the design to the response
+func writeImage(w http.ResponseWriter, design *goopendb.Design, options *render.Options, format imageFormat) {
+	var buf bytes.Buffer
+	if err := format.write(design, options, &buf); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Add("Content-Type", format.contentType)
+	w.Write(buf.Bytes())
+}
+
+// HandleRender renders the uploaded design in the image format of the URL
+// (svg or png). The render options are read from the query string, a
+// "render" form value with the JSON options takes precedence. Colors missing
+// from the JSON color scheme keep their default
+func HandleRender(w http.ResponseWriter, r *http.Request) {
+	format, ok := renderFormat(w, r)
+	if !ok {
+		return
+	}
+	options, err := parseRenderQuery(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	designFiles, cleanup, ok := receiveDesign(w, r)
+	defer cleanup()
+	if !ok {
+		return
+	}
+	if formOptions := r.MultipartForm.Value["render"]; len(formOptions) == 1 {
+		options = &render.Options{Colors: render.DefaultColorScheme()}
+		if err = json.Unmarshal([]byte(formOptions[0]), options); err != nil {
+			http.Error(w, "Invalid render options", http.StatusBadRequest)
+			return
+		}
+	}
+	design, err := goopendb.ParseDesign(designFiles)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	writeImage(w, design, options, format)
+}
+
+// HandleStoredRender renders a stored design in the image format of the URL
+// (svg or png), the render options are read from the query string
+func HandleStoredRender(w http.ResponseWriter, r *http.Request) {
+	format, ok := renderFormat(w, r)
+	if !ok {
+		return
+	}
+	options, err := parseRenderQuery(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	design := store.get(chi.URLParam(r, "id"))
+	if design == nil {
+		http.Error(w, "Design not found", http.StatusNotFound)
+		return
+	}
+	writeImage(w, design, options, format)
+}
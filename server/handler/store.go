@@ -0,0 +1,205 @@
+package handler
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ahmed-agiza/EDAViewer/server/goopendb"
+	"github.com/ahmed-agiza/EDAViewer/server/tiles"
+	"github.com/go-chi/chi"
+)
+
+// StoreLimit is the maximum number of designs kept by the server, the least
+// recently used design is dropped first
+const StoreLimit = 16
+
+// StoreTimeout is the time a design is kept after its last use
+const StoreTimeout = 30 * time.Minute
+
+// storedDesign is a parsed design kept for region requests
+type storedDesign struct {
+	design   *goopendb.Design
+	pyramid  *tiles.Pyramid // Nil for designs without tiles
+	tilesErr error          // Why the design has no tiles
+	lastUsed time.Time
+}
+
+// designStore keeps parsed designs in memory by ID
+type designStore struct {
+	sync.Mutex
+	designs map[string]*storedDesign
+}
+
+var store = &designStore{designs: make(map[string]*storedDesign)}
+
+// expire drops the timed out designs and the least recently used designs
+// above the limit, the store must be locked
+func (s *designStore) expire(now time.Time) {
+	for id, stored := range s.designs {
+		if now.Sub(stored.lastUsed) > StoreTimeout {
+			delete(s.designs, id)
+		}
+	}
+	for len(s.designs) > StoreLimit {
+		var oldest string
+		for id, stored := range s.designs {
+			if oldest == "" || stored.lastUsed.Before(s.designs[oldest].lastUsed) {
+				oldest = id
+			}
+		}
+		delete(s.designs, oldest)
+	}
+}
+
+// add stores the design and returns its ID
+func (s *designStore) add(design *goopendb.Design) (string, error) {
+	idBytes := make([]byte, 16)
+	if _, err := rand.Read(idBytes); err != nil {
+		return "", err
+	}
+	id := hex.EncodeToString(idBytes)
+	// Built once so concurrent requests only read the index and the tiles
+	design.BuildIndex()
+	pyramid, tilesErr := tiles.NewPyramid(design)
+	if tilesErr == nil {
+		tilesErr = pyramid.Precompute()
+	}
+	if tilesErr != nil {
+		fmt.Fprintf(os.Stderr, "Tiles error: %v\n", tilesErr)
+		pyramid = nil
+	}
+	s.Lock()
+	defer s.Unlock()
+	now := time.Now()
+	s.designs[id] = &storedDesign{design: design, pyramid: pyramid, tilesErr: tilesErr, lastUsed: now}
+	s.expire(now)
+	return id, nil
+}
+
+// lookup returns the stored design of the ID, nil if it is not stored
+func (s *designStore) lookup(id string) *storedDesign {
+	s.Lock()
+	defer s.Unlock()
+	now := time.Now()
+	s.expire(now)
+	stored := s.designs[id]
+	if stored != nil {
+		stored.lastUsed = now
+	}
+	return stored
+}
+
+// get returns the design of the ID, nil if it is not stored
+func (s *designStore) get(id string) *goopendb.Design {
+	if stored := s.lookup(id); stored != nil {
+		return stored.design
+	}
+	return nil
+}
+
+// remove drops the design of the ID, it returns false if it is not stored
+func (s *designStore) remove(id string) bool {
+	s.Lock()
+	defer s.Unlock()
+	_, ok := s.designs[id]
+	delete(s.designs, id)
+	return ok
+}
+
+// StoredDesign describes a design kept by the server
+type StoredDesign struct {
+	ID           string
+	Name         string
+	DBUPerMicron int
+	BoundingBox  *goopendb.Rect `json:",omitempty"`
+	Layers       []string
+	Thumbnail    string `json:",omitempty"` // Preview image URL
+}
+
+// HandleDesignStore parses the uploaded design and keeps it for region
+// requests, it responds with the design ID and summary
+func HandleDesignStore(w http.ResponseWriter, r *http.Request) {
+	designFiles, cleanup, ok := receiveDesign(w, r)
+	defer cleanup()
+	if !ok {
+		return
+	}
+	options, ok := receiveOptions(w, r)
+	if !ok {
+		return
+	}
+	design, err := goopendb.ParseDesign(designFiles)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	renderThumbnail(design, options)
+	id, err := store.add(design)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		http.Error(w, "Failed to store the design", http.StatusInternalServerError)
+		return
+	}
+	summary := &StoredDesign{
+		ID:           id,
+		Name:         design.Name,
+		DBUPerMicron: design.DBUPerMicron,
+		Thumbnail:    design.Thumbnail,
+	}
+	if design.BoundingBox != nil {
+		bbox := design.BoundingBox.Copy()
+		bbox.Layer, bbox.Via = nil, nil
+		summary.BoundingBox = &bbox
+	}
+	for _, layer := range design.Layers {
+		summary.Layers = append(summary.Layers, layer.Name)
+	}
+	w.Header().Add("Accept-Charset", "utf-8")
+	w.Header().Add("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(summary)
+}
+
+// HandleDesignRegion responds with the design JSON of the objects intersecting
+// the bbox query value (the whole design by default), layers filters the
+// shapes by layer name and lod is the level of detail in database units
+func HandleDesignRegion(w http.ResponseWriter, r *http.Request) {
+	design := store.get(chi.URLParam(r, "id"))
+	if design == nil {
+		http.Error(w, "Design not found", http.StatusNotFound)
+		return
+	}
+	query := r.URL.Query()
+	options := &goopendb.RegionOptions{Layers: splitList(query.Get("layers"))}
+	if lod := query.Get("lod"); lod != "" {
+		var err error
+		if options.LOD, err = strconv.Atoi(lod); err != nil || options.LOD < 0 {
+			http.Error(w, "Invalid lod "+lod, http.StatusBadRequest)
+			return
+		}
+	}
+	bbox, err := parseBBox(query.Get("bbox"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	writeDesign(w, r, design.Region(bbox, options), &goopendb.JSONOptions{
+		IncludeFills: query.Get("fills") == "true",
+		Microns:      query.Get("units") == goopendb.UnitsMicron,
+	})
+}
+
+// HandleDesignDelete drops a stored design
+func HandleDesignDelete(w http.ResponseWriter, r *http.Request) {
+	if !store.remove(chi.URLParam(r, "id")) {
+		http.Error(w, "Design not found", http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
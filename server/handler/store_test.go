@@ -0,0 +1,28 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ahmed-agiza/EDAViewer/server/goopendb"
+)
+
+func TestStoreTilesError(t *testing.T) {
+	id, err := store.add(&goopendb.Design{Name: "empty"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.remove(id)
+	stored := store.lookup(id)
+	if stored == nil || stored.pyramid != nil || stored.tilesErr == nil {
+		t.Fatalf("Expected a stored design without tiles, found %+v", stored)
+	}
+
+	recorder := httptest.NewRecorder()
+	NewRouter().ServeHTTP(recorder, httptest.NewRequest("GET", "/designs/"+id+"/tiles", nil))
+	if recorder.Code != http.StatusBadRequest || !strings.Contains(recorder.Body.String(), stored.tilesErr.Error()) {
+		t.Errorf("Unexpected response %v: %v", recorder.Code, recorder.Body.String())
+	}
+}
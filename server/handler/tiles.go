@@ -0,0 +1,60 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/ahmed-agiza/EDAViewer/server/tiles"
+	"github.com/go-chi/chi"
+)
+
+// storedPyramid returns the tile pyramid of the stored design of the URL, it
+// responds with an error and returns nil on failure. Pyramids are built when
+// the design is stored
+func storedPyramid(w http.ResponseWriter, r *http.Request) *tiles.Pyramid {
+	stored := store.lookup(chi.URLParam(r, "id"))
+	if stored == nil {
+		http.Error(w, "Design not found", http.StatusNotFound)
+		return nil
+	}
+	if stored.pyramid == nil {
+		http.Error(w, stored.tilesErr.Error(), http.StatusBadRequest)
+		return nil
+	}
+	return stored.pyramid
+}
+
+// HandleTileMetadata responds with the tiling of a stored design
+func HandleTileMetadata(w http.ResponseWriter, r *http.Request) {
+	pyramid := storedPyramid(w, r)
+	if pyramid == nil {
+		return
+	}
+	w.Header().Add("Accept-Charset", "utf-8")
+	w.Header().Add("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(pyramid.Metadata)
+}
+
+// HandleTile responds with a binary vector tile of a stored design
+func HandleTile(w http.ResponseWriter, r *http.Request) {
+	var zxy [3]int
+	for i, param := range []string{"z", "x", "y"} {
+		var err error
+		if zxy[i], err = strconv.Atoi(chi.URLParam(r, param)); err != nil {
+			http.Error(w, "Invalid tile "+param, http.StatusBadRequest)
+			return
+		}
+	}
+	pyramid := storedPyramid(w, r)
+	if pyramid == nil {
+		return
+	}
+	tile, err := pyramid.EncodedTile(zxy[0], zxy[1], zxy[2])
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	w.Header().Add("Content-Type", "application/octet-stream")
+	w.Write(tile)
+}
@@ -0,0 +1,32 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/ahmed-agiza/EDAViewer/server/goopendb"
+	"github.com/ahmed-agiza/EDAViewer/server/verify"
+	"github.com/go-chi/chi"
+)
+
+// HandleDesignCheck runs a verification check on a stored design and responds
+// with the violation markers
+func HandleDesignCheck(w http.ResponseWriter, r *http.Request) {
+	check, ok := verify.Checks[chi.URLParam(r, "check")]
+	if !ok {
+		http.Error(w, "Unsupported check "+chi.URLParam(r, "check"), http.StatusBadRequest)
+		return
+	}
+	design := store.get(chi.URLParam(r, "id"))
+	if design == nil {
+		http.Error(w, "Design not found", http.StatusNotFound)
+		return
+	}
+	markers := check(design)
+	if markers == nil {
+		markers = []*goopendb.Marker{}
+	}
+	w.Header().Add("Accept-Charset", "utf-8")
+	w.Header().Add("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(markers)
+}
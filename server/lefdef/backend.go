@@ -0,0 +1,111 @@
+package lefdef
+
+// Pure Go LEF/DEF parsing backend
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/ahmed-agiza/EDAViewer/server/goopendb"
+)
+
+func init() {
+	goopendb.RegisterBackend(goopendb.BackendNative, func() (goopendb.Backend, error) {
+		return NewBackend(), nil
+	})
+}
+
+// Backend is a goopendb.Backend implemented in pure Go
+type Backend struct {
+	Library *Library
+	design  *goopendb.Design
+}
+
+// NewBackend creates a new native backend
+func NewBackend() *Backend {
+	return &Backend{Library: NewLibrary()}
+}
+
+func libraryName(filepath string) string {
+	return strings.TrimSuffix(path.Base(filepath), path.Ext(filepath))
+}
+
+func (b *Backend) readLEF(filepath string, tech bool, cells bool) error {
+	file, err := os.Open(filepath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	err = b.Library.ReadLEF(file, libraryName(filepath), tech, cells)
+	if err != nil {
+		return fmt.Errorf("%v: %v", path.Base(filepath), err)
+	}
+	return nil
+}
+
+// ParseLEF reads the library and technology section of a LEF file
+func (b *Backend) ParseLEF(filepath string) error {
+	return b.readLEF(filepath, true, true)
+}
+
+// ParseLEFTechnology reads the technology section of a LEF file
+func (b *Backend) ParseLEFTechnology(filepath string) error {
+	return b.readLEF(filepath, true, false)
+}
+
+// ParseLEFLibrary reads the library section of a LEF file
+func (b *Backend) ParseLEFLibrary(filepath string) error {
+	return b.readLEF(filepath, false, true)
+}
+
+// ParseDEF reads a design DEF file, the LEF files must be parsed first
+func (b *Backend) ParseDEF(filepath string) error {
+	file, err := os.Open(filepath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	design, err := b.Library.ReadDEF(file)
+	if err != nil {
+		return fmt.Errorf("%v: %v", path.Base(filepath), err)
+	}
+	b.design = design
+	return nil
+}
+
+// SupportsLEFOnly reports that designs can be built without a DEF file
+func (b *Backend) SupportsLEFOnly() bool {
+	return true
+}
+
+// GetDesign builds the design from the parsed files, without a DEF file the
+// LEF vias are reported as via definitions
+func (b *Backend) GetDesign() (*goopendb.Design, error) {
+	lib := b.Library
+	if len(lib.Layers) == 0 {
+		return nil, fmt.Errorf("LEF technology is required")
+	}
+	if b.design != nil {
+		return b.design, nil
+	}
+	design := &goopendb.Design{
+		DBUPerMicron:   lib.DBUPerMicron,
+		LEFUnits:       lib.LEFUnits,
+		Layers:         lib.Layers,
+		ViaDefinitions: lib.Vias,
+		ViaRules:       lib.ViaRules,
+		Sites:          lib.Sites,
+		Masters:        lib.Masters,
+		Geometries:     lib.Geometries,
+	}
+	return design, nil
+}
+
+// FreeDatabase releases the parsed data
+func (b *Backend) FreeDatabase() error {
+	b.Library = NewLibrary()
+	b.design = nil
+	return nil
+}
@@ -0,0 +1,1678 @@
+package lefdef
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+
+	"github.com/ahmed-agiza/EDAViewer/server/goopendb"
+)
+
+// DefaultDEFUnits is the DEF distance precision when UNITS is missing
+const DefaultDEFUnits = 100
+
+var defOrientations = map[string]goopendb.Orientation{
+	"N":  goopendb.OrientationR0,
+	"W":  goopendb.OrientationR90,
+	"S":  goopendb.OrientationR180,
+	"E":  goopendb.OrientationR270,
+	"FN": goopendb.OrientationMY,
+	"FE": goopendb.OrientationMYR90,
+	"FS": goopendb.OrientationMX,
+	"FW": goopendb.OrientationMXR90,
+}
+
+// fillKey groups fill shapes the same way as the OpenDB backend
+type fillKey struct {
+	layer int
+	mask  int
+	opc   bool
+}
+
+// defReader holds the state of a DEF file being read into a design
+type defReader struct {
+	lex    *lexer
+	lib    *Library
+	design *goopendb.Design
+	scale  float64 // Database units per DEF distance unit
+	rectID int
+
+	hasName            bool
+	instanceMap        map[string]*goopendb.Instance
+	netMap             map[string]*goopendb.Net
+	blockPinMap        map[string]*goopendb.Pin
+	viaMap             map[string]*goopendb.Via // DEF VIAS
+	usedVias           map[*goopendb.Via]bool   // LEF vias used by the routing
+	regionMap          map[string]*goopendb.Region
+	trackMap           map[*goopendb.Layer]*goopendb.Grid
+	placementBlockages []*goopendb.Blockage
+	routingBlockages   []*goopendb.Blockage
+	fillMap            map[fillKey]*goopendb.Fill
+	specialWireID      int
+	bbox               box
+	hasBBox            bool
+}
+
+// ReadDEF reads a DEF file into a design using the library technology and
+// cells, coordinates are converted to the library database units
+func (lib *Library) ReadDEF(r io.Reader) (*goopendb.Design, error) {
+	if len(lib.Layers) == 0 {
+		return nil, fmt.Errorf("LEF technology is required")
+	}
+	lex, err := newLexer(r)
+	if err != nil {
+		return nil, err
+	}
+	if lib.DBUPerMicron == 0 {
+		lib.DBUPerMicron = DefaultLEFUnits
+	}
+	reader := &defReader{
+		lex: lex,
+		lib: lib,
+		design: &goopendb.Design{
+			DBUPerMicron: lib.DBUPerMicron,
+			LEFUnits:     lib.LEFUnits,
+			DEFUnits:     DefaultDEFUnits,
+			Layers:       lib.Layers,
+			ViaRules:     lib.ViaRules,
+			Sites:        lib.Sites,
+			Masters:      lib.Masters,
+			Geometries:   append([]*goopendb.Geometry(nil), lib.Geometries...),
+		},
+		scale:       float64(lib.DBUPerMicron) / DefaultDEFUnits,
+		rectID:      len(lib.rects),
+		instanceMap: make(map[string]*goopendb.Instance),
+		netMap:      make(map[string]*goopendb.Net),
+		blockPinMap: make(map[string]*goopendb.Pin),
+		viaMap:      make(map[string]*goopendb.Via),
+		usedVias:    make(map[*goopendb.Via]bool),
+		regionMap:   make(map[string]*goopendb.Region),
+		trackMap:    make(map[*goopendb.Layer]*goopendb.Grid),
+		fillMap:     make(map[fillKey]*goopendb.Fill),
+	}
+	if err = reader.read(); err != nil {
+		return nil, err
+	}
+	return reader.design, nil
+}
+
+func (r *defReader) read() error {
+	lex := r.lex
+	for !lex.done() {
+		keyword := lex.next()
+		var err error
+		switch keyword {
+		case "DESIGN":
+			r.design.Name = lex.next()
+			r.hasName = true
+			lex.skipStatement()
+		case "UNITS":
+			err = r.readUnits()
+		case "DIEAREA":
+			var points []goopendb.Point
+			if points, err = r.readPoints(); err != nil {
+				return err
+			}
+			lex.skipStatement()
+			if len(points) < 2 {
+				return lex.errorf("DIEAREA requires at least 2 points")
+			}
+			die, _ := bboxOf(pointBoxes(points))
+			r.design.Die = r.newRect(die, nil, nil)
+		case "ROW":
+			err = r.readRow()
+		case "TRACKS":
+			err = r.readTracks(false)
+		case "GCELLGRID":
+			err = r.readTracks(true)
+		case "VIAS":
+			err = r.readSection(keyword, r.readViaDefinition)
+		case "REGIONS":
+			err = r.readSection(keyword, r.readRegion)
+		case "COMPONENTS":
+			err = r.readSection(keyword, r.readComponent)
+		case "PINS":
+			err = r.readSection(keyword, r.readBlockPin)
+		case "BLOCKAGES":
+			err = r.readSection(keyword, r.readBlockage)
+		case "FILLS":
+			err = r.readSection(keyword, r.readFill)
+		case "SPECIALNETS":
+			err = r.readSection(keyword, func() error { return r.readNet(true) })
+		case "NETS":
+			err = r.readSection(keyword, func() error { return r.readNet(false) })
+		case "GROUPS":
+			err = r.readSection(keyword, r.readGroup)
+		case "PROPERTYDEFINITIONS", "NONDEFAULTRULES", "STYLES", "SLOTS", "PINPROPERTIES", "SCANCHAINS":
+			err = lex.skipBlock(keyword)
+		case "BEGINEXT":
+			for !lex.done() && lex.next() != "ENDEXT" {
+			}
+		case "END":
+			if !lex.accept("DESIGN") {
+				return lex.errorf("unexpected END %v", lex.next())
+			}
+			if !r.hasName {
+				return fmt.Errorf("DEF error, missing or wrong design header")
+			}
+			r.finish()
+			return nil
+		default:
+			lex.skipStatement()
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return lex.errorf("missing END DESIGN")
+}
+
+func (r *defReader) readUnits() error {
+	lex := r.lex
+	if err := lex.expect("DISTANCE"); err != nil {
+		return err
+	}
+	if err := lex.expect("MICRONS"); err != nil {
+		return err
+	}
+	units, err := lex.integer()
+	if err != nil {
+		return err
+	}
+	lex.skipStatement()
+	dbu := r.lib.DBUPerMicron
+	if units <= 0 || units > dbu || dbu%units != 0 {
+		return lex.errorf("DEF units %v do not divide the LEF database units %v", units, dbu)
+	}
+	r.design.DEFUnits = units
+	r.scale = float64(dbu) / float64(units)
+	return nil
+}
+
+// readSection reads the "- item ... ;" entries of a section up to its END
+func (r *defReader) readSection(name string, item func() error) error {
+	lex := r.lex
+	lex.skipStatement() // Item count
+	for {
+		switch tok := lex.next(); tok {
+		case "-":
+			if err := item(); err != nil {
+				return err
+			}
+		case "END":
+			return lex.expect(name)
+		case "":
+			return lex.errorf("missing END %v", name)
+		default:
+			return lex.errorf("unexpected %q in %v", tok, name)
+		}
+	}
+}
+
+// skipOption consumes the arguments of an unsupported "+ KEYWORD" option
+func (r *defReader) skipOption() {
+	lex := r.lex
+	for !lex.done() && lex.peek() != "+" && lex.peek() != ";" {
+		lex.next()
+	}
+}
+
+// acceptMask consumes an optional "MASK n" or "+ MASK n" and returns n
+func (r *defReader) acceptMask() int {
+	lex := r.lex
+	if lex.peek() == "+" && lex.peekAt(1) == "MASK" {
+		lex.next()
+	}
+	if !lex.accept("MASK") {
+		return 0
+	}
+	mask, _ := lex.integer()
+	return mask
+}
+
+// dist converts a DEF distance to database units
+func (r *defReader) dist(v float64) int {
+	return int(math.Round(v * r.scale))
+}
+
+func (r *defReader) readDist() (int, error) {
+	v, err := r.lex.float()
+	return r.dist(v), err
+}
+
+// readPoint reads "( x y [ext] )", "*" repeats the coordinate of prev
+func (r *defReader) readPoint(prev goopendb.Point) (pt goopendb.Point, ext int, hasExt bool, err error) {
+	lex := r.lex
+	if err = lex.expect("("); err != nil {
+		return
+	}
+	pt = prev
+	for _, coord := range []*int{&pt.X, &pt.Y} {
+		if lex.accept("*") {
+			continue
+		}
+		if *coord, err = r.readDist(); err != nil {
+			return
+		}
+	}
+	if lex.isNumber() {
+		ext, _ = r.readDist()
+		hasExt = true
+	}
+	err = lex.expect(")")
+	return
+}
+
+// readPoints reads consecutive points
+func (r *defReader) readPoints() ([]goopendb.Point, error) {
+	var points []goopendb.Point
+	var prev goopendb.Point
+	for r.lex.peek() == "(" {
+		pt, _, _, err := r.readPoint(prev)
+		if err != nil {
+			return nil, err
+		}
+		points = append(points, pt)
+		prev = pt
+	}
+	return points, nil
+}
+
+// readRect reads a rectangle given by two corner points
+func (r *defReader) readRect() (box, error) {
+	p, _, _, err := r.readPoint(goopendb.Point{})
+	if err != nil {
+		return box{}, err
+	}
+	q, _, _, err := r.readPoint(p)
+	if err != nil {
+		return box{}, err
+	}
+	return newBox(p.X, p.Y, q.X, q.Y), nil
+}
+
+// readPolygon reads polygon points and decomposes them into boxes
+func (r *defReader) readPolygon() ([]box, error) {
+	points, err := r.readPoints()
+	if err != nil {
+		return nil, err
+	}
+	if len(points) < 3 {
+		return nil, r.lex.errorf("POLYGON requires at least 3 points")
+	}
+	return polygonToBoxes(points), nil
+}
+
+func (r *defReader) readOrientation() (goopendb.Orientation, error) {
+	tok := r.lex.next()
+	orient, ok := defOrientations[tok]
+	if !ok {
+		return goopendb.OrientationR0, r.lex.errorf("unknown orientation %q", tok)
+	}
+	return orient, nil
+}
+
+// newRect creates a design shape
+func (r *defReader) newRect(b box, layer *goopendb.Layer, via *goopendb.Via) *goopendb.Rect {
+	r.rectID++
+	return &goopendb.Rect{
+		ID:        r.rectID,
+		XMin:      b.xMin,
+		YMin:      b.yMin,
+		XMax:      b.xMax,
+		YMax:      b.yMax,
+		ShapeType: -1,
+		Layer:     layer,
+		Via:       via,
+	}
+}
+
+// newGeometry creates a design geometry
+func (r *defReader) newGeometry(boxes []*goopendb.Rect) *goopendb.Geometry {
+	geom := &goopendb.Geometry{
+		ID:    len(r.design.Geometries) + 1,
+		Boxes: boxes,
+	}
+	r.design.Geometries = append(r.design.Geometries, geom)
+	return geom
+}
+
+// extend grows the design bounding box to cover b
+func (r *defReader) extend(b box) {
+	if !r.hasBBox {
+		r.bbox = b
+		r.hasBBox = true
+		return
+	}
+	r.bbox = r.bbox.merge(b)
+}
+
+func (r *defReader) readRow() error {
+	lex := r.lex
+	name := lex.next()
+	siteName := lex.next()
+	site := r.lib.Site(siteName)
+	if site == nil {
+		return lex.errorf("unknown site %v", siteName)
+	}
+	x, err := r.readDist()
+	if err != nil {
+		return err
+	}
+	y, err := r.readDist()
+	if err != nil {
+		return err
+	}
+	orient, err := r.readOrientation()
+	if err != nil {
+		return err
+	}
+	countX, countY := 1, 1
+	stepX, stepY := 0, 0
+	if lex.accept("DO") {
+		if countX, err = lex.integer(); err != nil {
+			return err
+		}
+		if err = lex.expect("BY"); err != nil {
+			return err
+		}
+		if countY, err = lex.integer(); err != nil {
+			return err
+		}
+		if lex.accept("STEP") {
+			if stepX, err = r.readDist(); err != nil {
+				return err
+			}
+			if stepY, err = r.readDist(); err != nil {
+				return err
+			}
+		}
+	}
+	lex.skipStatement()
+
+	row := &goopendb.Row{
+		ID:          len(r.design.Rows) + 1,
+		Name:        name,
+		Site:        site,
+		Direction:   goopendb.DirectionHORIZONTAL,
+		Orientation: orient,
+		OriginX:     x,
+		OriginY:     y,
+		Spacing:     stepX,
+		SiteCount:   countX,
+	}
+	if countY > 1 {
+		row.Direction = goopendb.DirectionVERTICAL
+		row.Spacing = stepY
+		row.SiteCount = countY
+	}
+	width, height := 0, 0
+	if info := r.lib.SiteInfo[siteName]; info != nil {
+		width, height = info.Width, info.Height
+	}
+	switch orient {
+	case goopendb.OrientationR90, goopendb.OrientationR270, goopendb.OrientationMYR90, goopendb.OrientationMXR90:
+		width, height = height, width
+	}
+	b := box{xMin: x, yMin: y, xMax: x + width, yMax: y + height}
+	if row.Direction == goopendb.DirectionHORIZONTAL {
+		b.xMax += (row.SiteCount - 1) * row.Spacing
+	} else {
+		b.yMax += (row.SiteCount - 1) * row.Spacing
+	}
+	row.BoundingBox = r.newRect(b, nil, nil)
+	r.design.Rows = append(r.design.Rows, row)
+	return nil
+}
+
+// readTracks reads a TRACKS or GCELLGRID statement, track patterns are
+// grouped in a grid per layer
+func (r *defReader) readTracks(gcell bool) error {
+	lex := r.lex
+	axis := lex.next()
+	if axis != "X" && axis != "Y" {
+		return lex.errorf("expected X or Y, found %q", axis)
+	}
+	origin, err := r.readDist()
+	if err != nil {
+		return err
+	}
+	if err = lex.expect("DO"); err != nil {
+		return err
+	}
+	count, err := lex.integer()
+	if err != nil {
+		return err
+	}
+	if err = lex.expect("STEP"); err != nil {
+		return err
+	}
+	step, err := r.readDist()
+	if err != nil {
+		return err
+	}
+	var grids []*goopendb.Grid
+	for !lex.done() && lex.peek() != ";" {
+		if lex.next() != "LAYER" {
+			continue
+		}
+		for !lex.done() && lex.peek() != ";" {
+			name := lex.next()
+			layer := r.lib.Layer(name)
+			if layer == nil {
+				return lex.errorf("unknown layer %v", name)
+			}
+			grid := r.trackMap[layer]
+			if grid == nil {
+				grid = &goopendb.Grid{ID: len(r.design.Tracks) + 1, Layer: layer}
+				r.trackMap[layer] = grid
+				r.design.Tracks = append(r.design.Tracks, grid)
+			}
+			grids = append(grids, grid)
+		}
+	}
+	lex.skipStatement()
+	if gcell {
+		if r.design.GCell == nil {
+			r.design.GCell = &goopendb.Grid{ID: 1}
+		}
+		grids = []*goopendb.Grid{r.design.GCell}
+	}
+	for _, grid := range grids {
+		if axis == "X" {
+			grid.GridXPatternOrigins = append(grid.GridXPatternOrigins, origin)
+			grid.GridXPatternLineCounts = append(grid.GridXPatternLineCounts, count)
+			grid.GridXPatternSteps = append(grid.GridXPatternSteps, step)
+		} else {
+			grid.GridYPatternOrigins = append(grid.GridYPatternOrigins, origin)
+			grid.GridYPatternLineCounts = append(grid.GridYPatternLineCounts, count)
+			grid.GridYPatternSteps = append(grid.GridYPatternSteps, step)
+		}
+	}
+	return nil
+}
+
+// gridLines expands grid patterns into sorted unique coordinates
+func gridLines(origins, counts, steps []int) []int {
+	seen := make(map[int]bool)
+	var lines []int
+	for i := range origins {
+		for j := 0; j < counts[i]; j++ {
+			line := origins[i] + j*steps[i]
+			if !seen[line] {
+				seen[line] = true
+				lines = append(lines, line)
+			}
+		}
+	}
+	sort.Ints(lines)
+	return lines
+}
+
+func (r *defReader) readViaDefinition() error {
+	lex := r.lex
+	name := lex.next()
+	via := &goopendb.Via{
+		ID:   len(r.lib.Vias) + len(r.design.ViaDefinitions) + 1, // After the LEF via IDs
+		Name: name,
+	}
+	var params *goopendb.ViaParams
+	var layers [3]*goopendb.Layer // Generated via bottom, cut and top layers
+	var boxes []*goopendb.Rect
+	for {
+		switch tok := lex.next(); tok {
+		case ";":
+			if params != nil {
+				via.Params = params
+				via.CutLayer = layers[1]
+				for _, shape := range viaParamsShapes(params) {
+					boxes = append(boxes, r.newRect(shape.box, layers[shape.layer], nil))
+				}
+			}
+			via.Boxes = boxes
+			var bbox box
+			bbox, via.BottomLayer, via.TopLayer = viaBounds(boxes)
+			via.Rect = r.newRect(bbox, nil, nil)
+			r.design.ViaDefinitions = append(r.design.ViaDefinitions, via)
+			r.viaMap[name] = via
+			return nil
+		case "+":
+			keyword := lex.next()
+			switch keyword {
+			case "VIARULE":
+				via.Rule = r.lib.ViaRule(lex.next())
+				if params == nil {
+					params = &goopendb.ViaParams{Rows: 1, Cols: 1}
+				}
+			case "CUTSIZE", "CUTSPACING", "ENCLOSURE", "ROWCOL", "ORIGIN", "OFFSET":
+				if params == nil {
+					params = &goopendb.ViaParams{Rows: 1, Cols: 1}
+				}
+				if err := setViaParam(lex, params, keyword, lex.floats(), r.dist); err != nil {
+					return err
+				}
+			case "LAYERS":
+				for i := range layers {
+					layers[i] = r.lib.Layer(lex.next())
+				}
+			case "PATTERN":
+				via.Pattern = lex.next()
+			case "RECT", "POLYGON":
+				layerName := lex.next()
+				layer := r.lib.Layer(layerName)
+				if layer == nil {
+					return lex.errorf("unknown layer %v in via %v", layerName, name)
+				}
+				r.acceptMask()
+				var shapes []box
+				var err error
+				if keyword == "RECT" {
+					var b box
+					b, err = r.readRect()
+					shapes = []box{b}
+				} else {
+					shapes, err = r.readPolygon()
+				}
+				if err != nil {
+					return err
+				}
+				for _, b := range shapes {
+					boxes = append(boxes, r.newRect(b, layer, nil))
+				}
+			default:
+				r.skipOption()
+			}
+		default:
+			return lex.errorf("unexpected %q in via %v", tok, name)
+		}
+	}
+}
+
+func (r *defReader) readRegion() error {
+	lex := r.lex
+	name := lex.next()
+	region := &goopendb.Region{
+		ID:   len(r.design.Regions) + 1,
+		Name: name,
+		Type: goopendb.RegionTypeFENCE,
+	}
+	for {
+		switch tok := lex.peek(); tok {
+		case "(":
+			b, err := r.readRect()
+			if err != nil {
+				return err
+			}
+			region.Boxes = append(region.Boxes, r.newRect(b, nil, nil))
+		case ";":
+			lex.next()
+			r.design.Regions = append(r.design.Regions, region)
+			r.regionMap[name] = region
+			return nil
+		case "+":
+			lex.next()
+			if lex.next() == "TYPE" {
+				switch lex.next() {
+				case "GUIDE":
+					region.Type = goopendb.RegionTypeGUIDE
+				case "EXCLUSIVE":
+					region.Type = goopendb.RegionTypeEXCLUSIVE
+				default:
+					region.Type = goopendb.RegionTypeFENCE
+				}
+			} else {
+				r.skipOption()
+			}
+		default:
+			return lex.errorf("unexpected %q in region %v", tok, name)
+		}
+	}
+}
+
+func (r *defReader) readComponent() error {
+	lex := r.lex
+	name := lex.next()
+	masterName := lex.next()
+	master := r.lib.Master(masterName)
+	if master == nil {
+		return lex.errorf("unknown master %v of component %v", masterName, name)
+	}
+	inst := &goopendb.Instance{
+		ID:           len(r.design.Instances) + 1,
+		Name:         name,
+		Orientation:  goopendb.OrientationR0,
+		Master:       masterName,
+		IsFiller:     master.IsFiller,
+		MasterType:   master.Type,
+		Obstructions: master.Obstructions,
+		MasterRef:    master,
+	}
+	var location goopendb.Point
+	var halo box
+	for {
+		tok := lex.next()
+		if tok == ";" {
+			break
+		}
+		if tok != "+" {
+			return lex.errorf("unexpected %q in component %v", tok, name)
+		}
+		var err error
+		switch keyword := lex.next(); keyword {
+		case "PLACED", "FIXED", "COVER", "UNPLACED":
+			inst.IsPlaced = keyword != "UNPLACED"
+			inst.Status = placementStatus(keyword)
+			if lex.peek() != "(" {
+				break
+			}
+			if location, _, _, err = r.readPoint(goopendb.Point{}); err != nil {
+				return err
+			}
+			if inst.Orientation, err = r.readOrientation(); err != nil {
+				return err
+			}
+		case "HALO":
+			lex.accept("SOFT")
+			var values [4]int
+			for i := range values {
+				if values[i], err = r.readDist(); err != nil {
+					return err
+				}
+			}
+			// Halo margins are kept as is, like the OpenDB backend
+			halo = box{xMin: values[0], yMin: values[1], xMax: values[2], yMax: values[3]}
+		case "REGION":
+			regionName := lex.next()
+			region := r.regionMap[regionName]
+			if region == nil {
+				return lex.errorf("unknown region %v of component %v", regionName, name)
+			}
+			inst.Region = region
+			region.Instances = append(region.Instances, inst)
+		default:
+			r.skipOption()
+		}
+	}
+
+	t := newTransform(inst.Orientation, location.X, location.Y, master.Width, master.Height)
+	bbox := t.box(box{xMax: master.Width, yMax: master.Height})
+	originX, originY := t.point(0, 0)
+	inst.Location = &goopendb.Point{X: bbox.xMin, Y: bbox.yMin}
+	inst.Origin = &goopendb.Point{X: originX, Y: originY}
+	inst.BoundingBox = r.newRect(bbox, nil, nil)
+	inst.Halo = r.newRect(halo, nil, nil)
+	r.extend(bbox)
+	for _, mpin := range master.Pins {
+		pin := &goopendb.Pin{
+			ID:         len(r.design.InstancePins) + 1,
+			Name:       mpin.Name,
+			Instance:   inst,
+			Direction:  goopendb.Direction(mpin.Direction),
+			Location:   pinLocation(t, mpin.Geometries, inst.Origin),
+			Geometries: mpin.Geometries,
+			SignalType: mpin.SignalType,
+		}
+		inst.Pins = append(inst.Pins, pin)
+		r.design.InstancePins = append(r.design.InstancePins, pin)
+	}
+	r.design.Instances = append(r.design.Instances, inst)
+	r.instanceMap[name] = inst
+	return nil
+}
+
+// pinLocation averages the placed centers of the master pin shapes
+func pinLocation(t transform, geometries []*goopendb.Geometry, fallback *goopendb.Point) *goopendb.Point {
+	x, y, n := 0, 0, 0
+	for _, geom := range geometries {
+		for _, rect := range geom.Boxes {
+			b := t.box(box{xMin: rect.XMin, yMin: rect.YMin, xMax: rect.XMax, yMax: rect.YMax})
+			x += b.xMin + b.xMax
+			y += b.yMin + b.yMax
+			n++
+		}
+	}
+	if n == 0 {
+		return &goopendb.Point{X: fallback.X, Y: fallback.Y}
+	}
+	return &goopendb.Point{X: x / (2 * n), Y: y / (2 * n)}
+}
+
+// pinPort is a block pin port, shapes are relative to the port placement
+type pinPort struct {
+	shapes []*goopendb.Rect
+	placed bool
+	orient goopendb.Orientation
+	at     goopendb.Point
+}
+
+func (r *defReader) readBlockPin() error {
+	lex := r.lex
+	name := lex.next()
+	pin := &goopendb.Pin{
+		ID:         len(r.design.BlockPins) + 1,
+		Name:       name,
+		Direction:  goopendb.Direction(goopendb.IOTypeINPUT),
+		SignalType: goopendb.SignalTypeSIGNAL,
+		IsBlock:    true,
+	}
+	port := &pinPort{}
+	ports := []*pinPort{port}
+	for {
+		tok := lex.next()
+		if tok == ";" {
+			break
+		}
+		if tok != "+" {
+			return lex.errorf("unexpected %q in pin %v", tok, name)
+		}
+		var err error
+		switch keyword := lex.next(); keyword {
+		case "NET":
+			net := r.net(lex.next())
+			pin.Net = net
+			net.Pins = append(net.Pins, pin)
+		case "SPECIAL":
+			pin.IsSpecial = true
+		case "DIRECTION":
+			pin.Direction = goopendb.Direction(ioType(lex.next()))
+		case "USE":
+			pin.SignalType = signalType(lex.next())
+		case "PORT":
+			if len(port.shapes) > 0 || port.placed {
+				port = &pinPort{}
+				ports = append(ports, port)
+			}
+		case "LAYER", "POLYGON":
+			layerName := lex.next()
+			layer := r.lib.Layer(layerName)
+			if layer == nil {
+				return lex.errorf("unknown layer %v of pin %v", layerName, name)
+			}
+			r.acceptMask()
+			if lex.accept("SPACING") || lex.accept("DESIGNRULEWIDTH") {
+				lex.next()
+			}
+			var shapes []box
+			if keyword == "LAYER" {
+				var b box
+				b, err = r.readRect()
+				shapes = []box{b}
+			} else {
+				shapes, err = r.readPolygon()
+			}
+			if err != nil {
+				return err
+			}
+			for _, b := range shapes {
+				port.shapes = append(port.shapes, r.newRect(b, layer, nil))
+			}
+		case "VIA":
+			viaName := lex.next()
+			via := r.lib.Via(viaName)
+			if via == nil {
+				via = r.viaMap[viaName]
+			}
+			if via == nil {
+				return lex.errorf("unknown via %v of pin %v", viaName, name)
+			}
+			r.acceptMask()
+			var at goopendb.Point
+			if at, _, _, err = r.readPoint(goopendb.Point{}); err != nil {
+				return err
+			}
+			b := box{xMin: via.Rect.XMin, yMin: via.Rect.YMin, xMax: via.Rect.XMax, yMax: via.Rect.YMax}
+			port.shapes = append(port.shapes, r.newRect(b.translate(at.X, at.Y), nil, via))
+		case "PLACED", "FIXED", "COVER":
+			port.placed = true
+			if port.at, _, _, err = r.readPoint(goopendb.Point{}); err != nil {
+				return err
+			}
+			if port.orient, err = r.readOrientation(); err != nil {
+				return err
+			}
+		default:
+			r.skipOption()
+		}
+	}
+
+	// Port shapes are placed at their final location
+	var boxes []*goopendb.Rect
+	for _, port := range ports {
+		for _, rect := range port.shapes {
+			b := box{xMin: rect.XMin, yMin: rect.YMin, xMax: rect.XMax, yMax: rect.YMax}
+			b = b.orient(port.orient).translate(port.at.X, port.at.Y)
+			rect.XMin, rect.YMin, rect.XMax, rect.YMax = b.xMin, b.yMin, b.xMax, b.yMax
+			boxes = append(boxes, rect)
+			r.extend(b)
+		}
+	}
+	pin.Location = &goopendb.Point{X: ports[0].at.X, Y: ports[0].at.Y}
+	pin.Geometries = []*goopendb.Geometry{r.newGeometry(boxes)}
+	r.design.BlockPins = append(r.design.BlockPins, pin)
+	r.blockPinMap[name] = pin
+	return nil
+}
+
+func (r *defReader) readBlockage() error {
+	lex := r.lex
+	blockage := &goopendb.Blockage{
+		MinSpacing:      -1,
+		DesignRuleWidth: -1,
+	}
+	switch kind := lex.next(); kind {
+	case "LAYER":
+		layerName := lex.next()
+		blockage.Type = goopendb.BlockageTypeROUTING
+		blockage.Layer = r.lib.Layer(layerName)
+		if blockage.Layer == nil {
+			return lex.errorf("unknown blockage layer %v", layerName)
+		}
+	case "PLACEMENT":
+		blockage.Type = goopendb.BlockageTypePLACEMENT
+	default:
+		return lex.errorf("unknown blockage type %q", kind)
+	}
+	var shapes []box
+	for {
+		var err error
+		switch tok := lex.next(); tok {
+		case ";":
+			// Each shape is a separate blockage
+			for _, b := range shapes {
+				cp := *blockage
+				cp.Rect = r.newRect(b, blockage.Layer, nil)
+				r.extend(b)
+				if cp.Type == goopendb.BlockageTypePLACEMENT {
+					r.placementBlockages = append(r.placementBlockages, &cp)
+				} else {
+					r.routingBlockages = append(r.routingBlockages, &cp)
+				}
+			}
+			return nil
+		case "+":
+			switch lex.next() {
+			case "SLOTS":
+				blockage.IsSlot = true
+			case "FILLS":
+				blockage.IsFill = true
+			case "PUSHDOWN":
+				blockage.IsPushedDown = true
+			case "EXCEPTPGNET":
+				blockage.IsExceptPGNets = true
+			case "SOFT":
+				blockage.IsSoft = true
+			case "PARTIAL":
+				blockage.MaxDensity, err = lex.float()
+			case "COMPONENT":
+				instName := lex.next()
+				blockage.Instance = r.instanceMap[instName]
+				if blockage.Instance == nil {
+					return lex.errorf("unknown blockage component %v", instName)
+				}
+			case "SPACING":
+				blockage.MinSpacing, err = r.readDist()
+			case "DESIGNRULEWIDTH":
+				blockage.DesignRuleWidth, err = r.readDist()
+			default:
+				r.skipOption()
+			}
+		case "RECT":
+			var b box
+			b, err = r.readRect()
+			shapes = append(shapes, b)
+		case "POLYGON":
+			var boxes []box
+			boxes, err = r.readPolygon()
+			shapes = append(shapes, boxes...)
+		default:
+			return lex.errorf("unexpected %q in blockage", tok)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (r *defReader) readFill() error {
+	lex := r.lex
+	kind := lex.next()
+	if kind == "VIA" {
+		// Via fills are not part of the design model
+		lex.skipStatement()
+		return nil
+	}
+	if kind != "LAYER" {
+		return lex.errorf("unknown fill type %q", kind)
+	}
+	layerName := lex.next()
+	layer := r.lib.Layer(layerName)
+	if layer == nil {
+		return lex.errorf("unknown fill layer %v", layerName)
+	}
+	key := fillKey{layer: layer.ID}
+	var shapes []box
+	for {
+		var err error
+		switch tok := lex.next(); tok {
+		case ";":
+			fill := r.fillMap[key]
+			if fill == nil {
+				fill = &goopendb.Fill{Layer: layer, Mask: key.mask, NeedsOPC: key.opc}
+				r.fillMap[key] = fill
+			}
+			for _, b := range shapes {
+				fill.Boxes = append(fill.Boxes, r.newRect(b, layer, nil))
+			}
+			return nil
+		case "+":
+			switch lex.next() {
+			case "MASK":
+				key.mask, err = lex.integer()
+			case "OPC":
+				key.opc = true
+			default:
+				r.skipOption()
+			}
+		case "RECT":
+			var b box
+			b, err = r.readRect()
+			shapes = append(shapes, b)
+		case "POLYGON":
+			var boxes []box
+			boxes, err = r.readPolygon()
+			shapes = append(shapes, boxes...)
+		default:
+			return lex.errorf("unexpected %q in fill", tok)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// net finds a net by name, creating it on first use
+func (r *defReader) net(name string) *goopendb.Net {
+	if net, ok := r.netMap[name]; ok {
+		return net
+	}
+	net := &goopendb.Net{
+		ID:       len(r.design.Nets) + 1,
+		Name:     name,
+		WireType: goopendb.WireTypeNONE,
+	}
+	r.design.Nets = append(r.design.Nets, net)
+	r.netMap[name] = net
+	return net
+}
+
+// connect attaches a pin to a net, "*" connects the pin of all instances
+func (r *defReader) connect(net *goopendb.Net, instName string, pinName string, special bool) error {
+	if instName == "PIN" {
+		pin := r.blockPinMap[pinName]
+		if pin == nil {
+			return r.lex.errorf("unknown pin %v of net %v", pinName, net.Name)
+		}
+		connectPin(net, pin)
+		return nil
+	}
+	insts := r.design.Instances
+	if instName != "*" {
+		inst := r.instanceMap[instName]
+		if inst == nil {
+			return r.lex.errorf("unknown component %v of net %v", instName, net.Name)
+		}
+		insts = []*goopendb.Instance{inst}
+	}
+	for _, inst := range insts {
+		var pin *goopendb.Pin
+		for _, instPin := range inst.Pins {
+			if instPin.Name == pinName {
+				pin = instPin
+				break
+			}
+		}
+		if pin == nil {
+			if instName == "*" {
+				continue
+			}
+			return r.lex.errorf("unknown pin %v/%v of net %v", instName, pinName, net.Name)
+		}
+		if special {
+			pin.IsSpecial = true
+		}
+		connectPin(net, pin)
+	}
+	return nil
+}
+
+// connectPin moves a pin to net
+func connectPin(net *goopendb.Net, pin *goopendb.Pin) {
+	if pin.Net == net {
+		return
+	}
+	if old := pin.Net; old != nil {
+		for i, p := range old.Pins {
+			if p == pin {
+				old.Pins = append(old.Pins[:i], old.Pins[i+1:]...)
+				break
+			}
+		}
+	}
+	pin.Net = net
+	net.Pins = append(net.Pins, pin)
+}
+
+func placementStatus(status string) goopendb.PlacementStatus {
+	switch status {
+	case "UNPLACED":
+		return goopendb.PlacementStatusUNPLACED
+	case "PLACED":
+		return goopendb.PlacementStatusPLACED
+	case "FIXED":
+		return goopendb.PlacementStatusFIXED
+	case "COVER":
+		return goopendb.PlacementStatusCOVER
+	}
+	return goopendb.PlacementStatusNONE
+}
+
+func wireType(status string) goopendb.WireType {
+	switch status {
+	case "COVER":
+		return goopendb.WireTypeCOVER
+	case "FIXED":
+		return goopendb.WireTypeFIXED
+	case "ROUTED":
+		return goopendb.WireTypeROUTED
+	case "SHIELD":
+		return goopendb.WireTypeSHIELD
+	case "NOSHIELD":
+		return goopendb.WireTypeNOSHIELD
+	}
+	return goopendb.WireTypeNONE
+}
+
+func wireShapeType(shape string) goopendb.WireShapeType {
+	switch shape {
+	case "RING":
+		return goopendb.WireShapeTypeRING
+	case "PADRING":
+		return goopendb.WireShapeTypePADRING
+	case "BLOCKRING":
+		return goopendb.WireShapeTypeBLOCKRING
+	case "STRIPE":
+		return goopendb.WireShapeTypeSTRIPE
+	case "FOLLOWPIN":
+		return goopendb.WireShapeTypeFOLLOWPIN
+	case "IOWIRE":
+		return goopendb.WireShapeTypeIOWIRE
+	case "COREWIRE":
+		return goopendb.WireShapeTypeCOREWIRE
+	case "BLOCKWIRE":
+		return goopendb.WireShapeTypeBLOCKWIRE
+	case "BLOCKAGEWIRE":
+		return goopendb.WireShapeTypeBLOCKAGEWIRE
+	case "FILLWIRE", "FILLWIREOPC":
+		return goopendb.WireShapeTypeFILLWIRE
+	case "DRCFILL":
+		return goopendb.WireShapeTypeDRCFILL
+	}
+	return goopendb.WireShapeTypeNONE
+}
+
+// readNet reads a NETS or SPECIALNETS entry
+func (r *defReader) readNet(special bool) error {
+	lex := r.lex
+	net := r.net(lex.next())
+	if special {
+		net.IsSpecial = true
+	}
+	for lex.accept("(") {
+		instName := lex.next()
+		pinName := lex.next()
+		for !lex.done() && lex.next() != ")" {
+		}
+		if err := r.connect(net, instName, pinName, special); err != nil {
+			return err
+		}
+	}
+	var wire *goopendb.SpecialWire
+	for {
+		tok := lex.next()
+		if tok == ";" {
+			return nil
+		}
+		if tok != "+" {
+			return lex.errorf("unexpected %q in net %v", tok, net.Name)
+		}
+		var err error
+		switch keyword := lex.next(); keyword {
+		case "ROUTED", "FIXED", "COVER", "NOSHIELD", "SHIELD":
+			shieldNet := ""
+			if keyword == "SHIELD" {
+				shieldNet = lex.next()
+			}
+			if special {
+				wire = r.newSpecialWire(net, wireType(keyword))
+				wire.ShieldNet = shieldNet
+				if lex.peek() != "+" {
+					err = r.readSpecialWiring(wire)
+				}
+				break
+			}
+			if net.WireType == goopendb.WireTypeNONE {
+				net.WireType = wireType(keyword)
+			}
+			net.IsRouted = true
+			err = r.readWiring(net)
+		case "USE":
+			net.Use = signalType(lex.next())
+		case "RECT", "POLYGON", "VIA":
+			if !special {
+				r.skipOption()
+				break
+			}
+			if wire == nil {
+				wire = r.newSpecialWire(net, goopendb.WireTypeROUTED)
+			}
+			err = r.readSpecialShape(wire, keyword)
+		default:
+			r.skipOption()
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// segmentBox returns the shape of a wire segment, ends are extended by their
+// extension values
+func segmentBox(p goopendb.Point, pExt int, q goopendb.Point, qExt int, halfWidth int) box {
+	switch {
+	case p.X == q.X:
+		if p.Y > q.Y {
+			p, q, pExt, qExt = q, p, qExt, pExt
+		}
+		return box{xMin: p.X - halfWidth, yMin: p.Y - pExt, xMax: p.X + halfWidth, yMax: q.Y + qExt}
+	case p.Y == q.Y:
+		if p.X > q.X {
+			p, q, pExt, qExt = q, p, qExt, pExt
+		}
+		return box{xMin: p.X - pExt, yMin: p.Y - halfWidth, xMax: q.X + qExt, yMax: p.Y + halfWidth}
+	}
+	b := newBox(p.X, p.Y, q.X, q.Y)
+	return box{xMin: b.xMin - halfWidth, yMin: b.yMin - halfWidth, xMax: b.xMax + halfWidth, yMax: b.yMax + halfWidth}
+}
+
+// via finds a LEF or DEF via by name, LEF vias are reported as routing vias
+func (r *defReader) via(name string) (*goopendb.Via, goopendb.EdgeType) {
+	if via := r.lib.Via(name); via != nil {
+		r.usedVias[via] = true
+		return via, goopendb.EdgeTypeTECHVIA
+	}
+	if via := r.viaMap[name]; via != nil {
+		return via, goopendb.EdgeTypeVIA
+	}
+	return nil, goopendb.EdgeTypeVIA
+}
+
+// viaBox returns the via shape placed at x, y
+func viaBox(via *goopendb.Via, x, y int) box {
+	if via.Rect == nil {
+		return box{xMin: x, yMin: y, xMax: x, yMax: y}
+	}
+	return box{xMin: via.Rect.XMin, yMin: via.Rect.YMin, xMax: via.Rect.XMax, yMax: via.Rect.YMax}.translate(x, y)
+}
+
+// readWiring reads the regular routing paths of a net into edges
+func (r *defReader) readWiring(net *goopendb.Net) error {
+	lex := r.lex
+	for {
+		layerName := lex.next()
+		layer := r.lib.Layer(layerName)
+		if layer == nil {
+			return lex.errorf("unknown layer %v in net %v", layerName, net.Name)
+		}
+		for {
+			if lex.accept("TAPER") {
+				continue
+			}
+			if lex.accept("TAPERRULE") || lex.accept("STYLE") {
+				lex.next()
+				continue
+			}
+			break
+		}
+		if err := r.readPath(net, layer); err != nil {
+			return err
+		}
+		if !lex.accept("NEW") {
+			return nil
+		}
+	}
+}
+
+func (r *defReader) addEdge(net *goopendb.Net, typ goopendb.EdgeType, b box, layer *goopendb.Layer, via *goopendb.Via) {
+	net.Edges = append(net.Edges, &goopendb.Edge{
+		Type:  typ,
+		Rect:  r.newRect(b, nil, nil),
+		Via:   via,
+		Layer: layer,
+	})
+	r.extend(b)
+}
+
+// readPath reads the points and vias of a regular routing path
+func (r *defReader) readPath(net *goopendb.Net, layer *goopendb.Layer) error {
+	lex := r.lex
+	halfWidth := layer.Width / 2
+	var prev goopendb.Point
+	prevExt := halfWidth
+	started := false
+	for {
+		switch tok := lex.peek(); tok {
+		case "(":
+			pt, ext, hasExt, err := r.readPoint(prev)
+			if err != nil {
+				return err
+			}
+			if !hasExt {
+				ext = halfWidth
+			}
+			if started {
+				r.addEdge(net, goopendb.EdgeTypeSEGMENT, segmentBox(prev, prevExt, pt, ext, halfWidth), layer, nil)
+			}
+			prev, prevExt, started = pt, ext, true
+		case "MASK":
+			lex.next()
+			lex.next()
+		case "VIRTUAL":
+			lex.next()
+			pt, _, _, err := r.readPoint(prev)
+			if err != nil {
+				return err
+			}
+			r.addEdge(net, goopendb.EdgeTypeVWIRE, newBox(prev.X, prev.Y, pt.X, pt.Y), layer, nil)
+			prev = pt
+		case "RECT":
+			lex.next()
+			if err := lex.expect("("); err != nil {
+				return err
+			}
+			var deltas [4]int
+			for i := range deltas {
+				var err error
+				if deltas[i], err = r.readDist(); err != nil {
+					return err
+				}
+			}
+			if err := lex.expect(")"); err != nil {
+				return err
+			}
+			b := newBox(prev.X+deltas[0], prev.Y+deltas[1], prev.X+deltas[2], prev.Y+deltas[3])
+			r.addEdge(net, goopendb.EdgeTypeSEGMENT, b, layer, nil)
+		case "NEW", "+", ";", "":
+			return nil
+		default:
+			lex.next()
+			via, typ := r.via(tok)
+			if via == nil {
+				return lex.errorf("unknown via %v in net %v", tok, net.Name)
+			}
+			if _, ok := defOrientations[lex.peek()]; ok {
+				lex.next()
+			}
+			r.addEdge(net, typ, viaBox(via, prev.X, prev.Y), layer, via)
+			// The path continues on the other routing layer of the via
+			next := via.TopLayer
+			if layer == via.TopLayer {
+				next = via.BottomLayer
+			}
+			if next != nil {
+				layer = next
+				halfWidth = layer.Width / 2
+				prevExt = halfWidth
+			}
+		}
+	}
+}
+
+func (r *defReader) newSpecialWire(net *goopendb.Net, typ goopendb.WireType) *goopendb.SpecialWire {
+	r.specialWireID++
+	wire := &goopendb.SpecialWire{
+		ID:       r.specialWireID,
+		WireType: typ,
+		Geometry: r.newGeometry(nil),
+	}
+	net.SpecialBoxes = append(net.SpecialBoxes, wire.Geometry)
+	net.SpecialWires = append(net.SpecialWires, wire)
+	return wire
+}
+
+// addSpecialShape adds a shape to a special wire, width is zero for vias
+func (r *defReader) addSpecialShape(wire *goopendb.SpecialWire, b box, layer *goopendb.Layer, via *goopendb.Via,
+	shapeType goopendb.WireShapeType, width int) {
+	rect := r.newRect(b, layer, via)
+	rect.ShapeType = int(shapeType)
+	wire.Geometry.Boxes = append(wire.Geometry.Boxes, rect)
+	wire.Shapes = append(wire.Shapes, &goopendb.SpecialShape{
+		Rect:      rect,
+		ShapeType: shapeType,
+		Width:     width,
+	})
+	r.extend(b)
+}
+
+// readSpecialWiring reads the special routing paths of a special wire
+func (r *defReader) readSpecialWiring(wire *goopendb.SpecialWire) error {
+	lex := r.lex
+	for {
+		layerName := lex.next()
+		layer := r.lib.Layer(layerName)
+		if layer == nil {
+			return lex.errorf("unknown layer %v in special wiring", layerName)
+		}
+		width, err := r.readDist()
+		if err != nil {
+			return err
+		}
+		var shapeType goopendb.WireShapeType = goopendb.WireShapeTypeNONE
+		for lex.peek() == "+" && (lex.peekAt(1) == "SHAPE" || lex.peekAt(1) == "STYLE" || lex.peekAt(1) == "MASK") {
+			lex.next()
+			if lex.next() == "SHAPE" {
+				shapeType = wireShapeType(lex.next())
+			} else {
+				lex.next()
+			}
+		}
+		if err = r.readSpecialPath(wire, layer, width, shapeType); err != nil {
+			return err
+		}
+		if !lex.accept("NEW") {
+			return nil
+		}
+	}
+}
+
+// readSpecialPath reads the points and vias of a special routing path, path
+// ends are not extended unless an extension value is given
+func (r *defReader) readSpecialPath(wire *goopendb.SpecialWire, layer *goopendb.Layer, width int,
+	shapeType goopendb.WireShapeType) error {
+	lex := r.lex
+	var prev goopendb.Point
+	prevExt := 0
+	started := false
+	for {
+		switch tok := lex.peek(); tok {
+		case "(":
+			pt, ext, _, err := r.readPoint(prev)
+			if err != nil {
+				return err
+			}
+			if started {
+				b := segmentBox(prev, prevExt, pt, ext, width/2)
+				shapeWidth := minInt(b.xMax-b.xMin, b.yMax-b.yMin)
+				if prev.Y == pt.Y && prev.X != pt.X {
+					shapeWidth = b.yMax - b.yMin
+				} else if prev.X == pt.X && prev.Y != pt.Y {
+					shapeWidth = b.xMax - b.xMin
+				}
+				r.addSpecialShape(wire, b, layer, nil, shapeType, shapeWidth)
+			}
+			prev, prevExt, started = pt, ext, true
+		case "MASK":
+			lex.next()
+			lex.next()
+		case "NEW", "+", ";", "":
+			return nil
+		default:
+			lex.next()
+			via, _ := r.via(tok)
+			if via == nil {
+				return lex.errorf("unknown via %v in special wiring", tok)
+			}
+			countX, countY := 1, 1
+			stepX, stepY := 0, 0
+			if lex.accept("DO") {
+				var err error
+				if countX, err = lex.integer(); err != nil {
+					return err
+				}
+				if err = lex.expect("BY"); err != nil {
+					return err
+				}
+				if countY, err = lex.integer(); err != nil {
+					return err
+				}
+				if err = lex.expect("STEP"); err != nil {
+					return err
+				}
+				if stepX, err = r.readDist(); err != nil {
+					return err
+				}
+				if stepY, err = r.readDist(); err != nil {
+					return err
+				}
+			}
+			for i := 0; i < countX; i++ {
+				for j := 0; j < countY; j++ {
+					r.addSpecialShape(wire, viaBox(via, prev.X+i*stepX, prev.Y+j*stepY), nil, via, shapeType, 0)
+				}
+			}
+		}
+	}
+}
+
+// readSpecialShape reads a special net RECT, POLYGON or VIA shape
+func (r *defReader) readSpecialShape(wire *goopendb.SpecialWire, keyword string) error {
+	lex := r.lex
+	name := lex.next()
+	r.acceptMask()
+	if keyword == "VIA" {
+		via, _ := r.via(name)
+		if via == nil {
+			return lex.errorf("unknown via %v in special wiring", name)
+		}
+		if _, ok := defOrientations[lex.peek()]; ok {
+			lex.next()
+		}
+		points, err := r.readPoints()
+		if err != nil {
+			return err
+		}
+		for _, pt := range points {
+			r.addSpecialShape(wire, viaBox(via, pt.X, pt.Y), nil, via, goopendb.WireShapeTypeNONE, 0)
+		}
+		return nil
+	}
+	layer := r.lib.Layer(name)
+	if layer == nil {
+		return lex.errorf("unknown layer %v in special wiring", name)
+	}
+	var shapes []box
+	var err error
+	if keyword == "RECT" {
+		var b box
+		b, err = r.readRect()
+		shapes = []box{b}
+	} else {
+		shapes, err = r.readPolygon()
+	}
+	if err != nil {
+		return err
+	}
+	for _, b := range shapes {
+		r.addSpecialShape(wire, b, layer, nil, goopendb.WireShapeTypeNONE, minInt(b.xMax-b.xMin, b.yMax-b.yMin))
+	}
+	return nil
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// matchPattern matches a DEF component name pattern, "*" matches any
+// sequence and "?" a single character
+func matchPattern(pattern, name string) bool {
+	if pattern == "" {
+		return name == ""
+	}
+	switch pattern[0] {
+	case '*':
+		for i := 0; i <= len(name); i++ {
+			if matchPattern(pattern[1:], name[i:]) {
+				return true
+			}
+		}
+		return false
+	case '?':
+		return name != "" && matchPattern(pattern[1:], name[1:])
+	}
+	return name != "" && pattern[0] == name[0] && matchPattern(pattern[1:], name[1:])
+}
+
+func (r *defReader) readGroup() error {
+	lex := r.lex
+	name := lex.next()
+	// Regions and groups share the OpenDB region IDs
+	group := &goopendb.Group{
+		ID:   len(r.design.Regions) + len(r.design.Groups) + 1,
+		Name: name,
+	}
+	var patterns []string
+	for !lex.done() && lex.peek() != "+" && lex.peek() != ";" {
+		patterns = append(patterns, lex.next())
+	}
+	for {
+		tok := lex.next()
+		if tok == ";" {
+			break
+		}
+		if tok != "+" {
+			return lex.errorf("unexpected %q in group %v", tok, name)
+		}
+		if lex.next() != "REGION" {
+			r.skipOption()
+			continue
+		}
+		regionName := lex.next()
+		group.Region = r.regionMap[regionName]
+		if group.Region == nil {
+			return lex.errorf("unknown region %v of group %v", regionName, name)
+		}
+	}
+	for _, inst := range r.design.Instances {
+		if inst.Group != nil {
+			continue
+		}
+		for _, pattern := range patterns {
+			if matchPattern(pattern, inst.Name) {
+				inst.Group = group
+				inst.Region = group.Region
+				group.Instances = append(group.Instances, inst)
+				break
+			}
+		}
+	}
+	if group.Region != nil {
+		group.Region.Groups = append(group.Region.Groups, group)
+		group.Region.Instances = append(group.Region.Instances, group.Instances...)
+	}
+	r.design.Groups = append(r.design.Groups, group)
+	return nil
+}
+
+// finish computes the derived design data once the whole file is read
+func (r *defReader) finish() {
+	design := r.design
+	for _, grid := range append(design.Tracks, design.GCell) {
+		if grid == nil {
+			continue
+		}
+		grid.GridX = gridLines(grid.GridXPatternOrigins, grid.GridXPatternLineCounts, grid.GridXPatternSteps)
+		grid.GridY = gridLines(grid.GridYPatternOrigins, grid.GridYPatternLineCounts, grid.GridYPatternSteps)
+	}
+
+	for _, via := range r.lib.Vias {
+		if r.usedVias[via] {
+			design.RoutingVias = append(design.RoutingVias, via)
+		}
+	}
+
+	// Placement blockages come first, like the OpenDB backend
+	design.Blockages = append(r.placementBlockages, r.routingBlockages...)
+	for i, blockage := range design.Blockages {
+		blockage.ID = i + 1
+	}
+
+	var keys []fillKey
+	for key := range r.fillMap {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := keys[i], keys[j]
+		if a.layer != b.layer {
+			return a.layer < b.layer
+		}
+		if a.mask != b.mask {
+			return a.mask < b.mask
+		}
+		return !a.opc && b.opc
+	})
+	for i, key := range keys {
+		fill := r.fillMap[key]
+		fill.ID = i + 1
+		design.Fills = append(design.Fills, fill)
+	}
+
+	// Instance pins are listed before block pins
+	for _, net := range design.Nets {
+		sort.SliceStable(net.Pins, func(i, j int) bool {
+			return !net.Pins[i].IsBlock && net.Pins[j].IsBlock
+		})
+	}
+
+	if design.Die == nil {
+		design.Die = r.newRect(box{}, nil, nil)
+	}
+	die := box{xMin: design.Die.XMin, yMin: design.Die.YMin, xMax: design.Die.XMax, yMax: design.Die.YMax}
+	core := die
+	for i, row := range design.Rows {
+		b := box{xMin: row.BoundingBox.XMin, yMin: row.BoundingBox.YMin, xMax: row.BoundingBox.XMax, yMax: row.BoundingBox.YMax}
+		if i == 0 {
+			core = b
+		} else {
+			core = core.merge(b)
+		}
+	}
+	design.Core = r.newRect(core, nil, nil)
+	design.BoundingBox = r.newRect(r.bbox, nil, nil)
+
+	meters := func(dist int) float64 {
+		return float64(dist) / float64(design.DBUPerMicron) * 1e-6
+	}
+	design.CoreArea = meters(core.xMax-core.xMin) * meters(core.yMax-core.yMin)
+	design.DieArea = meters(die.xMax-die.xMin) * meters(die.yMax-die.yMin)
+	for _, inst := range design.Instances {
+		// Core auto placeable masters
+		if master := inst.MasterRef; len(master.Class) >= 4 && master.Class[:4] == "CORE" {
+			design.DesignArea += meters(master.Width) * meters(master.Height)
+		}
+	}
+	if design.CoreArea > 0 {
+		design.Utilization = design.DesignArea / design.CoreArea
+	}
+}
+
+// pointBoxes converts points to zero size boxes
+func pointBoxes(points []goopendb.Point) []box {
+	var boxes []box
+	for _, pt := range points {
+		boxes = append(boxes, box{xMin: pt.X, yMin: pt.Y, xMax: pt.X, yMax: pt.Y})
+	}
+	return boxes
+}
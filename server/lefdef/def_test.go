@@ -0,0 +1,337 @@
+package lefdef
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/ahmed-agiza/EDAViewer/server/goopendb"
+)
+
+const defPath = "../example/Nangate45/gcd.def"
+
+func parseDesign(t *testing.T, backend goopendb.Backend) *goopendb.Design {
+	t.Helper()
+	if err := backend.ParseLEF(lefPath); err != nil {
+		t.Fatal(err)
+	}
+	if err := backend.ParseDEF(defPath); err != nil {
+		t.Fatal(err)
+	}
+	design, err := backend.GetDesign()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return design
+}
+
+func designCounts(design *goopendb.Design) map[string]int {
+	return map[string]int{
+		"DBUPerMicron":   design.DBUPerMicron,
+		"DEFUnits":       design.DEFUnits,
+		"Instances":      len(design.Instances),
+		"Nets":           len(design.Nets),
+		"InstancePins":   len(design.InstancePins),
+		"BlockPins":      len(design.BlockPins),
+		"RoutingVias":    len(design.RoutingVias),
+		"ViaDefinitions": len(design.ViaDefinitions),
+		"Layers":         len(design.Layers),
+		"Rows":           len(design.Rows),
+		"Tracks":         len(design.Tracks),
+		"Sites":          len(design.Sites),
+		"Blockages":      len(design.Blockages),
+		"Regions":        len(design.Regions),
+		"Groups":         len(design.Groups),
+		"Fills":          len(design.Fills),
+		"Masters":        len(design.Masters),
+		"ViaRules":       len(design.ViaRules),
+	}
+}
+
+func TestParseDEF(t *testing.T) {
+	backend := NewBackend()
+	defer backend.FreeDatabase()
+	design := parseDesign(t, backend)
+
+	if design.Name != "gcd" {
+		t.Fatal("Unexpected design name", design.Name)
+	}
+	expected := map[string]int{
+		"DBUPerMicron":   2000,
+		"DEFUnits":       1000,
+		"Instances":      182,
+		"Nets":           268,
+		"InstancePins":   1164,
+		"BlockPins":      56,
+		"RoutingVias":    8,
+		"ViaDefinitions": 7,
+		"Layers":         22,
+		"Rows":           15,
+		"Tracks":         10,
+		"Sites":          1,
+		"Blockages":      0,
+		"Regions":        0,
+		"Groups":         0,
+		"Fills":          0,
+		"Masters":        134,
+		"ViaRules":       19,
+	}
+	actual := designCounts(design)
+	for k, v := range expected {
+		if actual[k] != v {
+			t.Errorf("Expected %v to be %v, found %v", k, v, actual[k])
+		}
+	}
+
+	if die := design.Die; die.XMax != 45600 || die.YMax != 42000 {
+		t.Errorf("Unexpected die area %+v", die)
+	}
+
+	// - g297__8780 NOR2_X1 + PLACED ( 8170 0 ) FS ;
+	inst := design.Instances[0]
+	if inst.Name != "g297__8780" || inst.Orientation != goopendb.OrientationMX || !inst.IsPlaced {
+		t.Errorf("Unexpected instance %+v", inst)
+	}
+	if inst.Location.X != 16340 || inst.Location.Y != 0 || inst.Origin.Y != 2800 || inst.BoundingBox.XMax != 17480 {
+		t.Errorf("Unexpected instance placement %+v, %+v, %+v", inst.Location, inst.Origin, inst.BoundingBox)
+	}
+
+	clk := design.BlockPins[0]
+	if clk.Name != "clk" || clk.Direction != goopendb.Direction(goopendb.IOTypeINPUT) || clk.Net == nil || clk.Net.Name != "clk" {
+		t.Fatalf("Unexpected block pin %+v", clk)
+	}
+	if rect := clk.Geometries[0].Boxes[0]; rect.XMin != 21020 || rect.YMin != 41860 || rect.XMax != 21160 ||
+		rect.YMax != 42000 || rect.Layer.Name != "metal2" {
+		t.Errorf("Unexpected block pin shape %+v", rect)
+	}
+	if pins := clk.Net.Pins; len(pins) != 35 || pins[len(pins)-1] != clk || !clk.Net.IsRouted {
+		t.Errorf("Unexpected clk net %+v", clk.Net)
+	}
+
+	var vdd *goopendb.Net
+	for _, net := range design.Nets {
+		if net.Name == "VDD" {
+			vdd = net
+		}
+	}
+	if vdd == nil || !vdd.IsSpecial || len(vdd.SpecialWires) != 1 {
+		t.Fatalf("Unexpected VDD net %+v", vdd)
+	}
+	if shape := vdd.SpecialWires[0].Shapes[0]; shape.ShapeType != goopendb.WireShapeTypeFOLLOWPIN || shape.Width != 340 ||
+		shape.Rect.Layer.Name != "metal1" {
+		t.Errorf("Unexpected VDD shape %+v", shape)
+	}
+}
+
+func TestViaIDs(t *testing.T) {
+	backend := NewBackend()
+	defer backend.FreeDatabase()
+	design := parseDesign(t, backend)
+
+	// The LEF via1_4 and the DEF Via3Array-0_1 used to share an ID
+	vias := map[int]*goopendb.Via{}
+	for _, via := range append(design.RoutingVias, design.ViaDefinitions...) {
+		if other, ok := vias[via.ID]; ok && other != via {
+			t.Errorf("Vias %v and %v have the same ID %v", other.Name, via.Name, via.ID)
+		}
+		vias[via.ID] = via
+	}
+	for _, net := range design.Nets {
+		for _, edge := range net.Edges {
+			if edge.Via != nil && vias[edge.Via.ID] != edge.Via {
+				t.Errorf("Net %v via %v does not match its ID %v", net.Name, edge.Via.Name, edge.Via.ID)
+			}
+		}
+	}
+}
+
+const smallDEF = `
+VERSION 5.8 ;
+DESIGN small ;
+UNITS DISTANCE MICRONS 1000 ;
+DIEAREA ( 0 0 ) ( 10000 10000 ) ;
+REGIONS 1 ;
+- r1 ( 0 0 ) ( 5000 5000 ) + TYPE GUIDE ;
+END REGIONS
+COMPONENTS 3 ;
+- u1 INV_X1 + PLACED ( 1000 1400 ) W ;
+- u2 INV_X1 + FIXED ( 2000 1400 ) N + REGION r1 ;
+- v1 BUF_X1 + UNPLACED ;
+END COMPONENTS
+BLOCKAGES 2 ;
+- PLACEMENT + PARTIAL 50.0 RECT ( 0 0 ) ( 100 100 ) RECT ( 200 200 ) ( 300 300 ) ;
+- LAYER metal1 + COMPONENT u1 + SPACING 10 RECT ( 0 0 ) ( 50 50 ) ;
+END BLOCKAGES
+FILLS 2 ;
+- LAYER metal2 + OPC RECT ( 0 0 ) ( 100 100 ) ;
+- LAYER metal1 RECT ( 0 0 ) ( 100 100 ) ;
+END FILLS
+NETS 1 ;
+- n1 ( u1 ZN ) ( u2 A )
+  + ROUTED metal1 ( 0 0 ) ( 100 * ) via1_4 ( * 200 ) ;
+END NETS
+SPECIALNETS 1 ;
+- VSS ( * VSS ) + USE GROUND
+  + SHIELD n1 metal1 140 ( 0 2800 ) ( 1000 2800 ) ;
+END SPECIALNETS
+GROUPS 1 ;
+- g1 v* + REGION r1 ;
+END GROUPS
+END DESIGN
+`
+
+func TestReadDEF(t *testing.T) {
+	lef, err := os.Open(lefPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lef.Close()
+	lib := NewLibrary()
+	if err = lib.ReadLEF(lef, "lib", true, true); err != nil {
+		t.Fatal(err)
+	}
+	design, err := lib.ReadDEF(strings.NewReader(smallDEF))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	u1 := design.Instances[0]
+	if u1.Orientation != goopendb.OrientationR90 || u1.Location.X != 2000 || u1.Location.Y != 2800 ||
+		u1.BoundingBox.XMax-u1.BoundingBox.XMin != u1.MasterRef.Height {
+		t.Errorf("Unexpected rotated instance %+v, %+v", u1.Location, u1.BoundingBox)
+	}
+	if v1 := design.Instances[2]; v1.IsPlaced {
+		t.Errorf("Expected v1 to be unplaced")
+	}
+
+	if len(design.Blockages) != 3 {
+		t.Fatalf("Expected 3 blockages, found %v", len(design.Blockages))
+	}
+	placement, routing := design.Blockages[1], design.Blockages[2]
+	if placement.Type != goopendb.BlockageTypePLACEMENT || placement.MaxDensity != 50 || placement.Rect.XMin != 400 {
+		t.Errorf("Unexpected placement blockage %+v", placement)
+	}
+	if routing.Type != goopendb.BlockageTypeROUTING || routing.Instance != u1 || routing.MinSpacing != 20 ||
+		routing.DesignRuleWidth != -1 || routing.Rect.Layer.Name != "metal1" {
+		t.Errorf("Unexpected routing blockage %+v", routing)
+	}
+
+	if len(design.Fills) != 2 || design.Fills[0].Layer.Name != "metal1" || !design.Fills[1].NeedsOPC {
+		t.Errorf("Unexpected fills %+v", design.Fills)
+	}
+
+	region := design.Regions[0]
+	group := design.Groups[0]
+	if region.Type != goopendb.RegionTypeGUIDE || len(region.Instances) != 2 || len(region.Groups) != 1 {
+		t.Errorf("Unexpected region %+v", region)
+	}
+	if group.ID != 2 || group.Region != region || len(group.Instances) != 1 || design.Instances[2].Group != group {
+		t.Errorf("Unexpected group %+v", group)
+	}
+
+	net := design.Nets[0]
+	if len(net.Pins) != 2 || net.WireType != goopendb.WireTypeROUTED || len(net.Edges) != 3 {
+		t.Fatalf("Unexpected net %+v", net)
+	}
+	// Segments are extended by half the layer width
+	if edge := net.Edges[0]; edge.Type != goopendb.EdgeTypeSEGMENT || edge.Rect.XMin != -70 || edge.Rect.XMax != 270 ||
+		edge.Layer.Name != "metal1" {
+		t.Errorf("Unexpected segment %+v", edge.Rect)
+	}
+	if edge := net.Edges[1]; edge.Type != goopendb.EdgeTypeTECHVIA || edge.Via.Name != "via1_4" {
+		t.Errorf("Unexpected via %+v", edge)
+	}
+	if edge := net.Edges[2]; edge.Layer.Name != "metal2" || edge.Rect.YMax != 470 {
+		t.Errorf("Unexpected via segment %+v", edge.Rect)
+	}
+	if len(design.RoutingVias) != 1 {
+		t.Errorf("Expected 1 routing via, found %v", len(design.RoutingVias))
+	}
+
+	if _, err := lib.ReadDEF(strings.NewReader("DESIGN bad ;\nCOMPONENTS 1 ;\n- u1 UNKNOWN ;\nEND COMPONENTS\nEND DESIGN\n")); err == nil {
+		t.Errorf("Expected an unknown master error")
+	}
+}
+
+func TestWriteDEFStatus(t *testing.T) {
+	lef, err := os.Open(lefPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lef.Close()
+	lib := NewLibrary()
+	if err = lib.ReadLEF(lef, "lib", true, true); err != nil {
+		t.Fatal(err)
+	}
+	design, err := lib.ReadDEF(strings.NewReader(smallDEF))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err = goopendb.WriteDEF(design, &buf); err != nil {
+		t.Fatal(err)
+	}
+	written, err := lib.ReadDEF(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, status := range []goopendb.PlacementStatus{
+		goopendb.PlacementStatusPLACED, goopendb.PlacementStatusFIXED, goopendb.PlacementStatusUNPLACED,
+	} {
+		if inst := written.Instances[i]; inst.Status != status {
+			t.Errorf("Expected %v to be %v, found %v", inst.Name, status, inst.Status)
+		}
+	}
+	nets := map[string]*goopendb.Net{}
+	for _, net := range written.Nets {
+		nets[net.Name] = net
+	}
+	n1, vss := nets["n1"], nets["VSS"]
+	if n1.Use != goopendb.SignalTypeSIGNAL || vss.Use != goopendb.SignalTypeGROUND {
+		t.Errorf("Unexpected net uses %v, %v", n1.Use, vss.Use)
+	}
+	if len(vss.SpecialWires) != 1 {
+		t.Fatalf("Expected 1 VSS special wire, found %v", len(vss.SpecialWires))
+	}
+	if wire := vss.SpecialWires[0]; wire.WireType != goopendb.WireTypeSHIELD || wire.ShieldNet != "n1" || len(wire.Shapes) != 1 {
+		t.Errorf("Unexpected VSS wire %+v", wire)
+	}
+}
+
+// TestCompareOpenDB checks the native design against the OpenDB backend
+func TestCompareOpenDB(t *testing.T) {
+	openDB, err := goopendb.NewBackend(goopendb.BackendOpenDB)
+	if err != nil {
+		t.Skip(err)
+	}
+	defer openDB.FreeDatabase()
+	expected := parseDesign(t, openDB)
+
+	backend := NewBackend()
+	defer backend.FreeDatabase()
+	actual := parseDesign(t, backend)
+
+	expectedCounts := designCounts(expected)
+	for k, v := range designCounts(actual) {
+		if expectedCounts[k] != v {
+			t.Errorf("Expected %v to be %v, found %v", k, expectedCounts[k], v)
+		}
+	}
+	for i, inst := range actual.Instances {
+		other := expected.Instances[i]
+		if inst.Name != other.Name || *inst.Location != *other.Location || inst.Orientation != other.Orientation {
+			t.Errorf("Instance %v mismatch: %+v, %+v", inst.Name, inst.Location, other.Location)
+		}
+	}
+	for i, net := range actual.Nets {
+		other := expected.Nets[i]
+		if net.Name != other.Name || len(net.Pins) != len(other.Pins) || len(net.Edges) != len(other.Edges) {
+			t.Errorf("Net %v mismatch: %v, %v pins", net.Name, len(net.Pins), len(other.Pins))
+		}
+	}
+	if actual.Core.XMax != expected.Core.XMax || actual.Core.YMax != expected.Core.YMax {
+		t.Errorf("Core mismatch: %+v, %+v", actual.Core, expected.Core)
+	}
+}
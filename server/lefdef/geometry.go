@@ -0,0 +1,188 @@
+package lefdef
+
+import (
+	"math"
+	"sort"
+
+	"github.com/ahmed-agiza/EDAViewer/server/goopendb"
+)
+
+// box is an axis aligned rectangle in database units
+type box struct {
+	xMin int
+	yMin int
+	xMax int
+	yMax int
+}
+
+func newBox(x1, y1, x2, y2 int) box {
+	if x1 > x2 {
+		x1, x2 = x2, x1
+	}
+	if y1 > y2 {
+		y1, y2 = y2, y1
+	}
+	return box{xMin: x1, yMin: y1, xMax: x2, yMax: y2}
+}
+
+// merge extends the box to cover other
+func (b box) merge(other box) box {
+	if other.xMin < b.xMin {
+		b.xMin = other.xMin
+	}
+	if other.yMin < b.yMin {
+		b.yMin = other.yMin
+	}
+	if other.xMax > b.xMax {
+		b.xMax = other.xMax
+	}
+	if other.yMax > b.yMax {
+		b.yMax = other.yMax
+	}
+	return b
+}
+
+// translate moves the box by dx, dy
+func (b box) translate(dx, dy int) box {
+	return box{xMin: b.xMin + dx, yMin: b.yMin + dy, xMax: b.xMax + dx, yMax: b.yMax + dy}
+}
+
+// bboxOf returns the bounding box of boxes, false if there are none
+func bboxOf(boxes []box) (box, bool) {
+	if len(boxes) == 0 {
+		return box{}, false
+	}
+	bbox := boxes[0]
+	for _, b := range boxes[1:] {
+		bbox = bbox.merge(b)
+	}
+	return bbox, true
+}
+
+// polygonToBoxes decomposes a polygon into horizontal slabs, rectilinear
+// polygons are decomposed exactly while diagonal edges are approximated at
+// the middle of each slab
+func polygonToBoxes(points []goopendb.Point) []box {
+	if len(points) < 3 {
+		return nil
+	}
+	var ys []int
+	seen := make(map[int]bool)
+	for _, pt := range points {
+		if !seen[pt.Y] {
+			seen[pt.Y] = true
+			ys = append(ys, pt.Y)
+		}
+	}
+	sort.Ints(ys)
+	var boxes []box
+	open := make(map[[2]int]int) // x interval -> index in boxes of the box ending at the previous slab
+	for i := 0; i+1 < len(ys); i++ {
+		y0, y1 := ys[i], ys[i+1]
+		mid := float64(y0+y1) / 2
+		var xs []int
+		for j := range points {
+			p, q := points[j], points[(j+1)%len(points)]
+			if p.Y == q.Y {
+				continue
+			}
+			lo, hi := p, q
+			if lo.Y > hi.Y {
+				lo, hi = hi, lo
+			}
+			if float64(lo.Y) > mid || float64(hi.Y) < mid {
+				continue
+			}
+			x := float64(lo.X) + (mid-float64(lo.Y))*float64(hi.X-lo.X)/float64(hi.Y-lo.Y)
+			xs = append(xs, int(math.Round(x)))
+		}
+		sort.Ints(xs)
+		next := make(map[[2]int]int)
+		for k := 0; k+1 < len(xs); k += 2 {
+			interval := [2]int{xs[k], xs[k+1]}
+			if interval[0] == interval[1] {
+				continue
+			}
+			if idx, ok := open[interval]; ok {
+				boxes[idx].yMax = y1
+				next[interval] = idx
+				continue
+			}
+			boxes = append(boxes, box{xMin: xs[k], yMin: y0, xMax: xs[k+1], yMax: y1})
+			next[interval] = len(boxes) - 1
+		}
+		open = next
+	}
+	return boxes
+}
+
+// pathToBoxes converts a path centerline to boxes, each segment is extended
+// by half the width at both ends
+func pathToBoxes(points []goopendb.Point, width int) []box {
+	hw := width / 2
+	if len(points) == 1 {
+		pt := points[0]
+		return []box{newBox(pt.X-hw, pt.Y-hw, pt.X+hw, pt.Y+hw)}
+	}
+	var boxes []box
+	for i := 0; i+1 < len(points); i++ {
+		p, q := points[i], points[i+1]
+		b := newBox(p.X, p.Y, q.X, q.Y)
+		b.xMin -= hw
+		b.yMin -= hw
+		b.xMax += hw
+		b.yMax += hw
+		boxes = append(boxes, b)
+	}
+	return boxes
+}
+
+// orientPoint applies a DEF orientation to a point about the origin
+func orientPoint(orient goopendb.Orientation, x, y int) (int, int) {
+	switch orient {
+	case goopendb.OrientationR90:
+		return -y, x
+	case goopendb.OrientationR180:
+		return -x, -y
+	case goopendb.OrientationR270:
+		return y, -x
+	case goopendb.OrientationMY:
+		return -x, y
+	case goopendb.OrientationMYR90:
+		return -y, -x
+	case goopendb.OrientationMX:
+		return x, -y
+	case goopendb.OrientationMXR90:
+		return y, x
+	}
+	return x, y
+}
+
+// orient applies a DEF orientation to the box about the origin
+func (b box) orient(orient goopendb.Orientation) box {
+	x1, y1 := orientPoint(orient, b.xMin, b.yMin)
+	x2, y2 := orientPoint(orient, b.xMax, b.yMax)
+	return newBox(x1, y1, x2, y2)
+}
+
+// transform places master coordinates in the design, the oriented master
+// box has its lower left corner at the placement location
+type transform struct {
+	orient goopendb.Orientation
+	dx     int
+	dy     int
+}
+
+func newTransform(orient goopendb.Orientation, x, y, width, height int) transform {
+	placed := box{xMax: width, yMax: height}.orient(orient)
+	return transform{orient: orient, dx: x - placed.xMin, dy: y - placed.yMin}
+}
+
+func (t transform) point(x, y int) (int, int) {
+	x, y = orientPoint(t.orient, x, y)
+	return x + t.dx, y + t.dy
+}
+
+func (t transform) box(b box) box {
+	return b.orient(t.orient).translate(t.dx, t.dy)
+}
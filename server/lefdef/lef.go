@@ -0,0 +1,1127 @@
+package lefdef
+
+import (
+	"io"
+	"math"
+	"strings"
+
+	"github.com/ahmed-agiza/EDAViewer/server/goopendb"
+)
+
+// DefaultLEFUnits is the LEF database precision when UNITS is missing
+const DefaultLEFUnits = 100
+
+// SiteInfo holds the LEF site data that is not part of goopendb.Site
+type SiteInfo struct {
+	Class     string
+	Symmetry  []string
+	Width     int
+	Height    int
+	RowLength int // Sites in a ROWPATTERN, zero otherwise
+}
+
+// Library is the technology and cell library data read from LEF files
+type Library struct {
+	DBUPerMicron int // Database units per micron
+	LEFUnits     int // LEF UNITS DATABASE MICRONS
+	Layers       []*goopendb.Layer
+	Vias         []*goopendb.Via
+	ViaRules     []*goopendb.ViaRule
+	Sites        []*goopendb.Site
+	SiteInfo     map[string]*SiteInfo
+	Masters      []*goopendb.Master
+	Geometries   []*goopendb.Geometry
+	rects        []*goopendb.Rect
+
+	layerMap   map[string]*goopendb.Layer
+	viaMap     map[string]*goopendb.Via
+	viaRuleMap map[string]*goopendb.ViaRule
+	siteMap    map[string]*goopendb.Site
+	masterMap  map[string]*goopendb.Master
+	pinID      int
+}
+
+// NewLibrary creates an empty LEF library
+func NewLibrary() *Library {
+	return &Library{
+		SiteInfo:   make(map[string]*SiteInfo),
+		layerMap:   make(map[string]*goopendb.Layer),
+		viaMap:     make(map[string]*goopendb.Via),
+		viaRuleMap: make(map[string]*goopendb.ViaRule),
+		siteMap:    make(map[string]*goopendb.Site),
+		masterMap:  make(map[string]*goopendb.Master),
+	}
+}
+
+// Layer finds a layer by name
+func (lib *Library) Layer(name string) *goopendb.Layer {
+	return lib.layerMap[name]
+}
+
+// Via finds a LEF via by name
+func (lib *Library) Via(name string) *goopendb.Via {
+	return lib.viaMap[name]
+}
+
+// ViaRule finds a via generate rule by name
+func (lib *Library) ViaRule(name string) *goopendb.ViaRule {
+	return lib.viaRuleMap[name]
+}
+
+// Site finds a site by name
+func (lib *Library) Site(name string) *goopendb.Site {
+	return lib.siteMap[name]
+}
+
+// Master finds a macro by name
+func (lib *Library) Master(name string) *goopendb.Master {
+	return lib.masterMap[name]
+}
+
+// dist converts a distance in microns to database units
+func (lib *Library) dist(microns float64) int {
+	if lib.DBUPerMicron == 0 {
+		lib.DBUPerMicron = DefaultLEFUnits
+	}
+	return int(math.Round(microns * float64(lib.DBUPerMicron)))
+}
+
+// area converts an area in square microns to square database units
+func (lib *Library) area(microns float64) float64 {
+	if lib.DBUPerMicron == 0 {
+		lib.DBUPerMicron = DefaultLEFUnits
+	}
+	return math.Round(microns * float64(lib.DBUPerMicron) * float64(lib.DBUPerMicron))
+}
+
+// newRect creates a library shape
+func (lib *Library) newRect(b box, layer *goopendb.Layer, via *goopendb.Via) *goopendb.Rect {
+	rect := &goopendb.Rect{
+		ID:        len(lib.rects) + 1,
+		XMin:      b.xMin,
+		YMin:      b.yMin,
+		XMax:      b.xMax,
+		YMax:      b.yMax,
+		ShapeType: -1,
+		Layer:     layer,
+		Via:       via,
+	}
+	lib.rects = append(lib.rects, rect)
+	return rect
+}
+
+// newGeometry creates a library geometry
+func (lib *Library) newGeometry(boxes []*goopendb.Rect) *goopendb.Geometry {
+	geom := &goopendb.Geometry{
+		ID:    len(lib.Geometries) + 1,
+		Boxes: boxes,
+	}
+	lib.Geometries = append(lib.Geometries, geom)
+	return geom
+}
+
+// ReadLEF reads a LEF file, tech selects the technology section (units,
+// layers, vias and via rules) and cells selects the library section (sites
+// and macros)
+func (lib *Library) ReadLEF(r io.Reader, libName string, tech bool, cells bool) error {
+	lex, err := newLexer(r)
+	if err != nil {
+		return err
+	}
+	for !lex.done() {
+		keyword := lex.next()
+		switch keyword {
+		case "UNITS":
+			err = lib.readUnits(lex, tech)
+		case "LAYER":
+			name := lex.next()
+			if tech {
+				err = lib.readLayer(lex, name)
+			} else {
+				err = lex.skipBlock(name)
+			}
+		case "VIA":
+			name := lex.next()
+			if tech {
+				err = lib.readVia(lex, name)
+			} else {
+				err = lex.skipBlock(name)
+			}
+		case "VIARULE":
+			name := lex.next()
+			if tech && lex.peek() == "GENERATE" {
+				err = lib.readViaRule(lex, name)
+			} else {
+				err = lex.skipBlock(name)
+			}
+		case "SITE":
+			name := lex.next()
+			if cells {
+				err = lib.readSite(lex, name)
+			} else {
+				err = lex.skipBlock(name)
+			}
+		case "MACRO":
+			name := lex.next()
+			if cells {
+				err = lib.readMacro(lex, name, libName)
+			} else {
+				err = lex.skipBlock(name)
+			}
+		case "SPACING", "PROPERTYDEFINITIONS", "IRDROP", "NOISETABLE", "CORRECTIONTABLE":
+			err = lex.skipBlock(keyword)
+		case "NONDEFAULTRULE", "ARRAY":
+			err = lex.skipBlock(lex.next())
+		case "BEGINEXT":
+			for !lex.done() && lex.next() != "ENDEXT" {
+			}
+		case "END":
+			if lex.accept("LIBRARY") {
+				return nil
+			}
+			return lex.errorf("unexpected END %v", lex.next())
+		default:
+			lex.skipStatement()
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return lex.err
+}
+
+func (lib *Library) readUnits(lex *lexer, tech bool) error {
+	for !lex.done() {
+		switch lex.next() {
+		case "END":
+			return lex.expect("UNITS")
+		case "DATABASE":
+			if err := lex.expect("MICRONS"); err != nil {
+				return err
+			}
+			units, err := lex.integer()
+			if err != nil {
+				return err
+			}
+			if tech || lib.LEFUnits == 0 {
+				lib.LEFUnits = units
+			}
+			if lib.DBUPerMicron == 0 || tech {
+				lib.DBUPerMicron = units
+			}
+			lex.skipStatement()
+		default:
+			lex.skipStatement()
+		}
+	}
+	return lex.errorf("missing END UNITS")
+}
+
+func layerType(typ string) goopendb.LayerType {
+	switch typ {
+	case "ROUTING":
+		return goopendb.LayerTypeROUTING
+	case "CUT":
+		return goopendb.LayerTypeCUT
+	case "MASTERSLICE":
+		return goopendb.LayerTypeMASTERSLICE
+	case "OVERLAP":
+		return goopendb.LayerTypeOVERLAP
+	case "IMPLANT":
+		return goopendb.LayerTypeIMPLANT
+	}
+	return goopendb.LayerTypeNONE
+}
+
+func direction(dir string) goopendb.Direction {
+	switch dir {
+	case "HORIZONTAL":
+		return goopendb.DirectionHORIZONTAL
+	case "VERTICAL":
+		return goopendb.DirectionVERTICAL
+	}
+	return goopendb.DirectionNONE
+}
+
+func (lib *Library) readLayer(lex *lexer, name string) error {
+	layer := &goopendb.Layer{
+		ID:        len(lib.Layers) + 1,
+		Name:      name,
+		Type:      goopendb.LayerTypeNONE,
+		MaxWidth:  -1,
+		Height:    -1,
+		Thickness: -1,
+	}
+	antenna := &goopendb.AntennaRule{AreaFactor: 1.0}
+	hasAntenna := false
+	defaultModel := true // Only OXIDE1 rules make the default antenna rule
+	hasSpacing := false
+	hasMinWidth := false
+	for {
+		if lex.done() {
+			return lex.errorf("missing END %v", name)
+		}
+		keyword := lex.next()
+		var err error
+		switch keyword {
+		case "END":
+			if err = lex.expect(name); err != nil {
+				return err
+			}
+			if !hasMinWidth {
+				layer.MinWidth = layer.Width
+			}
+			if hasAntenna {
+				layer.AntennaRule = antenna
+			}
+			if n := len(lib.Layers); n > 0 {
+				lower := lib.Layers[n-1]
+				lower.UpperLayer = layer
+				layer.LowerLayer = lower
+			}
+			lib.Layers = append(lib.Layers, layer)
+			lib.layerMap[name] = layer
+			return nil
+		case "TYPE":
+			layer.Type = layerType(lex.next())
+			lex.skipStatement()
+		case "DIRECTION":
+			layer.Direction = direction(lex.next())
+			lex.skipStatement()
+		case "PITCH", "OFFSET":
+			values := lex.floats()
+			lex.skipStatement()
+			if len(values) == 0 {
+				return lex.errorf("missing %v value", keyword)
+			}
+			x, y := lib.dist(values[0]), lib.dist(values[0])
+			if len(values) > 1 {
+				y = lib.dist(values[1])
+			}
+			if keyword == "PITCH" {
+				layer.PitchX, layer.PitchY = x, y
+			} else {
+				layer.OffsetX, layer.OffsetY = x, y
+			}
+		case "WIDTH", "MINWIDTH", "MAXWIDTH", "MINSTEP", "HEIGHT", "THICKNESS":
+			var v float64
+			if v, err = lex.float(); err != nil {
+				return err
+			}
+			lex.skipStatement()
+			switch keyword {
+			case "WIDTH":
+				layer.Width = lib.dist(v)
+			case "MINWIDTH":
+				layer.MinWidth = lib.dist(v)
+				hasMinWidth = true
+			case "MAXWIDTH":
+				layer.MaxWidth = lib.dist(v)
+			case "MINSTEP":
+				layer.MinStep = lib.dist(v)
+			case "HEIGHT":
+				layer.Height = lib.dist(v)
+			case "THICKNESS":
+				layer.Thickness = lib.dist(v)
+			}
+		case "AREA":
+			if layer.Area, err = lex.float(); err != nil {
+				return err
+			}
+			lex.skipStatement()
+		case "SPACING":
+			var v float64
+			if v, err = lex.float(); err != nil {
+				return err
+			}
+			lex.skipStatement()
+			if !hasSpacing {
+				layer.Spacing = lib.dist(v)
+				hasSpacing = true
+			}
+		case "SPACINGTABLE":
+			if err = lib.readSpacingTable(lex, layer); err != nil {
+				return err
+			}
+		case "RESISTANCE", "CAPACITANCE", "EDGECAPACITANCE":
+			lex.accept("RPERSQ")
+			lex.accept("CPERSQDIST")
+			if !lex.isNumber() {
+				lex.skipStatement()
+				break
+			}
+			v, _ := lex.float()
+			lex.skipStatement()
+			switch keyword {
+			case "RESISTANCE":
+				layer.Resistance = v
+			case "CAPACITANCE":
+				layer.Capacitance = v
+			case "EDGECAPACITANCE":
+				layer.EdgeCapacitance = v
+			}
+		case "MINENCLOSEDAREA":
+			for lex.isNumber() {
+				area, _ := lex.float()
+				rule := &goopendb.MinEnclosedArea{
+					Area:  lib.area(area),
+					Width: -1,
+				}
+				if lex.accept("WIDTH") {
+					var width float64
+					if width, err = lex.float(); err != nil {
+						return err
+					}
+					rule.Width = lib.dist(width)
+				}
+				layer.MinEnclosedAreas = append(layer.MinEnclosedAreas, rule)
+			}
+			lex.skipStatement()
+		case "ANTENNAMODEL":
+			defaultModel = lex.next() == "OXIDE1"
+			lex.skipStatement()
+		case "ANTENNAAREAFACTOR", "ANTENNAAREARATIO", "ANTENNACUMAREARATIO",
+			"ANTENNASIDEAREARATIO", "ANTENNACUMSIDEAREARATIO":
+			if !lex.isNumber() {
+				lex.skipStatement()
+				break
+			}
+			v, _ := lex.float()
+			lex.skipStatement()
+			if !defaultModel {
+				break
+			}
+			hasAntenna = true
+			switch keyword {
+			case "ANTENNAAREAFACTOR":
+				antenna.AreaFactor = v
+			case "ANTENNAAREARATIO":
+				antenna.PAR = v
+			case "ANTENNACUMAREARATIO":
+				antenna.CAR = v
+			case "ANTENNASIDEAREARATIO":
+				antenna.PSR = v
+			case "ANTENNACUMSIDEAREARATIO":
+				antenna.CSR = v
+			}
+		case "ACCURRENTDENSITY", "DCCURRENTDENSITY":
+			// Either a single value or a table ending with TABLEENTRIES
+			lex.next()
+			if lex.isNumber() {
+				lex.skipStatement()
+				break
+			}
+			for !lex.done() && lex.next() != "TABLEENTRIES" {
+			}
+			lex.skipStatement()
+		default:
+			lex.skipStatement()
+		}
+	}
+}
+
+func (lib *Library) readSpacingTable(lex *lexer, layer *goopendb.Layer) error {
+	switch lex.next() {
+	case "PARALLELRUNLENGTH":
+		table := &goopendb.SpacingTable{}
+		for _, length := range lex.floats() {
+			table.Lengths = append(table.Lengths, lib.dist(length))
+		}
+		for lex.accept("WIDTH") {
+			values := lex.floats()
+			if len(values) != len(table.Lengths)+1 {
+				return lex.errorf("spacing table row size mismatch")
+			}
+			table.Widths = append(table.Widths, lib.dist(values[0]))
+			var row []int
+			for _, spacing := range values[1:] {
+				row = append(row, lib.dist(spacing))
+			}
+			table.Spacings = append(table.Spacings, row)
+		}
+		layer.SpacingTable = table
+	case "INFLUENCE":
+		for lex.accept("WIDTH") {
+			values := make([]float64, 3)
+			var err error
+			if values[0], err = lex.float(); err != nil {
+				return err
+			}
+			if err = lex.expect("WITHIN"); err != nil {
+				return err
+			}
+			if values[1], err = lex.float(); err != nil {
+				return err
+			}
+			if err = lex.expect("SPACING"); err != nil {
+				return err
+			}
+			if values[2], err = lex.float(); err != nil {
+				return err
+			}
+			layer.InfluenceSpacing = append(layer.InfluenceSpacing, &goopendb.InfluenceSpacing{
+				Width:   lib.dist(values[0]),
+				Within:  lib.dist(values[1]),
+				Spacing: lib.dist(values[2]),
+			})
+		}
+	}
+	lex.skipStatement()
+	return nil
+}
+
+// readPoints reads X Y pairs up to the end of the statement
+func (lib *Library) readPoints(lex *lexer) ([]goopendb.Point, error) {
+	var points []goopendb.Point
+	for lex.isNumber() {
+		x, _ := lex.float()
+		y, err := lex.float()
+		if err != nil {
+			return nil, err
+		}
+		points = append(points, goopendb.Point{X: lib.dist(x), Y: lib.dist(y)})
+	}
+	return points, lex.expect(";")
+}
+
+// readBox reads a RECT statement
+func (lib *Library) readBox(lex *lexer) (box, error) {
+	if lex.accept("MASK") {
+		lex.next()
+	}
+	values := lex.floats()
+	if len(values) != 4 {
+		return box{}, lex.errorf("RECT requires 4 coordinates")
+	}
+	return newBox(lib.dist(values[0]), lib.dist(values[1]), lib.dist(values[2]), lib.dist(values[3])), lex.expect(";")
+}
+
+func (lib *Library) readVia(lex *lexer, name string) error {
+	via := &goopendb.Via{
+		ID:     len(lib.Vias) + 1,
+		Name:   name,
+		IsTech: true,
+	}
+	for lex.accept("DEFAULT") || lex.accept("GENERATED") {
+	}
+	var layer *goopendb.Layer
+	var layers [3]*goopendb.Layer // Generated via bottom, cut and top layers
+	var params *goopendb.ViaParams
+	var boxes []*goopendb.Rect
+	for {
+		if lex.done() {
+			return lex.errorf("missing END %v", name)
+		}
+		keyword := lex.next()
+		switch keyword {
+		case "END":
+			if err := lex.expect(name); err != nil {
+				return err
+			}
+			if params != nil {
+				via.Params = params
+				via.CutLayer = layers[1]
+				boxes = lib.viaParamsBoxes(params, layers)
+			}
+			lib.finishVia(via, boxes)
+			lib.Vias = append(lib.Vias, via)
+			lib.viaMap[name] = via
+			return nil
+		case "LAYER":
+			layer = lib.layerMap[lex.next()]
+			if layer == nil {
+				return lex.errorf("unknown layer in via %v", name)
+			}
+			lex.skipStatement()
+		case "RECT":
+			b, err := lib.readBox(lex)
+			if err != nil {
+				return err
+			}
+			boxes = append(boxes, lib.newRect(b, layer, nil))
+		case "POLYGON":
+			if lex.accept("MASK") {
+				lex.next()
+			}
+			points, err := lib.readPoints(lex)
+			if err != nil {
+				return err
+			}
+			for _, b := range polygonToBoxes(points) {
+				boxes = append(boxes, lib.newRect(b, layer, nil))
+			}
+		case "VIARULE":
+			via.Rule = lib.viaRuleMap[lex.next()]
+			params = &goopendb.ViaParams{Rows: 1, Cols: 1}
+			lex.skipStatement()
+		case "LAYERS":
+			for i := range layers {
+				layers[i] = lib.layerMap[lex.next()]
+			}
+			lex.skipStatement()
+		case "PATTERN":
+			via.Pattern = lex.next()
+			lex.skipStatement()
+		case "CUTSIZE", "CUTSPACING", "ENCLOSURE", "ROWCOL", "ORIGIN", "OFFSET":
+			values := lex.floats()
+			lex.skipStatement()
+			if params == nil {
+				params = &goopendb.ViaParams{Rows: 1, Cols: 1}
+			}
+			if err := setViaParam(lex, params, keyword, values, lib.dist); err != nil {
+				return err
+			}
+		default:
+			lex.skipStatement()
+		}
+	}
+}
+
+// setViaParam stores a generated via parameter statement, dist converts the
+// file distances to database units
+func setViaParam(lex *lexer, params *goopendb.ViaParams, keyword string, values []float64, dist func(float64) int) error {
+	sizes := map[string]int{"CUTSIZE": 2, "CUTSPACING": 2, "ENCLOSURE": 4, "ROWCOL": 2, "ORIGIN": 2, "OFFSET": 4}
+	if len(values) != sizes[keyword] {
+		return lex.errorf("%v requires %v values", keyword, sizes[keyword])
+	}
+	switch keyword {
+	case "CUTSIZE":
+		params.CutSizeX, params.CutSizeY = dist(values[0]), dist(values[1])
+	case "CUTSPACING":
+		params.CutSpacingX, params.CutSpacingY = dist(values[0]), dist(values[1])
+	case "ENCLOSURE":
+		params.BottomEnclosureX, params.BottomEnclosureY = dist(values[0]), dist(values[1])
+		params.TopEnclosureX, params.TopEnclosureY = dist(values[2]), dist(values[3])
+	case "ROWCOL":
+		params.Rows, params.Cols = int(values[0]), int(values[1])
+	case "ORIGIN":
+		params.OriginX, params.OriginY = dist(values[0]), dist(values[1])
+	case "OFFSET":
+		params.BottomOffsetX, params.BottomOffsetY = dist(values[0]), dist(values[1])
+		params.TopOffsetX, params.TopOffsetY = dist(values[2]), dist(values[3])
+	}
+	return nil
+}
+
+// viaParamsBoxes generates the shapes of a generated via: a cut array
+// centered at the origin and the enclosing bottom and top metal
+func (lib *Library) viaParamsBoxes(params *goopendb.ViaParams, layers [3]*goopendb.Layer) []*goopendb.Rect {
+	var boxes []*goopendb.Rect
+	for _, b := range viaParamsShapes(params) {
+		boxes = append(boxes, lib.newRect(b.box, layers[b.layer], nil))
+	}
+	return boxes
+}
+
+// viaShape is a generated via shape on the bottom (0), cut (1) or top (2) layer
+type viaShape struct {
+	layer int
+	box   box
+}
+
+func viaParamsShapes(params *goopendb.ViaParams) []viaShape {
+	width := params.Cols*params.CutSizeX + (params.Cols-1)*params.CutSpacingX
+	height := params.Rows*params.CutSizeY + (params.Rows-1)*params.CutSpacingY
+	x0 := params.OriginX - width/2
+	y0 := params.OriginY - height/2
+	cuts := newBox(x0, y0, x0+width, y0+height)
+	var shapes []viaShape
+	bottom := box{
+		xMin: cuts.xMin - params.BottomEnclosureX,
+		yMin: cuts.yMin - params.BottomEnclosureY,
+		xMax: cuts.xMax + params.BottomEnclosureX,
+		yMax: cuts.yMax + params.BottomEnclosureY,
+	}
+	shapes = append(shapes, viaShape{layer: 0, box: bottom.translate(params.BottomOffsetX, params.BottomOffsetY)})
+	for row := 0; row < params.Rows; row++ {
+		for col := 0; col < params.Cols; col++ {
+			x := x0 + col*(params.CutSizeX+params.CutSpacingX)
+			y := y0 + row*(params.CutSizeY+params.CutSpacingY)
+			shapes = append(shapes, viaShape{layer: 1, box: newBox(x, y, x+params.CutSizeX, y+params.CutSizeY)})
+		}
+	}
+	top := box{
+		xMin: cuts.xMin - params.TopEnclosureX,
+		yMin: cuts.yMin - params.TopEnclosureY,
+		xMax: cuts.xMax + params.TopEnclosureX,
+		yMax: cuts.yMax + params.TopEnclosureY,
+	}
+	shapes = append(shapes, viaShape{layer: 2, box: top.translate(params.TopOffsetX, params.TopOffsetY)})
+	return shapes
+}
+
+// finishVia sets the via shapes, bounding box and routing layers
+func (lib *Library) finishVia(via *goopendb.Via, boxes []*goopendb.Rect) {
+	via.Boxes = boxes
+	var bbox box
+	bbox, via.BottomLayer, via.TopLayer = viaBounds(boxes)
+	via.Rect = lib.newRect(bbox, nil, nil)
+}
+
+// viaBounds returns the bounding box of via shapes and the bottom and top
+// layers, which are the lowest and highest non-cut layers
+func viaBounds(boxes []*goopendb.Rect) (bbox box, bottom *goopendb.Layer, top *goopendb.Layer) {
+	for i, rect := range boxes {
+		b := box{xMin: rect.XMin, yMin: rect.YMin, xMax: rect.XMax, yMax: rect.YMax}
+		if i == 0 {
+			bbox = b
+		} else {
+			bbox = bbox.merge(b)
+		}
+		if rect.Layer == nil || rect.Layer.Type == goopendb.LayerTypeCUT {
+			continue
+		}
+		if bottom == nil || rect.Layer.ID < bottom.ID {
+			bottom = rect.Layer
+		}
+		if top == nil || rect.Layer.ID > top.ID {
+			top = rect.Layer
+		}
+	}
+	return
+}
+
+func (lib *Library) readViaRule(lex *lexer, name string) error {
+	lex.next() // GENERATE
+	rule := &goopendb.ViaRule{
+		ID:        len(lib.ViaRules) + 1,
+		Name:      name,
+		IsDefault: lex.accept("DEFAULT"),
+	}
+	var ruleLayer *goopendb.ViaRuleLayer
+	for {
+		if lex.done() {
+			return lex.errorf("missing END %v", name)
+		}
+		keyword := lex.next()
+		if keyword == "END" {
+			if err := lex.expect(name); err != nil {
+				return err
+			}
+			lib.ViaRules = append(lib.ViaRules, rule)
+			lib.viaRuleMap[name] = rule
+			return nil
+		}
+		if keyword == "LAYER" {
+			ruleLayer = &goopendb.ViaRuleLayer{Layer: lib.layerMap[lex.next()]}
+			rule.Layers = append(rule.Layers, ruleLayer)
+			lex.skipStatement()
+			continue
+		}
+		if ruleLayer == nil {
+			lex.skipStatement()
+			continue
+		}
+		switch keyword {
+		case "DIRECTION":
+			ruleLayer.Direction = direction(lex.next())
+			lex.skipStatement()
+		case "ENCLOSURE":
+			values := lex.floats()
+			lex.skipStatement()
+			if len(values) != 2 {
+				return lex.errorf("ENCLOSURE requires 2 values")
+			}
+			ruleLayer.HasEnclosure = true
+			ruleLayer.EnclosureOverhang1 = lib.dist(values[0])
+			ruleLayer.EnclosureOverhang2 = lib.dist(values[1])
+		case "WIDTH":
+			minWidth, err := lex.float()
+			if err != nil {
+				return err
+			}
+			if err = lex.expect("TO"); err != nil {
+				return err
+			}
+			maxWidth, err := lex.float()
+			if err != nil {
+				return err
+			}
+			lex.skipStatement()
+			ruleLayer.HasWidth = true
+			ruleLayer.MinWidth = lib.dist(minWidth)
+			ruleLayer.MaxWidth = lib.dist(maxWidth)
+		case "RECT":
+			b, err := lib.readBox(lex)
+			if err != nil {
+				return err
+			}
+			ruleLayer.Rect = lib.newRect(b, nil, nil)
+		case "SPACING":
+			x, err := lex.float()
+			if err != nil {
+				return err
+			}
+			if err = lex.expect("BY"); err != nil {
+				return err
+			}
+			y, err := lex.float()
+			if err != nil {
+				return err
+			}
+			lex.skipStatement()
+			ruleLayer.HasSpacing = true
+			ruleLayer.SpacingX = lib.dist(x)
+			ruleLayer.SpacingY = lib.dist(y)
+		case "RESISTANCE":
+			v, err := lex.float()
+			if err != nil {
+				return err
+			}
+			lex.skipStatement()
+			ruleLayer.Resistance = v
+		default:
+			lex.skipStatement()
+		}
+	}
+}
+
+func (lib *Library) readSite(lex *lexer, name string) error {
+	info := &SiteInfo{}
+	for {
+		if lex.done() {
+			return lex.errorf("missing END %v", name)
+		}
+		switch lex.next() {
+		case "END":
+			if err := lex.expect(name); err != nil {
+				return err
+			}
+			site, ok := lib.siteMap[name]
+			if !ok {
+				site = &goopendb.Site{
+					ID:   len(lib.Sites) + 1,
+					Name: name,
+				}
+				lib.Sites = append(lib.Sites, site)
+				lib.siteMap[name] = site
+			}
+			site.Class, site.Width, site.Height = info.Class, info.Width, info.Height
+			for _, symmetry := range info.Symmetry {
+				switch symmetry {
+				case "X":
+					site.SymmetryX = true
+				case "Y":
+					site.SymmetryY = true
+				case "R90":
+					site.SymmetryR90 = true
+				}
+			}
+			lib.SiteInfo[name] = info
+			return nil
+		case "CLASS":
+			info.Class = lex.next()
+			lex.skipStatement()
+		case "SYMMETRY":
+			for lex.peek() != ";" && !lex.done() {
+				info.Symmetry = append(info.Symmetry, lex.next())
+			}
+			lex.skipStatement()
+		case "SIZE":
+			w, err := lex.float()
+			if err != nil {
+				return err
+			}
+			if err = lex.expect("BY"); err != nil {
+				return err
+			}
+			h, err := lex.float()
+			if err != nil {
+				return err
+			}
+			lex.skipStatement()
+			info.Width, info.Height = lib.dist(w), lib.dist(h)
+		case "ROWPATTERN":
+			for lex.peek() != ";" && !lex.done() {
+				lex.next()
+				lex.next()
+				info.RowLength++
+			}
+			lex.skipStatement()
+		default:
+			lex.skipStatement()
+		}
+	}
+}
+
+func masterType(class string) goopendb.MasterType {
+	switch {
+	case strings.HasPrefix(class, "BLOCK"):
+		return goopendb.MasterTypeBLOCK
+	case strings.HasPrefix(class, "CORE"):
+		return goopendb.MasterTypeCORE
+	case strings.HasPrefix(class, "ENDCAP"):
+		return goopendb.MasterTypeENDCAP
+	case strings.HasPrefix(class, "PAD"):
+		return goopendb.MasterTypePAD
+	case strings.HasPrefix(class, "COVER"):
+		return goopendb.MasterTypeCOVER
+	case class == "RING":
+		return goopendb.MasterTypeRING
+	}
+	return goopendb.MasterTypeCORE
+}
+
+func ioType(dir string) goopendb.IoType {
+	switch dir {
+	case "OUTPUT":
+		return goopendb.IOTypeOUTPUT
+	case "INOUT":
+		return goopendb.IOTypeINOUT
+	case "FEEDTHRU":
+		return goopendb.IOTypeFEEDTHRU
+	}
+	return goopendb.IOTypeINPUT
+}
+
+func signalType(use string) goopendb.SignalType {
+	switch use {
+	case "POWER":
+		return goopendb.SignalTypePOWER
+	case "GROUND":
+		return goopendb.SignalTypeGROUND
+	case "CLOCK":
+		return goopendb.SignalTypeCLOCK
+	case "ANALOG":
+		return goopendb.SignalTypeANALOG
+	case "RESET":
+		return goopendb.SignalTypeRESET
+	case "SCAN":
+		return goopendb.SignalTypeSCAN
+	case "TIEOFF":
+		return goopendb.SignalTypeTIEOFF
+	}
+	return goopendb.SignalTypeSIGNAL
+}
+
+func (lib *Library) readMacro(lex *lexer, name string, libName string) error {
+	master := &goopendb.Master{
+		ID:      len(lib.Masters) + 1,
+		Name:    name,
+		Library: libName,
+		Type:    goopendb.MasterTypeCORE,
+		Class:   "CORE",
+		Origin:  &goopendb.Point{},
+	}
+	var obstructions []*goopendb.Rect
+	for {
+		if lex.done() {
+			return lex.errorf("missing END %v", name)
+		}
+		switch lex.next() {
+		case "END":
+			if err := lex.expect(name); err != nil {
+				return err
+			}
+			master.Obstructions = lib.newGeometry(obstructions)
+			master.IsFiller = master.Class == "CORE_SPACER"
+			if existing, ok := lib.masterMap[name]; ok {
+				// A redefined macro replaces the earlier definition
+				master.ID = existing.ID
+				lib.Masters[existing.ID-1] = master
+			} else {
+				lib.Masters = append(lib.Masters, master)
+			}
+			lib.masterMap[name] = master
+			return nil
+		case "CLASS":
+			var words []string
+			for lex.peek() != ";" && !lex.done() {
+				words = append(words, lex.next())
+			}
+			lex.skipStatement()
+			master.Class = strings.Join(words, "_")
+			master.Type = masterType(master.Class)
+		case "FOREIGN":
+			foreign := &goopendb.Foreign{Name: lex.next()}
+			if values := lex.floats(); len(values) == 2 {
+				foreign.Origin = &goopendb.Point{X: lib.dist(values[0]), Y: lib.dist(values[1])}
+			} else if len(values) != 0 {
+				return lex.errorf("FOREIGN origin requires 2 values")
+			}
+			if orient, ok := defOrientations[lex.peek()]; ok {
+				lex.next()
+				foreign.Orientation = orient
+			}
+			lex.skipStatement()
+			master.Foreign = foreign
+		case "ORIGIN":
+			values := lex.floats()
+			lex.skipStatement()
+			if len(values) != 2 {
+				return lex.errorf("ORIGIN requires 2 values")
+			}
+			master.Origin = &goopendb.Point{X: lib.dist(values[0]), Y: lib.dist(values[1])}
+		case "SIZE":
+			w, err := lex.float()
+			if err != nil {
+				return err
+			}
+			if err = lex.expect("BY"); err != nil {
+				return err
+			}
+			h, err := lex.float()
+			if err != nil {
+				return err
+			}
+			lex.skipStatement()
+			master.Width, master.Height = lib.dist(w), lib.dist(h)
+		case "SYMMETRY":
+			for lex.peek() != ";" && !lex.done() {
+				switch lex.next() {
+				case "X":
+					master.SymmetryX = true
+				case "Y":
+					master.SymmetryY = true
+				case "R90":
+					master.SymmetryR90 = true
+				}
+			}
+			lex.skipStatement()
+		case "SITE":
+			master.Site = lib.siteMap[lex.next()]
+			lex.skipStatement()
+		case "PIN":
+			pin, err := lib.readPin(lex, lex.next())
+			if err != nil {
+				return err
+			}
+			master.Pins = append(master.Pins, pin)
+		case "OBS":
+			boxes, err := lib.readShapes(lex)
+			if err != nil {
+				return err
+			}
+			obstructions = append(obstructions, boxes...)
+		case "DENSITY":
+			for !lex.done() && lex.next() != "END" {
+			}
+		case "TIMING":
+			if err := lex.skipBlock("TIMING"); err != nil {
+				return err
+			}
+		default:
+			lex.skipStatement()
+		}
+	}
+}
+
+func (lib *Library) readPin(lex *lexer, name string) (*goopendb.MasterPin, error) {
+	lib.pinID++
+	pin := &goopendb.MasterPin{
+		ID:         lib.pinID,
+		Name:       name,
+		Direction:  goopendb.IOTypeINPUT,
+		SignalType: goopendb.SignalTypeSIGNAL,
+	}
+	for {
+		if lex.done() {
+			return nil, lex.errorf("missing END %v", name)
+		}
+		switch lex.next() {
+		case "END":
+			return pin, lex.expect(name)
+		case "DIRECTION":
+			pin.Direction = ioType(lex.next())
+			lex.skipStatement()
+		case "USE":
+			pin.SignalType = signalType(lex.next())
+			lex.skipStatement()
+		case "PORT":
+			boxes, err := lib.readShapes(lex)
+			if err != nil {
+				return nil, err
+			}
+			pin.Geometries = append(pin.Geometries, lib.newGeometry(boxes))
+		default:
+			lex.skipStatement()
+		}
+	}
+}
+
+// readShapes reads PORT or OBS geometries up to END
+func (lib *Library) readShapes(lex *lexer) ([]*goopendb.Rect, error) {
+	var layer *goopendb.Layer
+	var boxes []*goopendb.Rect
+	width := 0
+	for {
+		if lex.done() {
+			return nil, lex.errorf("missing END")
+		}
+		keyword := lex.next()
+		switch keyword {
+		case "END":
+			return boxes, nil
+		case "LAYER":
+			layer = lib.layerMap[lex.next()]
+			if layer == nil {
+				return nil, lex.errorf("unknown layer")
+			}
+			width = layer.Width
+			lex.skipStatement()
+		case "WIDTH":
+			v, err := lex.float()
+			if err != nil {
+				return nil, err
+			}
+			width = lib.dist(v)
+			lex.skipStatement()
+		case "RECT":
+			if lex.peek() == "ITERATE" || (lex.peek() == "MASK" && lex.peekAt(2) == "ITERATE") {
+				lex.skipStatement()
+				break
+			}
+			b, err := lib.readBox(lex)
+			if err != nil {
+				return nil, err
+			}
+			boxes = append(boxes, lib.newRect(b, layer, nil))
+		case "PATH", "POLYGON":
+			if lex.accept("MASK") {
+				lex.next()
+			}
+			if lex.peek() == "ITERATE" {
+				lex.skipStatement()
+				break
+			}
+			points, err := lib.readPoints(lex)
+			if err != nil {
+				return nil, err
+			}
+			var shapes []box
+			if keyword == "PATH" {
+				shapes = pathToBoxes(points, width)
+			} else {
+				shapes = polygonToBoxes(points)
+			}
+			for _, b := range shapes {
+				boxes = append(boxes, lib.newRect(b, layer, nil))
+			}
+		case "VIA":
+			if lex.accept("MASK") {
+				lex.next()
+			}
+			if lex.peek() == "ITERATE" {
+				lex.skipStatement()
+				break
+			}
+			x, err := lex.float()
+			if err != nil {
+				return nil, err
+			}
+			y, err := lex.float()
+			if err != nil {
+				return nil, err
+			}
+			via := lib.viaMap[lex.next()]
+			lex.skipStatement()
+			if via == nil || via.Rect == nil {
+				return nil, lex.errorf("unknown via")
+			}
+			b := box{xMin: via.Rect.XMin, yMin: via.Rect.YMin, xMax: via.Rect.XMax, yMax: via.Rect.YMax}
+			boxes = append(boxes, lib.newRect(b.translate(lib.dist(x), lib.dist(y)), nil, via))
+		default:
+			lex.skipStatement()
+		}
+	}
+}
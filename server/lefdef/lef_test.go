@@ -0,0 +1,295 @@
+package lefdef
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ahmed-agiza/EDAViewer/server/goopendb"
+)
+
+const lefPath = "../example/Nangate45/NangateOpenCellLibrary.mod.lef"
+
+func TestParseLEF(t *testing.T) {
+	backend := NewBackend()
+	defer backend.FreeDatabase()
+	if err := backend.ParseLEF(lefPath); err != nil {
+		t.Fatal(err)
+	}
+	design, err := backend.GetDesign()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]int{
+		"DBUPerMicron":   2000,
+		"Layers":         22,
+		"ViaDefinitions": 27,
+		"ViaRules":       19,
+		"Sites":          1,
+		"Masters":        134,
+	}
+	actual := map[string]int{
+		"DBUPerMicron":   design.DBUPerMicron,
+		"Layers":         len(design.Layers),
+		"ViaDefinitions": len(design.ViaDefinitions),
+		"ViaRules":       len(design.ViaRules),
+		"Sites":          len(design.Sites),
+		"Masters":        len(design.Masters),
+	}
+	for k, v := range expected {
+		if actual[k] != v {
+			t.Errorf("Expected %v to be %v, found %v", k, v, actual[k])
+		}
+	}
+
+	metal2 := backend.Library.Layer("metal2")
+	if metal2 == nil || metal2.Type != goopendb.LayerTypeROUTING || metal2.Direction != goopendb.DirectionVERTICAL {
+		t.Fatalf("Unexpected metal2 layer %+v", metal2)
+	}
+	if metal2.Width != 140 || metal2.PitchX != 380 || metal2.PitchY != 280 || metal2.Thickness != 280 ||
+		metal2.Height != 1240 {
+		t.Errorf("Unexpected metal2 rules %+v", metal2)
+	}
+	if via1 := backend.Library.Layer("via1"); via1.Height != -1 || via1.Thickness != -1 {
+		t.Errorf("Expected unset via1 height and thickness, found %v and %v", via1.Height, via1.Thickness)
+	}
+	if table := metal2.SpacingTable; table == nil || len(table.Widths) != 6 || len(table.Lengths) != 6 || table.Spacings[5][5] != 3000 {
+		t.Errorf("Unexpected metal2 spacing table %+v", table)
+	}
+	if metal2.LowerLayer.Name != "via1" || metal2.UpperLayer.Name != "via2" {
+		t.Errorf("Unexpected metal2 neighbours %v, %v", metal2.LowerLayer.Name, metal2.UpperLayer.Name)
+	}
+
+	via := backend.Library.Via("via1_1")
+	if via.BottomLayer.Name != "metal1" || via.TopLayer.Name != "metal2" || len(via.Boxes) != 3 {
+		t.Errorf("Unexpected via %+v", via)
+	}
+	if via.Rect.XMin != -140 || via.Rect.YMax != 140 {
+		t.Errorf("Unexpected via bounding box %+v", via.Rect)
+	}
+
+	rule := backend.Library.ViaRule("Via1Array-0")
+	if len(rule.Layers) != 3 || !rule.Layers[0].HasEnclosure || rule.Layers[0].EnclosureOverhang1 != 70 ||
+		rule.Layers[2].SpacingX != 300 || rule.Layers[2].Rect.XMax != 70 {
+		t.Errorf("Unexpected via rule %+v", rule)
+	}
+
+	master := backend.Library.Master("AND2_X1")
+	if master.Width != 1520 || master.Height != 2800 || !master.SymmetryX || !master.SymmetryY || master.SymmetryR90 {
+		t.Errorf("Unexpected master %+v", master)
+	}
+	if master.Site == nil || master.Site.Name != "FreePDK45_38x28_10R_NP_162NW_34O" || master.Class != "CORE" {
+		t.Errorf("Unexpected master site or class %+v", master)
+	}
+	if foreign := master.Foreign; foreign == nil || foreign.Name != "AND2_X1" || foreign.Origin == nil || *foreign.Origin != (goopendb.Point{}) {
+		t.Errorf("Unexpected master foreign %+v", foreign)
+	}
+	if len(master.Pins) != 5 || len(master.Obstructions.Boxes) != 5 {
+		t.Fatalf("Expected 5 pins and 5 obstructions, found %v and %v", len(master.Pins), len(master.Obstructions.Boxes))
+	}
+	vdd := master.Pins[3]
+	if vdd.Name != "VDD" || vdd.SignalType != goopendb.SignalTypePOWER || vdd.Direction != goopendb.IOTypeINOUT ||
+		len(vdd.Geometries) != 1 || len(vdd.Geometries[0].Boxes) != 3 || vdd.Geometries[0].Boxes[0].Layer.Name != "metal1" {
+		t.Errorf("Unexpected VDD pin %+v", vdd)
+	}
+}
+
+func TestParseLEFSections(t *testing.T) {
+	backend := NewBackend()
+	if err := backend.ParseLEFTechnology(lefPath); err != nil {
+		t.Fatal(err)
+	}
+	if len(backend.Library.Masters) != 0 || len(backend.Library.Layers) != 22 {
+		t.Errorf("Technology section should only read the technology")
+	}
+	if err := backend.ParseLEFLibrary(lefPath); err != nil {
+		t.Fatal(err)
+	}
+	if len(backend.Library.Masters) != 134 || len(backend.Library.Layers) != 22 {
+		t.Errorf("Library section should only read the cells")
+	}
+}
+
+func TestParseLEFStrings(t *testing.T) {
+	lef := `UNITS
+  DATABASE MICRONS 2000 ;
+END UNITS
+LAYER metal1
+  TYPE ROUTING ;
+  SPACING 0.07 ;
+  PROPERTY LEF58_SPACING "
+    SPACING 0.15 ENDOFLINE 0.1 WITHIN 0.05 ;
+    SPACING 0.2 PARALLELEDGE 0.1 ; " ;
+  WIDTH 0.07 ;
+END metal1
+MACRO INV_X1
+  CLASS CORE ;
+  SIZE 0.38 BY 1.4 ;
+END INV_X1
+MACRO INV_X1
+  CLASS CORE ;
+  SIZE 0.57 BY 1.4 ;
+END INV_X1
+MACRO BUF_X1
+  SIZE 0.76 BY 1.4 ;
+END BUF_X1
+END LIBRARY
+`
+	lib := NewLibrary()
+	if err := lib.ReadLEF(strings.NewReader(lef), "lib", true, true); err != nil {
+		t.Fatal(err)
+	}
+	metal1 := lib.Layer("metal1")
+	if metal1.Spacing != 140 || metal1.Width != 140 {
+		t.Errorf("Multi-line property changed metal1 rules %+v", metal1)
+	}
+
+	// The redefined macro replaces the first one
+	if len(lib.Masters) != 2 {
+		t.Fatalf("Expected 2 masters, found %v", len(lib.Masters))
+	}
+	inv := lib.Master("INV_X1")
+	if lib.Masters[0] != inv || inv.ID != 1 || inv.Width != 1140 || lib.Masters[1].ID != 2 {
+		t.Errorf("Unexpected masters %+v, %+v", lib.Masters[0], lib.Masters[1])
+	}
+}
+
+func TestLexerStrings(t *testing.T) {
+	lex, err := newLexer(strings.NewReader("PROPERTY a \"x ;\ny\" ; # comment\nB \"open"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tokens []string
+	for !lex.done() {
+		tokens = append(tokens, lex.next())
+	}
+	expected := []string{"PROPERTY", "a", "x ;\ny", ";", "B", "open"}
+	if !reflect.DeepEqual(tokens, expected) {
+		t.Errorf("Expected tokens %q, found %q", expected, tokens)
+	}
+	if lex.line() != 3 {
+		t.Errorf("Expected line 3, found %v", lex.line())
+	}
+}
+
+func TestWriteLEF(t *testing.T) {
+	backend := NewBackend()
+	defer backend.FreeDatabase()
+	if err := backend.ParseLEF(lefPath); err != nil {
+		t.Fatal(err)
+	}
+	design, err := backend.GetDesign()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var tech, cells bytes.Buffer
+	if err = goopendb.WriteLEFTechnology(design, &tech); err != nil {
+		t.Fatal(err)
+	}
+	if err = goopendb.WriteLEFLibrary(design, &cells); err != nil {
+		t.Fatal(err)
+	}
+	lib := NewLibrary()
+	if err = lib.ReadLEF(&tech, "tech", true, false); err != nil {
+		t.Fatal(err)
+	}
+	if len(lib.Masters) != 0 {
+		t.Errorf("Technology section should not have masters")
+	}
+	if err = lib.ReadLEF(&cells, "cells", false, true); err != nil {
+		t.Fatal(err)
+	}
+
+	original := backend.Library
+	if lib.DBUPerMicron != original.DBUPerMicron || len(lib.Layers) != len(original.Layers) ||
+		len(lib.Vias) != len(original.Vias) || len(lib.ViaRules) != len(original.ViaRules) ||
+		len(lib.Sites) != len(original.Sites) || len(lib.Masters) != len(original.Masters) {
+		t.Fatalf("Unexpected library sizes")
+	}
+	for i, layer := range lib.Layers {
+		other := original.Layers[i]
+		if layer.Name != other.Name || layer.Type != other.Type || layer.Direction != other.Direction ||
+			layer.Width != other.Width || layer.MinWidth != other.MinWidth || layer.Spacing != other.Spacing ||
+			layer.PitchX != other.PitchX || layer.PitchY != other.PitchY || layer.OffsetX != other.OffsetX ||
+			layer.Height != other.Height || layer.Thickness != other.Thickness || layer.Resistance != other.Resistance ||
+			layer.Capacitance != other.Capacitance || layer.EdgeCapacitance != other.EdgeCapacitance ||
+			!reflect.DeepEqual(layer.SpacingTable, other.SpacingTable) ||
+			!reflect.DeepEqual(layer.MinEnclosedAreas, other.MinEnclosedAreas) ||
+			!reflect.DeepEqual(layer.AntennaRule, other.AntennaRule) {
+			t.Errorf("Layer %v mismatch", layer.Name)
+		}
+	}
+	for i, via := range lib.Vias {
+		other := original.Vias[i]
+		if via.Name != other.Name || len(via.Boxes) != len(other.Boxes) || via.Rect.XMin != other.Rect.XMin ||
+			via.Rect.YMax != other.Rect.YMax || via.BottomLayer.Name != other.BottomLayer.Name {
+			t.Errorf("Via %v mismatch", via.Name)
+		}
+	}
+	for i, rule := range lib.ViaRules {
+		other := original.ViaRules[i]
+		if rule.Name != other.Name || rule.IsDefault != other.IsDefault || len(rule.Layers) != len(other.Layers) {
+			t.Errorf("Via rule %v mismatch", rule.Name)
+			continue
+		}
+		for j, ruleLayer := range rule.Layers {
+			a, b := *ruleLayer, *other.Layers[j]
+			a.Layer, b.Layer, a.Rect, b.Rect = nil, nil, nil, nil
+			if a != b {
+				t.Errorf("Via rule %v layer %v mismatch", rule.Name, j)
+			}
+		}
+	}
+	if site, other := lib.Sites[0], original.Sites[0]; *site != *other {
+		t.Errorf("Unexpected site %+v", site)
+	}
+	for i, master := range lib.Masters {
+		other := original.Masters[i]
+		if master.Name != other.Name || master.Class != other.Class || master.Width != other.Width ||
+			master.Height != other.Height || master.SymmetryX != other.SymmetryX || master.SymmetryR90 != other.SymmetryR90 ||
+			master.Site.Name != other.Site.Name || !reflect.DeepEqual(master.Foreign, other.Foreign) || len(master.Pins) != len(other.Pins) ||
+			len(master.Obstructions.Boxes) != len(other.Obstructions.Boxes) {
+			t.Errorf("Master %v mismatch", master.Name)
+			continue
+		}
+		for j, pin := range master.Pins {
+			otherPin := other.Pins[j]
+			if pin.Name != otherPin.Name || pin.Direction != otherPin.Direction || pin.SignalType != otherPin.SignalType ||
+				len(pin.Geometries) != len(otherPin.Geometries) ||
+				len(pin.Geometries[0].Boxes) != len(otherPin.Geometries[0].Boxes) {
+				t.Errorf("Master %v pin %v mismatch", master.Name, pin.Name)
+			}
+		}
+	}
+}
+
+func TestPolygonToBoxes(t *testing.T) {
+	// L shape
+	points := []goopendb.Point{{X: 0, Y: 0}, {X: 20, Y: 0}, {X: 20, Y: 10}, {X: 10, Y: 10}, {X: 10, Y: 30}, {X: 0, Y: 30}}
+	boxes := polygonToBoxes(points)
+	if len(boxes) != 2 {
+		t.Fatalf("Expected 2 boxes, found %v", boxes)
+	}
+	if boxes[0] != (box{0, 0, 20, 10}) || boxes[1] != (box{0, 10, 10, 30}) {
+		t.Errorf("Unexpected boxes %v", boxes)
+	}
+}
+
+func TestMasterType(t *testing.T) {
+	for class, expected := range map[string]goopendb.MasterType{
+		"CORE_SPACER": goopendb.MasterTypeCORE,
+		"BLOCK":       goopendb.MasterTypeBLOCK,
+		"PAD_INPUT":   goopendb.MasterTypePAD,
+		"ENDCAP_PRE":  goopendb.MasterTypeENDCAP,
+		"COVER":       goopendb.MasterTypeCOVER,
+		"COVER_BUMP":  goopendb.MasterTypeCOVER,
+		"RING":        goopendb.MasterTypeRING,
+	} {
+		if typ := masterType(class); typ != expected {
+			t.Errorf("Expected %v master type of %v, found %v", expected, class, typ)
+		}
+	}
+}
@@ -0,0 +1,232 @@
+package lefdef
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// token is a single LEF/DEF word and the line it appears on
+type token struct {
+	text string
+	line int
+}
+
+// lexer splits LEF/DEF input into whitespace separated tokens, dropping
+// comments and splitting statement terminators. Lines are read as the tokens
+// are consumed, quoted strings may span several lines
+type lexer struct {
+	r      *bufio.Reader
+	lineNo int     // Lines read so far
+	tokens []token // Read tokens which are not consumed yet
+	last   int     // Line of the last consumed token
+	eof    bool
+	err    error // Read error other than io.EOF
+
+	quote     *strings.Builder // Open quoted string continued on the next line
+	quoteLine int
+}
+
+func newLexer(r io.Reader) (*lexer, error) {
+	lex := &lexer{r: bufio.NewReader(r)}
+	lex.fill(1)
+	if lex.err != nil {
+		return nil, lex.err
+	}
+	return lex, nil
+}
+
+// fill reads lines until n tokens are available or the input ends
+func (lex *lexer) fill(n int) {
+	for len(lex.tokens) < n && !lex.eof {
+		text, err := lex.r.ReadString('\n')
+		if err != nil {
+			lex.eof = true
+			if err != io.EOF {
+				lex.err = err
+				return
+			}
+			if text == "" {
+				break
+			}
+		}
+		lex.lineNo++
+		text = strings.TrimSuffix(strings.TrimSuffix(text, "\n"), "\r")
+		lex.tokenizeLine(text, lex.lineNo)
+	}
+	if lex.eof && lex.quote != nil {
+		// Unterminated string at the end of input
+		text := strings.TrimSuffix(lex.quote.String(), "\n")
+		lex.tokens = append(lex.tokens, token{text: text, line: lex.quoteLine})
+		lex.quote = nil
+	}
+}
+
+func (lex *lexer) tokenizeLine(text string, line int) {
+	i := 0
+	if lex.quote != nil {
+		end := strings.IndexByte(text, '"')
+		if end < 0 {
+			lex.quote.WriteString(text + "\n")
+			return
+		}
+		lex.quote.WriteString(text[:end])
+		lex.tokens = append(lex.tokens, token{text: lex.quote.String(), line: lex.quoteLine})
+		lex.quote = nil
+		i = end + 1
+	}
+	for i < len(text) {
+		c := text[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\r':
+			i++
+		case c == '#':
+			return
+		case c == '"':
+			end := strings.IndexByte(text[i+1:], '"')
+			if end < 0 {
+				// The string continues on the next lines
+				lex.quote = &strings.Builder{}
+				lex.quote.WriteString(text[i+1:] + "\n")
+				lex.quoteLine = line
+				return
+			}
+			lex.tokens = append(lex.tokens, token{text: text[i+1 : i+1+end], line: line})
+			i += end + 2
+		default:
+			start := i
+			for i < len(text) && text[i] != ' ' && text[i] != '\t' && text[i] != '\r' {
+				i++
+			}
+			word := text[start:i]
+			if len(word) > 1 && strings.HasSuffix(word, ";") {
+				lex.tokens = append(lex.tokens, token{text: word[:len(word)-1], line: line})
+				word = ";"
+			}
+			lex.tokens = append(lex.tokens, token{text: word, line: line})
+		}
+	}
+}
+
+// done reports whether all tokens are consumed
+func (lex *lexer) done() bool {
+	lex.fill(1)
+	return len(lex.tokens) == 0
+}
+
+// next consumes a token, empty at the end of input
+func (lex *lexer) next() string {
+	if lex.done() {
+		return ""
+	}
+	tok := lex.tokens[0]
+	lex.tokens = lex.tokens[1:]
+	lex.last = tok.line
+	return tok.text
+}
+
+// peek returns the next token without consuming it
+func (lex *lexer) peek() string {
+	return lex.peekAt(0)
+}
+
+// peekAt returns the token offset positions ahead without consuming it
+func (lex *lexer) peekAt(offset int) string {
+	lex.fill(offset + 1)
+	if offset >= len(lex.tokens) {
+		return ""
+	}
+	return lex.tokens[offset].text
+}
+
+// line returns the line of the last consumed token
+func (lex *lexer) line() int {
+	if lex.last == 0 && !lex.done() {
+		return lex.tokens[0].line
+	}
+	return lex.last
+}
+
+// errorf reports a syntax error at the current line, read errors take
+// precedence as they cut the input short
+func (lex *lexer) errorf(format string, args ...interface{}) error {
+	if lex.err != nil {
+		return lex.err
+	}
+	return fmt.Errorf("line %d: %v", lex.line(), fmt.Sprintf(format, args...))
+}
+
+// expect consumes a token that must match text
+func (lex *lexer) expect(text string) error {
+	if tok := lex.next(); tok != text {
+		return lex.errorf("expected %q, found %q", text, tok)
+	}
+	return nil
+}
+
+// accept consumes the next token if it matches text
+func (lex *lexer) accept(text string) bool {
+	if lex.peek() == text {
+		lex.next()
+		return true
+	}
+	return false
+}
+
+// skipStatement consumes tokens up to and including the next ";"
+func (lex *lexer) skipStatement() {
+	for !lex.done() {
+		if lex.next() == ";" {
+			return
+		}
+	}
+}
+
+// skipBlock consumes tokens up to and including "END name"
+func (lex *lexer) skipBlock(name string) error {
+	for !lex.done() {
+		if lex.next() == "END" && lex.peek() == name {
+			lex.next()
+			return nil
+		}
+	}
+	return lex.errorf("missing END %v", name)
+}
+
+// float consumes a number
+func (lex *lexer) float() (float64, error) {
+	tok := lex.next()
+	v, err := strconv.ParseFloat(tok, 64)
+	if err != nil {
+		return 0, lex.errorf("expected a number, found %q", tok)
+	}
+	return v, nil
+}
+
+// integer consumes an integer
+func (lex *lexer) integer() (int, error) {
+	tok := lex.next()
+	v, err := strconv.Atoi(tok)
+	if err != nil {
+		return 0, lex.errorf("expected an integer, found %q", tok)
+	}
+	return v, nil
+}
+
+// isNumber reports whether the next token is a number
+func (lex *lexer) isNumber() bool {
+	_, err := strconv.ParseFloat(lex.peek(), 64)
+	return err == nil
+}
+
+// floats consumes numbers up to the next non-numeric token
+func (lex *lexer) floats() []float64 {
+	var values []float64
+	for lex.isNumber() {
+		v, _ := lex.float()
+		values = append(values, v)
+	}
+	return values
+}
@@ -0,0 +1,149 @@
+package render
+
+// PNG rasterization of a design
+
+import (
+	"bytes"
+	"encoding/base64"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"math"
+	"strconv"
+
+	"github.com/ahmed-agiza/EDAViewer/server/goopendb"
+)
+
+// ThumbnailSize is the largest side of design thumbnails in pixels
+const ThumbnailSize = 512
+
+// parseColor converts a validated "#rrggbb" color with an opacity
+func parseColor(hex string, opacity float64) color.NRGBA {
+	v, _ := strconv.ParseUint(hex[1:], 16, 32)
+	return color.NRGBA{R: uint8(v >> 16), G: uint8(v >> 8), B: uint8(v), A: uint8(math.Round(opacity * 255))}
+}
+
+// raster draws scene objects into an image
+type raster struct {
+	s   *scene
+	img *image.RGBA
+}
+
+// pixels converts a rectangle to pixel bounds, shapes are at least one pixel
+// wide so thin wires stay visible
+func (ra *raster) pixels(r rect) image.Rectangle {
+	scale := ra.s.scale()
+	v := ra.s.viewport
+	x0 := int(math.Floor(float64(r.xMin-v.xMin) * scale))
+	x1 := int(math.Ceil(float64(r.xMax-v.xMin) * scale))
+	y0 := int(math.Floor(float64(v.yMax-r.yMax) * scale))
+	y1 := int(math.Ceil(float64(v.yMax-r.yMin) * scale))
+	if x1 == x0 {
+		x1++
+	}
+	if y1 == y0 {
+		y1++
+	}
+	return image.Rect(x0, y0, x1, y1)
+}
+
+// outlines draws one pixel wide rectangle outlines
+func (ra *raster) outlines(rects []rect, c color.Color) {
+	src := image.NewUniform(c)
+	for _, r := range rects {
+		p := ra.pixels(r)
+		for _, edge := range []image.Rectangle{
+			image.Rect(p.Min.X, p.Min.Y, p.Max.X, p.Min.Y+1),
+			image.Rect(p.Min.X, p.Max.Y-1, p.Max.X, p.Max.Y),
+			image.Rect(p.Min.X, p.Min.Y, p.Min.X+1, p.Max.Y),
+			image.Rect(p.Max.X-1, p.Min.Y, p.Max.X, p.Max.Y),
+		} {
+			draw.Draw(ra.img, edge, src, image.Point{}, draw.Src)
+		}
+	}
+}
+
+// layer blends the layer shapes and tracks over the image, overlapping shapes
+// of the same layer are blended once
+func (ra *raster) layer(sl *sceneLayer, mask *image.Alpha) {
+	draw.Draw(mask, mask.Bounds(), image.Transparent, image.Point{}, draw.Src)
+	for _, r := range sl.shapes {
+		draw.Draw(mask, ra.pixels(r), image.Opaque, image.Point{}, draw.Src)
+	}
+	scale := ra.s.scale()
+	v := ra.s.viewport
+	for _, x := range sl.trackX {
+		px := int(float64(x-v.xMin) * scale)
+		draw.Draw(mask, image.Rect(px, 0, px+1, ra.s.height), image.Opaque, image.Point{}, draw.Src)
+	}
+	for _, y := range sl.trackY {
+		py := int(float64(v.yMax-y) * scale)
+		draw.Draw(mask, image.Rect(0, py, ra.s.width, py+1), image.Opaque, image.Point{}, draw.Src)
+	}
+	src := image.NewUniform(parseColor(sl.color, ra.s.colors.Opacity))
+	draw.DrawMask(ra.img, ra.img.Bounds(), src, image.Point{}, mask, image.Point{}, draw.Over)
+}
+
+// Rasterize renders the design into an image, layers are alpha blended in
+// technology order over the rows and instances outlines
+func Rasterize(design *goopendb.Design, options *Options) (*image.RGBA, error) {
+	s, err := newScene(design, options)
+	if err != nil {
+		return nil, err
+	}
+	bounds := image.Rect(0, 0, s.width, s.height)
+	ra := &raster{s: s, img: image.NewRGBA(bounds)}
+	draw.Draw(ra.img, bounds, image.NewUniform(parseColor(s.colors.Background, 1)), image.Point{}, draw.Src)
+	ra.outlines(s.rows, parseColor(s.colors.Rows, 1))
+	ra.outlines(s.instances, parseColor(s.colors.Instances, 1))
+	mask := image.NewAlpha(bounds)
+	for _, sl := range s.layers {
+		if len(sl.shapes) > 0 || len(sl.trackX) > 0 || len(sl.trackY) > 0 {
+			ra.layer(sl, mask)
+		}
+	}
+	return ra.img, nil
+}
+
+// WritePNG renders the design as a PNG image
+func WritePNG(design *goopendb.Design, options *Options, w io.Writer) error {
+	img, err := Rasterize(design, options)
+	if err != nil {
+		return err
+	}
+	return png.Encode(w, img)
+}
+
+// Thumbnail renders the whole design without tracks in a PNG data URL, the
+// largest image side is ThumbnailSize and the smallest side is at least one
+// pixel
+func Thumbnail(design *goopendb.Design) (string, error) {
+	bbox := design.BoundingBox
+	if bbox == nil || bbox.XMax <= bbox.XMin || bbox.YMax <= bbox.YMin {
+		return "", errEmptyViewport
+	}
+	viewport := &goopendb.Rect{XMin: bbox.XMin, YMin: bbox.YMin, XMax: bbox.XMax, YMax: bbox.YMax}
+	// Thin designs are padded to one pixel on the short side
+	width := ThumbnailSize
+	if w, h := bbox.XMax-bbox.XMin, bbox.YMax-bbox.YMin; h > w {
+		if minWidth := (h + ThumbnailSize - 1) / ThumbnailSize; w < minWidth {
+			w = minWidth
+			viewport.XMax = viewport.XMin + w
+		}
+		width = int(math.Max(1, math.Round(float64(ThumbnailSize*w)/float64(h))))
+	} else if minHeight := (w + ThumbnailSize - 1) / ThumbnailSize; h < minHeight {
+		viewport.YMax = viewport.YMin + minHeight
+	}
+	options := &Options{
+		Viewport: viewport,
+		Width:    width,
+		Objects:  []string{ObjectInstances, ObjectPins, ObjectNets, ObjectSpecialNets, ObjectRows},
+	}
+	var buf bytes.Buffer
+	if err := WritePNG(design, options, &buf); err != nil {
+		return "", err
+	}
+	return "data:image/png;base64," + base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
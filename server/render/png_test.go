@@ -0,0 +1,122 @@
+package render
+
+import (
+	"bytes"
+	"encoding/base64"
+	"image/color"
+	"image/png"
+	"strings"
+	"testing"
+
+	"github.com/ahmed-agiza/EDAViewer/server/goopendb"
+)
+
+// blend composes a color with the default opacity over dst
+func blend(src, dst color.RGBA) color.RGBA {
+	alpha := 0.6
+	mix := func(s, d uint8) uint8 {
+		return uint8(float64(s)*alpha + float64(d)*(1-alpha) + 0.5)
+	}
+	return color.RGBA{R: mix(src.R, dst.R), G: mix(src.G, dst.G), B: mix(src.B, dst.B), A: 255}
+}
+
+func sameColor(a, b color.RGBA) bool {
+	near := func(x, y uint8) bool {
+		return x-y <= 1 || y-x <= 1
+	}
+	return near(a.R, b.R) && near(a.G, b.G) && near(a.B, b.B) && near(a.A, b.A)
+}
+
+func TestRasterize(t *testing.T) {
+	design := testDesign()
+	metal1 := design.Layers[0]
+	// Overlapping shapes of the same layer are blended once
+	design.Nets[1].SpecialBoxes[0].Boxes = append(design.Nets[1].SpecialBoxes[0].Boxes,
+		&goopendb.Rect{XMin: 2000, YMin: 2730, XMax: 4000, YMax: 2870, Layer: metal1},
+		&goopendb.Rect{XMin: 3500, YMin: 0, XMax: 3600, YMax: 4000, Layer: design.Layers[1]})
+
+	img, err := Rasterize(design, &Options{Width: 400})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size := img.Bounds().Size(); size.X != 400 || size.Y != 400 {
+		t.Fatalf("Unexpected image size %v", size)
+	}
+	black := color.RGBA{A: 255}
+	blue := color.RGBA{R: 0x4e, G: 0x79, B: 0xa7, A: 255}
+	orange := color.RGBA{R: 0xf2, G: 0x8e, B: 0x2b, A: 255}
+	for _, tc := range []struct {
+		x, y     int
+		expected color.RGBA
+	}{
+		{300, 50, black},                                          // Background
+		{300, 117, blend(blue, black)},                            // Special wire
+		{250, 117, blend(blue, black)},                            // Overlapping special wires
+		{50, 395, blend(orange, black)},                           // Routing segment
+		{355, 117, blend(orange, blend(blue, black))},             // metal2 over metal1
+		{161, 395, blend(blue, black)},                            // Flipped instance pin
+		{175, 200, color.RGBA{R: 0x80, G: 0x80, B: 0x80, A: 255}}, // Instance outline
+	} {
+		if c := img.RGBAAt(tc.x, tc.y); !sameColor(c, tc.expected) {
+			t.Errorf("Expected %v at (%v, %v), found %v", tc.expected, tc.x, tc.y, c)
+		}
+	}
+
+	// Zoomed crop
+	img, err = Rasterize(design, &Options{Viewport: &goopendb.Rect{XMin: 1500, XMax: 1700, YMax: 1000}, Width: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size := img.Bounds().Size(); size.X != 100 || size.Y != 500 {
+		t.Fatalf("Unexpected image size %v", size)
+	}
+	if c := img.RGBAAt(50, 250); !sameColor(c, blend(blue, black)) {
+		t.Errorf("Expected the pin in the crop, found %v", c)
+	}
+}
+
+func TestThumbnail(t *testing.T) {
+	design := parseDesign(t)
+	thumbnail, err := Thumbnail(design)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const prefix = "data:image/png;base64,"
+	if !strings.HasPrefix(thumbnail, prefix) {
+		t.Fatalf("Expected a PNG data URL")
+	}
+	data, err := base64.StdEncoding.DecodeString(thumbnail[len(prefix):])
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size := img.Bounds().Size(); size.X != ThumbnailSize || size.Y > ThumbnailSize {
+		t.Errorf("Unexpected thumbnail size %v", size)
+	}
+
+	if _, err = Thumbnail(&goopendb.Design{}); err == nil {
+		t.Errorf("Expected empty designs to fail")
+	}
+
+	// Thin designs keep at least one pixel on the short side
+	for _, bbox := range []*goopendb.Rect{{XMax: 100000, YMax: 10}, {XMax: 10, YMax: 100000}} {
+		thumbnail, err := Thumbnail(&goopendb.Design{BoundingBox: bbox})
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := base64.StdEncoding.DecodeString(thumbnail[len(prefix):])
+		if err != nil {
+			t.Fatal(err)
+		}
+		img, err := png.Decode(bytes.NewReader(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if size := img.Bounds().Size(); size.X < 1 || size.Y < 1 || size.X > ThumbnailSize || size.Y > ThumbnailSize {
+			t.Errorf("Unexpected thumbnail size %v of %v", size, bbox)
+		}
+	}
+}
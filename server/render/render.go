@@ -0,0 +1,287 @@
+// Package render draws designs into images for reports and previews
+package render
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+
+	"github.com/ahmed-agiza/EDAViewer/server/goopendb"
+)
+
+// Object kinds that can be rendered
+const (
+	ObjectInstances   = "instances"
+	ObjectPins        = "pins"
+	ObjectNets        = "nets"
+	ObjectSpecialNets = "specialnets"
+	ObjectRows        = "rows"
+	ObjectTracks      = "tracks"
+)
+
+// Objects lists the object kinds that can be rendered
+var Objects = []string{ObjectInstances, ObjectPins, ObjectNets, ObjectSpecialNets, ObjectRows, ObjectTracks}
+
+// DefaultWidth is the image width used when Options.Width is not set
+const DefaultWidth = 1024
+
+// MaxWidth is the largest image width or height in pixels
+const MaxWidth = 8192
+
+// Options selects the rendered region and objects
+type Options struct {
+	Viewport *goopendb.Rect `json:",omitempty"` // Rendered region in database units, the design bounding box when nil
+	Width    int            // Image width in pixels, the height follows the viewport aspect ratio
+	Layers   []string       `json:",omitempty"` // Visible layers, all layers when empty
+	Objects  []string       `json:",omitempty"` // Visible object kinds, all kinds when empty
+	Colors   *ColorScheme   `json:",omitempty"` // DefaultColorScheme when nil
+}
+
+// ColorScheme holds the "#rrggbb" colors of the rendered objects
+type ColorScheme struct {
+	Background string
+	Instances  string
+	Rows       string
+	Layers     map[string]string `json:",omitempty"` // By layer name, missing layers use the default palette
+	Opacity    float64           // Fill opacity of the layer shapes
+}
+
+// defaultPalette colors the layers in technology order
+var defaultPalette = []string{
+	"#4e79a7", "#f28e2b", "#e15759", "#76b7b2", "#59a14f",
+	"#edc948", "#b07aa1", "#ff9da7", "#9c755f", "#bab0ac",
+}
+
+// DefaultColorScheme returns the colors used when no scheme is given
+func DefaultColorScheme() *ColorScheme {
+	return &ColorScheme{
+		Background: "#000000",
+		Instances:  "#808080",
+		Rows:       "#404040",
+		Opacity:    0.6,
+	}
+}
+
+var errEmptyViewport = fmt.Errorf("Empty viewport")
+
+var colorRe = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
+// layerColor returns the scheme color of the layer at the technology index
+func (colors *ColorScheme) layerColor(layer *goopendb.Layer, index int) string {
+	if color, ok := colors.Layers[layer.Name]; ok {
+		return color
+	}
+	return defaultPalette[index%len(defaultPalette)]
+}
+
+// validate checks the scheme colors so they can be written as is
+func (colors *ColorScheme) validate() error {
+	for _, color := range []string{colors.Background, colors.Instances, colors.Rows} {
+		if !colorRe.MatchString(color) {
+			return fmt.Errorf("Invalid color %q", color)
+		}
+	}
+	for name, color := range colors.Layers {
+		if !colorRe.MatchString(color) {
+			return fmt.Errorf("Invalid color %q of layer %v", color, name)
+		}
+	}
+	if colors.Opacity < 0 || colors.Opacity > 1 {
+		return fmt.Errorf("Invalid opacity %v", colors.Opacity)
+	}
+	return nil
+}
+
+// rect is an axis aligned rectangle in database units
+type rect struct {
+	xMin int
+	yMin int
+	xMax int
+	yMax int
+}
+
+func (r rect) intersects(other rect) bool {
+	return r.xMin <= other.xMax && other.xMin <= r.xMax && r.yMin <= other.yMax && other.yMin <= r.yMax
+}
+
+func newRect(r *goopendb.Rect) rect {
+	return rect{xMin: r.XMin, yMin: r.YMin, xMax: r.XMax, yMax: r.YMax}
+}
+
+// sceneLayer holds the visible shapes and track lines of a layer
+type sceneLayer struct {
+	layer  *goopendb.Layer
+	color  string
+	shapes []rect
+	trackX []int // Vertical track lines
+	trackY []int // Horizontal track lines
+}
+
+// scene is the visible design content in drawing order: rows, instances,
+// then the layers in technology order
+type scene struct {
+	viewport  rect
+	width     int
+	height    int
+	colors    *ColorScheme
+	rows      []rect
+	instances []rect
+	layers    []*sceneLayer
+}
+
+// scale returns the pixels per database unit
+func (s *scene) scale() float64 {
+	return float64(s.width) / float64(s.viewport.xMax-s.viewport.xMin)
+}
+
+func stringSet(values []string) map[string]bool {
+	if len(values) == 0 {
+		return nil
+	}
+	set := make(map[string]bool)
+	for _, v := range values {
+		set[v] = true
+	}
+	return set
+}
+
+// newScene collects the design objects visible in the options viewport
+func newScene(design *goopendb.Design, options *Options) (*scene, error) {
+	if options == nil {
+		options = &Options{}
+	}
+	colors := options.Colors
+	if colors == nil {
+		colors = DefaultColorScheme()
+	}
+	if err := colors.validate(); err != nil {
+		return nil, err
+	}
+	objects := stringSet(options.Objects)
+	for object := range objects {
+		known := false
+		for _, kind := range Objects {
+			known = known || kind == object
+		}
+		if !known {
+			return nil, fmt.Errorf("Unknown object kind %v", object)
+		}
+	}
+	visible := func(kind string) bool {
+		return objects == nil || objects[kind]
+	}
+	viewport := options.Viewport
+	if viewport == nil {
+		viewport = design.BoundingBox
+	}
+	if viewport == nil || viewport.XMax <= viewport.XMin || viewport.YMax <= viewport.YMin {
+		return nil, errEmptyViewport
+	}
+	width := options.Width
+	if width == 0 {
+		width = DefaultWidth
+	}
+	s := &scene{
+		viewport: newRect(viewport),
+		width:    width,
+		colors:   colors,
+	}
+	s.height = int(float64(viewport.YMax-viewport.YMin)*s.scale() + 0.5)
+	if width < 1 || width > MaxWidth || s.height < 1 || s.height > MaxWidth {
+		return nil, fmt.Errorf("Invalid image size %vx%v", width, s.height)
+	}
+
+	layerNames := stringSet(options.Layers)
+	// Shapes may hold copies of the design layers, layers are matched by ID
+	designLayers := design.LayersByID()
+	layerMap := make(map[*goopendb.Layer]*sceneLayer)
+	for i, layer := range design.Layers {
+		if layerNames != nil && !layerNames[layer.Name] {
+			continue
+		}
+		sl := &sceneLayer{layer: layer, color: colors.layerColor(layer, i)}
+		layerMap[layer] = sl
+		s.layers = append(s.layers, sl)
+	}
+	addShape := func(layer *goopendb.Layer, xMin, yMin, xMax, yMax int) {
+		if layer == nil {
+			return
+		}
+		sl := layerMap[designLayers[layer.ID]]
+		if sl == nil {
+			return
+		}
+		r := rect{xMin: xMin, yMin: yMin, xMax: xMax, yMax: yMax}
+		if r.intersects(s.viewport) {
+			sl.shapes = append(sl.shapes, r)
+		}
+	}
+
+	if visible(ObjectRows) {
+		for _, row := range design.Rows {
+			if row.BoundingBox != nil && newRect(row.BoundingBox).intersects(s.viewport) {
+				s.rows = append(s.rows, newRect(row.BoundingBox))
+			}
+		}
+	}
+	for _, inst := range design.Instances {
+		if !inst.IsPlaced || inst.BoundingBox == nil || !newRect(inst.BoundingBox).intersects(s.viewport) {
+			continue
+		}
+		if visible(ObjectInstances) {
+			s.instances = append(s.instances, newRect(inst.BoundingBox))
+		}
+		if visible(ObjectPins) {
+			placed := inst.Placed(addShape)
+			for _, pin := range inst.Pins {
+				goopendb.VisitGeometries(pin.Geometries, placed)
+			}
+		}
+	}
+	if visible(ObjectPins) {
+		for _, pin := range design.BlockPins {
+			goopendb.VisitGeometries(pin.Geometries, addShape)
+		}
+	}
+	for _, net := range design.Nets {
+		if visible(ObjectNets) {
+			net.VisitWires(addShape)
+		}
+		if visible(ObjectSpecialNets) {
+			net.VisitSpecialWires(addShape)
+		}
+	}
+	if visible(ObjectTracks) {
+		for _, grid := range design.Tracks {
+			if grid.Layer == nil {
+				continue
+			}
+			if sl := layerMap[designLayers[grid.Layer.ID]]; sl != nil {
+				sl.trackX = append(sl.trackX, s.visibleLines(grid.GridX, s.viewport.xMin, s.viewport.xMax)...)
+				sl.trackY = append(sl.trackY, s.visibleLines(grid.GridY, s.viewport.yMin, s.viewport.yMax)...)
+			}
+		}
+	}
+	return s, nil
+}
+
+// minTrackSpacing is the smallest track spacing in pixels, denser tracks are
+// not drawn
+const minTrackSpacing = 2
+
+// visibleLines returns the track lines inside [lo, hi], none when the lines
+// are too dense to be distinguished
+func (s *scene) visibleLines(lines []int, lo, hi int) []int {
+	lines = append([]int(nil), lines...)
+	sort.Ints(lines)
+	var visible []int
+	for i, line := range lines {
+		if i > 0 && float64(line-lines[i-1])*s.scale() < minTrackSpacing {
+			return nil
+		}
+		if line >= lo && line <= hi {
+			visible = append(visible, line)
+		}
+	}
+	return visible
+}
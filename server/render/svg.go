@@ -0,0 +1,85 @@
+package render
+
+// SVG rendering of a design
+
+import (
+	"encoding/xml"
+	"io"
+	"strings"
+
+	"github.com/ahmed-agiza/EDAViewer/server/goopendb"
+)
+
+// svgWriter writes SVG elements, the first write error is kept
+type svgWriter struct {
+	*goopendb.ErrWriter
+}
+
+func escapeXML(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+func (sw *svgWriter) rects(rects []rect) {
+	for _, r := range rects {
+		sw.Printf("<rect x=\"%d\" y=\"%d\" width=\"%d\" height=\"%d\"/>\n", r.xMin, r.yMin, r.xMax-r.xMin, r.yMax-r.yMin)
+	}
+}
+
+// tracks writes the track lines of a layer as a single path
+func (sw *svgWriter) tracks(s *scene, sl *sceneLayer, strokeWidth float64) {
+	if len(sl.trackX) == 0 && len(sl.trackY) == 0 {
+		return
+	}
+	sw.Printf("<path fill=\"none\" stroke=\"%v\" stroke-width=\"%g\" d=\"", sl.color, strokeWidth)
+	for _, x := range sl.trackX {
+		sw.Printf("M%d %dV%d", x, s.viewport.yMin, s.viewport.yMax)
+	}
+	for _, y := range sl.trackY {
+		sw.Printf("M%d %dH%d", s.viewport.xMin, y, s.viewport.xMax)
+	}
+	sw.Printf("\"/>\n")
+}
+
+// WriteSVG renders the design as an SVG image, the image is in database
+// units with the Y axis pointing up like the design
+func WriteSVG(design *goopendb.Design, options *Options, w io.Writer) error {
+	s, err := newScene(design, options)
+	if err != nil {
+		return err
+	}
+	sw := &svgWriter{goopendb.NewErrWriter(w)}
+	v := s.viewport
+	// One pixel in database units
+	strokeWidth := 1 / s.scale()
+
+	sw.Printf("<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n")
+	sw.Printf("<svg xmlns=\"http://www.w3.org/2000/svg\" width=\"%d\" height=\"%d\" viewBox=\"%d %d %d %d\">\n",
+		s.width, s.height, v.xMin, -v.yMax, v.xMax-v.xMin, v.yMax-v.yMin)
+	sw.Printf("<title>%v</title>\n", escapeXML(design.Name))
+	sw.Printf("<rect x=\"%d\" y=\"%d\" width=\"%d\" height=\"%d\" fill=\"%v\"/>\n",
+		v.xMin, -v.yMax, v.xMax-v.xMin, v.yMax-v.yMin, s.colors.Background)
+	sw.Printf("<g transform=\"scale(1 -1)\">\n")
+	if len(s.rows) > 0 {
+		sw.Printf("<g id=\"rows\" fill=\"none\" stroke=\"%v\" stroke-width=\"%g\">\n", s.colors.Rows, strokeWidth)
+		sw.rects(s.rows)
+		sw.Printf("</g>\n")
+	}
+	if len(s.instances) > 0 {
+		sw.Printf("<g id=\"instances\" fill=\"none\" stroke=\"%v\" stroke-width=\"%g\">\n", s.colors.Instances, strokeWidth)
+		sw.rects(s.instances)
+		sw.Printf("</g>\n")
+	}
+	for _, sl := range s.layers {
+		if len(sl.shapes) == 0 && len(sl.trackX) == 0 && len(sl.trackY) == 0 {
+			continue
+		}
+		sw.Printf("<g id=\"layer-%v\" fill=\"%v\" fill-opacity=\"%g\">\n", escapeXML(sl.layer.Name), sl.color, s.colors.Opacity)
+		sw.rects(sl.shapes)
+		sw.tracks(s, sl, strokeWidth)
+		sw.Printf("</g>\n")
+	}
+	sw.Printf("</g>\n</svg>\n")
+	return sw.Flush()
+}
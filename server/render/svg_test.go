@@ -0,0 +1,177 @@
+package render
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ahmed-agiza/EDAViewer/server/goopendb"
+	"github.com/ahmed-agiza/EDAViewer/server/lefdef"
+)
+
+var (
+	lefPath = filepath.Join("..", "example", "Nangate45", "NangateOpenCellLibrary.mod.lef")
+	defPath = filepath.Join("..", "example", "Nangate45", "gcd.def")
+)
+
+func parseDesign(t *testing.T) *goopendb.Design {
+	t.Helper()
+	backend := lefdef.NewBackend()
+	defer backend.FreeDatabase()
+	if err := backend.ParseLEF(lefPath); err != nil {
+		t.Fatal(err)
+	}
+	if err := backend.ParseDEF(defPath); err != nil {
+		t.Fatal(err)
+	}
+	design, err := backend.GetDesign()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return design
+}
+
+// testDesign has an instance with a pin, a routed net and a special net
+func testDesign() *goopendb.Design {
+	metal1 := &goopendb.Layer{ID: 1, Name: "metal1"}
+	metal2 := &goopendb.Layer{ID: 2, Name: "metal2"}
+	inst := &goopendb.Instance{
+		ID:          1,
+		Name:        "u1",
+		Master:      "INV_X1",
+		IsPlaced:    true,
+		Origin:      &goopendb.Point{X: 1760, Y: 0},
+		Orientation: goopendb.OrientationMY,
+		BoundingBox: &goopendb.Rect{XMin: 1000, XMax: 1760, YMax: 2800},
+	}
+	inst.Pins = []*goopendb.Pin{{
+		ID:         1,
+		Name:       "ZN",
+		Instance:   inst,
+		Geometries: []*goopendb.Geometry{{ID: 1, Boxes: []*goopendb.Rect{{XMin: 100, XMax: 200, YMax: 1000, Layer: metal1}}}},
+	}}
+	return &goopendb.Design{
+		Name:         "top & bottom",
+		DBUPerMicron: 2000,
+		Layers:       []*goopendb.Layer{metal1, metal2},
+		Instances:    []*goopendb.Instance{inst},
+		InstancePins: inst.Pins,
+		BoundingBox:  &goopendb.Rect{XMax: 4000, YMax: 4000},
+		Rows:         []*goopendb.Row{{ID: 1, Name: "row0", BoundingBox: &goopendb.Rect{XMax: 4000, YMax: 2800}}},
+		Tracks:       []*goopendb.Grid{{ID: 1, Layer: metal2, GridX: []int{200, 600, 1000}}},
+		Nets: []*goopendb.Net{
+			{
+				ID:    1,
+				Name:  "n1",
+				Edges: []*goopendb.Edge{{Type: goopendb.EdgeTypeSEGMENT, Layer: metal2, Rect: &goopendb.Rect{XMax: 1000, YMax: 140}}},
+			},
+			{
+				ID:           2,
+				Name:         "VDD",
+				IsSpecial:    true,
+				SpecialBoxes: []*goopendb.Geometry{{ID: 2, Boxes: []*goopendb.Rect{{YMin: 2730, XMax: 4000, YMax: 2870, Layer: metal1}}}},
+			},
+		},
+	}
+}
+
+// checkXML verifies the document is well formed
+func checkXML(t *testing.T, data []byte) {
+	t.Helper()
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		_, err := dec.Token()
+		if err == io.EOF {
+			return
+		}
+		if err != nil {
+			t.Fatalf("Invalid SVG: %v", err)
+		}
+	}
+}
+
+func TestWriteSVG(t *testing.T) {
+	design := testDesign()
+	var buf bytes.Buffer
+	if err := WriteSVG(design, &Options{Width: 400}, &buf); err != nil {
+		t.Fatal(err)
+	}
+	checkXML(t, buf.Bytes())
+	svg := buf.String()
+	for _, s := range []string{
+		`width="400" height="400" viewBox="0 -4000 4000 4000"`,
+		`<title>top &amp; bottom</title>`,
+		`<g id="rows" fill="none" stroke="#404040" stroke-width="10">`,
+		`<g id="instances"`,
+		`<rect x="1000" y="0" width="760" height="2800"/>`,
+		`<g id="layer-metal1" fill="#4e79a7" fill-opacity="0.6">`,
+		`<rect x="1560" y="0" width="100" height="1000"/>`, // Flipped pin
+		`<rect x="0" y="2730" width="4000" height="140"/>`,
+		`<g id="layer-metal2" fill="#f28e2b"`,
+		`<rect x="0" y="0" width="1000" height="140"/>`,
+		`d="M200 0V4000M600 0V4000M1000 0V4000"`,
+	} {
+		if !strings.Contains(svg, s) {
+			t.Errorf("Expected %q in\n%v", s, svg)
+		}
+	}
+
+	// Filters and colors
+	buf.Reset()
+	colors := DefaultColorScheme()
+	colors.Layers = map[string]string{"metal1": "#ff0000"}
+	options := &Options{
+		Viewport: &goopendb.Rect{XMin: 1000, XMax: 2000, YMax: 500},
+		Width:    200,
+		Layers:   []string{"metal1"},
+		Objects:  []string{ObjectPins, ObjectSpecialNets},
+		Colors:   colors,
+	}
+	if err := WriteSVG(design, options, &buf); err != nil {
+		t.Fatal(err)
+	}
+	svg = buf.String()
+	if !strings.Contains(svg, `width="200" height="100" viewBox="1000 -500 1000 500"`) {
+		t.Errorf("Unexpected viewport in\n%v", svg)
+	}
+	if !strings.Contains(svg, `<g id="layer-metal1" fill="#ff0000"`) || !strings.Contains(svg, `width="100" height="1000"`) {
+		t.Errorf("Expected the metal1 pin in\n%v", svg)
+	}
+	for _, s := range []string{"metal2", `id="rows"`, `id="instances"`, `height="140"`} {
+		if strings.Contains(svg, s) {
+			t.Errorf("Unexpected %q in\n%v", s, svg)
+		}
+	}
+
+	for _, options := range []*Options{
+		{Objects: []string{"cells"}},
+		{Width: MaxWidth + 1},
+		{Viewport: &goopendb.Rect{XMax: 10}},
+		{Colors: &ColorScheme{Background: "red"}},
+	} {
+		if err := WriteSVG(design, options, &buf); err == nil {
+			t.Errorf("Expected %+v to fail", options)
+		}
+	}
+}
+
+func TestWriteSVGDesign(t *testing.T) {
+	design := parseDesign(t)
+	var buf bytes.Buffer
+	if err := WriteSVG(design, nil, &buf); err != nil {
+		t.Fatal(err)
+	}
+	checkXML(t, buf.Bytes())
+	svg := buf.String()
+	if n := strings.Count(svg, "<rect "); n < len(design.Instances) {
+		t.Errorf("Expected at least %v rectangles, found %v", len(design.Instances), n)
+	}
+	for _, layer := range []string{"metal1", "metal2", "metal3", "via1"} {
+		if !strings.Contains(svg, `<g id="layer-`+layer+`"`) {
+			t.Errorf("Expected %v shapes", layer)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package tiles
+
+// Binary tile encoding
+//
+// A tile starts with the magic "EDVT" and the format version byte followed by
+// unsigned varints: the zoom level, the tile X and Y and the layer count.
+// Every layer is the layer ID, a flags varint (1 for aggregated layers) and
+// the rectangle count followed by the rectangles as the signed varint X and Y
+// deltas from the previous rectangle and the unsigned varint width and height
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+)
+
+// TileMagic starts every encoded tile
+const TileMagic = "EDVT"
+
+// TileVersion is the version of the binary tile encoding
+const TileVersion = 1
+
+const flagAggregated = 1
+
+// Encode serializes the tile in the compact binary encoding
+func (tile *Tile) Encode() []byte {
+	var buf bytes.Buffer
+	var scratch [binary.MaxVarintLen64]byte
+	uvarint := func(v int) {
+		buf.Write(scratch[:binary.PutUvarint(scratch[:], uint64(v))])
+	}
+	varint := func(v int) {
+		buf.Write(scratch[:binary.PutVarint(scratch[:], int64(v))])
+	}
+	buf.WriteString(TileMagic)
+	buf.WriteByte(TileVersion)
+	uvarint(tile.Z)
+	uvarint(tile.X)
+	uvarint(tile.Y)
+	uvarint(len(tile.Layers))
+	for _, tl := range tile.Layers {
+		uvarint(tl.ID)
+		flags := 0
+		if tl.Aggregated {
+			flags |= flagAggregated
+		}
+		uvarint(flags)
+		uvarint(len(tl.Rects))
+		x, y := 0, 0
+		for _, r := range tl.Rects {
+			varint(r.X - x)
+			varint(r.Y - y)
+			uvarint(r.Width)
+			uvarint(r.Height)
+			x, y = r.X, r.Y
+		}
+	}
+	return buf.Bytes()
+}
+
+// Decode parses a binary encoded tile
+func Decode(data []byte) (*Tile, error) {
+	if !bytes.HasPrefix(data, []byte(TileMagic)) {
+		return nil, fmt.Errorf("Invalid tile header")
+	}
+	data = data[len(TileMagic):]
+	if len(data) == 0 || data[0] != TileVersion {
+		return nil, fmt.Errorf("Unsupported tile version")
+	}
+	data = data[1:]
+	var err error
+	uvarint := func() int {
+		v, n := binary.Uvarint(data)
+		if n <= 0 {
+			err = fmt.Errorf("Truncated tile")
+			data = nil
+			return 0
+		}
+		data = data[n:]
+		return int(v)
+	}
+	varint := func() int {
+		v, n := binary.Varint(data)
+		if n <= 0 {
+			err = fmt.Errorf("Truncated tile")
+			data = nil
+			return 0
+		}
+		data = data[n:]
+		return int(v)
+	}
+	tile := &Tile{Z: uvarint(), X: uvarint(), Y: uvarint()}
+	layerCount := uvarint()
+	for i := 0; i < layerCount && err == nil; i++ {
+		tl := &TileLayer{ID: uvarint()}
+		tl.Aggregated = uvarint()&flagAggregated != 0
+		rectCount := uvarint()
+		x, y := 0, 0
+		for j := 0; j < rectCount && err == nil; j++ {
+			x += varint()
+			y += varint()
+			tl.Rects = append(tl.Rects, Rect{X: x, Y: y, Width: uvarint(), Height: uvarint()})
+		}
+		tile.Layers = append(tile.Layers, tl)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return tile, nil
+}
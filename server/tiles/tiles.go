@@ -0,0 +1,314 @@
+// Package tiles splits designs into a quadtree of vector tiles so viewers can
+// load huge layouts one viewport at a time like a map. Tiles are kept in
+// memory with the stored design, writing the pyramid to the S3 bucket of
+// deploy/server is not implemented
+package tiles
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/ahmed-agiza/EDAViewer/server/goopendb"
+)
+
+const (
+	// Extent is the tile side in tile units, tile coordinates start at the
+	// top left corner with the Y axis pointing down
+	Extent = 4096
+	// GridSize is the side in cells of the coverage grid of aggregated layers
+	GridSize = 256
+	// MaxShapes is the largest number of shapes of a tile layer, denser
+	// layers are aggregated into coverage blocks
+	MaxShapes = 2048
+	// InstanceLayer is the tile layer ID of the instance outlines
+	InstanceLayer = 0
+	// CacheZoom is the last zoom level kept by Precompute, tiles of the first
+	// levels cover most of the design and are the slowest to cut
+	CacheZoom = 5
+)
+
+// LayerInfo names a tile layer
+type LayerInfo struct {
+	ID   int
+	Name string
+}
+
+// Metadata describes the tiling of a design, the tiles of zoom level z split
+// the square of side Size at (XMin, YMin) into 2^z by 2^z tiles, tile (0, 0)
+// is the top left one
+type Metadata struct {
+	XMin    int
+	YMin    int
+	Size    int // Database units
+	MaxZoom int // Tiles of the last level have a resolution of one database unit at most
+	Extent  int
+	Layers  []LayerInfo
+}
+
+// Pyramid generates the tiles of a design
+type Pyramid struct {
+	Metadata
+	design *goopendb.Design
+	cache  map[[3]int][]byte // Encoded tiles by zoom level, X and Y
+}
+
+// NewPyramid prepares the tiling of the design bounding box, tiles are cut
+// from the design spatial index
+func NewPyramid(design *goopendb.Design) (*Pyramid, error) {
+	bbox := design.BoundingBox
+	if bbox == nil || bbox.XMax <= bbox.XMin || bbox.YMax <= bbox.YMin {
+		return nil, fmt.Errorf("Empty design")
+	}
+	size := bbox.XMax - bbox.XMin
+	if height := bbox.YMax - bbox.YMin; height > size {
+		size = height
+	}
+	maxZoom := 0
+	for size>>uint(maxZoom) > Extent {
+		maxZoom++
+	}
+	p := &Pyramid{
+		Metadata: Metadata{
+			XMin:    bbox.XMin,
+			YMin:    bbox.YMin,
+			Size:    size,
+			MaxZoom: maxZoom,
+			Extent:  Extent,
+			Layers:  []LayerInfo{{ID: InstanceLayer, Name: "instances"}},
+		},
+		design: design,
+	}
+	for _, layer := range design.Layers {
+		p.Layers = append(p.Layers, LayerInfo{ID: layer.ID, Name: layer.Name})
+	}
+	return p, nil
+}
+
+// Rect is a tile rectangle in tile units
+type Rect struct {
+	X      int
+	Y      int
+	Width  int
+	Height int
+}
+
+// TileLayer holds the shapes of a layer in a tile
+type TileLayer struct {
+	ID         int
+	Aggregated bool // Rects are coverage blocks of GridSize cells
+	Rects      []Rect
+}
+
+// Tile is a vector tile
+type Tile struct {
+	Z      int
+	X      int
+	Y      int
+	Layers []*TileLayer
+}
+
+// Empty checks if the tile has no shapes
+func (tile *Tile) Empty() bool {
+	return len(tile.Layers) == 0
+}
+
+// bounds returns the tile rectangle in database units
+func (p *Pyramid) bounds(z, x, y int) (xMin, yMin, size float64) {
+	size = float64(p.Size) / float64(int(1)<<uint(z))
+	xMin = float64(p.XMin) + float64(x)*size
+	yMin = float64(p.YMin) + float64(p.Size) - float64(y+1)*size
+	return
+}
+
+// Tile cuts the tile at zoom level z, layers with more than MaxShapes shapes
+// are aggregated into coverage blocks
+func (p *Pyramid) Tile(z, x, y int) (*Tile, error) {
+	if z < 0 || z > p.MaxZoom {
+		return nil, fmt.Errorf("Invalid zoom level %v", z)
+	}
+	if n := int(1) << uint(z); x < 0 || x >= n || y < 0 || y >= n {
+		return nil, fmt.Errorf("Invalid tile %v/%v/%v", z, x, y)
+	}
+	xMin, yMin, size := p.bounds(z, x, y)
+	query := &goopendb.Rect{
+		XMin: int(math.Floor(xMin)),
+		YMin: int(math.Floor(yMin)),
+		XMax: int(math.Ceil(xMin + size)),
+		YMax: int(math.Ceil(yMin + size)),
+	}
+	scale := Extent / size
+	// Tile coordinates of a database unit coordinate, clamped to the tile
+	tileX := func(v int) int {
+		return clamp(int(math.Round((float64(v)-xMin)*scale)), 0, Extent)
+	}
+	tileY := func(v int) int {
+		return clamp(int(math.Round((yMin+size-float64(v))*scale)), 0, Extent)
+	}
+
+	layers := make(map[int]*TileLayer)
+	// Shapes merging into the same tile rectangle are kept once
+	type layerRect struct {
+		id int
+		r  Rect
+	}
+	seen := make(map[layerRect]bool)
+	for _, shape := range p.design.Query(query, nil) {
+		id := InstanceLayer
+		if shape.Kind != goopendb.ShapeInstance {
+			if shape.Layer == nil {
+				continue
+			}
+			id = shape.Layer.ID
+		}
+		x0, x1 := tileX(shape.XMin), tileX(shape.XMax)
+		y0, y1 := tileY(shape.YMax), tileY(shape.YMin)
+		// Shapes touching the tile edge only
+		if (x0 == x1 && (x0 == 0 || x0 == Extent)) || (y0 == y1 && (y0 == 0 || y0 == Extent)) {
+			continue
+		}
+		// Shapes are at least one unit wide so thin wires stay visible
+		r := Rect{X: x0, Y: y0, Width: maxInt(x1-x0, 1), Height: maxInt(y1-y0, 1)}
+		if seen[layerRect{id, r}] {
+			continue
+		}
+		seen[layerRect{id, r}] = true
+		tl := layers[id]
+		if tl == nil {
+			tl = &TileLayer{ID: id}
+			layers[id] = tl
+		}
+		tl.Rects = append(tl.Rects, r)
+	}
+
+	tile := &Tile{Z: z, X: x, Y: y}
+	// Layers in technology order, instances first
+	for _, info := range p.Layers {
+		if tl := layers[info.ID]; tl != nil {
+			if len(tl.Rects) > MaxShapes {
+				tl.aggregate()
+			}
+			tile.Layers = append(tile.Layers, tl)
+		}
+	}
+	return tile, nil
+}
+
+// aggregate replaces the layer shapes by the blocks of covered grid cells
+func (tl *TileLayer) aggregate() {
+	const cell = Extent / GridSize
+	var covered [GridSize][GridSize]bool
+	for _, r := range tl.Rects {
+		for gy := r.Y / cell; gy < GridSize && gy*cell < r.Y+r.Height; gy++ {
+			for gx := r.X / cell; gx < GridSize && gx*cell < r.X+r.Width; gx++ {
+				covered[gy][gx] = true
+			}
+		}
+	}
+	// Runs of covered cells are merged with the matching runs of the previous rows
+	type run struct{ x0, x1 int }
+	open := make(map[run]*Rect)
+	var blocks []*Rect
+	for gy := 0; gy <= GridSize; gy++ {
+		next := make(map[run]*Rect)
+		for gx := 0; gy < GridSize && gx < GridSize; {
+			if !covered[gy][gx] {
+				gx++
+				continue
+			}
+			start := gx
+			for gx < GridSize && covered[gy][gx] {
+				gx++
+			}
+			key := run{start, gx}
+			if block := open[key]; block != nil {
+				block.Height += cell
+				next[key] = block
+				delete(open, key)
+			} else {
+				block := &Rect{X: start * cell, Y: gy * cell, Width: (gx - start) * cell, Height: cell}
+				blocks = append(blocks, block)
+				next[key] = block
+			}
+		}
+		open = next
+	}
+	tl.Aggregated = true
+	tl.Rects = make([]Rect, len(blocks))
+	for i, block := range blocks {
+		tl.Rects[i] = *block
+	}
+}
+
+// Generate cuts the non-empty tiles of all zoom levels, the children of
+// empty tiles are skipped
+func (p *Pyramid) Generate(visit func(tile *Tile) error) error {
+	return p.generate(p.MaxZoom, visit)
+}
+
+// Precompute encodes the non-empty tiles up to CacheZoom, they are served
+// from memory by EncodedTile. The pyramid must not be shared while the tiles
+// are precomputed
+func (p *Pyramid) Precompute() error {
+	p.cache = make(map[[3]int][]byte)
+	return p.generate(CacheZoom, func(tile *Tile) error {
+		p.cache[[3]int{tile.Z, tile.X, tile.Y}] = tile.Encode()
+		return nil
+	})
+}
+
+// EncodedTile returns the binary encoding of a tile, precomputed tiles are
+// not cut again
+func (p *Pyramid) EncodedTile(z, x, y int) ([]byte, error) {
+	if data, ok := p.cache[[3]int{z, x, y}]; ok {
+		return data, nil
+	}
+	tile, err := p.Tile(z, x, y)
+	if err != nil {
+		return nil, err
+	}
+	return tile.Encode(), nil
+}
+
+// generate cuts the non-empty tiles up to the zoom level
+func (p *Pyramid) generate(maxZoom int, visit func(tile *Tile) error) error {
+	var walk func(z, x, y int) error
+	walk = func(z, x, y int) error {
+		tile, err := p.Tile(z, x, y)
+		if err != nil {
+			return err
+		}
+		if tile.Empty() {
+			return nil
+		}
+		if err = visit(tile); err != nil {
+			return err
+		}
+		if z == p.MaxZoom || z == maxZoom {
+			return nil
+		}
+		for _, child := range [][2]int{{0, 0}, {1, 0}, {0, 1}, {1, 1}} {
+			if err = walk(z+1, 2*x+child[0], 2*y+child[1]); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return walk(0, 0, 0)
+}
+
+func clamp(v, lo, hi int) int {
+	if v < lo {
+		return lo
+	}
+	if v > hi {
+		return hi
+	}
+	return v
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
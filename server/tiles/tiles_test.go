@@ -0,0 +1,192 @@
+package tiles
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/ahmed-agiza/EDAViewer/server/goopendb"
+	"github.com/ahmed-agiza/EDAViewer/server/lefdef"
+)
+
+// testDesign has an instance, a wire and a dense grid of special wire shapes
+func testDesign() *goopendb.Design {
+	metal1 := &goopendb.Layer{ID: 1, Name: "metal1"}
+	metal2 := &goopendb.Layer{ID: 2, Name: "metal2"}
+	grid := &goopendb.Geometry{ID: 1}
+	for i := 0; i < 48; i++ {
+		for j := 0; j < 48; j++ {
+			x, y := 2560+i*64, j*64
+			grid.Boxes = append(grid.Boxes, &goopendb.Rect{XMin: x, YMin: y, XMax: x + 48, YMax: y + 48, Layer: metal1})
+		}
+	}
+	return &goopendb.Design{
+		Layers:      []*goopendb.Layer{metal1, metal2},
+		BoundingBox: &goopendb.Rect{XMax: 8192, YMax: 4096},
+		Instances: []*goopendb.Instance{{
+			ID:          1,
+			IsPlaced:    true,
+			BoundingBox: &goopendb.Rect{XMin: 1000, YMin: 1000, XMax: 2000, YMax: 3000},
+		}},
+		Nets: []*goopendb.Net{
+			{
+				ID:    1,
+				Edges: []*goopendb.Edge{{Type: goopendb.EdgeTypeSEGMENT, Layer: metal2, Rect: &goopendb.Rect{XMax: 1000, YMax: 140}}},
+			},
+			{ID: 2, IsSpecial: true, SpecialBoxes: []*goopendb.Geometry{grid}},
+		},
+	}
+}
+
+func TestPyramid(t *testing.T) {
+	p, err := NewPyramid(testDesign())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Size != 8192 || p.MaxZoom != 1 || len(p.Layers) != 3 {
+		t.Fatalf("Unexpected metadata %+v", p.Metadata)
+	}
+
+	tile, err := p.Tile(0, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tile.Layers) != 3 {
+		t.Fatalf("Expected instances, metal1 and metal2 layers, found %v", len(tile.Layers))
+	}
+	instances, metal1, metal2 := tile.Layers[0], tile.Layers[1], tile.Layers[2]
+	if instances.ID != InstanceLayer || !reflect.DeepEqual(instances.Rects, []Rect{{X: 500, Y: 2596, Width: 500, Height: 1000}}) {
+		t.Errorf("Unexpected instances %+v", instances)
+	}
+	if metal2.ID != 2 || metal2.Aggregated || !reflect.DeepEqual(metal2.Rects, []Rect{{X: 0, Y: 4026, Width: 500, Height: 70}}) {
+		t.Errorf("Unexpected metal2 shapes %+v", metal2)
+	}
+	// The via grid is denser than MaxShapes, it is covered by one block
+	if !metal1.Aggregated || !reflect.DeepEqual(metal1.Rects, []Rect{{X: 1280, Y: 2560, Width: 1536, Height: 1536}}) {
+		t.Errorf("Unexpected metal1 blocks %+v", metal1.Rects)
+	}
+
+	// The bottom right tile of the last level keeps the vias of its half
+	tile, err = p.Tile(1, 1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tile.Layers) != 1 || tile.Layers[0].Aggregated || len(tile.Layers[0].Rects) != 24*48 {
+		t.Fatalf("Expected the via shapes in the tile")
+	}
+	found := false
+	for _, r := range tile.Layers[0].Rects {
+		found = found || r == Rect{X: 0, Y: 4048, Width: 48, Height: 48}
+	}
+	if !found {
+		t.Errorf("Expected the via at the tile left edge")
+	}
+	tile, err = p.Tile(1, 1, 0)
+	if err != nil || !tile.Empty() {
+		t.Errorf("Expected an empty tile")
+	}
+
+	for _, zxy := range [][3]int{{2, 0, 0}, {-1, 0, 0}, {1, 2, 0}, {1, 0, -1}} {
+		if _, err := p.Tile(zxy[0], zxy[1], zxy[2]); err == nil {
+			t.Errorf("Expected tile %v to fail", zxy)
+		}
+	}
+	if _, err := NewPyramid(&goopendb.Design{}); err == nil {
+		t.Errorf("Expected empty designs to fail")
+	}
+}
+
+func TestTileEncoding(t *testing.T) {
+	p, err := NewPyramid(testDesign())
+	if err != nil {
+		t.Fatal(err)
+	}
+	tile, err := p.Tile(0, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := tile.Encode()
+	decoded, err := Decode(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(tile, decoded) {
+		t.Errorf("Expected %+v, found %+v", tile, decoded)
+	}
+	if _, err := Decode(data[:len(data)-1]); err == nil {
+		t.Errorf("Expected truncated tiles to fail")
+	}
+	if _, err := Decode([]byte("tile")); err == nil {
+		t.Errorf("Expected invalid tiles to fail")
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	backend := lefdef.NewBackend()
+	defer backend.FreeDatabase()
+	if err := backend.ParseLEF(filepath.Join("..", "example", "Nangate45", "NangateOpenCellLibrary.mod.lef")); err != nil {
+		t.Fatal(err)
+	}
+	if err := backend.ParseDEF(filepath.Join("..", "example", "Nangate45", "gcd.def")); err != nil {
+		t.Fatal(err)
+	}
+	design, err := backend.GetDesign()
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := NewPyramid(design)
+	if err != nil {
+		t.Fatal(err)
+	}
+	levels := make([]int, p.MaxZoom+1)
+	size := 0
+	err = p.Generate(func(tile *Tile) error {
+		levels[tile.Z]++
+		data := tile.Encode()
+		size += len(data)
+		_, err := Decode(data)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if levels[0] != 1 || levels[p.MaxZoom] < 1<<uint(p.MaxZoom) {
+		t.Errorf("Unexpected tile counts %v", levels)
+	}
+	t.Logf("%v tiles per level, %v bytes", levels, size)
+}
+
+func TestPrecompute(t *testing.T) {
+	p, err := NewPyramid(testDesign())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = p.Precompute(); err != nil {
+		t.Fatal(err)
+	}
+	if len(p.cache) == 0 {
+		t.Fatalf("Expected precomputed tiles")
+	}
+	for key := range p.cache {
+		if key[0] > CacheZoom {
+			t.Errorf("Unexpected precomputed tile %v", key)
+		}
+	}
+	// Precomputed and cut tiles have the same encoding
+	for z := 0; z <= p.MaxZoom; z++ {
+		tile, err := p.Tile(z, 0, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := p.EncodedTile(z, 0, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(data, tile.Encode()) {
+			t.Errorf("Unexpected encoding of tile %v/0/0", z)
+		}
+	}
+	if _, err := p.EncodedTile(p.MaxZoom+1, 0, 0); err == nil {
+		t.Errorf("Expected invalid tiles to fail")
+	}
+}
@@ -0,0 +1,210 @@
+package verify
+
+// Connectivity extraction from the routed geometry
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ahmed-agiza/EDAViewer/server/goopendb"
+)
+
+// unionFind groups connected shapes
+type unionFind []int
+
+func newUnionFind(n int) unionFind {
+	uf := make(unionFind, n)
+	for i := range uf {
+		uf[i] = i
+	}
+	return uf
+}
+
+func (uf unionFind) find(i int) int {
+	for uf[i] != i {
+		uf[i] = uf[uf[i]]
+		i = uf[i]
+	}
+	return i
+}
+
+func (uf unionFind) union(i, j int) {
+	uf[uf.find(i)] = uf.find(j)
+}
+
+// component is a connected group of shapes of a net
+type component struct {
+	marker *goopendb.Marker
+	pins   []string
+}
+
+// CheckConnectivity extracts the connectivity of every routed net from its
+// wires, vias, special wires and pin shapes. Shapes of the same net connect
+// when they touch on the same layer and the shapes of a via or a pin are
+// connected together. It reports pins disconnected from the rest of their
+// net as opens, routing not reaching any pin as dangling and touching shapes
+// of different nets as shorts
+func CheckConnectivity(design *goopendb.Design) []*goopendb.Marker {
+	index := design.Index()
+	shapes := index.Shapes()
+	layers := design.LayersByID()
+	ids := make(map[*goopendb.Shape]int, len(shapes))
+	for i, shape := range shapes {
+		ids[shape] = i
+	}
+	uf := newUnionFind(len(shapes))
+	vias := make(map[*goopendb.Edge]int)
+	pins := make(map[*goopendb.Pin]int)
+	specialVias := make(map[*goopendb.Rect]int)
+	for i, shape := range shapes {
+		switch shape.Kind {
+		case goopendb.ShapeVia:
+			if j, ok := vias[shape.Edge]; ok {
+				uf.union(i, j)
+			} else {
+				vias[shape.Edge] = i
+			}
+		case goopendb.ShapePin:
+			if j, ok := pins[shape.Pin]; ok {
+				uf.union(i, j)
+			} else {
+				pins[shape.Pin] = i
+			}
+		case goopendb.ShapeSpecialWire:
+			// Special vias are special wire shapes expanded to the via boxes
+			if shape.Rect == nil || shape.Rect.Via == nil {
+				continue
+			}
+			if j, ok := specialVias[shape.Rect]; ok {
+				uf.union(i, j)
+			} else {
+				specialVias[shape.Rect] = i
+			}
+		}
+	}
+
+	var markers []*goopendb.Marker
+	shorts := make(map[string]bool)
+	for i, shape := range shapes {
+		if shape.Layer == nil || shape.Net == nil {
+			continue
+		}
+		rect := &goopendb.Rect{XMin: shape.XMin, YMin: shape.YMin, XMax: shape.XMax, YMax: shape.YMax}
+		for _, other := range index.Query(rect, nil) {
+			j := ids[other]
+			if j <= i || other.Layer == nil || other.Net == nil || other.Layer.ID != shape.Layer.ID {
+				continue
+			}
+			if other.Net == shape.Net {
+				uf.union(i, j)
+				continue
+			}
+			short := &goopendb.Marker{
+				Type:  goopendb.MarkerShort,
+				Layer: layerName(layers, shape.Layer),
+				XMin:  maxInt(shape.XMin, other.XMin),
+				YMin:  maxInt(shape.YMin, other.YMin),
+				XMax:  minInt(shape.XMax, other.XMax),
+				YMax:  minInt(shape.YMax, other.YMax),
+				Nets:  []string{shape.Net.Name, other.Net.Name},
+			}
+			if other.Net.Name < shape.Net.Name {
+				short.Nets[0], short.Nets[1] = short.Nets[1], short.Nets[0]
+			}
+			key := fmt.Sprint(short.Nets, short.Layer, short.XMin, short.YMin, short.XMax, short.YMax)
+			if shorts[key] {
+				continue
+			}
+			shorts[key] = true
+			short.Message = fmt.Sprintf("Short between nets %v and %v on %v", short.Nets[0], short.Nets[1], short.Layer)
+			markers = append(markers, short)
+		}
+	}
+
+	netShapes := make(map[*goopendb.Net][]int)
+	for i, shape := range shapes {
+		if shape.Net != nil && shape.Layer != nil {
+			netShapes[shape.Net] = append(netShapes[shape.Net], i)
+		}
+	}
+	for _, net := range design.Nets {
+		// Unrouted nets are not checked
+		if len(net.Edges) == 0 && len(net.SpecialBoxes) == 0 {
+			continue
+		}
+		components := make(map[int]*component)
+		var order []*component
+		for _, i := range netShapes[net] {
+			shape := shapes[i]
+			root := uf.find(i)
+			c := components[root]
+			if c == nil {
+				c = &component{marker: &goopendb.Marker{
+					Layer: layerName(layers, shape.Layer),
+					XMin:  shape.XMin,
+					YMin:  shape.YMin,
+					XMax:  shape.XMax,
+					YMax:  shape.YMax,
+					Nets:  []string{net.Name},
+				}}
+				components[root] = c
+				order = append(order, c)
+			}
+			extend(c.marker, shape.XMin, shape.YMin, shape.XMax, shape.YMax)
+			if c.marker.Layer != layerName(layers, shape.Layer) {
+				c.marker.Layer = ""
+			}
+			if shape.Kind == goopendb.ShapePin && pins[shape.Pin] == i {
+				c.pins = append(c.pins, pinName(shape.Pin))
+			}
+		}
+		// The component with most pins is the net, the other pin groups are open
+		var main *component
+		for _, c := range order {
+			if len(c.pins) > 0 && (main == nil || len(c.pins) > len(main.pins)) {
+				main = c
+			}
+		}
+		for _, c := range order {
+			if c == main {
+				continue
+			}
+			if len(c.pins) == 0 {
+				c.marker.Type = goopendb.MarkerDangling
+				c.marker.Message = fmt.Sprintf("Dangling routing of net %v", net.Name)
+			} else {
+				c.marker.Type = goopendb.MarkerOpen
+				c.marker.Pins = c.pins
+				c.marker.Message = fmt.Sprintf("Net %v is open at %v", net.Name, strings.Join(c.pins, ", "))
+			}
+			markers = append(markers, c.marker)
+		}
+	}
+	sortMarkers(markers)
+	return markers
+}
+
+// layerName returns the technology name of a layer, shapes may hold layer copies
+func layerName(layers map[int]*goopendb.Layer, layer *goopendb.Layer) string {
+	if layer.Name != "" {
+		return layer.Name
+	}
+	if l := layers[layer.ID]; l != nil {
+		return l.Name
+	}
+	return ""
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
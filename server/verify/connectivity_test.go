@@ -0,0 +1,124 @@
+package verify
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/ahmed-agiza/EDAViewer/server/goopendb"
+	"github.com/ahmed-agiza/EDAViewer/server/lefdef"
+)
+
+// connectivityDesign has a net with an open pin, dangling routing and a short
+// to another net, and a power net connected through a special via
+func connectivityDesign() *goopendb.Design {
+	metal1 := &goopendb.Layer{ID: 1, Name: "metal1"}
+	via1 := &goopendb.Layer{ID: 2, Name: "via1"}
+	metal2 := &goopendb.Layer{ID: 3, Name: "metal2"}
+	via := &goopendb.Via{
+		ID:   1,
+		Name: "VIA12",
+		Rect: &goopendb.Rect{XMin: -35, YMin: -35, XMax: 35, YMax: 35},
+		Boxes: []*goopendb.Rect{
+			{XMin: -35, YMin: -35, XMax: 35, YMax: 35, Layer: metal1},
+			{XMin: -20, YMin: -20, XMax: 20, YMax: 20, Layer: via1},
+			{XMin: -35, YMin: -35, XMax: 35, YMax: 35, Layer: metal2},
+		},
+	}
+	pin := func(net *goopendb.Net, name string, layer *goopendb.Layer, xMin, yMin, xMax, yMax int) *goopendb.Pin {
+		p := &goopendb.Pin{
+			Name:       name,
+			Net:        net,
+			IsBlock:    true,
+			Geometries: []*goopendb.Geometry{{Boxes: []*goopendb.Rect{{XMin: xMin, YMin: yMin, XMax: xMax, YMax: yMax, Layer: layer}}}},
+		}
+		net.Pins = append(net.Pins, p)
+		return p
+	}
+	segment := func(layer *goopendb.Layer, xMin, yMin, xMax, yMax int) *goopendb.Edge {
+		return &goopendb.Edge{Type: goopendb.EdgeTypeSEGMENT, Layer: layer, Rect: &goopendb.Rect{XMin: xMin, YMin: yMin, XMax: xMax, YMax: yMax}}
+	}
+
+	n1 := &goopendb.Net{ID: 1, Name: "n1"}
+	n2 := &goopendb.Net{ID: 2, Name: "n2"}
+	n3 := &goopendb.Net{ID: 3, Name: "n3"}
+	power := &goopendb.Net{ID: 4, Name: "VDD", IsSpecial: true}
+	pins := []*goopendb.Pin{
+		pin(n1, "P1", metal1, 0, 0, 100, 100),
+		pin(n1, "P2", metal1, 1000, 0, 1100, 100),
+		pin(n1, "P3", metal1, 5000, 0, 5100, 100),
+		pin(n2, "Q", metal2, 0, 900, 100, 970),
+		pin(n3, "R1", metal1, 8000, 0, 8100, 100),
+		pin(n3, "R2", metal1, 9000, 0, 9100, 100),
+		pin(power, "VDD", metal2, 3000, 400, 3070, 500),
+	}
+	n1.Edges = []*goopendb.Edge{
+		segment(metal1, 0, 30, 1100, 70),
+		{Type: goopendb.EdgeTypeVIA, Via: via, Rect: &goopendb.Rect{XMin: 465, YMin: 15, XMax: 535, YMax: 85}},
+		segment(metal2, 465, 15, 535, 1000),
+		segment(metal2, 2000, 2000, 3000, 2070),
+	}
+	// Crosses the metal2 wire of n1 and the metal1 wire of n1 on another layer
+	n2.Edges = []*goopendb.Edge{segment(metal2, 0, 900, 1000, 970)}
+	power.SpecialBoxes = []*goopendb.Geometry{
+		{Boxes: []*goopendb.Rect{{XMin: 0, YMin: -300, XMax: 6000, YMax: -250, Layer: metal1}}},
+		{Boxes: []*goopendb.Rect{{XMin: 3000, YMin: -300, XMax: 3070, YMax: 500, Layer: metal2}}},
+		{Boxes: []*goopendb.Rect{{XMin: 3000, YMin: -300, XMax: 3070, YMax: -230, Via: via}}},
+	}
+	return &goopendb.Design{
+		Layers:    []*goopendb.Layer{metal1, via1, metal2},
+		BlockPins: pins,
+		Nets:      []*goopendb.Net{n1, n2, n3, power},
+	}
+}
+
+func TestCheckConnectivity(t *testing.T) {
+	markers := CheckConnectivity(connectivityDesign())
+	expected := []goopendb.Marker{
+		{Type: goopendb.MarkerOpen, Layer: "metal1", XMin: 5000, YMin: 0, XMax: 5100, YMax: 100, Nets: []string{"n1"}, Pins: []string{"PIN/P3"}},
+		{Type: goopendb.MarkerDangling, Layer: "metal2", XMin: 2000, YMin: 2000, XMax: 3000, YMax: 2070, Nets: []string{"n1"}},
+		{Type: goopendb.MarkerShort, Layer: "metal2", XMin: 465, YMin: 900, XMax: 535, YMax: 970, Nets: []string{"n1", "n2"}},
+	}
+	if len(markers) != len(expected) {
+		for _, m := range markers {
+			t.Logf("%+v", *m)
+		}
+		t.Fatalf("Expected %v markers, found %v", len(expected), len(markers))
+	}
+	for i, m := range markers {
+		e := expected[i]
+		if m.Type != e.Type || m.Layer != e.Layer || m.XMin != e.XMin || m.YMin != e.YMin || m.XMax != e.XMax || m.YMax != e.YMax ||
+			len(m.Nets) != len(e.Nets) || m.Nets[0] != e.Nets[0] || len(m.Pins) != len(e.Pins) || m.Message == "" {
+			t.Errorf("Expected %v marker %+v, found %+v", e.Type, e, *m)
+		}
+	}
+}
+
+// routedDesign parses the routed gcd example
+func routedDesign(t *testing.T) *goopendb.Design {
+	backend := lefdef.NewBackend()
+	defer backend.FreeDatabase()
+	if err := backend.ParseLEF(filepath.Join("..", "example", "Nangate45", "NangateOpenCellLibrary.mod.lef")); err != nil {
+		t.Fatal(err)
+	}
+	if err := backend.ParseDEF(filepath.Join("..", "example", "Nangate45", "gcd.def")); err != nil {
+		t.Fatal(err)
+	}
+	design, err := backend.GetDesign()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return design
+}
+
+func TestCheckConnectivityRouted(t *testing.T) {
+	// The example power pins are placed away from their stripes
+	markers := CheckConnectivity(routedDesign(t))
+	if len(markers) != 2 {
+		t.Fatalf("Expected the two power pin opens, found %v markers", len(markers))
+	}
+	for _, m := range markers {
+		if m.Type != goopendb.MarkerOpen || len(m.Pins) != 1 || m.Pins[0] != "PIN/"+m.Nets[0] {
+			t.Errorf("Unexpected marker %+v", *m)
+		}
+	}
+}
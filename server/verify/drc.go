@@ -0,0 +1,543 @@
+package verify
+
+// Design rule checks of the routed shapes using the LEF layer rules
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/ahmed-agiza/EDAViewer/server/goopendb"
+)
+
+// enclosure is a pair of via overhangs, either one may be the horizontal overhang
+type enclosure struct {
+	overhang1 int
+	overhang2 int
+}
+
+// satisfied checks the horizontal and vertical overhangs against the pair
+func (e enclosure) satisfied(x, y int) bool {
+	return (x >= e.overhang1 && y >= e.overhang2) || (x >= e.overhang2 && y >= e.overhang1)
+}
+
+// drc holds the rules and shapes of a design rule check
+type drc struct {
+	design  *goopendb.Design
+	layers  map[int]*goopendb.Layer
+	index   *goopendb.SpatialIndex
+	shapes  []*goopendb.Shape
+	ids     map[*goopendb.Shape]int
+	markers []*goopendb.Marker
+}
+
+// CheckDesignRules checks the routing wires, vias, special wires and pin
+// shapes against the minimum width, spacing and area rules of their layers
+// and the cuts of generated vias against the enclosure rules of their via
+// rules. Widths and areas are measured on the shapes merged with the touching
+// shapes of their conductor. Spacing uses the parallel run length spacing
+// table of the layer when available
+func CheckDesignRules(design *goopendb.Design) []*goopendb.Marker {
+	c := &drc{
+		design: design,
+		// Shapes may hold layer copies, rules are read from the design layers
+		layers: design.LayersByID(),
+		index:  design.Index(),
+	}
+	for _, shape := range c.index.Shapes() {
+		if shape.Kind != goopendb.ShapeInstance && shape.Layer != nil && c.layers[shape.Layer.ID] != nil {
+			c.shapes = append(c.shapes, shape)
+		}
+	}
+	// Shapes are checked in a stable order
+	sort.SliceStable(c.shapes, func(i, j int) bool {
+		a, b := c.shapes[i], c.shapes[j]
+		if a.YMin != b.YMin {
+			return a.YMin < b.YMin
+		}
+		return a.XMin < b.XMin
+	})
+	c.ids = make(map[*goopendb.Shape]int, len(c.shapes))
+	for i, shape := range c.shapes {
+		c.ids[shape] = i
+	}
+	c.checkWidth()
+	c.checkSpacing()
+	c.checkArea()
+	c.checkEnclosure()
+	sortMarkers(c.markers)
+	return c.markers
+}
+
+// layer returns the design layer of a shape
+func (c *drc) layer(shape *goopendb.Shape) *goopendb.Layer {
+	return c.layers[shape.Layer.ID]
+}
+
+// microns formats a distance in microns
+func (c *drc) microns(dist int) string {
+	return fmt.Sprint(c.design.ToMicrons(dist))
+}
+
+// marker creates a marker of the shapes
+func (c *drc) marker(typ goopendb.MarkerType, layer *goopendb.Layer, shapes ...*goopendb.Shape) *goopendb.Marker {
+	m := &goopendb.Marker{
+		Type:  typ,
+		Layer: layer.Name,
+		XMin:  shapes[0].XMin,
+		YMin:  shapes[0].YMin,
+		XMax:  shapes[0].XMax,
+		YMax:  shapes[0].YMax,
+	}
+	nets := make(map[string]bool)
+	for _, shape := range shapes {
+		extend(m, shape.XMin, shape.YMin, shape.XMax, shape.YMax)
+		if shape.Net != nil && !nets[shape.Net.Name] {
+			nets[shape.Net.Name] = true
+			m.Nets = append(m.Nets, shape.Net.Name)
+		}
+		if shape.Kind == goopendb.ShapePin {
+			m.Pins = append(m.Pins, pinName(shape.Pin))
+		}
+	}
+	c.markers = append(c.markers, m)
+	return m
+}
+
+// sameConductor checks if two shapes are parts of the same net, pin or cell
+func sameConductor(a, b *goopendb.Shape) bool {
+	if a.Net != nil && a.Net == b.Net {
+		return true
+	}
+	if a.Kind == goopendb.ShapePin && b.Kind == goopendb.ShapePin {
+		return a.Pin == b.Pin || (a.Instance != nil && a.Instance == b.Instance)
+	}
+	return false
+}
+
+func (c *drc) checkWidth() {
+	for i, shape := range c.shapes {
+		layer := c.layer(shape)
+		if layer.Type != goopendb.LayerTypeROUTING || layer.MinWidth <= 0 {
+			continue
+		}
+		width := minInt(shape.XMax-shape.XMin, shape.YMax-shape.YMin)
+		if width >= layer.MinWidth || c.widened(i, layer.MinWidth) {
+			continue
+		}
+		m := c.marker(goopendb.MarkerMinWidth, layer, shape)
+		m.Rule = "MINWIDTH " + c.microns(layer.MinWidth)
+		m.Message = fmt.Sprintf("Width %v of %v is below the minimum width %v", c.microns(width), layer.Name, c.microns(layer.MinWidth))
+	}
+}
+
+// widened checks if the polygon merging the shape with the touching shapes of
+// its conductor is at least the minimum width across the whole shape, wires
+// may be split into narrower rectangles
+func (c *drc) widened(i int, minWidth int) bool {
+	shape := c.shapes[i]
+	rect := &goopendb.Rect{XMin: shape.XMin - minWidth, YMin: shape.YMin - minWidth, XMax: shape.XMax + minWidth, YMax: shape.YMax + minWidth}
+	var boxes []box
+	for _, other := range c.index.Query(rect, nil) {
+		if j, ok := c.ids[other]; ok && other.Layer.ID == shape.Layer.ID && (j == i || sameConductor(shape, other)) {
+			boxes = append(boxes, box{other.XMin, other.YMin, other.XMax, other.YMax})
+		}
+	}
+	if len(boxes) < 2 {
+		return false
+	}
+	// The narrow sides are extended to the minimum width at the offsets
+	// aligned with the merged shapes, the window has to be covered
+	xStarts, xSize := windowStarts(shape.XMin, shape.XMax, minWidth, boxes, func(b box) (int, int) { return b.xMin, b.xMax })
+	yStarts, ySize := windowStarts(shape.YMin, shape.YMax, minWidth, boxes, func(b box) (int, int) { return b.yMin, b.yMax })
+	for _, x := range xStarts {
+		for _, y := range yStarts {
+			window := box{x, y, x + xSize, y + ySize}
+			var clipped []box
+			for _, b := range boxes {
+				if b.intersects(window) {
+					clipped = append(clipped, b.clip(window))
+				}
+			}
+			if unionArea(clipped) >= window.area() {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// windowStarts returns the starts of the minimum width windows containing a
+// side of a shape, and the window size
+func windowStarts(lo, hi, minWidth int, boxes []box, side func(box) (int, int)) ([]int, int) {
+	if hi-lo >= minWidth {
+		return []int{lo}, hi - lo
+	}
+	first, last := hi-minWidth, lo
+	starts := []int{first, last}
+	for _, b := range boxes {
+		bLo, bHi := side(b)
+		for _, start := range []int{bLo, bHi - minWidth} {
+			if start > first && start < last {
+				starts = append(starts, start)
+			}
+		}
+	}
+	return starts, minWidth
+}
+
+// spacing returns the required spacing between shapes of the widest width
+// with the parallel run length, and the largest spacing of the layer
+func spacing(layer *goopendb.Layer, width, length int) (required, max int) {
+	table := layer.SpacingTable
+	if table == nil || len(table.Widths) == 0 || len(table.Lengths) == 0 {
+		return layer.Spacing, layer.Spacing
+	}
+	// Rows and columns apply to widths and lengths over their value
+	row, col := 0, 0
+	for i, w := range table.Widths {
+		if i == 0 || width > w {
+			row = i
+		}
+	}
+	for j, l := range table.Lengths {
+		if j == 0 || length > l {
+			col = j
+		}
+	}
+	for _, spacings := range table.Spacings {
+		for _, s := range spacings {
+			if s > max {
+				max = s
+			}
+		}
+	}
+	if row < len(table.Spacings) && col < len(table.Spacings[row]) {
+		required = table.Spacings[row][col]
+	}
+	return required, max
+}
+
+func (c *drc) checkSpacing() {
+	for i, shape := range c.shapes {
+		layer := c.layer(shape)
+		if layer.Type != goopendb.LayerTypeROUTING {
+			continue
+		}
+		_, max := spacing(layer, 0, 0)
+		if max <= 0 {
+			continue
+		}
+		rect := &goopendb.Rect{XMin: shape.XMin - max, YMin: shape.YMin - max, XMax: shape.XMax + max, YMax: shape.YMax + max}
+		for _, other := range c.index.Query(rect, nil) {
+			j, ok := c.ids[other]
+			if !ok || j <= i || other.Layer.ID != layer.ID || sameConductor(shape, other) {
+				continue
+			}
+			dx := maxInt(other.XMin-shape.XMax, shape.XMin-other.XMax)
+			dy := maxInt(other.YMin-shape.YMax, shape.YMin-other.YMax)
+			// Touching shapes of different nets are shorts
+			if dx <= 0 && dy <= 0 {
+				continue
+			}
+			var length int
+			if dx > 0 {
+				length = minInt(shape.YMax, other.YMax) - maxInt(shape.YMin, other.YMin)
+			} else {
+				length = minInt(shape.XMax, other.XMax) - maxInt(shape.XMin, other.XMin)
+			}
+			width := maxInt(minInt(shape.XMax-shape.XMin, shape.YMax-shape.YMin), minInt(other.XMax-other.XMin, other.YMax-other.YMin))
+			required, _ := spacing(layer, width, length)
+			// Corner to corner spacing is euclidean
+			dist := float64(maxInt(dx, dy))
+			if dx > 0 && dy > 0 {
+				dist = math.Hypot(float64(dx), float64(dy))
+			}
+			if dist >= float64(required) {
+				continue
+			}
+			m := c.marker(goopendb.MarkerMinSpacing, layer, shape, other)
+			// The marker covers the gap between the shapes
+			m.XMin, m.XMax = minMax(shape.XMin, shape.XMax, other.XMin, other.XMax)
+			m.YMin, m.YMax = minMax(shape.YMin, shape.YMax, other.YMin, other.YMax)
+			m.Rule = "SPACING " + c.microns(required)
+			m.Message = fmt.Sprintf("Spacing %v on %v is below the minimum spacing %v", c.microns(int(math.Round(dist))), layer.Name, c.microns(required))
+		}
+	}
+}
+
+// minMax returns the gap between two intervals, or their overlap when they overlap
+func minMax(aMin, aMax, bMin, bMax int) (int, int) {
+	lo, hi := maxInt(aMin, bMin), minInt(aMax, bMax)
+	if lo > hi {
+		lo, hi = hi, lo
+	}
+	return lo, hi
+}
+
+func (c *drc) checkArea() {
+	dbu := float64(c.design.DBUPerMicron)
+	if dbu <= 0 {
+		return
+	}
+	// Touching shapes of the same conductor on a layer make one polygon
+	uf := newUnionFind(len(c.shapes))
+	for i, shape := range c.shapes {
+		if c.layer(shape).Area <= 0 {
+			continue
+		}
+		rect := &goopendb.Rect{XMin: shape.XMin, YMin: shape.YMin, XMax: shape.XMax, YMax: shape.YMax}
+		for _, other := range c.index.Query(rect, nil) {
+			if j, ok := c.ids[other]; ok && j > i && other.Layer.ID == shape.Layer.ID && sameConductor(shape, other) {
+				uf.union(i, j)
+			}
+		}
+	}
+	polygons := make(map[int][]*goopendb.Shape)
+	var roots []int
+	for i, shape := range c.shapes {
+		layer := c.layer(shape)
+		if layer.Type != goopendb.LayerTypeROUTING || layer.Area <= 0 {
+			continue
+		}
+		root := uf.find(i)
+		if polygons[root] == nil {
+			roots = append(roots, root)
+		}
+		polygons[root] = append(polygons[root], shape)
+	}
+	for _, root := range roots {
+		shapes := polygons[root]
+		layer := c.layer(shapes[0])
+		minArea := layer.Area * dbu * dbu
+		area := polygonArea(shapes, minArea)
+		if area >= minArea {
+			continue
+		}
+		m := c.marker(goopendb.MarkerMinArea, layer, shapes...)
+		m.Rule = fmt.Sprint("AREA ", layer.Area)
+		m.Message = fmt.Sprintf("Area %v of %v is below the minimum area %v", area/(dbu*dbu), layer.Name, layer.Area)
+	}
+}
+
+// polygonArea returns the area covered by the shapes, polygons with a shape
+// larger than the minimum area are not measured
+func polygonArea(shapes []*goopendb.Shape, minArea float64) float64 {
+	boxes := make([]box, len(shapes))
+	for i, shape := range shapes {
+		boxes[i] = box{shape.XMin, shape.YMin, shape.XMax, shape.YMax}
+		if area := boxes[i].area(); area >= minArea {
+			return area
+		}
+	}
+	return unionArea(boxes)
+}
+
+// box is a rectangle of the area computations
+type box struct {
+	xMin, yMin, xMax, yMax int
+}
+
+func (b box) area() float64 {
+	return float64(b.xMax-b.xMin) * float64(b.yMax-b.yMin)
+}
+
+func (b box) intersects(other box) bool {
+	return b.xMin < other.xMax && other.xMin < b.xMax && b.yMin < other.yMax && other.yMin < b.yMax
+}
+
+// clip returns the part of the box inside the other box
+func (b box) clip(other box) box {
+	return box{maxInt(b.xMin, other.xMin), maxInt(b.yMin, other.yMin), minInt(b.xMax, other.xMax), minInt(b.yMax, other.yMax)}
+}
+
+// coverTree is a segment tree of the Y intervals covered by the boxes crossing
+// the sweep line, count is the number of boxes covering a whole node and
+// length the covered length of the node
+type coverTree struct {
+	ys     []int
+	count  []int
+	length []int
+}
+
+// update adds delta boxes over the elementary intervals [from, to) of the
+// node covering the intervals [lo, hi)
+func (t *coverTree) update(node, lo, hi, from, to, delta int) {
+	if to <= lo || hi <= from {
+		return
+	}
+	if from <= lo && hi <= to {
+		t.count[node] += delta
+	} else {
+		mid := (lo + hi) / 2
+		t.update(2*node, lo, mid, from, to, delta)
+		t.update(2*node+1, mid, hi, from, to, delta)
+	}
+	switch {
+	case t.count[node] > 0:
+		t.length[node] = t.ys[hi] - t.ys[lo]
+	case hi-lo == 1:
+		t.length[node] = 0
+	default:
+		t.length[node] = t.length[2*node] + t.length[2*node+1]
+	}
+}
+
+// unionArea returns the area covered by the boxes, a vertical line sweeps
+// the box edges keeping the covered Y length in a segment tree
+func unionArea(boxes []box) float64 {
+	type edge struct {
+		x, yMin, yMax, delta int
+	}
+	var edges []edge
+	var ys []int
+	for _, b := range boxes {
+		if b.xMin >= b.xMax || b.yMin >= b.yMax {
+			continue
+		}
+		edges = append(edges, edge{b.xMin, b.yMin, b.yMax, 1}, edge{b.xMax, b.yMin, b.yMax, -1})
+		ys = append(ys, b.yMin, b.yMax)
+	}
+	if len(edges) == 0 {
+		return 0
+	}
+	sort.Slice(edges, func(i, j int) bool { return edges[i].x < edges[j].x })
+	sort.Ints(ys)
+	unique := ys[:1]
+	for _, y := range ys[1:] {
+		if y != unique[len(unique)-1] {
+			unique = append(unique, y)
+		}
+	}
+	segments := len(unique) - 1
+	t := &coverTree{ys: unique, count: make([]int, 4*segments), length: make([]int, 4*segments)}
+	area := 0.0
+	for i, e := range edges {
+		if i > 0 {
+			area += float64(t.length[1]) * float64(e.x-edges[i-1].x)
+		}
+		from := sort.SearchInts(unique, e.yMin)
+		to := sort.SearchInts(unique, e.yMax)
+		t.update(1, 0, segments, from, to, e.delta)
+	}
+	return area
+}
+
+// enclosures returns the allowed overhang pairs of the via rules by rule ID
+// and metal layer, and merged by cut layer and metal layer
+func (c *drc) enclosures() (byRule, byCut map[int]map[int][]enclosure) {
+	byRule = make(map[int]map[int][]enclosure)
+	byCut = make(map[int]map[int][]enclosure)
+	for _, rule := range c.design.ViaRules {
+		byRule[rule.ID] = make(map[int][]enclosure)
+		cut := -1
+		for _, rl := range rule.Layers {
+			if rl.Layer != nil && c.layers[rl.Layer.ID] != nil && c.layers[rl.Layer.ID].Type == goopendb.LayerTypeCUT {
+				cut = rl.Layer.ID
+			}
+		}
+		if cut >= 0 && byCut[cut] == nil {
+			byCut[cut] = make(map[int][]enclosure)
+		}
+		for _, rl := range rule.Layers {
+			if rl.Layer == nil || !rl.HasEnclosure {
+				continue
+			}
+			e := enclosure{rl.EnclosureOverhang1, rl.EnclosureOverhang2}
+			byRule[rule.ID][rl.Layer.ID] = append(byRule[rule.ID][rl.Layer.ID], e)
+			if cut >= 0 {
+				byCut[cut][rl.Layer.ID] = append(byCut[cut][rl.Layer.ID], e)
+			}
+		}
+	}
+	return byRule, byCut
+}
+
+// checkEnclosure checks the cuts of the vias generated from a via rule, fixed
+// vias are drawn by the technology and are not checked
+func (c *drc) checkEnclosure() {
+	byRule, byCut := c.enclosures()
+	// Shapes of every placed generated via
+	vias := make(map[interface{}][]*goopendb.Shape)
+	viaRules := make(map[interface{}]map[int][]enclosure)
+	var order []interface{}
+	for _, shape := range c.shapes {
+		var key interface{}
+		var via *goopendb.Via
+		switch {
+		case shape.Kind == goopendb.ShapeVia:
+			key, via = shape.Edge, shape.Edge.Via
+		case shape.Kind == goopendb.ShapeSpecialWire && shape.Rect != nil && shape.Rect.Via != nil:
+			key, via = shape.Rect, shape.Rect.Via
+		default:
+			continue
+		}
+		if via == nil || (via.Params == nil && via.Rule == nil) {
+			continue
+		}
+		if vias[key] == nil {
+			order = append(order, key)
+			// Vias without a rule name may come from any rule of the cut layer
+			if via.Rule != nil {
+				viaRules[key] = byRule[via.Rule.ID]
+			}
+		}
+		vias[key] = append(vias[key], shape)
+	}
+	for _, key := range order {
+		shapes := vias[key]
+		for _, cut := range shapes {
+			cutLayer := c.layer(cut)
+			if cutLayer.Type != goopendb.LayerTypeCUT {
+				continue
+			}
+			// Best overhangs of the via metal shapes by layer
+			overhangs := make(map[int][2]int)
+			var metals []int
+			for _, metal := range shapes {
+				id := metal.Layer.ID
+				if c.layer(metal).Type != goopendb.LayerTypeROUTING {
+					continue
+				}
+				x := minInt(cut.XMin-metal.XMin, metal.XMax-cut.XMax)
+				y := minInt(cut.YMin-metal.YMin, metal.YMax-cut.YMax)
+				best, ok := overhangs[id]
+				if !ok {
+					metals = append(metals, id)
+				}
+				if !ok || minInt(x, y) > minInt(best[0], best[1]) || (minInt(x, y) == minInt(best[0], best[1]) && x+y > best[0]+best[1]) {
+					overhangs[id] = [2]int{x, y}
+				}
+			}
+			for _, id := range metals {
+				x, y := overhangs[id][0], overhangs[id][1]
+				rules := viaRules[key]
+				if rules == nil {
+					rules = byCut[cutLayer.ID]
+				}
+				allowed := rules[id]
+				if len(allowed) == 0 {
+					allowed = []enclosure{{}}
+				}
+				var violated *enclosure
+				for i := range allowed {
+					if allowed[i].satisfied(x, y) {
+						violated = nil
+						break
+					}
+					if violated == nil {
+						violated = &allowed[i]
+					}
+				}
+				if violated == nil {
+					continue
+				}
+				layer := c.layers[id]
+				m := c.marker(goopendb.MarkerViaEnclosure, layer, cut)
+				m.Rule = "ENCLOSURE " + c.microns(violated.overhang1) + " " + c.microns(violated.overhang2)
+				m.Message = fmt.Sprintf("Enclosure %v %v of the %v cut by %v is below the minimum enclosure %v %v",
+					c.microns(x), c.microns(y), cutLayer.Name, layer.Name, c.microns(violated.overhang1), c.microns(violated.overhang2))
+			}
+		}
+	}
+}
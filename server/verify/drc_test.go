@@ -0,0 +1,153 @@
+package verify
+
+import (
+	"testing"
+
+	"github.com/ahmed-agiza/EDAViewer/server/goopendb"
+)
+
+// drcDesign has a wire narrower than the minimum width, wires closer than the
+// minimum spacing, small polygons and a generated via with a short enclosure
+func drcDesign() *goopendb.Design {
+	metal1 := &goopendb.Layer{ID: 1, Name: "metal1", Type: goopendb.LayerTypeROUTING, MinWidth: 100, Spacing: 100, Area: 0.1}
+	via1 := &goopendb.Layer{ID: 2, Name: "via1", Type: goopendb.LayerTypeCUT}
+	metal2 := &goopendb.Layer{
+		ID:       3,
+		Name:     "metal2",
+		Type:     goopendb.LayerTypeROUTING,
+		MinWidth: 100,
+		SpacingTable: &goopendb.SpacingTable{
+			Widths:   []int{0, 300},
+			Lengths:  []int{0, 1000},
+			Spacings: [][]int{{100, 100}, {100, 300}},
+		},
+	}
+	via := &goopendb.Via{
+		ID:   1,
+		Name: "VIA12",
+		Rect: &goopendb.Rect{XMin: -100, YMin: -50, XMax: 100, YMax: 50},
+		Boxes: []*goopendb.Rect{
+			{XMin: -100, YMin: -50, XMax: 100, YMax: 50, Layer: metal1},
+			{XMin: -50, YMin: -50, XMax: 50, YMax: 50, Layer: via1},
+			{XMin: -50, YMin: -50, XMax: 50, YMax: 50, Layer: metal2},
+		},
+	}
+	rule := &goopendb.ViaRule{
+		ID:   1,
+		Name: "VIA12GEN",
+		Layers: []*goopendb.ViaRuleLayer{
+			{Layer: metal1, HasEnclosure: true, EnclosureOverhang1: 50},
+			{Layer: metal2, HasEnclosure: true, EnclosureOverhang1: 50},
+			{Layer: via1},
+		},
+	}
+	via.Rule = rule
+	// Fixed vias are not checked against the via rules
+	fixed := &goopendb.Via{ID: 2, Name: "VIA12FIX", Rect: via.Rect, Boxes: via.Boxes}
+	wires := func(name string, layer *goopendb.Layer, rects ...[4]int) *goopendb.Net {
+		net := &goopendb.Net{Name: name}
+		for _, r := range rects {
+			net.Edges = append(net.Edges, &goopendb.Edge{
+				Type:  goopendb.EdgeTypeSEGMENT,
+				Layer: layer,
+				Rect:  &goopendb.Rect{XMin: r[0], YMin: r[1], XMax: r[2], YMax: r[3]},
+			})
+		}
+		return net
+	}
+	n1 := wires("n1", metal1, [4]int{0, 0, 2000, 100})
+	n1.Edges = append(n1.Edges, &goopendb.Edge{Type: goopendb.EdgeTypeVIA, Via: via, Rect: &goopendb.Rect{XMin: 1400, YMin: 0, XMax: 1600, YMax: 100}})
+	n9 := wires("n9", metal1, [4]int{8000, 0, 10000, 100})
+	n9.Edges = append(n9.Edges, &goopendb.Edge{Type: goopendb.EdgeTypeVIA, Via: fixed, Rect: &goopendb.Rect{XMin: 9400, YMin: 0, XMax: 9600, YMax: 100}})
+	nets := []*goopendb.Net{
+		n1,
+		wires("n2", metal1, [4]int{0, 150, 1000, 250}),
+		wires("n3", metal1, [4]int{0, 1000, 1000, 1080}),
+		wires("n4", metal1, [4]int{3000, 0, 3200, 200}, [4]int{3100, 0, 3300, 200}),
+		// The merged polygon is large enough
+		wires("n5", metal1, [4]int{5000, 0, 5300, 200}, [4]int{5200, 0, 5500, 200}),
+		// Wide wire with a long parallel run
+		wires("n6", metal2, [4]int{0, 2000, 2000, 2400}),
+		wires("n7", metal2, [4]int{0, 2600, 2000, 2700}),
+		// Short parallel runs use the first spacing column
+		wires("n8", metal2, [4]int{2200, 2000, 2300, 3000}),
+		n9,
+		// Narrow rectangles merging into a wide enough wire
+		wires("n10", metal1, [4]int{6000, 0, 7800, 60}, [4]int{6000, 60, 7800, 120}),
+	}
+	for i, net := range nets {
+		net.ID = i + 1
+	}
+	return &goopendb.Design{
+		DBUPerMicron: 1000,
+		Layers:       []*goopendb.Layer{metal1, via1, metal2},
+		RoutingVias:  []*goopendb.Via{via, fixed},
+		ViaRules:     []*goopendb.ViaRule{rule},
+		Nets:         nets,
+	}
+}
+
+func TestCheckDesignRules(t *testing.T) {
+	markers := CheckDesignRules(drcDesign())
+	expected := []goopendb.Marker{
+		{Type: goopendb.MarkerMinWidth, Rule: "MINWIDTH 0.1", Layer: "metal1", XMin: 0, YMin: 1000, XMax: 1000, YMax: 1080, Nets: []string{"n3"}},
+		{Type: goopendb.MarkerMinSpacing, Rule: "SPACING 0.1", Layer: "metal1", XMin: 0, YMin: 100, XMax: 1000, YMax: 150, Nets: []string{"n1", "n2"}},
+		{Type: goopendb.MarkerMinSpacing, Rule: "SPACING 0.3", Layer: "metal2", XMin: 0, YMin: 2400, XMax: 2000, YMax: 2600, Nets: []string{"n6", "n7"}},
+		{Type: goopendb.MarkerMinArea, Rule: "AREA 0.1", Layer: "metal1", XMin: 3000, YMin: 0, XMax: 3300, YMax: 200, Nets: []string{"n4"}},
+		{Type: goopendb.MarkerMinArea, Rule: "AREA 0.1", Layer: "metal1", XMin: 0, YMin: 1000, XMax: 1000, YMax: 1080, Nets: []string{"n3"}},
+		{Type: goopendb.MarkerViaEnclosure, Rule: "ENCLOSURE 0.05 0", Layer: "metal2", XMin: 1450, YMin: 0, XMax: 1550, YMax: 100, Nets: []string{"n1"}},
+	}
+	if len(markers) != len(expected) {
+		for _, m := range markers {
+			t.Logf("%+v", *m)
+		}
+		t.Fatalf("Expected %v markers, found %v", len(expected), len(markers))
+	}
+	for i, m := range markers {
+		e := expected[i]
+		if m.Type != e.Type || m.Rule != e.Rule || m.Layer != e.Layer || m.XMin != e.XMin || m.YMin != e.YMin || m.XMax != e.XMax || m.YMax != e.YMax ||
+			len(m.Nets) != len(e.Nets) || m.Nets[0] != e.Nets[0] || m.Message == "" {
+			t.Errorf("Expected %v marker %+v, found %+v", e.Type, e, *m)
+		}
+	}
+}
+
+func TestCheckDesignRulesRouted(t *testing.T) {
+	if markers := CheckDesignRules(routedDesign(t)); len(markers) != 0 {
+		t.Errorf("Expected a clean routed design, found %v markers starting with %+v", len(markers), *markers[0])
+	}
+}
+
+func TestRun(t *testing.T) {
+	design := drcDesign()
+	markers, err := Run(design, []string{"connectivity", "drc"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The nets without pins are dangling
+	if expected := len(CheckConnectivity(design)) + len(CheckDesignRules(design)); len(markers) != expected {
+		t.Errorf("Expected %v markers, found %v", expected, len(markers))
+	}
+	if _, err := Run(design, []string{"lvs"}); err == nil {
+		t.Errorf("Expected unknown checks to fail")
+	}
+}
+
+func TestUnionArea(t *testing.T) {
+	for _, tc := range []struct {
+		boxes    []box
+		expected float64
+	}{
+		{nil, 0},
+		{[]box{{0, 0, 10, 10}}, 100},
+		{[]box{{0, 0, 10, 10}, {5, 5, 15, 15}}, 175},
+		{[]box{{0, 0, 10, 10}, {2, 2, 8, 8}}, 100},
+		{[]box{{0, 0, 10, 10}, {10, 0, 20, 10}, {0, 10, 20, 20}}, 400},
+		// A cross and a degenerate box
+		{[]box{{0, 10, 30, 20}, {10, 0, 20, 30}, {5, 5, 5, 25}}, 500},
+	} {
+		if area := unionArea(tc.boxes); area != tc.expected {
+			t.Errorf("Expected area %v of %v, found %v", tc.expected, tc.boxes, area)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+// Package verify checks the connectivity, design rules and placement of
+// designs and reports the violations as markers for the viewer
+package verify
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/ahmed-agiza/EDAViewer/server/goopendb"
+)
+
+// Check verifies a design and returns the violation markers
+type Check func(design *goopendb.Design) []*goopendb.Marker
+
+// Checks are the available checks by name
+var Checks = map[string]Check{
+	"connectivity": CheckConnectivity,
+	"drc":          CheckDesignRules,
+	"placement":    CheckPlacement,
+}
+
+// Run runs the named checks and returns their markers in order
+func Run(design *goopendb.Design, names []string) ([]*goopendb.Marker, error) {
+	var markers []*goopendb.Marker
+	for _, name := range names {
+		check, ok := Checks[strings.TrimSpace(name)]
+		if !ok {
+			return nil, fmt.Errorf("Unsupported check %v", name)
+		}
+		markers = append(markers, check(design)...)
+	}
+	return markers, nil
+}
+
+// extend grows the marker to cover the rectangle
+func extend(m *goopendb.Marker, xMin, yMin, xMax, yMax int) {
+	if xMin < m.XMin {
+		m.XMin = xMin
+	}
+	if yMin < m.YMin {
+		m.YMin = yMin
+	}
+	if xMax > m.XMax {
+		m.XMax = xMax
+	}
+	if yMax > m.YMax {
+		m.YMax = yMax
+	}
+}
+
+// sortMarkers orders markers by type and location
+func sortMarkers(markers []*goopendb.Marker) {
+	sort.SliceStable(markers, func(i, j int) bool {
+		a, b := markers[i], markers[j]
+		if a.Type != b.Type {
+			return a.Type < b.Type
+		}
+		if a.YMin != b.YMin {
+			return a.YMin < b.YMin
+		}
+		return a.XMin < b.XMin
+	})
+}
+
+// pinName names instance pins as instance/pin and block pins as PIN/pin
+func pinName(pin *goopendb.Pin) string {
+	if pin.Instance != nil {
+		return pin.Instance.Name + "/" + pin.Name
+	}
+	return "PIN/" + pin.Name
+}
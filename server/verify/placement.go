@@ -0,0 +1,209 @@
+package verify
+
+// Placement legality of the instances against the rows, sites and regions
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/ahmed-agiza/EDAViewer/server/goopendb"
+)
+
+// flippedOrientations mirror row orientations about the Y axis, cells may be
+// placed in the row orientation or flipped
+var flippedOrientations = map[goopendb.Orientation]goopendb.Orientation{
+	goopendb.OrientationR0:    goopendb.OrientationMY,
+	goopendb.OrientationMY:    goopendb.OrientationR0,
+	goopendb.OrientationMX:    goopendb.OrientationR180,
+	goopendb.OrientationR180:  goopendb.OrientationMX,
+	goopendb.OrientationR90:   goopendb.OrientationMXR90,
+	goopendb.OrientationMXR90: goopendb.OrientationR90,
+	goopendb.OrientationR270:  goopendb.OrientationMYR90,
+	goopendb.OrientationMYR90: goopendb.OrientationR270,
+}
+
+// rowKey locates the rows by direction and the coordinate of their bottom, or
+// of their left side for vertical rows
+type rowKey struct {
+	vertical bool
+	position int
+}
+
+// legalOrientation checks if the instance orientation is legal on the row
+func legalOrientation(inst *goopendb.Instance, row *goopendb.Row) bool {
+	return inst.Orientation == row.Orientation || inst.Orientation == flippedOrientations[row.Orientation]
+}
+
+// rowPlaced checks if the instance master is placed in rows, covers, rings,
+// blocks and pads are not
+func rowPlaced(inst *goopendb.Instance) bool {
+	return inst.MasterType == goopendb.MasterTypeCORE || inst.MasterType == goopendb.MasterTypeENDCAP
+}
+
+// instanceMarker creates a marker on the instances
+func instanceMarker(typ goopendb.MarkerType, message string, instances ...*goopendb.Instance) *goopendb.Marker {
+	bbox := instances[0].BoundingBox
+	m := &goopendb.Marker{
+		Type:    typ,
+		Message: message,
+		XMin:    bbox.XMin,
+		YMin:    bbox.YMin,
+		XMax:    bbox.XMax,
+		YMax:    bbox.YMax,
+	}
+	for _, inst := range instances {
+		extend(m, inst.BoundingBox.XMin, inst.BoundingBox.YMin, inst.BoundingBox.XMax, inst.BoundingBox.YMax)
+		m.Instances = append(m.Instances, inst.Name)
+	}
+	return m
+}
+
+// inside checks if the rectangle is inside the box
+func inside(r, box *goopendb.Rect) bool {
+	return box.XMin <= r.XMin && r.XMax <= box.XMax && box.YMin <= r.YMin && r.YMax <= box.YMax
+}
+
+// covered checks if the rectangle is covered by the boxes
+func covered(r *goopendb.Rect, boxes []*goopendb.Rect) bool {
+	xs := []int{r.XMin, r.XMax}
+	ys := []int{r.YMin, r.YMax}
+	for _, box := range boxes {
+		xs = append(xs, clampInt(box.XMin, r.XMin, r.XMax), clampInt(box.XMax, r.XMin, r.XMax))
+		ys = append(ys, clampInt(box.YMin, r.YMin, r.YMax), clampInt(box.YMax, r.YMin, r.YMax))
+	}
+	sort.Ints(xs)
+	sort.Ints(ys)
+	// Every cell of the grid of box edges is covered by a box
+	for i := 0; i+1 < len(xs); i++ {
+		for j := 0; j+1 < len(ys); j++ {
+			cell := &goopendb.Rect{XMin: xs[i], YMin: ys[j], XMax: xs[i+1], YMax: ys[j+1]}
+			if cell.XMin == cell.XMax || cell.YMin == cell.YMax {
+				continue
+			}
+			found := false
+			for _, box := range boxes {
+				if inside(cell, box) {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// CheckPlacement checks that the core cells are on a row, on the row sites
+// and in an orientation legal for the row, that placed instances don't
+// overlap, that they are inside the die and core cells inside the core, and
+// that the members of fence regions are inside their region
+func CheckPlacement(design *goopendb.Design) []*goopendb.Marker {
+	var markers []*goopendb.Marker
+	var placed []*goopendb.Instance
+	for _, inst := range design.Instances {
+		if inst.IsPlaced && inst.BoundingBox != nil {
+			placed = append(placed, inst)
+		}
+	}
+
+	rows := make(map[rowKey][]*goopendb.Row)
+	for _, row := range design.Rows {
+		if row.BoundingBox == nil {
+			continue
+		}
+		key := rowKey{position: row.BoundingBox.YMin}
+		if row.Direction == goopendb.DirectionVERTICAL {
+			key = rowKey{vertical: true, position: row.BoundingBox.XMin}
+		}
+		rows[key] = append(rows[key], row)
+	}
+	for _, inst := range placed {
+		if len(design.Rows) == 0 || !rowPlaced(inst) {
+			continue
+		}
+		bbox := inst.BoundingBox
+		// Rows of the instance position, a row of a legal orientation is
+		// preferred when rows overlap
+		var row *goopendb.Row
+		for _, r := range rows[rowKey{position: bbox.YMin}] {
+			if r.BoundingBox.XMin <= bbox.XMin && bbox.XMax <= r.BoundingBox.XMax && (row == nil || !legalOrientation(inst, row)) {
+				row = r
+			}
+		}
+		for _, r := range rows[rowKey{vertical: true, position: bbox.XMin}] {
+			if r.BoundingBox.YMin <= bbox.YMin && bbox.YMax <= r.BoundingBox.YMax && (row == nil || !legalOrientation(inst, row)) {
+				row = r
+			}
+		}
+		if row == nil {
+			markers = append(markers, instanceMarker(goopendb.MarkerOffRow, fmt.Sprintf("Instance %v is not on a row", inst.Name), inst))
+			continue
+		}
+		step, offset := row.Spacing, bbox.XMin-row.OriginX
+		if row.Direction == goopendb.DirectionVERTICAL {
+			offset = bbox.YMin - row.OriginY
+		}
+		if step <= 0 && row.Site != nil {
+			step = row.Site.Width
+		}
+		if step > 0 && offset%step != 0 {
+			markers = append(markers, instanceMarker(goopendb.MarkerOffSite, fmt.Sprintf("Instance %v is %v off the sites of row %v", inst.Name, design.ToMicrons(offset%step), row.Name), inst))
+		}
+		if !legalOrientation(inst, row) {
+			markers = append(markers, instanceMarker(goopendb.MarkerOrientation, fmt.Sprintf("Instance %v orientation %v is illegal on row %v of orientation %v", inst.Name, inst.Orientation, row.Name, row.Orientation), inst))
+		}
+	}
+
+	index := design.Index()
+	for _, inst := range placed {
+		bbox := inst.BoundingBox
+		for _, shape := range index.Query(bbox, &goopendb.QueryFilter{Kinds: []goopendb.ShapeKind{goopendb.ShapeInstance}}) {
+			other := shape.Instance
+			// Every pair is reported once
+			if other.ID <= inst.ID || other.BoundingBox.XMin >= bbox.XMax || bbox.XMin >= other.BoundingBox.XMax ||
+				other.BoundingBox.YMin >= bbox.YMax || bbox.YMin >= other.BoundingBox.YMax {
+				continue
+			}
+			m := instanceMarker(goopendb.MarkerOverlap, fmt.Sprintf("Instances %v and %v overlap", inst.Name, other.Name), inst, other)
+			m.XMin, m.XMax = minMax(bbox.XMin, bbox.XMax, other.BoundingBox.XMin, other.BoundingBox.XMax)
+			m.YMin, m.YMax = minMax(bbox.YMin, bbox.YMax, other.BoundingBox.YMin, other.BoundingBox.YMax)
+			markers = append(markers, m)
+		}
+	}
+
+	for _, inst := range placed {
+		bbox := inst.BoundingBox
+		if design.Die != nil && !inside(bbox, design.Die) {
+			markers = append(markers, instanceMarker(goopendb.MarkerOutsideDie, fmt.Sprintf("Instance %v is outside the die", inst.Name), inst))
+		} else if design.Core != nil && rowPlaced(inst) && !inside(bbox, design.Core) {
+			markers = append(markers, instanceMarker(goopendb.MarkerOutsideCore, fmt.Sprintf("Instance %v is outside the core", inst.Name), inst))
+		}
+	}
+
+	// Instances may hold region stubs, regions are matched by ID
+	regions := make(map[int]*goopendb.Region)
+	for _, region := range design.Regions {
+		regions[region.ID] = region
+	}
+	for _, inst := range placed {
+		region := inst.Region
+		if region == nil && inst.Group != nil {
+			region = inst.Group.Region
+		}
+		if region != nil && regions[region.ID] != nil {
+			region = regions[region.ID]
+		}
+		if region == nil || region.Type != goopendb.RegionTypeFENCE || len(region.Boxes) == 0 || covered(inst.BoundingBox, region.Boxes) {
+			continue
+		}
+		markers = append(markers, instanceMarker(goopendb.MarkerFence, fmt.Sprintf("Instance %v is outside its fence region %v", inst.Name, region.Name), inst))
+	}
+	sortMarkers(markers)
+	return markers
+}
+
+func clampInt(v, lo, hi int) int {
+	return minInt(maxInt(v, lo), hi)
+}
@@ -0,0 +1,128 @@
+package verify
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/ahmed-agiza/EDAViewer/server/goopendb"
+)
+
+// placementDesign has two rows of ten sites and an instance for every
+// placement violation
+func placementDesign() *goopendb.Design {
+	site := &goopendb.Site{ID: 1, Name: "core", Width: 100, Height: 200}
+	rows := []*goopendb.Row{
+		{ID: 1, Name: "ROW_0", Site: site, Orientation: goopendb.OrientationR0, Spacing: 100, SiteCount: 10, BoundingBox: &goopendb.Rect{XMax: 1000, YMax: 200}},
+		{ID: 2, Name: "ROW_1", Site: site, Orientation: goopendb.OrientationMX, OriginY: 200, Spacing: 100, SiteCount: 10, BoundingBox: &goopendb.Rect{YMin: 200, XMax: 1000, YMax: 400}},
+	}
+	fence := &goopendb.Region{ID: 1, Name: "fence", Type: goopendb.RegionTypeFENCE, Boxes: []*goopendb.Rect{{XMin: 600, YMin: 200, XMax: 1000, YMax: 400}}}
+	var instances []*goopendb.Instance
+	add := func(name string, typ goopendb.MasterType, orientation goopendb.Orientation, xMin, yMin, xMax, yMax int) *goopendb.Instance {
+		inst := &goopendb.Instance{
+			ID:          len(instances) + 1,
+			Name:        name,
+			IsPlaced:    true,
+			MasterType:  typ,
+			Orientation: orientation,
+			BoundingBox: &goopendb.Rect{XMin: xMin, YMin: yMin, XMax: xMax, YMax: yMax},
+		}
+		instances = append(instances, inst)
+		return inst
+	}
+	add("legal", goopendb.MasterTypeCORE, goopendb.OrientationR0, 0, 0, 200, 200)
+	add("flipped", goopendb.MasterTypeCORE, goopendb.OrientationMY, 200, 0, 300, 200)
+	add("offsite", goopendb.MasterTypeCORE, goopendb.OrientationR0, 350, 0, 450, 200)
+	add("orientation", goopendb.MasterTypeCORE, goopendb.OrientationR0, 0, 200, 100, 400)
+	add("offrow", goopendb.MasterTypeCORE, goopendb.OrientationR0, 800, 100, 900, 300)
+	add("overlap", goopendb.MasterTypeCORE, goopendb.OrientationR0, 100, 0, 200, 200)
+	add("outside", goopendb.MasterTypeCORE, goopendb.OrientationR0, 1000, 0, 1100, 200)
+	add("macro", goopendb.MasterTypeBLOCK, goopendb.OrientationR0, 1050, 400, 1200, 600)
+	// Fence members hold region stubs or belong to a group of the region
+	add("unfenced", goopendb.MasterTypeCORE, goopendb.OrientationMX, 500, 200, 600, 400).Region = &goopendb.Region{ID: 1, InComplete: true}
+	add("fenced", goopendb.MasterTypeCORE, goopendb.OrientationR180, 600, 200, 700, 400).Group = &goopendb.Group{ID: 1, Region: fence}
+	add("unplaced", goopendb.MasterTypeCORE, goopendb.OrientationR0, 0, 0, 100, 200).IsPlaced = false
+	return &goopendb.Design{
+		Instances: instances,
+		Rows:      rows,
+		Sites:     []*goopendb.Site{site},
+		Regions:   []*goopendb.Region{fence},
+		Core:      &goopendb.Rect{XMax: 1000, YMax: 400},
+		Die:       &goopendb.Rect{XMin: -100, YMin: -100, XMax: 1100, YMax: 500},
+	}
+}
+
+func TestCheckPlacement(t *testing.T) {
+	markers := CheckPlacement(placementDesign())
+	expected := []goopendb.Marker{
+		{Type: goopendb.MarkerOffRow, XMin: 1000, XMax: 1100, YMax: 200, Instances: []string{"outside"}},
+		{Type: goopendb.MarkerOffRow, XMin: 800, YMin: 100, XMax: 900, YMax: 300, Instances: []string{"offrow"}},
+		{Type: goopendb.MarkerOffSite, XMin: 350, XMax: 450, YMax: 200, Instances: []string{"offsite"}},
+		{Type: goopendb.MarkerOrientation, YMin: 200, XMax: 100, YMax: 400, Instances: []string{"orientation"}},
+		{Type: goopendb.MarkerOverlap, XMin: 100, XMax: 200, YMax: 200, Instances: []string{"legal", "overlap"}},
+		{Type: goopendb.MarkerOutsideCore, XMin: 1000, XMax: 1100, YMax: 200, Instances: []string{"outside"}},
+		{Type: goopendb.MarkerOutsideDie, XMin: 1050, YMin: 400, XMax: 1200, YMax: 600, Instances: []string{"macro"}},
+		{Type: goopendb.MarkerFence, XMin: 500, YMin: 200, XMax: 600, YMax: 400, Instances: []string{"unfenced"}},
+	}
+	if len(markers) != len(expected) {
+		for _, m := range markers {
+			t.Logf("%+v", *m)
+		}
+		t.Fatalf("Expected %v markers, found %v", len(expected), len(markers))
+	}
+	for i, m := range markers {
+		e := expected[i]
+		e.Message = m.Message
+		if m.Message == "" || !reflect.DeepEqual(*m, e) {
+			t.Errorf("Expected %v marker %+v, found %+v", e.Type, e, *m)
+		}
+	}
+}
+
+func TestCheckPlacementRouted(t *testing.T) {
+	if markers := CheckPlacement(routedDesign(t)); len(markers) != 0 {
+		t.Errorf("Expected a legal placement, found %v markers starting with %+v", len(markers), *markers[0])
+	}
+}
+
+func TestCheckPlacementVertical(t *testing.T) {
+	site := &goopendb.Site{ID: 1, Name: "core", Width: 100, Height: 200}
+	row := &goopendb.Row{
+		ID:          1,
+		Name:        "COLUMN_0",
+		Site:        site,
+		Orientation: goopendb.OrientationR0,
+		Direction:   goopendb.DirectionVERTICAL,
+		Spacing:     200,
+		SiteCount:   10,
+		BoundingBox: &goopendb.Rect{XMax: 100, YMax: 2000},
+	}
+	var instances []*goopendb.Instance
+	add := func(name string, typ goopendb.MasterType, xMin, yMin, xMax, yMax int) {
+		instances = append(instances, &goopendb.Instance{
+			ID:          len(instances) + 1,
+			Name:        name,
+			IsPlaced:    true,
+			MasterType:  typ,
+			Orientation: goopendb.OrientationR0,
+			BoundingBox: &goopendb.Rect{XMin: xMin, YMin: yMin, XMax: xMax, YMax: yMax},
+		})
+	}
+	add("legal", goopendb.MasterTypeCORE, 0, 400, 100, 600)
+	add("offsite", goopendb.MasterTypeCORE, 0, 650, 100, 850)
+	// Covers and rings are not placed in rows
+	add("cover", goopendb.MasterTypeCOVER, 500, 0, 1000, 500)
+	add("ring", goopendb.MasterTypeRING, 500, 1000, 1000, 1500)
+	design := &goopendb.Design{
+		Instances: instances,
+		Rows:      []*goopendb.Row{row},
+		Sites:     []*goopendb.Site{site},
+		Core:      &goopendb.Rect{XMax: 100, YMax: 2000},
+	}
+	markers := CheckPlacement(design)
+	if len(markers) != 1 || markers[0].Type != goopendb.MarkerOffSite || !reflect.DeepEqual(markers[0].Instances, []string{"offsite"}) {
+		for _, m := range markers {
+			t.Logf("%+v", *m)
+		}
+		t.Errorf("Expected an off site marker of the vertical row")
+	}
+}